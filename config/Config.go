@@ -0,0 +1,280 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds every startup setting. Values are layered, each layer overriding the previous one:
+// defaults, the JSON config file, environment variables, then command line flags.
+type Config struct {
+	ListenAddress string         `json:"listenAddress"`
+	StoreBackend  string         `json:"storeBackend"`
+	DatabaseURL   string         `json:"databaseUrl"`
+	Stage         string         `json:"stage"`
+	TablePrefix   string         `json:"tablePrefix"`
+	FeedMode      string         `json:"feedMode"`
+	CacheSize     int            `json:"cacheSize"`
+	CacheTTL      string         `json:"cacheTtl"`
+	Admins        []string       `json:"admins"` // usernames allowed to manage tags
+	DynamoDB      DynamoDBConfig `json:"dynamoDB"`
+}
+
+type DynamoDBConfig struct {
+	// Endpoint overrides the AWS endpoint, e.g. http://localhost:8000 for DynamoDB Local
+	Endpoint        string `json:"endpoint"`
+	Region          string `json:"region"`
+	AccessKeyId     string `json:"accessKeyId"`
+	SecretAccessKey string `json:"secretAccessKey"`
+	SessionToken    string `json:"sessionToken"`
+}
+
+var storeBackends = map[string]bool{
+	"dynamodb": true,
+	"sqlite":   true,
+	"postgres": true,
+	"memory":   true,
+}
+
+var feedModes = map[string]bool{
+	"read":  true,
+	"write": true,
+}
+
+var tableNamePartPattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]*$`)
+
+func Default() Config {
+	return Config{
+		ListenAddress: ":8080",
+		StoreBackend:  "dynamodb",
+		TablePrefix:   "realworld",
+		FeedMode:      "read",
+		CacheSize:     10000,
+		CacheTTL:      "30s",
+	}
+}
+
+// Load builds the configuration from the config file, the environment and args, then validates it.
+// It registers its flags on flags, so callers can add their own flags before calling Load.
+func Load(flags *flag.FlagSet, args []string) (Config, error) {
+	configFile := flags.String("config", os.Getenv("CONFIG_FILE"), "path of a JSON config file")
+	overrides := Config{}
+	flags.IntVar(&overrides.CacheSize, "cache-size", -1, "entries of the user, article and tag cache, 0 disables it")
+	flags.StringVar(&overrides.CacheTTL, "cache-ttl", "", "time an entry stays in the cache, e.g. 30s")
+	flags.StringVar(&overrides.ListenAddress, "listen", "", "address to listen on, e.g. :8080")
+	flags.StringVar(&overrides.StoreBackend, "store", "", "storage backend: dynamodb, sqlite, postgres or memory")
+	flags.StringVar(&overrides.DatabaseURL, "database-url", "", "database file or connection string of the SQL backends")
+	flags.StringVar(&overrides.Stage, "stage", "", "stage the DynamoDB tables are named after")
+	flags.StringVar(&overrides.TablePrefix, "table-prefix", "", "prefix of the DynamoDB table names")
+	flags.StringVar(&overrides.FeedMode, "feed-mode", "", "feed assembly: read merges followed authors per request, write keeps a timeline per user")
+	flags.StringVar(&overrides.DynamoDB.Endpoint, "dynamodb-endpoint", "", "DynamoDB endpoint override, e.g. http://localhost:8000")
+	flags.StringVar(&overrides.DynamoDB.Region, "dynamodb-region", "", "AWS region of DynamoDB")
+	admins := flags.String("admins", "", "comma separated usernames allowed to manage tags")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return Config{}, err
+	}
+	overrides.Admins = splitList(*admins)
+
+	config := Default()
+
+	if *configFile != "" {
+		err = config.loadFile(*configFile)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
+	err = config.loadEnv(os.LookupEnv)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.merge(overrides)
+
+	err = config.Validate()
+	if err != nil {
+		return Config{}, err
+	}
+
+	return config, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+
+	err = decoder.Decode(c)
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func (c *Config) loadEnv(lookup func(string) (string, bool)) error {
+	set := func(field *string, names ...string) {
+		for _, name := range names {
+			if value, ok := lookup(name); ok && value != "" {
+				*field = value
+				return
+			}
+		}
+	}
+
+	set(&c.ListenAddress, "LISTEN_ADDRESS")
+	set(&c.StoreBackend, "STORE_BACKEND")
+	set(&c.DatabaseURL, "DATABASE_URL")
+	set(&c.Stage, "STAGE")
+	set(&c.TablePrefix, "TABLE_PREFIX")
+	set(&c.FeedMode, "FEED_MODE")
+	set(&c.DynamoDB.Endpoint, "DYNAMODB_ENDPOINT")
+	set(&c.DynamoDB.Region, "AWS_REGION", "AWS_DEFAULT_REGION")
+	set(&c.DynamoDB.AccessKeyId, "AWS_ACCESS_KEY_ID")
+	set(&c.DynamoDB.SecretAccessKey, "AWS_SECRET_ACCESS_KEY")
+	set(&c.DynamoDB.SessionToken, "AWS_SESSION_TOKEN")
+	set(&c.CacheTTL, "CACHE_TTL")
+
+	if value, ok := lookup("ADMINS"); ok && value != "" {
+		c.Admins = splitList(value)
+	}
+
+	if value, ok := lookup("CACHE_SIZE"); ok && value != "" {
+		cacheSize, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("CACHE_SIZE %q: %w", value, err)
+		}
+		c.CacheSize = cacheSize
+	}
+
+	return nil
+}
+
+// merge copies the non-empty settings of overrides into c.
+func (c *Config) merge(overrides Config) {
+	set := func(field *string, value string) {
+		if value != "" {
+			*field = value
+		}
+	}
+
+	set(&c.ListenAddress, overrides.ListenAddress)
+	set(&c.StoreBackend, overrides.StoreBackend)
+	set(&c.DatabaseURL, overrides.DatabaseURL)
+	set(&c.Stage, overrides.Stage)
+	set(&c.TablePrefix, overrides.TablePrefix)
+	set(&c.FeedMode, overrides.FeedMode)
+	set(&c.DynamoDB.Endpoint, overrides.DynamoDB.Endpoint)
+	set(&c.DynamoDB.Region, overrides.DynamoDB.Region)
+	set(&c.DynamoDB.AccessKeyId, overrides.DynamoDB.AccessKeyId)
+	set(&c.DynamoDB.SecretAccessKey, overrides.DynamoDB.SecretAccessKey)
+	set(&c.DynamoDB.SessionToken, overrides.DynamoDB.SessionToken)
+	set(&c.CacheTTL, overrides.CacheTTL)
+
+	if overrides.CacheSize >= 0 {
+		c.CacheSize = overrides.CacheSize
+	}
+
+	if len(overrides.Admins) > 0 {
+		c.Admins = overrides.Admins
+	}
+}
+
+// splitList splits a comma separated list, leaving out blank items.
+func splitList(value string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func (c Config) Validate() error {
+	_, _, err := net.SplitHostPort(c.ListenAddress)
+	if err != nil {
+		return fmt.Errorf("listen address %q: %w", c.ListenAddress, err)
+	}
+
+	if !storeBackends[c.StoreBackend] {
+		return fmt.Errorf("unknown storage backend %q", c.StoreBackend)
+	}
+
+	if !feedModes[c.FeedMode] {
+		return fmt.Errorf("unknown feed mode %q, expected read or write", c.FeedMode)
+	}
+
+	if c.CacheSize < 0 {
+		return errors.New("cache size can't be negative")
+	}
+
+	ttl, err := time.ParseDuration(c.CacheTTL)
+	if err != nil || ttl <= 0 {
+		return fmt.Errorf("cache ttl %q must be a positive duration, e.g. 30s", c.CacheTTL)
+	}
+
+	if c.StoreBackend == "postgres" && c.DatabaseURL == "" {
+		return errors.New("database url is required by the postgres backend")
+	}
+
+	if c.StoreBackend == "dynamodb" {
+		return c.validateDynamoDB()
+	}
+
+	return nil
+}
+
+// CacheTTLDuration returns the validated CacheTTL.
+func (c Config) CacheTTLDuration() time.Duration {
+	ttl, _ := time.ParseDuration(c.CacheTTL)
+	return ttl
+}
+
+func (c Config) validateDynamoDB() error {
+	if c.Stage == "" {
+		return errors.New("stage is required by the dynamodb backend")
+	}
+
+	if c.TablePrefix == "" {
+		return errors.New("table prefix can't be blank")
+	}
+
+	if !tableNamePartPattern.MatchString(c.Stage) || !tableNamePartPattern.MatchString(c.TablePrefix) {
+		return errors.New("stage and table prefix may only contain letters, digits, '_', '-' and '.'")
+	}
+
+	if c.DynamoDB.Endpoint != "" {
+		endpoint, err := url.Parse(c.DynamoDB.Endpoint)
+		if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+			return fmt.Errorf("dynamodb endpoint %q must be an absolute URL", c.DynamoDB.Endpoint)
+		}
+
+		// The SDK can't resolve a region from a custom endpoint
+		if c.DynamoDB.Region == "" {
+			return errors.New("dynamodb region is required with a custom endpoint")
+		}
+	}
+
+	if (c.DynamoDB.AccessKeyId == "") != (c.DynamoDB.SecretAccessKey == "") {
+		return errors.New("dynamodb access key id and secret access key must be set together")
+	}
+
+	return nil
+}
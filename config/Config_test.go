@@ -0,0 +1,68 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(path, []byte(`{"listenAddress": ":9000", "stage": "file", "dynamoDB": {"region": "eu-west-1"}}`), 0600)
+	assert.NoError(t, err)
+
+	t.Setenv("STAGE", "env")
+	t.Setenv("DYNAMODB_ENDPOINT", "http://localhost:8000")
+	t.Setenv("ADMINS", "alice, bob")
+
+	config, err := Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-config", path, "-stage", "flag"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, ":9000", config.ListenAddress)
+	assert.Equal(t, "flag", config.Stage)
+	assert.Equal(t, "realworld", config.TablePrefix)
+	assert.Equal(t, "http://localhost:8000", config.DynamoDB.Endpoint)
+	assert.Equal(t, "eu-west-1", config.DynamoDB.Region)
+	assert.Equal(t, []string{"alice", "bob"}, config.Admins)
+
+	config, err = Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-stage", "flag", "-dynamodb-region", "local", "-admins", "carol"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"carol"}, config.Admins)
+}
+
+func TestValidate(t *testing.T) {
+	valid := Default()
+	valid.Stage = "dev"
+
+	testCases := []struct {
+		name   string
+		modify func(c *Config)
+		valid  bool
+	}{
+		{"defaults with stage", func(c *Config) {}, true},
+		{"missing stage", func(c *Config) { c.Stage = "" }, false},
+		{"bad listen address", func(c *Config) { c.ListenAddress = "8080" }, false},
+		{"unknown backend", func(c *Config) { c.StoreBackend = "mongodb" }, false},
+		{"sqlite without stage", func(c *Config) { c.StoreBackend = "sqlite"; c.Stage = "" }, true},
+		{"postgres without url", func(c *Config) { c.StoreBackend = "postgres" }, false},
+		{"relative endpoint", func(c *Config) { c.DynamoDB.Endpoint = "localhost:8000"; c.DynamoDB.Region = "local" }, false},
+		{"endpoint without region", func(c *Config) { c.DynamoDB.Endpoint = "http://localhost:8000" }, false},
+		{"endpoint with region", func(c *Config) { c.DynamoDB.Endpoint = "http://localhost:8000"; c.DynamoDB.Region = "local" }, true},
+		{"access key without secret", func(c *Config) { c.DynamoDB.AccessKeyId = "key" }, false},
+		{"invalid stage", func(c *Config) { c.Stage = "a/b" }, false},
+		{"write feed mode", func(c *Config) { c.FeedMode = "write" }, true},
+		{"unknown feed mode", func(c *Config) { c.FeedMode = "push" }, false},
+		{"cache disabled", func(c *Config) { c.CacheSize = 0 }, true},
+		{"negative cache size", func(c *Config) { c.CacheSize = -1 }, false},
+		{"bad cache ttl", func(c *Config) { c.CacheTTL = "30" }, false},
+	}
+
+	for _, testCase := range testCases {
+		config := valid
+		testCase.modify(&config)
+		assert.Equal(t, testCase.valid, config.Validate() == nil, testCase.name)
+	}
+}
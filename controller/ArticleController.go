@@ -1,8 +1,11 @@
 package controller
 
 import (
+	"errors"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"realworld-go-nolambda/util"
@@ -15,23 +18,39 @@ import (
 type AResponse struct {
 	Articles      []ArticleResponse `json:"articles"`
 	ArticlesCount int               `json:"articlesCount"`
+	Next          string            `json:"next,omitempty"`
 }
 
 type A1Response struct {
 	Article ArticleResponse `json:"article"`
 }
 
+
 type ArticleResponse struct {
-	Slug           string         `json:"slug"`
-	Title          string         `json:"title"`
-	Description    string         `json:"description"`
-	Body           string         `json:"body"`
-	TagList        []string       `json:"tagList"`
-	CreatedAt      string         `json:"createdAt"`
-	UpdatedAt      string         `json:"updatedAt"`
-	Favorited      bool           `json:"favorited"`
-	FavoritesCount int64          `json:"favoritesCount"`
-	Author         AuthorResponse `json:"author"`
+	Slug           string            `json:"slug"`
+	Title          string            `json:"title"`
+	Description    string            `json:"description"`
+	Body           string            `json:"body"`
+	BodyHtml       string            `json:"bodyHtml,omitempty"` // Only with html=true
+	TagList        []string          `json:"tagList"`
+	CreatedAt      string            `json:"createdAt"`
+	UpdatedAt      string            `json:"updatedAt"`
+	Favorited      bool              `json:"favorited"`
+	FavoritesCount int64             `json:"favoritesCount"`
+	Status         string            `json:"status"`
+	PublishAt      string            `json:"publishAt,omitempty"`
+	ExpireAt       string            `json:"expireAt,omitempty"`
+	WordCount      int64             `json:"wordCount"`
+	ReadingTime    int64             `json:"readingTime"` // estimated minutes
+	Outline        []HeadingResponse `json:"outline"`
+	Author         AuthorResponse    `json:"author"`
+}
+
+// HeadingResponse is a heading of an article's outline. Anchor is the id of the heading in bodyHtml.
+type HeadingResponse struct {
+	Level  int    `json:"level"`
+	Text   string `json:"text"`
+	Anchor string `json:"anchor"`
 }
 
 type AuthorResponse struct {
@@ -50,6 +69,9 @@ type ArticlePutRequest struct {
 	Description string   `json:"description"`
 	Body        string   `json:"body"`
 	TagList     []string `json:"tagList"`
+	Status      string   `json:"status"`    // draft or published, only read on creation
+	PublishAt   *string  `json:"publishAt"` // RFC 3339, an empty string clears it
+	ExpireAt    *string  `json:"expireAt"`  // RFC 3339, an empty string clears it
 }
 
 func GetArticlesFeed(w http.ResponseWriter, r *http.Request) {
@@ -58,19 +80,13 @@ func GetArticlesFeed(w http.ResponseWriter, r *http.Request) {
 		util.NewErrorResponse(http.StatusUnauthorized, err, w)
 	}
 
-	query := r.URL.Query()
-	offset, err := strconv.Atoi(query.Get("offset"))
-
+	page, err := parsePage(r.URL.Query())
 	if err != nil {
-		offset = 0
-	}
-
-	limit, err := strconv.Atoi(query.Get("limit"))
-	if err != nil {
-		limit = 20
+		util.NewErrorResponse(http.StatusBadRequest, err, w)
+		return
 	}
 
-	articles, err := service.GetFeed(user.Username, offset, limit)
+	articles, next, err := service.GetFeed(r.Context(), user.Username, page)
 	if err != nil {
 		util.NewErrorResponse(http.StatusBadRequest, err, w)
 	}
@@ -81,30 +97,14 @@ func GetArticlesFeed(w http.ResponseWriter, r *http.Request) {
 	}
 
 	articleResponses := make([]ArticleResponse, 0, len(articles))
-
 	for i, article := range articles {
-		articleResponses = append(articleResponses, ArticleResponse{
-			Slug:           article.Slug,
-			Title:          article.Title,
-			Description:    article.Description,
-			Body:           article.Body,
-			TagList:        article.TagList,
-			CreatedAt:      time.Unix(0, article.CreatedAt).Format(model.TimestampFormat),
-			UpdatedAt:      time.Unix(0, article.UpdatedAt).Format(model.TimestampFormat),
-			Favorited:      isFavorited[i],
-			FavoritesCount: article.FavoritesCount,
-			Author: AuthorResponse{
-				Username:  authors[i].Username,
-				Bio:       authors[i].Bio,
-				Image:     authors[i].Image,
-				Following: true,
-			},
-		})
+		articleResponses = append(articleResponses, newArticleResponse(r, article, isFavorited[i], authors[i], true))
 	}
 
 	response := AResponse{
 		Articles:      articleResponses,
 		ArticlesCount: len(articleResponses),
+		Next:          setNextLink(w, r, next),
 	}
 
 	util.NewSuccessResponse(response, w, r)
@@ -118,21 +118,19 @@ func GetArticles(w http.ResponseWriter, r *http.Request) {
 	}
 	query := r.URL.Query()
 
-	offset, err := strconv.Atoi(query.Get("offset"))
+	page, err := parsePage(query)
 	if err != nil {
-		offset = 0
+		util.NewErrorResponse(http.StatusBadRequest, err, w)
+		return
 	}
 
-	limit, err := strconv.Atoi(query.Get("limit"))
+	articleQuery, err := parseArticleQuery(query)
 	if err != nil {
-		limit = 20
+		util.NewErrorResponse(http.StatusBadRequest, err, w)
+		return
 	}
 
-	author := query.Get("author")
-	tag := query.Get("tag")
-	favorited := query.Get("favorited")
-
-	articles, err := service.GetArticles(offset, limit, author, tag, favorited)
+	articles, next, err := service.FindArticles(articleQuery, page)
 	if err != nil {
 		util.NewErrorResponse(http.StatusNotFound, err, w)
 		return
@@ -145,30 +143,14 @@ func GetArticles(w http.ResponseWriter, r *http.Request) {
 	}
 
 	articleResponses := make([]ArticleResponse, 0, len(articles))
-
 	for i, article := range articles {
-		articleResponses = append(articleResponses, ArticleResponse{
-			Slug:           article.Slug,
-			Title:          article.Title,
-			Description:    article.Description,
-			Body:           article.Body,
-			TagList:        article.TagList,
-			CreatedAt:      time.Unix(0, article.CreatedAt).Format(model.TimestampFormat),
-			UpdatedAt:      time.Unix(0, article.UpdatedAt).Format(model.TimestampFormat),
-			Favorited:      isFavorited[i],
-			FavoritesCount: article.FavoritesCount,
-			Author: AuthorResponse{
-				Username:  authors[i].Username,
-				Bio:       authors[i].Bio,
-				Image:     authors[i].Image,
-				Following: following[i],
-			},
-		})
+		articleResponses = append(articleResponses, newArticleResponse(r, article, isFavorited[i], authors[i], following[i]))
 	}
 
 	response := AResponse{
 		Articles:      articleResponses,
 		ArticlesCount: len(articleResponses),
+		Next:          setNextLink(w, r, next),
 	}
 
 	util.NewSuccessResponse(response, w, r)
@@ -182,40 +164,28 @@ func GetArticleSlug(w http.ResponseWriter, r *http.Request) {
 	article, err := service.GetArticleBySlug(slug)
 	if err != nil {
 		util.NewErrorResponse(http.StatusInternalServerError, err, w)
+		return
 	}
 
-	isFavorited, authors, following, err := service.GetArticleRelatedProperties(user, []model.Article{article}, true)
-	if err != nil {
-		util.NewErrorResponse(http.StatusInternalServerError, err, w)
+	if !article.IsVisibleTo(usernameOf(user)) {
+		util.NewErrorResponse(http.StatusNotFound, model.NewInputError("slug", "not found"), w)
+		return
 	}
 
-	response := A1Response{
-		Article: ArticleResponse{
-			Slug:           article.Slug,
-			Title:          article.Title,
-			Description:    article.Description,
-			Body:           article.Body,
-			TagList:        article.TagList,
-			CreatedAt:      time.Unix(0, article.CreatedAt).Format(model.TimestampFormat),
-			UpdatedAt:      time.Unix(0, article.UpdatedAt).Format(model.TimestampFormat),
-			Favorited:      isFavorited[0],
-			FavoritesCount: article.FavoritesCount,
-			Author: AuthorResponse{
-				Username:  authors[0].Username,
-				Bio:       authors[0].Bio,
-				Image:     authors[0].Image,
-				Following: following[0],
-			},
-		},
+	w.Header().Set("ETag", article.ETag())
+	if util.MatchETag(r.Header.Get("If-None-Match"), article.ETag(), true) {
+		util.NewNotModifiedResponse(w)
+		return
 	}
 
-	util.NewSuccessResponse(response, w, r)
+	writeArticleResponse(w, r, user, article)
 }
 
 func PutArticleSlug(w http.ResponseWriter, r *http.Request) {
 	user, _, err := service.GetCurrentUser(r.Header.Get("Authorization"))
 	if err != nil {
 		util.NewUnauthorizedResponse(w)
+		return
 	}
 
 	request := &APuRequest{}
@@ -229,45 +199,31 @@ func PutArticleSlug(w http.ResponseWriter, r *http.Request) {
 	slug := vars["slug"]
 	oldArticle, err := service.GetArticleBySlug(slug)
 	if err != nil {
-		util.NewErrorResponse(http.StatusInternalServerError, err, w)
+		newLookupErrorResponse(err, w)
+		return
 	}
 
-	newArticle := createNewArticle(*request, oldArticle)
-
-	err = service.UpdateArticle(oldArticle, &newArticle)
+	newArticle, err := createNewArticle(*request, oldArticle)
 	if err != nil {
-		util.NewErrorResponse(http.StatusInternalServerError, err, w)
+		util.NewErrorResponse(http.StatusBadRequest, err, w)
+		return
 	}
 
-	isFavorited, authors, following, err := service.GetArticleRelatedProperties(user, []model.Article{newArticle}, true)
-	if err != nil {
-		util.NewErrorResponse(http.StatusInternalServerError, err, w)
+	err = service.UpdateArticle(oldArticle, &newArticle, user.Username, r.Header.Get("If-Match"))
+	if errors.Is(err, service.ErrArticleModified) {
+		util.NewPreconditionFailedResponse(w)
+		return
 	}
 
-	response := A1Response{
-		Article: ArticleResponse{
-			Slug:           newArticle.Slug,
-			Title:          newArticle.Title,
-			Description:    newArticle.Description,
-			Body:           newArticle.Body,
-			TagList:        newArticle.TagList,
-			CreatedAt:      time.Unix(0, newArticle.CreatedAt).Format(model.TimestampFormat),
-			UpdatedAt:      time.Unix(0, newArticle.UpdatedAt).Format(model.TimestampFormat),
-			Favorited:      isFavorited[0],
-			FavoritesCount: newArticle.FavoritesCount,
-			Author: AuthorResponse{
-				Username:  authors[0].Username,
-				Bio:       authors[0].Bio,
-				Image:     authors[0].Image,
-				Following: following[0],
-			},
-		},
+	if err != nil {
+		newLookupErrorResponse(err, w)
+		return
 	}
 
-	util.NewSuccessResponse(response, w, r)
+	writeArticleResponse(w, r, user, newArticle)
 }
 
-func createNewArticle(request APuRequest, oldArticle model.Article) model.Article {
+func createNewArticle(request APuRequest, oldArticle model.Article) (model.Article, error) {
 	newArticle := model.Article{
 		ArticleId:      oldArticle.ArticleId,
 		Title:          request.Article.Title,
@@ -277,6 +233,9 @@ func createNewArticle(request APuRequest, oldArticle model.Article) model.Articl
 		CreatedAt:      oldArticle.CreatedAt,
 		UpdatedAt:      time.Now().UTC().UnixNano(),
 		FavoritesCount: oldArticle.FavoritesCount,
+		Status:         oldArticle.Status,
+		PublishAt:      oldArticle.PublishAt,
+		ExpireAt:       oldArticle.ExpireAt,
 		Author:         oldArticle.Author,
 	}
 
@@ -296,7 +255,147 @@ func createNewArticle(request APuRequest, oldArticle model.Article) model.Articl
 		newArticle.TagList = oldArticle.TagList
 	}
 
-	return newArticle
+	err := parseArticleSchedule(request.Article, &newArticle)
+	if err != nil {
+		return model.Article{}, err
+	}
+
+	return newArticle, nil
+}
+
+// parseArticleSchedule sets the publication and expiry times of article that request specifies.
+func parseArticleSchedule(request ArticlePutRequest, article *model.Article) error {
+	var err error
+
+	if request.PublishAt != nil {
+		article.PublishAt, err = parseOptionalTimestamp("publishAt", *request.PublishAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	if request.ExpireAt != nil {
+		article.ExpireAt, err = parseOptionalTimestamp("expireAt", *request.ExpireAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseOptionalTimestamp parses an RFC 3339 timestamp into Unix nanoseconds, 0 if it is empty.
+func parseOptionalTimestamp(field string, value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	timestamp, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return 0, model.NewInputError(field, "must be an RFC 3339 timestamp")
+	}
+
+	return timestamp.UnixNano(), nil
+}
+
+// parseArticleQuery reads the filters and order of an article listing. Tags are given by repeating tag or as a comma
+// separated tags, and match any of them unless tagMode is all. from and until bound CreatedAt, an until date includes that day.
+func parseArticleQuery(query url.Values) (service.ArticleQuery, error) {
+	tags := make([]string, 0)
+	for _, tag := range query["tag"] {
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	for _, tag := range strings.Split(query.Get("tags"), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	tagMode := query.Get("tagMode")
+	if tagMode != "" && tagMode != "any" && tagMode != "all" {
+		return service.ArticleQuery{}, model.NewInputError("tagMode", "must be any or all")
+	}
+
+	createdFrom, err := parseDateBound("from", query.Get("from"), false)
+	if err != nil {
+		return service.ArticleQuery{}, err
+	}
+
+	createdUntil, err := parseDateBound("until", query.Get("until"), true)
+	if err != nil {
+		return service.ArticleQuery{}, err
+	}
+
+	minReadingTime, err := parseReadingTime("minReadingTime", query.Get("minReadingTime"))
+	if err != nil {
+		return service.ArticleQuery{}, err
+	}
+
+	maxReadingTime, err := parseReadingTime("maxReadingTime", query.Get("maxReadingTime"))
+	if err != nil {
+		return service.ArticleQuery{}, err
+	}
+
+	return service.ArticleQuery{
+		Author:         query.Get("author"),
+		Tags:           tags,
+		AllTags:        tagMode == "all",
+		Favorited:      query.Get("favorited"),
+		CreatedFrom:    createdFrom,
+		CreatedUntil:   createdUntil,
+		MinReadingTime: minReadingTime,
+		MaxReadingTime: maxReadingTime,
+		Order:          query.Get("sort"),
+	}, nil
+}
+
+// parseReadingTime parses a bound of the reading time in minutes, 0 if value is empty.
+func parseReadingTime(field string, value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	minutes, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || minutes <= 0 {
+		return 0, model.NewInputError(field, "must be a positive number of minutes")
+	}
+
+	return minutes, nil
+}
+
+// parseDateBound parses an RFC 3339 timestamp or a date to Unix nanoseconds, 0 if value is empty.
+// A date is its first instant in UTC, or the first instant of the next day if endOfDay is set.
+func parseDateBound(field string, value string, endOfDay bool) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	timestamp, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return timestamp.UnixNano(), nil
+	}
+
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return 0, model.NewInputError(field, "must be an RFC 3339 timestamp or a date")
+	}
+
+	if endOfDay {
+		date = date.AddDate(0, 0, 1)
+	}
+
+	return date.UnixNano(), nil
+}
+
+// formatOptionalTimestamp formats Unix nanoseconds like every other timestamp, as an empty string if it is 0.
+func formatOptionalTimestamp(timestamp int64) string {
+	if timestamp == 0 {
+		return ""
+	}
+
+	return time.Unix(0, timestamp).UTC().Format(model.TimestampFormat)
 }
 
 func PostArticles(w http.ResponseWriter, r *http.Request) {
@@ -311,45 +410,37 @@ func PostArticles(w http.ResponseWriter, r *http.Request) {
 		util.NewErrorResponse(http.StatusBadRequest, err, w)
 		return
 	}
-	now := time.Now().UTC()
-	nowUnixNano := now.UnixNano()
-	nowStr := now.Format(model.TimestampFormat)
+	nowUnixNano := time.Now().UTC().UnixNano()
 
 	article := model.Article{
 		Title:       request.Article.Title,
 		Description: request.Article.Description,
 		Body:        request.Article.Body,
-		TagList:     request.Article.TagList, // TODO .distinct()
+		TagList:     request.Article.TagList,
+		Status:      request.Article.Status,
 		CreatedAt:   nowUnixNano,
 		UpdatedAt:   nowUnixNano,
 		Author:      user.Username,
 	}
 
+	err = parseArticleSchedule(request.Article, &article)
+	if err != nil {
+		util.NewErrorResponse(http.StatusBadRequest, err, w)
+		return
+	}
+
 	err = service.PutArticle(&article)
 	if err != nil {
 		util.NewErrorResponse(http.StatusInternalServerError, err, w)
+		return
 	}
 
+	// A new article is favorited by no one, and its author can't follow themselves
 	response := A1Response{
-		Article: ArticleResponse{
-			Slug:           article.Slug,
-			Title:          article.Title,
-			Description:    article.Description,
-			Body:           article.Body,
-			TagList:        article.TagList,
-			CreatedAt:      nowStr,
-			UpdatedAt:      nowStr,
-			Favorited:      false,
-			FavoritesCount: 0,
-			Author: AuthorResponse{
-				Username:  user.Username,
-				Bio:       user.Bio,
-				Image:     user.Image,
-				Following: false,
-			},
-		},
+		Article: newArticleResponse(r, article, false, *user, false),
 	}
 
+	w.Header().Set("ETag", article.ETag())
 	util.NewSuccessResponse(response, w, r)
 }
 
@@ -361,7 +452,11 @@ func DeleteArticleSlug(w http.ResponseWriter, r *http.Request) {
 
 	vars := mux.Vars(r)
 	slug := vars["slug"]
-	err = service.DeleteArticle(slug, user.Username)
+	err = service.DeleteArticle(slug, user.Username, r.Header.Get("If-Match"))
+	if errors.Is(err, service.ErrArticleModified) {
+		util.NewPreconditionFailedResponse(w)
+		return
+	}
 
 	if err != nil {
 		util.NewErrorResponse(http.StatusInternalServerError, err, w)
@@ -369,3 +464,86 @@ func DeleteArticleSlug(w http.ResponseWriter, r *http.Request) {
 
 	util.NewSuccessResponse(nil, w, r)
 }
+
+// usernameOf returns the username of the current user, empty for anonymous users.
+func usernameOf(user *model.User) string {
+	if user == nil {
+		return ""
+	}
+	return user.Username
+}
+
+// newLookupErrorResponse responds to an error looking something up with 404 if it wasn't found,
+// 422 for other input errors and 500 for the rest.
+func newLookupErrorResponse(err error, w http.ResponseWriter) {
+	if _, ok := err.(model.InputError); !ok {
+		util.NewErrorResponse(http.StatusInternalServerError, err, w)
+	} else if model.IsNotFound(err) {
+		util.NewErrorResponse(http.StatusNotFound, err, w)
+	} else {
+		util.NewErrorResponse(http.StatusUnprocessableEntity, err, w)
+	}
+}
+
+// newOutlineResponse lists the headings of an outline, none rather than null for articles without one.
+func newOutlineResponse(outline []model.ArticleHeading) []HeadingResponse {
+	headings := make([]HeadingResponse, 0, len(outline))
+	for _, heading := range outline {
+		headings = append(headings, HeadingResponse{
+			Level:  heading.Level,
+			Text:   heading.Text,
+			Anchor: heading.Anchor,
+		})
+	}
+	return headings
+}
+
+// writeArticleResponse responds with a single article as seen by user, tagged with its ETag.
+func writeArticleResponse(w http.ResponseWriter, r *http.Request, user *model.User, article model.Article) {
+	isFavorited, authors, following, err := service.GetArticleRelatedProperties(user, []model.Article{article}, true)
+	if err != nil {
+		util.NewErrorResponse(http.StatusInternalServerError, err, w)
+		return
+	}
+
+	response := A1Response{
+		Article: newArticleResponse(r, article, isFavorited[0], authors[0], following[0]),
+	}
+
+	w.Header().Set("ETag", article.ETag())
+	util.NewSuccessResponse(response, w, r)
+}
+
+// newArticleResponse builds the response for an article, as favorited or not by the current user, written by author
+// and with the author followed or not. It renders bodyHtml if the request asks for it.
+func newArticleResponse(r *http.Request, article model.Article, favorited bool, author model.User, following bool) ArticleResponse {
+	response := ArticleResponse{
+		Slug:           article.Slug,
+		Title:          article.Title,
+		Description:    article.Description,
+		Body:           article.Body,
+		TagList:        article.TagList,
+		CreatedAt:      time.Unix(0, article.CreatedAt).Format(model.TimestampFormat),
+		UpdatedAt:      time.Unix(0, article.UpdatedAt).Format(model.TimestampFormat),
+		Favorited:      favorited,
+		FavoritesCount: article.FavoritesCount,
+		Status:         article.EffectiveStatus(),
+		PublishAt:      formatOptionalTimestamp(article.PublishAt),
+		ExpireAt:       formatOptionalTimestamp(article.ExpireAt),
+		WordCount:      article.WordCount,
+		ReadingTime:    article.ReadingTime,
+		Outline:        newOutlineResponse(article.Outline),
+		Author: AuthorResponse{
+			Username:  author.Username,
+			Bio:       author.Bio,
+			Image:     author.Image,
+			Following: following,
+		},
+	}
+
+	if wantsBodyHtml(r) {
+		response.BodyHtml = service.RenderArticleBody(article)
+	}
+
+	return response
+}
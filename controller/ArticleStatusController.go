@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"realworld-go-nolambda/model"
+	"realworld-go-nolambda/service"
+	"realworld-go-nolambda/util"
+
+	"github.com/gorilla/mux"
+)
+
+func PostArticlePublish(w http.ResponseWriter, r *http.Request) {
+	setArticleStatus(w, r, service.PublishArticle)
+}
+
+func PostArticleUnpublish(w http.ResponseWriter, r *http.Request) {
+	setArticleStatus(w, r, service.UnpublishArticle)
+}
+
+func setArticleStatus(w http.ResponseWriter, r *http.Request, transition func(slug, username, ifMatch string) (model.Article, error)) {
+	user, _, err := service.GetCurrentUser(r.Header.Get("Authorization"))
+	if err != nil {
+		util.NewUnauthorizedResponse(w)
+		return
+	}
+
+	article, err := transition(mux.Vars(r)["slug"], user.Username, r.Header.Get("If-Match"))
+	if errors.Is(err, service.ErrArticleModified) {
+		util.NewPreconditionFailedResponse(w)
+		return
+	}
+
+	if err != nil {
+		util.NewErrorResponse(http.StatusUnprocessableEntity, err, w)
+		return
+	}
+
+	writeArticleResponse(w, r, user, article)
+}
+
+// GetUserDrafts lists the draft and archived articles of the current user.
+func GetUserDrafts(w http.ResponseWriter, r *http.Request) {
+	user, _, err := service.GetCurrentUser(r.Header.Get("Authorization"))
+	if err != nil {
+		util.NewUnauthorizedResponse(w)
+		return
+	}
+
+	page, err := parsePage(r.URL.Query())
+	if err != nil {
+		util.NewErrorResponse(http.StatusBadRequest, err, w)
+		return
+	}
+
+	articles, next, err := service.GetDrafts(user.Username, page)
+	if err != nil {
+		util.NewErrorResponse(http.StatusBadRequest, err, w)
+		return
+	}
+
+	articleResponses := make([]ArticleResponse, 0, len(articles))
+	for _, article := range articles {
+		articleResponses = append(articleResponses, newArticleResponse(r, article, false, *user, false))
+	}
+
+	response := AResponse{
+		Articles:      articleResponses,
+		ArticlesCount: len(articleResponses),
+		Next:          setNextLink(w, r, next),
+	}
+
+	util.NewSuccessResponse(response, w, r)
+}
@@ -14,7 +14,9 @@ import (
 )
 
 type CResponse struct {
-	Comments []CommentResponse `json:"comments"`
+	Comments      []CommentResponse `json:"comments"`
+	CommentsCount int64             `json:"commentsCount"`
+	Next          string            `json:"next,omitempty"`
 }
 
 type C1Response struct {
@@ -22,11 +24,30 @@ type C1Response struct {
 }
 
 type CommentResponse struct {
-	Id        int64          `json:"id"`
-	CreatedAt string         `json:"createdAt"`
-	UpdatedAt string         `json:"updatedAt"`
-	Body      string         `json:"body"`
-	Author    AuthorResponse `json:"author"`
+	Id         int64           `json:"id"`
+	CreatedAt  string          `json:"createdAt"`
+	UpdatedAt  string          `json:"updatedAt"`
+	Body       string          `json:"body"`
+	BodyHtml   string          `json:"bodyHtml,omitempty"` // Only with html=true
+	Author     *AuthorResponse `json:"author"`
+	ParentId   int64           `json:"parentId,omitempty"`
+	Depth      int             `json:"depth"`
+	ReplyCount int64           `json:"replyCount"`
+	Deleted    bool            `json:"deleted"`
+	Edited     bool            `json:"edited"`
+	// Reactions counts every reaction, OwnReactions lists the ones the current user added
+	Reactions    map[string]int64 `json:"reactions"`
+	OwnReactions []string         `json:"ownReactions"`
+}
+
+type CommentEditsResponse struct {
+	Edits []CommentEditResponse `json:"edits"`
+}
+
+type CommentEditResponse struct {
+	Body      string `json:"body"`
+	WrittenAt string `json:"writtenAt"`
+	EditedAt  string `json:"editedAt"`
 }
 
 type CRequest struct {
@@ -34,7 +55,8 @@ type CRequest struct {
 }
 
 type CommentRequest struct {
-	Body string `json:"body"`
+	Body     string `json:"body"`
+	ParentId int64  `json:"parentId"`
 }
 
 func DeleteComment(w http.ResponseWriter, r *http.Request) {
@@ -53,46 +75,206 @@ func DeleteComment(w http.ResponseWriter, r *http.Request) {
 	slug := vars["slug"]
 	err = service.DeleteComment(slug, commentId, user.Username)
 	if err != nil {
-		util.NewErrorResponse(http.StatusInternalServerError, err, w)
+		util.NewErrorResponse(http.StatusNotFound, err, w)
+		return
 	}
 
 	util.NewSuccessResponse(nil, w, r)
 }
 
+// PutComment replaces the body of a comment. Only its author can edit it.
+func PutComment(w http.ResponseWriter, r *http.Request) {
+	user, _, err := service.GetCurrentUser(r.Header.Get("Authorization"))
+	if err != nil {
+		util.NewUnauthorizedResponse(w)
+		return
+	}
+
+	vars := mux.Vars(r)
+	commentId, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		util.NewErrorResponse(http.StatusNotFound, model.NewInputError("id", "invalid"), w)
+		return
+	}
+
+	request := &CRequest{}
+	err = util.ParseBody(r, request)
+	if err != nil {
+		util.NewErrorResponse(http.StatusBadRequest, err, w)
+		return
+	}
+
+	comment, err := service.UpdateComment(vars["slug"], commentId, user.Username, request.Comment.Body)
+	if err != nil {
+		util.NewErrorResponse(http.StatusUnprocessableEntity, err, w)
+		return
+	}
+
+	ownReactions, err := service.GetOwnCommentReactions(user, []model.Comment{comment})
+	if err != nil {
+		util.NewErrorResponse(http.StatusInternalServerError, err, w)
+		return
+	}
+
+	commentResponse := newCommentResponse(comment, ownReactions[0])
+	commentResponse.Author = &AuthorResponse{
+		Username:  user.Username,
+		Bio:       user.Bio,
+		Image:     user.Image,
+		Following: false,
+	}
+
+	util.NewSuccessResponse(C1Response{Comment: commentResponse}, w, r)
+}
+
+// newCommentResponse fills in the fields of a comment but its author. ownReactions are the current user's reactions to it.
+func newCommentResponse(comment model.Comment, ownReactions []string) CommentResponse {
+	reactions := make(map[string]int64, len(model.Reactions))
+	for _, reaction := range model.Reactions {
+		reactions[reaction] = comment.ReactionCount(reaction)
+	}
+
+	if ownReactions == nil {
+		ownReactions = make([]string, 0)
+	}
+
+	return CommentResponse{
+		Id:           comment.CommentId,
+		Body:         comment.Body,
+		CreatedAt:    time.Unix(0, comment.CreatedAt).Format(model.TimestampFormat),
+		UpdatedAt:    time.Unix(0, comment.UpdatedAt).Format(model.TimestampFormat),
+		ParentId:     comment.ParentId,
+		Depth:        comment.Depth,
+		ReplyCount:   comment.ReplyCount,
+		Deleted:      comment.IsDeleted(),
+		Edited:       comment.IsEdited(),
+		Reactions:    reactions,
+		OwnReactions: ownReactions,
+	}
+}
+
+// GetCommentEdits returns the previous bodies of a comment, newest first. Only its author can see them.
+func GetCommentEdits(w http.ResponseWriter, r *http.Request) {
+	user, _, err := service.GetCurrentUser(r.Header.Get("Authorization"))
+	if err != nil {
+		util.NewUnauthorizedResponse(w)
+		return
+	}
+
+	vars := mux.Vars(r)
+	commentId, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		util.NewErrorResponse(http.StatusNotFound, model.NewInputError("id", "invalid"), w)
+		return
+	}
+
+	edits, err := service.GetCommentEdits(vars["slug"], commentId, user.Username)
+	if err != nil {
+		util.NewErrorResponse(http.StatusNotFound, err, w)
+		return
+	}
+
+	response := CommentEditsResponse{
+		Edits: make([]CommentEditResponse, 0, len(edits)),
+	}
+
+	for _, edit := range edits {
+		response.Edits = append(response.Edits, CommentEditResponse{
+			Body:      edit.Body,
+			WrittenAt: time.Unix(0, edit.WrittenAt).Format(model.TimestampFormat),
+			EditedAt:  time.Unix(0, edit.EditedAt).Format(model.TimestampFormat),
+		})
+	}
+
+	util.NewSuccessResponse(response, w, r)
+}
+
 func GetComments(w http.ResponseWriter, r *http.Request) {
 	user, _, _ := service.GetCurrentUser(r.Header.Get("Authorization"))
 
+	query := r.URL.Query()
+
+	page, err := parsePage(query)
+	if err != nil {
+		util.NewErrorResponse(http.StatusBadRequest, err, w)
+		return
+	}
+
 	vars := mux.Vars(r)
 	slug := vars["slug"]
-	comments, err := service.GetComments(slug)
+	comments, next, commentsCount, err := service.GetComments(slug, usernameOf(user), query.Get("sort"), page)
 	if err != nil {
-		util.NewErrorResponse(http.StatusInternalServerError, err, w)
+		newLookupErrorResponse(err, w)
+		return
+	}
+
+	writeCommentsResponse(w, r, user, comments, next, commentsCount)
+}
+
+// GetCommentReplies lists the replies to a comment beyond those that come with it in GetComments.
+func GetCommentReplies(w http.ResponseWriter, r *http.Request) {
+	user, _, _ := service.GetCurrentUser(r.Header.Get("Authorization"))
+
+	page, err := parsePage(r.URL.Query())
+	if err != nil {
+		util.NewErrorResponse(http.StatusBadRequest, err, w)
+		return
+	}
+
+	vars := mux.Vars(r)
+	commentId, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		util.NewErrorResponse(http.StatusNotFound, model.NewInputError("id", "invalid"), w)
+		return
+	}
+
+	replies, next, replyCount, err := service.GetCommentReplies(vars["slug"], usernameOf(user), commentId, page)
+	if err != nil {
+		newLookupErrorResponse(err, w)
+		return
 	}
 
+	writeCommentsResponse(w, r, user, replies, next, replyCount)
+}
+
+func writeCommentsResponse(w http.ResponseWriter, r *http.Request, user *model.User, comments []model.Comment, next *service.Cursor, commentsCount int64) {
 	authors, following, err := service.GetCommentRelatedProperties(user, comments)
 	if err != nil {
 		util.NewErrorResponse(http.StatusInternalServerError, err, w)
+		return
+	}
+
+	ownReactions, err := service.GetOwnCommentReactions(user, comments)
+	if err != nil {
+		util.NewErrorResponse(http.StatusInternalServerError, err, w)
+		return
 	}
 
 	commentResponses := make([]CommentResponse, 0, len(comments))
 
 	for i, comment := range comments {
-		commentResponses = append(commentResponses, CommentResponse{
-			Id:        comment.CommentId,
-			Body:      comment.Body,
-			CreatedAt: time.Unix(0, comment.CreatedAt).Format(model.TimestampFormat),
-			UpdatedAt: time.Unix(0, comment.UpdatedAt).Format(model.TimestampFormat),
-			Author: AuthorResponse{
+		commentResponse := newCommentResponse(comment, ownReactions[i])
+		if wantsBodyHtml(r) {
+			commentResponse.BodyHtml = service.RenderCommentBody(comment)
+		}
+
+		// Tombstones don't tell who wrote the deleted comment
+		if !comment.IsDeleted() {
+			commentResponse.Author = &AuthorResponse{
 				Username:  authors[i].Username,
 				Bio:       authors[i].Bio,
 				Image:     authors[i].Image,
 				Following: following[i],
-			},
-		})
+			}
+		}
+
+		commentResponses = append(commentResponses, commentResponse)
 	}
 
 	response := CResponse{
-		Comments: commentResponses,
+		Comments:      commentResponses,
+		CommentsCount: commentsCount,
+		Next:          setNextLink(w, r, next),
 	}
 
 	util.NewSuccessResponse(response, w, r)
@@ -117,11 +299,16 @@ func PostComment(w http.ResponseWriter, r *http.Request) {
 	article, err := service.GetArticleBySlug(slug)
 	if err != nil {
 		util.NewErrorResponse(http.StatusInternalServerError, err, w)
+		return
+	}
+
+	if !article.IsVisibleTo(user.Username) {
+		util.NewErrorResponse(http.StatusNotFound, model.NewInputError("slug", "not found"), w)
+		return
 	}
 
 	now := time.Now().UTC()
 	nowUnixNano := now.UnixNano()
-	nowStr := now.Format(model.TimestampFormat)
 
 	comment := model.Comment{
 		CommentKey: model.CommentKey{
@@ -131,27 +318,76 @@ func PostComment(w http.ResponseWriter, r *http.Request) {
 		UpdatedAt: nowUnixNano,
 		Body:      request.Comment.Body,
 		Author:    user.Username,
+		ParentId:  request.Comment.ParentId,
 	}
 
 	err = service.PutComment(&comment)
+	if err != nil {
+		util.NewErrorResponse(http.StatusUnprocessableEntity, err, w)
+		return
+	}
+
+	commentResponse := newCommentResponse(comment, nil)
+	commentResponse.Author = &AuthorResponse{
+		Username:  user.Username,
+		Bio:       user.Bio,
+		Image:     user.Image,
+		Following: false,
+	}
+
+	util.NewSuccessResponse(C1Response{Comment: commentResponse}, w, r)
+}
+
+// PostCommentReaction adds a reaction of the current user to a comment.
+func PostCommentReaction(w http.ResponseWriter, r *http.Request) {
+	reactToComment(w, r, service.ReactToComment)
+}
+
+// DeleteCommentReaction removes a reaction of the current user from a comment.
+func DeleteCommentReaction(w http.ResponseWriter, r *http.Request) {
+	reactToComment(w, r, service.UnreactToComment)
+}
+
+func reactToComment(w http.ResponseWriter, r *http.Request, react func(slug string, commentId int64, username string, reaction string) (model.Comment, error)) {
+	user, _, err := service.GetCurrentUser(r.Header.Get("Authorization"))
+	if err != nil {
+		util.NewUnauthorizedResponse(w)
+		return
+	}
+
+	vars := mux.Vars(r)
+	commentId, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		util.NewErrorResponse(http.StatusNotFound, model.NewInputError("id", "invalid"), w)
+		return
+	}
+
+	comment, err := react(vars["slug"], commentId, user.Username, vars["reaction"])
+	if err != nil {
+		util.NewErrorResponse(http.StatusUnprocessableEntity, err, w)
+		return
+	}
+
+	comments := []model.Comment{comment}
+	authors, following, err := service.GetCommentRelatedProperties(user, comments)
 	if err != nil {
 		util.NewErrorResponse(http.StatusInternalServerError, err, w)
+		return
 	}
 
-	response := C1Response{
-		Comment: CommentResponse{
-			Id:        comment.CommentId,
-			Body:      comment.Body,
-			CreatedAt: nowStr,
-			UpdatedAt: nowStr,
-			Author: AuthorResponse{
-				Username:  user.Username,
-				Bio:       user.Bio,
-				Image:     user.Image,
-				Following: false,
-			},
-		},
+	ownReactions, err := service.GetOwnCommentReactions(user, comments)
+	if err != nil {
+		util.NewErrorResponse(http.StatusInternalServerError, err, w)
+		return
 	}
 
-	util.NewSuccessResponse(response, w, r)
+	commentResponse := newCommentResponse(comment, ownReactions[0])
+	commentResponse.Author = &AuthorResponse{
+		Username:  authors[0].Username,
+		Bio:       authors[0].Bio,
+		Image:     authors[0].Image,
+		Following: following[0],
+	}
+
+	util.NewSuccessResponse(C1Response{Comment: commentResponse}, w, r)
 }
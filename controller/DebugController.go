@@ -0,0 +1,30 @@
+package controller
+
+import (
+	"net/http"
+
+	"realworld-go-nolambda/service"
+	"realworld-go-nolambda/util"
+)
+
+type CacheStatsResponse struct {
+	Enabled bool   `json:"enabled"`
+	Hits    uint64 `json:"hits"`
+	Misses  uint64 `json:"misses"`
+}
+
+// GetCacheStats shows admins the hits and misses of the service cache.
+func GetCacheStats(w http.ResponseWriter, r *http.Request) {
+	if !isAdminRequest(w, r) {
+		return
+	}
+
+	stats, enabled := service.GetCacheStats()
+	util.NewSuccessResponse(map[string]CacheStatsResponse{
+		"cache": {
+			Enabled: enabled,
+			Hits:    stats.Hits,
+			Misses:  stats.Misses,
+		},
+	}, w, r)
+}
@@ -49,32 +49,7 @@ func DeleteFavorite(w http.ResponseWriter, r *http.Request) {
 		util.NewErrorResponse(http.StatusInternalServerError, err, w)
 	}
 
-	isFavorited, authors, following, err := service.GetArticleRelatedProperties(user, []model.Article{article}, true)
-	if err != nil {
-		util.NewErrorResponse(http.StatusInternalServerError, err, w)
-	}
-
-	response := A1Response{
-		Article: ArticleResponse{
-			Slug:           article.Slug,
-			Title:          article.Title,
-			Description:    article.Description,
-			Body:           article.Body,
-			TagList:        article.TagList,
-			CreatedAt:      time.Unix(0, article.CreatedAt).Format(model.TimestampFormat),
-			UpdatedAt:      time.Unix(0, article.UpdatedAt).Format(model.TimestampFormat),
-			Favorited:      isFavorited[0],
-			FavoritesCount: article.FavoritesCount,
-			Author: AuthorResponse{
-				Username:  authors[0].Username,
-				Bio:       authors[0].Bio,
-				Image:     authors[0].Image,
-				Following: following[0],
-			},
-		},
-	}
-
-	util.NewSuccessResponse(response, w, r)
+	writeArticleResponse(w, r, user, article)
 }
 
 func PostFavorite(w http.ResponseWriter, r *http.Request) {
@@ -108,32 +83,7 @@ func PostFavorite(w http.ResponseWriter, r *http.Request) {
 		util.NewErrorResponse(http.StatusInternalServerError, err, w)
 	}
 
-	isFavorited, authors, following, err := service.GetArticleRelatedProperties(user, []model.Article{article}, true)
-	if err != nil {
-		util.NewErrorResponse(http.StatusInternalServerError, err, w)
-	}
-
-	response := A1Response{
-		Article: ArticleResponse{
-			Slug:           article.Slug,
-			Title:          article.Title,
-			Description:    article.Description,
-			Body:           article.Body,
-			TagList:        article.TagList,
-			CreatedAt:      time.Unix(0, article.CreatedAt).Format(model.TimestampFormat),
-			UpdatedAt:      time.Unix(0, article.UpdatedAt).Format(model.TimestampFormat),
-			Favorited:      isFavorited[0],
-			FavoritesCount: article.FavoritesCount,
-			Author: AuthorResponse{
-				Username:  authors[0].Username,
-				Bio:       authors[0].Bio,
-				Image:     authors[0].Image,
-				Following: following[0],
-			},
-		},
-	}
-
-	util.NewSuccessResponse(response, w, r)
+	writeArticleResponse(w, r, user, article)
 }
 
 func DeleteProfileFollow(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"realworld-go-nolambda/service"
+)
+
+const defaultLimit = 20
+
+// parsePage reads offset, limit and cursor from the query string. Pages continue after cursor when it is given.
+func parsePage(query url.Values) (service.Page, error) {
+	offset, err := strconv.Atoi(query.Get("offset"))
+	if err != nil {
+		offset = 0
+	}
+
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil {
+		limit = defaultLimit
+	}
+
+	after, err := service.DecodeCursor(query.Get("cursor"))
+	if err != nil {
+		return service.Page{}, err
+	}
+
+	return service.Page{
+		Offset: offset,
+		Limit:  limit,
+		After:  after,
+	}, nil
+}
+
+// setNextLink returns the encoded next cursor and sets an RFC 5988 Link header to the next page, if there is one.
+func setNextLink(w http.ResponseWriter, r *http.Request, next *service.Cursor) string {
+	if next == nil {
+		return ""
+	}
+
+	token := next.Encode()
+
+	query := r.URL.Query()
+	query.Del("offset")
+	query.Set("cursor", token)
+
+	nextURL := url.URL{
+		Path:     r.URL.Path,
+		RawQuery: query.Encode(),
+	}
+
+	w.Header().Add("Link", "<"+nextURL.String()+`>; rel="next"`)
+	return token
+}
@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"net/http"
+
+	"realworld-go-nolambda/service"
+	"realworld-go-nolambda/util"
+)
+
+type RenderPreviewRequest struct {
+	Preview struct {
+		Body string `json:"body"`
+	} `json:"preview"`
+}
+
+type RenderPreviewResponse struct {
+	Preview struct {
+		BodyHtml string `json:"bodyHtml"`
+	} `json:"preview"`
+}
+
+// PostRenderPreview renders a Markdown body to sanitized HTML, as bodyHtml of articles and comments is, without saving it.
+func PostRenderPreview(w http.ResponseWriter, r *http.Request) {
+	_, _, err := service.GetCurrentUser(r.Header.Get("Authorization"))
+	if err != nil {
+		util.NewUnauthorizedResponse(w)
+		return
+	}
+
+	request := RenderPreviewRequest{}
+	err = util.ParseBody(r, &request)
+	if err != nil {
+		util.NewErrorResponse(http.StatusBadRequest, err, w)
+		return
+	}
+
+	bodyHtml, err := service.RenderPreview(request.Preview.Body)
+	if err != nil {
+		util.NewErrorResponse(http.StatusUnprocessableEntity, err, w)
+		return
+	}
+
+	response := RenderPreviewResponse{}
+	response.Preview.BodyHtml = bodyHtml
+	util.NewSuccessResponse(response, w, r)
+}
+
+// wantsBodyHtml reports whether the request asks for bodies rendered to HTML too, with html=true.
+func wantsBodyHtml(r *http.Request) bool {
+	return r.URL.Query().Get("html") == "true"
+}
@@ -0,0 +1,195 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"realworld-go-nolambda/model"
+	"realworld-go-nolambda/service"
+	"realworld-go-nolambda/util"
+
+	"github.com/gorilla/mux"
+)
+
+type RevisionsResponse struct {
+	Revisions      []RevisionResponse `json:"revisions"`
+	RevisionsCount int                `json:"revisionsCount"`
+	Next           string             `json:"next,omitempty"`
+}
+
+type R1Response struct {
+	Revision RevisionResponse `json:"revision"`
+}
+
+type RevisionResponse struct {
+	Revision      int64    `json:"revision"`
+	Title         string   `json:"title"`
+	Description   string   `json:"description"`
+	Body          string   `json:"body"`
+	TagList       []string `json:"tagList"`
+	CreatedAt     string   `json:"createdAt"`
+	Editor        string   `json:"editor"`
+	ChangedFields []string `json:"changedFields"`
+	RestoredFrom  int64    `json:"restoredFrom,omitempty"`
+}
+
+type RDResponse struct {
+	Diff RevisionDiffResponse `json:"diff"`
+}
+
+type RevisionDiffResponse struct {
+	From   int64               `json:"from"`
+	To     int64               `json:"to"`
+	Fields []FieldDiffResponse `json:"fields"`
+}
+
+type FieldDiffResponse struct {
+	Field string          `json:"field"`
+	Lines []util.DiffLine `json:"lines"`
+}
+
+func newRevisionResponse(revision model.ArticleRevision) RevisionResponse {
+	return RevisionResponse{
+		Revision:      revision.Revision,
+		Title:         revision.Title,
+		Description:   revision.Description,
+		Body:          revision.Body,
+		TagList:       revision.TagList,
+		CreatedAt:     time.Unix(0, revision.CreatedAt).Format(model.TimestampFormat),
+		Editor:        revision.Editor,
+		ChangedFields: revision.ChangedFields,
+		RestoredFrom:  revision.RestoredFrom,
+	}
+}
+
+func parseRevision(value string, name string) (int64, error) {
+	revision, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || revision < 0 {
+		return 0, model.NewInputError(name, "invalid")
+	}
+	return revision, nil
+}
+
+func GetArticleRevisions(w http.ResponseWriter, r *http.Request) {
+	user, _, _ := service.GetCurrentUser(r.Header.Get("Authorization"))
+
+	page, err := parsePage(r.URL.Query())
+	if err != nil {
+		util.NewErrorResponse(http.StatusBadRequest, err, w)
+		return
+	}
+
+	slug := mux.Vars(r)["slug"]
+	revisions, next, err := service.GetArticleRevisions(slug, usernameOf(user), page)
+	if err != nil {
+		util.NewErrorResponse(http.StatusNotFound, err, w)
+		return
+	}
+
+	revisionResponses := make([]RevisionResponse, 0, len(revisions))
+	for _, revision := range revisions {
+		revisionResponses = append(revisionResponses, newRevisionResponse(revision))
+	}
+
+	response := RevisionsResponse{
+		Revisions:      revisionResponses,
+		RevisionsCount: len(revisionResponses),
+		Next:           setNextLink(w, r, next),
+	}
+
+	util.NewSuccessResponse(response, w, r)
+}
+
+func GetArticleRevision(w http.ResponseWriter, r *http.Request) {
+	user, _, _ := service.GetCurrentUser(r.Header.Get("Authorization"))
+
+	vars := mux.Vars(r)
+	n, err := parseRevision(vars["n"], "revision")
+	if err != nil {
+		util.NewErrorResponse(http.StatusBadRequest, err, w)
+		return
+	}
+
+	revision, err := service.GetArticleRevision(vars["slug"], usernameOf(user), n)
+	if err != nil {
+		util.NewErrorResponse(http.StatusNotFound, err, w)
+		return
+	}
+
+	util.NewSuccessResponse(R1Response{Revision: newRevisionResponse(revision)}, w, r)
+}
+
+// GetArticleRevisionDiff diffs revision n against revision ?from=, the previous revision by default.
+func GetArticleRevisionDiff(w http.ResponseWriter, r *http.Request) {
+	user, _, _ := service.GetCurrentUser(r.Header.Get("Authorization"))
+
+	vars := mux.Vars(r)
+	to, err := parseRevision(vars["n"], "revision")
+	if err != nil {
+		util.NewErrorResponse(http.StatusBadRequest, err, w)
+		return
+	}
+
+	from := to - 1
+	if value := r.URL.Query().Get("from"); value != "" {
+		from, err = parseRevision(value, "from")
+		if err != nil {
+			util.NewErrorResponse(http.StatusBadRequest, err, w)
+			return
+		}
+	}
+
+	diffs, err := service.DiffArticleRevisions(vars["slug"], usernameOf(user), from, to)
+	if err != nil {
+		util.NewErrorResponse(http.StatusNotFound, err, w)
+		return
+	}
+
+	fieldResponses := make([]FieldDiffResponse, 0, len(diffs))
+	for _, diff := range diffs {
+		fieldResponses = append(fieldResponses, FieldDiffResponse{
+			Field: diff.Field,
+			Lines: diff.Lines,
+		})
+	}
+
+	response := RDResponse{
+		Diff: RevisionDiffResponse{
+			From:   from,
+			To:     to,
+			Fields: fieldResponses,
+		},
+	}
+
+	util.NewSuccessResponse(response, w, r)
+}
+
+func PostArticleRevisionRestore(w http.ResponseWriter, r *http.Request) {
+	user, _, err := service.GetCurrentUser(r.Header.Get("Authorization"))
+	if err != nil {
+		util.NewUnauthorizedResponse(w)
+		return
+	}
+
+	vars := mux.Vars(r)
+	n, err := parseRevision(vars["n"], "revision")
+	if err != nil {
+		util.NewErrorResponse(http.StatusBadRequest, err, w)
+		return
+	}
+
+	article, err := service.RestoreArticleRevision(vars["slug"], n, user.Username, r.Header.Get("If-Match"))
+	if errors.Is(err, service.ErrArticleModified) {
+		util.NewPreconditionFailedResponse(w)
+		return
+	}
+
+	if err != nil {
+		util.NewErrorResponse(http.StatusUnprocessableEntity, err, w)
+		return
+	}
+
+	writeArticleResponse(w, r, user, article)
+}
@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"net/http"
+
+	"realworld-go-nolambda/model"
+	"realworld-go-nolambda/service"
+	"realworld-go-nolambda/util"
+)
+
+type SearchResponse struct {
+	Articles      []SearchResultResponse `json:"articles"`
+	ArticlesCount int                    `json:"articlesCount"`
+	Next          string                 `json:"next,omitempty"`
+}
+
+type SearchResultResponse struct {
+	ArticleResponse
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights"` // HTML snippets of the matching fields, matches in <mark>
+}
+
+// GetArticlesSearch lists the published articles matching the query q, most relevant first.
+func GetArticlesSearch(w http.ResponseWriter, r *http.Request) {
+	user, _, err := service.GetCurrentUser(r.Header.Get("Authorization"))
+	if err != nil {
+		util.NewErrorResponse(http.StatusUnauthorized, err, w)
+		return
+	}
+	query := r.URL.Query()
+
+	page, err := parsePage(query)
+	if err != nil {
+		util.NewErrorResponse(http.StatusBadRequest, err, w)
+		return
+	}
+
+	results, next, err := service.SearchArticles(query.Get("q"), page)
+	if err != nil {
+		util.NewErrorResponse(http.StatusBadRequest, err, w)
+		return
+	}
+
+	articles := make([]model.Article, 0, len(results))
+	for _, result := range results {
+		articles = append(articles, result.Article)
+	}
+
+	isFavorited, authors, following, err := service.GetArticleRelatedProperties(user, articles, true)
+	if err != nil {
+		util.NewErrorResponse(http.StatusInternalServerError, err, w)
+		return
+	}
+
+	resultResponses := make([]SearchResultResponse, 0, len(results))
+	for i, article := range articles {
+		resultResponses = append(resultResponses, SearchResultResponse{
+			ArticleResponse: newArticleResponse(r, article, isFavorited[i], authors[i], following[i]),
+			Score:      results[i].Score,
+			Highlights: results[i].Highlights,
+		})
+	}
+
+	response := SearchResponse{
+		Articles:      resultResponses,
+		ArticlesCount: len(resultResponses),
+		Next:          setNextLink(w, r, next),
+	}
+
+	util.NewSuccessResponse(response, w, r)
+}
@@ -2,23 +2,258 @@ package controller
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 	"realworld-go-nolambda/util"
 	"realworld-go-nolambda/service"
+	"realworld-go-nolambda/model"
+
+	"github.com/gorilla/mux"
 )
 
 type TResponse struct {
+	Tags      []string           `json:"tags"`
+	TagCounts []TagCountResponse `json:"tagCounts,omitempty"`
+	Next      string             `json:"next,omitempty"`
+}
+
+type TagCountResponse struct {
+	Tag           string `json:"tag"`
+	ArticlesCount int64  `json:"articlesCount"`
+}
+
+type TagResponse struct {
+	Tag           string   `json:"tag"`
+	Description   string   `json:"description"`
+	ArticlesCount int64    `json:"articlesCount"`
+	Aliases       []string `json:"aliases"`
+}
+
+type T1Response struct {
+	Tag TagResponse `json:"tag"`
+}
+
+type TagPutRequest struct {
+	Tag struct {
+		Description string `json:"description"`
+	} `json:"tag"`
+}
+
+type TagRenameRequest struct {
+	Tag struct {
+		Name string `json:"name"`
+	} `json:"tag"`
+}
+
+type TagMergeRequest struct {
 	Tags []string `json:"tags"`
 }
 
+// GetTags lists the tags with the most articles, filtered by prefix or counted over a recent window,
+// and their article counts if counts is true.
 func GetTags(w http.ResponseWriter, r *http.Request) {
-	tags, err := service.GetTags()
+	query := r.URL.Query()
+
+	page, err := parsePage(query)
+	if err != nil {
+		util.NewErrorResponse(http.StatusBadRequest, err, w)
+		return
+	}
+
+	window, err := parseWindow(query.Get("window"))
+	if err != nil {
+		util.NewErrorResponse(http.StatusBadRequest, err, w)
+		return
+	}
+
+	tagQuery := service.TagQuery{
+		Prefix: query.Get("prefix"),
+		Window: window,
+	}
+
+	tags, next, err := service.QueryTags(tagQuery, page)
 	if err != nil {
 		util.NewErrorResponse(http.StatusBadRequest, err, w)
 		return
 	}
 
 	response := TResponse{
-		Tags: tags,
+		Tags: make([]string, 0, len(tags)),
+		Next: setNextLink(w, r, next),
+	}
+
+	withCounts := query.Get("counts") == "true"
+	for _, tag := range tags {
+		response.Tags = append(response.Tags, tag.Tag)
+		if withCounts {
+			response.TagCounts = append(response.TagCounts, TagCountResponse{
+				Tag:           tag.Tag,
+				ArticlesCount: tag.ArticleCount,
+			})
+		}
+	}
+
+	util.NewSuccessResponse(response, w, r)
+}
+
+// parseWindow reads a trending window, a Go duration like 12h or a number of days like 7d. Empty means none.
+func parseWindow(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+		if err == nil && days > 0 {
+			return time.Duration(days) * 24 * time.Hour, nil
+		}
+	}
+
+	window, err := time.ParseDuration(value)
+	if err != nil || window <= 0 {
+		return 0, model.NewInputError("window", "must be a positive duration like 7d or 12h")
+	}
+
+	return window, nil
+}
+
+// GetTag returns a tag by its name or one of its aliases.
+func GetTag(w http.ResponseWriter, r *http.Request) {
+	writeTagResponse(w, r, mux.Vars(r)["tag"])
+}
+
+// PutTag sets the description of a tag. Only admins can manage tags.
+func PutTag(w http.ResponseWriter, r *http.Request) {
+	if !isAdminRequest(w, r) {
+		return
+	}
+
+	request := TagPutRequest{}
+	err := util.ParseBody(r, &request)
+	if err != nil {
+		util.NewErrorResponse(http.StatusBadRequest, err, w)
+		return
+	}
+
+	tag, err := service.SetTagDescription(mux.Vars(r)["tag"], request.Tag.Description)
+	if err != nil {
+		util.NewErrorResponse(http.StatusUnprocessableEntity, err, w)
+		return
+	}
+
+	writeTagResponse(w, r, tag)
+}
+
+// PostTagRename renames a tag, its old name becomes an alias.
+func PostTagRename(w http.ResponseWriter, r *http.Request) {
+	if !isAdminRequest(w, r) {
+		return
+	}
+
+	request := TagRenameRequest{}
+	err := util.ParseBody(r, &request)
+	if err != nil {
+		util.NewErrorResponse(http.StatusBadRequest, err, w)
+		return
+	}
+
+	tag, _, err := service.RenameTag(mux.Vars(r)["tag"], request.Tag.Name)
+	if err != nil {
+		util.NewErrorResponse(http.StatusUnprocessableEntity, err, w)
+		return
+	}
+
+	writeTagResponse(w, r, tag)
+}
+
+// PostTagMerge merges the tags of the request into the tag of the path.
+func PostTagMerge(w http.ResponseWriter, r *http.Request) {
+	if !isAdminRequest(w, r) {
+		return
+	}
+
+	request := TagMergeRequest{}
+	err := util.ParseBody(r, &request)
+	if err != nil {
+		util.NewErrorResponse(http.StatusBadRequest, err, w)
+		return
+	}
+
+	tag := mux.Vars(r)["tag"]
+	_, err = service.MergeTags(tag, request.Tags)
+	if err != nil {
+		util.NewErrorResponse(http.StatusUnprocessableEntity, err, w)
+		return
+	}
+
+	writeTagResponse(w, r, tag)
+}
+
+// PutTagAlias makes the alias of the path stand for the tag of the path.
+func PutTagAlias(w http.ResponseWriter, r *http.Request) {
+	if !isAdminRequest(w, r) {
+		return
+	}
+
+	alias, err := service.PutTagAlias(mux.Vars(r)["tag"], mux.Vars(r)["alias"])
+	if err != nil {
+		util.NewErrorResponse(http.StatusUnprocessableEntity, err, w)
+		return
+	}
+
+	writeTagResponse(w, r, alias.Tag)
+}
+
+func DeleteTagAlias(w http.ResponseWriter, r *http.Request) {
+	if !isAdminRequest(w, r) {
+		return
+	}
+
+	err := service.DeleteTagAlias(mux.Vars(r)["tag"], mux.Vars(r)["alias"])
+	if err != nil {
+		util.NewErrorResponse(http.StatusUnprocessableEntity, err, w)
+		return
+	}
+
+	writeTagResponse(w, r, mux.Vars(r)["tag"])
+}
+
+// isAdminRequest reports whether the request is made by an admin, responding with an error if it isn't.
+func isAdminRequest(w http.ResponseWriter, r *http.Request) bool {
+	user, _, err := service.GetCurrentUser(r.Header.Get("Authorization"))
+	if err != nil {
+		util.NewUnauthorizedResponse(w)
+		return false
+	}
+
+	if !service.IsAdmin(user.Username) {
+		util.NewForbiddenResponse(w)
+		return false
+	}
+
+	return true
+}
+
+func writeTagResponse(w http.ResponseWriter, r *http.Request, name string) {
+	tag, found, err := service.GetTag(name)
+	if err != nil {
+		util.NewErrorResponse(http.StatusInternalServerError, err, w)
+		return
+	}
+
+	if !found {
+		util.NewErrorResponse(http.StatusNotFound, model.NewInputError("tag", "not found"), w)
+		return
+	}
+
+	response := T1Response{
+		Tag: TagResponse{
+			Tag:           tag.Tag.Tag,
+			Description:   tag.Description,
+			ArticlesCount: tag.ArticleCount,
+			Aliases:       tag.Aliases,
+		},
 	}
 
 	util.NewSuccessResponse(response, w, r)
@@ -1,22 +1,85 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
 	"net/http"
+	"os"
+	"time"
 
+	"realworld-go-nolambda/config"
 	"realworld-go-nolambda/routes"
+	"realworld-go-nolambda/service"
+
 	"github.com/gorilla/mux"
 )
 
+// articleCleanupInterval is how often interrupted cleanups of deleted articles are resumed.
+const articleCleanupInterval = 5 * time.Minute
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "provision" {
+		provision(os.Args[2:])
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "reindex" {
+		reindex(os.Args[2:])
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "normalize-tags" {
+		normalizeTags(os.Args[2:])
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "recount-comments" {
+		recountComments(os.Args[2:])
+		return
+	}
+
+	cfg, err := config.Load(flag.NewFlagSet(os.Args[0], flag.ExitOnError), os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	store, err := setup(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	service.SetStore(store)
 
+	go service.RunArticleCleaner(context.Background(), articleCleanupInterval)
+	go service.RunArticleScheduler(context.Background())
 
-	// database.Setup()
 	route := mux.NewRouter()
 	routes.RegisterRoutes(route)
 
-	if err := http.ListenAndServe(":8080", route); err != nil {
+	log.Printf("listening on %s with %s storage", cfg.ListenAddress, cfg.StoreBackend)
+	if err := http.ListenAndServe(cfg.ListenAddress, route); err != nil {
 		log.Fatal(err)
 	}
 
 }
+
+// setup applies cfg to the service layer and opens the configured store, behind a cache unless it is disabled.
+func setup(cfg config.Config) (service.Store, error) {
+	service.SetTableNames(cfg.TablePrefix, cfg.Stage)
+	service.SetFeedMode(cfg.FeedMode)
+	service.SetAdmins(cfg.Admins)
+	service.ConfigureDynamoDB(service.DynamoDBOptions{
+		Endpoint:        cfg.DynamoDB.Endpoint,
+		Region:          cfg.DynamoDB.Region,
+		AccessKeyId:     cfg.DynamoDB.AccessKeyId,
+		SecretAccessKey: cfg.DynamoDB.SecretAccessKey,
+		SessionToken:    cfg.DynamoDB.SessionToken,
+	})
+
+	store, err := service.OpenStore(cfg.StoreBackend, cfg.DatabaseURL)
+	if err != nil || cfg.CacheSize == 0 {
+		return store, err
+	}
+
+	return service.NewCachingStore(store, service.NewLRUCache(cfg.CacheSize, cfg.CacheTTLDuration())), nil
+}
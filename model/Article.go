@@ -3,13 +3,23 @@ package model
 import (
 	"fmt"
 	"github.com/gosimple/slug"
+	"hash/fnv"
 	"strconv"
 	"strings"
 )
 
 const TimestampFormat = "2006-01-02T15:04:05.000Z"
-const MaxArticleId = 0x1000000 // exclusive
+const MaxArticleId = 0x1000000 // exclusive bound of the legacy random ids, allocated ids start above it
 const MaxNumTagsPerArticle = 5
+const WordsPerMinute = 200     // reading speed ReadingTime is estimated at
+const MaxOutlineHeadings = 100 // headings kept in an article's outline, the first ones
+
+// Article statuses. Articles stored before statuses were introduced have none and count as published.
+const (
+	StatusDraft     = "draft"     // never published
+	StatusPublished = "published" // listed and visible to everyone
+	StatusArchived  = "archived"  // unpublished after having been published
+)
 
 type Article struct {
 	ArticleId      int64
@@ -21,22 +31,48 @@ type Article struct {
 	CreatedAt      int64
 	UpdatedAt      int64
 	FavoritesCount int64
+	CommentsCount  int64 // comments not deleted, replies included
 	Author         string
-	Dummy          byte // Always 0, used for sorting articles by index CreatedAt
+	Revision       int64 // number of the current ArticleRevision, 0 for articles created before revisions were kept
+	Status         string
+	PublishAt      int64            // when a draft is due to be published, or was published, 0 if never
+	ExpireAt       int64            // when a published article is due to be archived, 0 if never
+	WordCount      int64            // words of the body as rendered, 0 for articles not edited since they were counted
+	ReadingTime    int64            // estimated minutes to read the body, at WordsPerMinute
+	Outline        []ArticleHeading `dynamodbav:",omitempty"` // headings of the body, in order
+	Dummy          byte             // Always 0, used for sorting articles by index CreatedAt, which leaves out unpublished articles
+}
+
+// ArticleHeading is a heading in the outline of an article, with the anchor of its element in the rendered body.
+type ArticleHeading struct {
+	Level  int
+	Text   string
+	Anchor string
 }
 
 type ArticleTag struct {
 	Tag       string
 	ArticleId int64
 	CreatedAt int64
+	Dummy     byte // Always 0, used for sorting the links of every tag by index CreatedAt
 }
 
 type Tag struct {
 	Tag          string
 	ArticleCount int64
+	Description  string
 	Dummy        byte // Always 0, used for sorting articles by index ArticleCount
 }
 
+// ArticleCleanup marks a deleted article whose comments, favorites, search postings and timeline entries
+// are still being removed. Its id can't be reused until the cleanup finishes.
+type ArticleCleanup struct {
+	ArticleId   int64
+	DeletedAt   int64
+	Author      string   // finds the timelines the article is on, through the author's followers
+	SearchTerms []string // terms of the article's search postings
+}
+
 type FavoriteArticleKey struct {
 	Username  string
 	ArticleId int64
@@ -60,6 +96,10 @@ func (article *Article) Validate() error {
 		return NewInputError("body", "can't be blank")
 	}
 
+	if article.Status != "" && article.Status != StatusDraft && article.Status != StatusPublished && article.Status != StatusArchived {
+		return NewInputError("status", fmt.Sprintf("must be one of %s, %s and %s", StatusDraft, StatusPublished, StatusArchived))
+	}
+
 	if article.TagList == nil {
 		article.TagList = make([]string, 0)
 	} else if len(article.TagList) > MaxNumTagsPerArticle {
@@ -84,3 +124,64 @@ func SlugToArticleId(slug string) (int64, error) {
 
 	return articleId, nil
 }
+
+// ETag returns the entity tag of the article's current version. It changes with UpdatedAt,
+// i.e. whenever the article is edited, and with the tag list, which tag renames and merges rewrite
+// without editing the article, but not when it is favorited.
+func (article *Article) ETag() string {
+	tags := fnv.New32a()
+	tags.Write([]byte(strings.Join(article.TagList, "\n")))
+	return `"` + strconv.FormatInt(article.UpdatedAt, 36) + "-" + strconv.FormatUint(uint64(tags.Sum32()), 36) + `"`
+}
+
+func (article *Article) IsPublished() bool {
+	return article.Status == "" || article.Status == StatusPublished
+}
+
+// EffectiveStatus returns the status of the article, published if it has none.
+func (article *Article) EffectiveStatus() string {
+	if article.IsPublished() {
+		return StatusPublished
+	}
+	return article.Status
+}
+
+// IsVisibleTo reports whether the user with the given username, empty for anonymous users, may read the article.
+// Unpublished articles are only visible to their author.
+func (article *Article) IsVisibleTo(username string) bool {
+	return article.IsPublished() || (username != "" && article.Author == username)
+}
+
+// IsLiveAt reports whether the article is published and not yet expired at now.
+func (article *Article) IsLiveAt(now int64) bool {
+	return article.IsPublished() && (article.ExpireAt == 0 || now < article.ExpireAt)
+}
+
+// Schedules returns the reminders of what is still due to happen to the article.
+func (article *Article) Schedules() []ArticleSchedule {
+	schedules := make([]ArticleSchedule, 0, 2)
+
+	if article.Status == StatusDraft && article.PublishAt != 0 {
+		schedules = append(schedules, ArticleSchedule{
+			ArticleScheduleKey: ArticleScheduleKey{ArticleId: article.ArticleId, Action: ScheduleActionPublish},
+			DueAt:              article.PublishAt,
+		})
+	}
+
+	if article.Status != StatusArchived && article.ExpireAt != 0 {
+		schedules = append(schedules, ArticleSchedule{
+			ArticleScheduleKey: ArticleScheduleKey{ArticleId: article.ArticleId, Action: ScheduleActionExpire},
+			DueAt:              article.ExpireAt,
+		})
+	}
+
+	return schedules
+}
+
+// LinkedTags returns the tags the article is listed under, none unless it is published.
+func (article *Article) LinkedTags() []string {
+	if !article.IsPublished() {
+		return nil
+	}
+	return article.TagList
+}
@@ -15,7 +15,11 @@ func (pq ArticlePriorityQueue) Less(i, j int) bool {
 		return false
 	}
 	// We want Pop to give us the latest, not earliest, article so we use greater than here.
-	return pq[i][0].CreatedAt > pq[j][0].CreatedAt
+	// Ties are broken by article id, so that merged pages can be resumed from the last article.
+	if pq[i][0].CreatedAt != pq[j][0].CreatedAt {
+		return pq[i][0].CreatedAt > pq[j][0].CreatedAt
+	}
+	return pq[i][0].ArticleId > pq[j][0].ArticleId
 }
 
 func (pq ArticlePriorityQueue) Swap(i, j int) {
@@ -0,0 +1,88 @@
+package model
+
+import "strings"
+
+// Names of the article fields a revision can change, as they appear in the API.
+const (
+	TitleField       = "title"
+	DescriptionField = "description"
+	BodyField        = "body"
+	TagListField     = "tagList"
+)
+
+// ArticleFields lists every field of an article kept in its revisions.
+var ArticleFields = []string{TitleField, DescriptionField, BodyField, TagListField}
+
+type ArticleRevisionKey struct {
+	ArticleId int64
+	Revision  int64
+}
+
+// ArticleRevision is an immutable copy of the content of one version of an article.
+// Revisions are numbered from 1, the version the article was created with.
+type ArticleRevision struct {
+	ArticleRevisionKey
+	Title         string
+	Description   string
+	Body          string
+	TagList       []string
+	CreatedAt     int64    // UpdatedAt of the article at this version
+	Editor        string   // username of the user who made this version
+	ChangedFields []string // fields that differ from the previous revision, every field for the first one
+	RestoredFrom  int64    // the revision this one restores, 0 if it was an ordinary edit
+}
+
+// NewArticleRevision copies the content of article as its revision article.Revision.
+func NewArticleRevision(article Article, editor string, changedFields []string) ArticleRevision {
+	return ArticleRevision{
+		ArticleRevisionKey: ArticleRevisionKey{
+			ArticleId: article.ArticleId,
+			Revision:  article.Revision,
+		},
+		Title:         article.Title,
+		Description:   article.Description,
+		Body:          article.Body,
+		TagList:       append([]string{}, article.TagList...),
+		CreatedAt:     article.UpdatedAt,
+		Editor:        editor,
+		ChangedFields: changedFields,
+	}
+}
+
+// Field returns the text of a field of the revision, the tag list with one tag per line.
+func (revision *ArticleRevision) Field(field string) string {
+	switch field {
+	case TitleField:
+		return revision.Title
+	case DescriptionField:
+		return revision.Description
+	case BodyField:
+		return revision.Body
+	case TagListField:
+		return strings.Join(revision.TagList, "\n")
+	}
+	return ""
+}
+
+// ChangedArticleFields returns the fields of ArticleFields that differ between two versions of an article.
+func ChangedArticleFields(oldArticle Article, newArticle Article) []string {
+	changedFields := make([]string, 0, len(ArticleFields))
+
+	if oldArticle.Title != newArticle.Title {
+		changedFields = append(changedFields, TitleField)
+	}
+
+	if oldArticle.Description != newArticle.Description {
+		changedFields = append(changedFields, DescriptionField)
+	}
+
+	if oldArticle.Body != newArticle.Body {
+		changedFields = append(changedFields, BodyField)
+	}
+
+	if strings.Join(oldArticle.TagList, "\n") != strings.Join(newArticle.TagList, "\n") {
+		changedFields = append(changedFields, TagListField)
+	}
+
+	return changedFields
+}
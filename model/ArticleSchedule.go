@@ -0,0 +1,20 @@
+package model
+
+// Actions an ArticleSchedule can remind of.
+const (
+	ScheduleActionPublish = "publish"
+	ScheduleActionExpire  = "expire"
+)
+
+type ArticleScheduleKey struct {
+	ArticleId int64
+	Action    string
+}
+
+// ArticleSchedule reminds the scheduler to publish or expire an article at DueAt.
+// It is only a reminder: the article's PublishAt, ExpireAt and Status decide whether there is anything to do.
+type ArticleSchedule struct {
+	ArticleScheduleKey
+	DueAt int64
+	Dummy byte // Always 0, used for sorting schedules by index DueAt
+}
@@ -1,6 +1,9 @@
 package model
 
-const MaxCommentId = 0x1000000 // exclusive
+const MaxCommentId = 0x1000000 // exclusive bound of the legacy random ids, allocated ids start above it
+
+// MaxCommentDepth bounds the nesting of replies, top-level comments are at depth 0.
+const MaxCommentDepth = 8
 
 type CommentKey struct {
 	ArticleId int64
@@ -9,10 +12,17 @@ type CommentKey struct {
 
 type Comment struct {
 	CommentKey
-	CreatedAt int64
-	UpdatedAt int64
-	Body      string
-	Author    string
+	CreatedAt  int64
+	UpdatedAt  int64
+	Body       string
+	Author     string
+	ParentId   int64 `dynamodbav:",omitempty"` // 0 for a top-level comment, omitted to keep it out of index ParentId
+	Depth      int
+	ReplyCount int64
+	DeletedAt  int64 // set when a comment with replies is deleted, leaving a tombstone
+	EditCount  int64
+	Upvotes    int64            // upvote reactions, which comments can be sorted by
+	Reactions  map[string]int64 `dynamodbav:",omitempty"` // counts of the emoji reactions, by reaction
 }
 
 func (comment *Comment) Validate() error {
@@ -22,3 +32,13 @@ func (comment *Comment) Validate() error {
 
 	return nil
 }
+
+// IsEdited reports whether the body of the comment was edited since it was written.
+func (comment *Comment) IsEdited() bool {
+	return comment.EditCount > 0
+}
+
+// IsDeleted reports whether the comment is a tombstone, kept in place of a deleted comment to hold its replies.
+func (comment *Comment) IsDeleted() bool {
+	return comment.DeletedAt != 0
+}
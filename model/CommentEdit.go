@@ -0,0 +1,11 @@
+package model
+
+// CommentEdit keeps a body of a comment that was replaced by an edit. Edit ids are allocated from their own counter.
+type CommentEdit struct {
+	ArticleId int64
+	EditId    int64
+	CommentId int64
+	Body      string
+	WrittenAt int64 // when the body was written
+	EditedAt  int64 // when it was replaced
+}
@@ -0,0 +1,45 @@
+package model
+
+// Reactions to comments. Every user can add each of them once to a comment.
+const (
+	ReactionUpvote   = "upvote"
+	ReactionHeart    = "heart"
+	ReactionLaugh    = "laugh"
+	ReactionHooray   = "hooray"
+	ReactionConfused = "confused"
+	ReactionRocket   = "rocket"
+	ReactionEyes     = "eyes"
+)
+
+// Reactions lists every reaction, upvotes first. The others are emoji reactions, counted in Comment.Reactions.
+var Reactions = []string{ReactionUpvote, ReactionHeart, ReactionLaugh, ReactionHooray, ReactionConfused, ReactionRocket, ReactionEyes}
+
+type CommentReactionKey struct {
+	ArticleId int64
+	CommentId int64
+	Username  string
+	Reaction  string
+}
+
+type CommentReaction struct {
+	CommentReactionKey
+	ReactedAt int64
+}
+
+// IsReaction reports whether reaction is one of Reactions.
+func IsReaction(reaction string) bool {
+	for _, known := range Reactions {
+		if reaction == known {
+			return true
+		}
+	}
+	return false
+}
+
+// ReactionCount returns the number of times the comment got a reaction.
+func (comment *Comment) ReactionCount(reaction string) int64 {
+	if reaction == ReactionUpvote {
+		return comment.Upvotes
+	}
+	return comment.Reactions[reaction]
+}
@@ -18,3 +18,21 @@ func NewInputError(inputName, message string) InputError {
 		inputName: {message},
 	}
 }
+
+// IsNotFound reports whether err is an input error telling that something wasn't found.
+func IsNotFound(err error) bool {
+	inputError, ok := err.(InputError)
+	if !ok {
+		return false
+	}
+
+	for _, messages := range inputError {
+		for _, message := range messages {
+			if message == "not found" {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,13 @@
+package model
+
+type SearchPostingKey struct {
+	Term      string
+	ArticleId int64
+}
+
+// SearchPosting is an entry of the inverted index of published articles: where a term occurs in an article.
+type SearchPosting struct {
+	SearchPostingKey
+	Positions map[string][]int // word positions of Term in each field of ArticleFields it occurs in
+	TermHead  string           // first character of Term, used for prefix queries by index TermHead
+}
@@ -0,0 +1,11 @@
+package model
+
+// MaxTagDescriptionLength bounds the description of a tag in characters.
+const MaxTagDescriptionLength = 500
+
+// TagAlias makes a normalized tag stand for another one. Aliases are resolved whenever tags are written or queried,
+// so no article is linked with an alias.
+type TagAlias struct {
+	Alias string
+	Tag   string
+}
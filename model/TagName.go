@@ -0,0 +1,115 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxTagLength bounds the length of a normalized tag in characters.
+const MaxTagLength = 32
+
+// tagPunctuation lists the characters allowed in a tag besides letters, marks and digits, as in c++, c# or .net
+const tagPunctuation = "-_.+#"
+
+// NormalizeTag returns the canonical form of a tag: compatibility-normalized (NFKC), case-folded and trimmed,
+// with inner runs of whitespace replaced by a single dash. Tags with the same canonical form are the same tag.
+// It fails if the tag is empty, longer than MaxTagLength or has characters other than letters, marks, digits
+// and tagPunctuation, or punctuation only.
+func NormalizeTag(tag string) (string, error) {
+	tag = NormalizeTagPrefix(tag)
+
+	if tag == "" {
+		return "", NewInputError("tagList", "can't have a blank tag")
+	}
+
+	if utf8.RuneCountInString(tag) > MaxTagLength {
+		return "", NewInputError("tagList", fmt.Sprintf("can't have a tag longer than %d characters", MaxTagLength))
+	}
+
+	hasAlphanumeric := false
+	for _, r := range tag {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			hasAlphanumeric = true
+		case unicode.IsMark(r) || strings.ContainsRune(tagPunctuation, r):
+		default:
+			return "", NewInputError("tagList", fmt.Sprintf("can't have %q in a tag, only letters, digits, spaces and %s", r, tagPunctuation))
+		}
+	}
+
+	if !hasAlphanumeric {
+		return "", NewInputError("tagList", "can't have a tag without letters or digits")
+	}
+
+	return tag, nil
+}
+
+// NormalizeTagPrefix normalizes the beginning of a tag like NormalizeTag, without checking what it is made of.
+func NormalizeTagPrefix(prefix string) string {
+	// Folding may leave a string that isn't normalized anymore, e.g. by splitting a ligature
+	prefix = norm.NFKC.String(cases.Fold().String(norm.NFKC.String(prefix)))
+	return strings.Join(strings.Fields(prefix), "-")
+}
+
+// NormalizeTagList normalizes every tag of tags and drops the duplicates, keeping the first of each in order.
+func NormalizeTagList(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+
+	for _, tag := range tags {
+		tag, err := NormalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+
+	return normalized, nil
+}
+
+// CanonicalTagList is NormalizeTagList for tag lists stored before tags were normalized.
+// It drops the tags that can't be normalized instead of failing, and returns them.
+func CanonicalTagList(tags []string) ([]string, []string) {
+	canonical := make([]string, 0, len(tags))
+	invalid := make([]string, 0)
+	seen := make(map[string]bool, len(tags))
+
+	for _, tag := range tags {
+		normalized, err := NormalizeTag(tag)
+		if err != nil {
+			invalid = append(invalid, tag)
+			continue
+		}
+
+		if !seen[normalized] {
+			seen[normalized] = true
+			canonical = append(canonical, normalized)
+		}
+	}
+
+	return canonical, invalid
+}
+
+// NormalizeTags replaces the tag list of the article with its normalized form.
+func (article *Article) NormalizeTags() error {
+	if article.TagList == nil {
+		return nil
+	}
+
+	tagList, err := NormalizeTagList(article.TagList)
+	if err != nil {
+		return err
+	}
+
+	article.TagList = tagList
+	return nil
+}
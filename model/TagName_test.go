@@ -0,0 +1,53 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	testCases := []struct {
+		tag           string
+		expected      string
+		expectedError bool
+	}{
+		{"go", "go", false},
+		{"  GoLang ", "golang", false},
+		{"Machine  Learning", "machine-learning", false},
+		{"C++", "c++", false},
+		{"C#", "c#", false},
+		{".NET", ".net", false},
+		{"Straße", "strasse", false},
+		{"ＧＯ", "go", false},
+		{"ﬁle", "file", false},
+		{"café", "café", false},
+		{"ΣΊΣΥΦΟΣ", "σίσυφοσ", false},
+		{"日本語", "日本語", false},
+		{"", "", true},
+		{"   ", "", true},
+		{"++", "", true},
+		{"a/b", "", true},
+		{"<script>", "", true},
+		{"abcdefghijklmnopqrstuvwxyzabcdefg", "", true},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := NormalizeTag(testCase.tag)
+		assert.Equal(t, testCase.expected, actual, "%+v", testCase)
+		assert.Equal(t, testCase.expectedError, err != nil, "%+v", testCase)
+	}
+}
+
+func TestNormalizeTagList(t *testing.T) {
+	tags, err := NormalizeTagList([]string{"Go", "web", "GO", " go ", "Web"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"go", "web"}, tags)
+
+	_, err = NormalizeTagList([]string{"go", "a/b"})
+	assert.Error(t, err)
+
+	tags, invalid := CanonicalTagList([]string{"Go", "a/b", "go"})
+	assert.Equal(t, []string{"go"}, tags)
+	assert.Equal(t, []string{"a/b"}, invalid)
+}
@@ -0,0 +1,13 @@
+package model
+
+type TimelineKey struct {
+	Username  string
+	ArticleId int64
+}
+
+// TimelineEntry puts an article on the personal feed of a follower of its author.
+type TimelineEntry struct {
+	TimelineKey
+	CreatedAt int64
+	Author    string
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"realworld-go-nolambda/config"
+	"realworld-go-nolambda/service"
+)
+
+// normalizeTags rewrites the tags of every published article into canonical form and recounts the articles of every tag,
+// e.g. after upgrading from a version that stored tags as they were typed. Run it while no server writes articles.
+// It accepts the same configuration as the server.
+//
+//	realworld-go-nolambda normalize-tags [-store sqlite] [-database-url realworld.db]
+func normalizeTags(args []string) {
+	cfg, err := config.Load(flag.NewFlagSet("normalize-tags", flag.ExitOnError), args)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	store, err := setup(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	service.SetStore(store)
+
+	numArticles, err := service.NormalizeArticleTags()
+	if err != nil {
+		log.Fatalf("normalize-tags: %v after %d articles", err, numArticles)
+	}
+
+	log.Printf("normalized the tags of %d articles", numArticles)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"realworld-go-nolambda/config"
+	"realworld-go-nolambda/service"
+)
+
+// provision creates or updates the DynamoDB tables of a stage, or deletes them with -teardown.
+// It accepts the same configuration as the server.
+//
+//	realworld-go-nolambda provision [-stage dev] [-dynamodb-endpoint http://localhost:8000] [-teardown]
+func provision(args []string) {
+	flags := flag.NewFlagSet("provision", flag.ExitOnError)
+	teardown := flags.Bool("teardown", false, "delete the tables of the stage instead, losing all of their data")
+
+	cfg, err := config.Load(flags, args)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if cfg.StoreBackend != "dynamodb" {
+		log.Fatalf("provision: only the dynamodb backend has tables to provision, not %s", cfg.StoreBackend)
+	}
+
+	_, err = setup(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *teardown {
+		err = service.TeardownTables()
+	} else {
+		err = service.ProvisionTables()
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
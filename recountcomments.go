@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"realworld-go-nolambda/config"
+	"realworld-go-nolambda/service"
+)
+
+// recountComments sets the comment count of every article from its comments, e.g. after upgrading a DynamoDB store
+// from a version without comment counts. Run it while no server writes comments.
+// It accepts the same configuration as the server.
+//
+//	realworld-go-nolambda recount-comments [-store sqlite] [-database-url realworld.db]
+func recountComments(args []string) {
+	cfg, err := config.Load(flag.NewFlagSet("recount-comments", flag.ExitOnError), args)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	store, err := setup(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	service.SetStore(store)
+
+	numArticles, err := service.RecountComments()
+	if err != nil {
+		log.Fatalf("recount-comments: %v after %d articles", err, numArticles)
+	}
+
+	log.Printf("recounted the comments of %d articles", numArticles)
+}
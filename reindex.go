@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"realworld-go-nolambda/config"
+	"realworld-go-nolambda/service"
+)
+
+// reindex writes the search postings of every published article, e.g. after upgrading from a version without search.
+// It accepts the same configuration as the server.
+//
+//	realworld-go-nolambda reindex [-store sqlite] [-database-url realworld.db]
+func reindex(args []string) {
+	cfg, err := config.Load(flag.NewFlagSet("reindex", flag.ExitOnError), args)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	store, err := setup(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	service.SetStore(store)
+
+	numArticles, err := service.ReindexArticles()
+	if err != nil {
+		log.Fatalf("reindex: %v after %d articles", err, numArticles)
+	}
+
+	log.Printf("reindexed %d articles", numArticles)
+}
@@ -16,13 +16,26 @@ func RegisterRoutes(router *mux.Router) {
 		json.NewEncoder(rw).Encode(map[string]string{"data": "Hello from Mux & mongoDB"})
 	}).Methods("GET", "OPTIONS")
 	router.HandleFunc("/articles/feed", controller.GetArticlesFeed).Methods("GET")
+	router.HandleFunc("/articles/search", controller.GetArticlesSearch).Methods("GET")
 	router.HandleFunc("/articles", controller.GetArticles).Methods("GET")
 	router.HandleFunc("/articles/{slug}", controller.GetArticleSlug).Methods("GET")
 	router.HandleFunc("/articles/{slug}", controller.PutArticleSlug).Methods("PUT")
 	router.HandleFunc("/articles", controller.PostArticles).Methods("POST")
 	router.HandleFunc("/articles/{slug}", controller.DeleteArticleSlug).Methods("DELETE")
+	router.HandleFunc("/articles/{slug}/publish", controller.PostArticlePublish).Methods("POST")
+	router.HandleFunc("/articles/{slug}/unpublish", controller.PostArticleUnpublish).Methods("POST")
+
+	router.HandleFunc("/articles/{slug}/revisions", controller.GetArticleRevisions).Methods("GET")
+	router.HandleFunc("/articles/{slug}/revisions/{n}", controller.GetArticleRevision).Methods("GET")
+	router.HandleFunc("/articles/{slug}/revisions/{n}/diff", controller.GetArticleRevisionDiff).Methods("GET")
+	router.HandleFunc("/articles/{slug}/revisions/{n}/restore", controller.PostArticleRevisionRestore).Methods("POST")
 
 	router.HandleFunc("/articles/{slug}/comments/{id}", controller.DeleteComment).Methods("DELETE")
+	router.HandleFunc("/articles/{slug}/comments/{id}", controller.PutComment).Methods("PUT")
+	router.HandleFunc("/articles/{slug}/comments/{id}/edits", controller.GetCommentEdits).Methods("GET")
+	router.HandleFunc("/articles/{slug}/comments/{id}/replies", controller.GetCommentReplies).Methods("GET")
+	router.HandleFunc("/articles/{slug}/comments/{id}/reactions/{reaction}", controller.PostCommentReaction).Methods("POST")
+	router.HandleFunc("/articles/{slug}/comments/{id}/reactions/{reaction}", controller.DeleteCommentReaction).Methods("DELETE")
 	router.HandleFunc("/articles/{slug}/comments", controller.GetComments).Methods("GET")
 	router.HandleFunc("/articles/{slug}/comments", controller.PostComment).Methods("POST")
 
@@ -34,10 +47,22 @@ func RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/profiles/{username}", controller.GetProfiles).Methods("GET")
 
 
+	router.HandleFunc("/render/preview", controller.PostRenderPreview).Methods("POST")
+
 	router.HandleFunc("/tags", controller.GetTags).Methods("GET")
+	router.HandleFunc("/tags/{tag}", controller.GetTag).Methods("GET")
+	router.HandleFunc("/tags/{tag}", controller.PutTag).Methods("PUT")
+	router.HandleFunc("/tags/{tag}/rename", controller.PostTagRename).Methods("POST")
+	router.HandleFunc("/tags/{tag}/merge", controller.PostTagMerge).Methods("POST")
+	router.HandleFunc("/tags/{tag}/aliases/{alias}", controller.PutTagAlias).Methods("PUT")
+	router.HandleFunc("/tags/{tag}/aliases/{alias}", controller.DeleteTagAlias).Methods("DELETE")
 
 	router.HandleFunc("/user", controller.GetUser).Methods("GET")
+	router.HandleFunc("/user/drafts", controller.GetUserDrafts).Methods("GET")
 	router.HandleFunc("/users/login", controller.UserLogin).Methods("POST")
 	router.HandleFunc("/users", controller.PostUser).Methods("POST")
 	router.HandleFunc("/user", controller.PutUser).Methods("PUT")
+
+	// Hits and misses of the service cache, for admins only
+	router.HandleFunc("/debug/cache", controller.GetCacheStats).Methods("GET")
 }
\ No newline at end of file
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"realworld-go-nolambda/model"
+	"realworld-go-nolambda/util"
+)
+
+// cleanupBatchSize bounds the items removed per store call, so a popular article never needs one huge write.
+const cleanupBatchSize = 25
+
+var cleanupRequests = make(chan struct{}, 1)
+
+// requestArticleCleanup wakes up RunArticleCleaner without waiting for it.
+func requestArticleCleanup() {
+	select {
+	case cleanupRequests <- struct{}{}:
+	default:
+	}
+}
+
+// RunArticleCleaner cleans up deleted articles whenever one is deleted, and every interval to resume
+// cleanups interrupted by a crash or a failure. It returns when ctx is done.
+func RunArticleCleaner(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := CleanupDeletedArticles()
+		if err != nil {
+			log.Printf("article cleanup: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-cleanupRequests:
+		case <-ticker.C:
+		}
+	}
+}
+
+// CleanupDeletedArticles removes the comments, favorites, search postings and timeline entries
+// of every article marked for cleanup.
+// Progress is kept in the store, so it can be interrupted and run again at any time.
+func CleanupDeletedArticles() error {
+	for {
+		cleanups, err := GetStore().QueryArticleCleanups(cleanupBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, cleanup := range cleanups {
+			err = cleanupArticle(cleanup)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(cleanups) < cleanupBatchSize {
+			return nil
+		}
+	}
+}
+
+func cleanupArticle(cleanup model.ArticleCleanup) error {
+	err := removeArticleFromSearchIndex(cleanup)
+	if err != nil {
+		return err
+	}
+
+	err = removeArticleFromTimelines(model.Article{ArticleId: cleanup.ArticleId, Author: cleanup.Author})
+	if err != nil {
+		return err
+	}
+
+	for {
+		removed, err := GetStore().DeleteArticleItems(cleanup.ArticleId, cleanupBatchSize)
+		if err != nil {
+			return err
+		}
+
+		if removed == 0 {
+			break
+		}
+	}
+
+	return GetStore().DeleteArticleCleanup(cleanup.ArticleId)
+}
+
+// removeArticleFromSearchIndex deletes the search postings of a deleted article in batches.
+func removeArticleFromSearchIndex(cleanup model.ArticleCleanup) error {
+	for start := 0; start < len(cleanup.SearchTerms); start += cleanupBatchSize {
+		end := util.MinInt(start+cleanupBatchSize, len(cleanup.SearchTerms))
+
+		keys := make([]model.SearchPostingKey, 0, end-start)
+		for _, term := range cleanup.SearchTerms[start:end] {
+			keys = append(keys, model.SearchPostingKey{Term: term, ArticleId: cleanup.ArticleId})
+		}
+
+		err := GetStore().DeleteSearchPostings(keys)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"realworld-go-nolambda/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreArticleCleanup(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		newTestUser(t, "alice")
+		newTestUser(t, "bob")
+		article := newTestArticle(t, "alice", 1)
+
+		comment := model.Comment{}
+		for i := 0; i < cleanupBatchSize+1; i++ {
+			comment = newTestComment(t, article, "bob", int64(2+i), 0)
+		}
+
+		_, err := UpdateComment(article.Slug, comment.CommentId, "bob", "Nicer")
+		assert.NoError(t, err)
+		_, err = ReactToComment(article.Slug, comment.CommentId, "alice", model.ReactionHeart)
+		assert.NoError(t, err)
+
+		favoriteTestArticle(t, "bob", article, 2)
+
+		assert.NoError(t, DeleteArticle(article.Slug, "alice", ""))
+
+		// The id stays taken until the cleanup finishes
+		revision := model.NewArticleRevision(article, "alice", model.ArticleFields)
+		assert.True(t, IsConditionalCheckFailed(GetStore().PutArticle(article, revision)))
+
+		assert.NoError(t, CleanupDeletedArticles())
+
+		comments, _, err := GetStore().QueryComments(article.ArticleId, OrderNewest, Page{Limit: 10})
+		assert.NoError(t, err)
+		assert.Empty(t, comments)
+
+		edits, err := GetStore().QueryCommentEdits(comment.CommentKey)
+		assert.NoError(t, err)
+		assert.Empty(t, edits)
+
+		reactions, err := GetStore().GetUserCommentReactions("alice", article.ArticleId, []int64{comment.CommentId})
+		assert.NoError(t, err)
+		assert.Empty(t, reactions)
+
+		favoriteIds, _, err := GetFavoriteArticleIdsByUsername("bob", Page{Limit: 10})
+		assert.NoError(t, err)
+		assert.Empty(t, favoriteIds)
+
+		cleanups, err := GetStore().QueryArticleCleanups(10)
+		assert.NoError(t, err)
+		assert.Empty(t, cleanups)
+
+		// Revisions are gone too
+		assert.NoError(t, GetStore().PutArticle(article, revision))
+	})
+}
+
+// failingSearchStore fails every removal of search postings.
+type failingSearchStore struct {
+	Store
+}
+
+func (s failingSearchStore) DeleteSearchPostings(keys []model.SearchPostingKey) error {
+	return errors.New("search store failed")
+}
+
+func TestStoreArticleCleanupRetries(t *testing.T) {
+	SetFeedMode(FeedModeWrite)
+	defer SetFeedMode(FeedModeRead)
+
+	forEachStore(t, func(t *testing.T) {
+		newTestUser(t, "alice")
+		newTestUser(t, "bob")
+		assert.NoError(t, Follow("bob", "alice"))
+		article := newTestArticle(t, "alice", 1)
+
+		// The delete succeeds although the postings can't be removed yet, the cleaner retries them
+		store := GetStore()
+		SetStore(failingSearchStore{Store: store})
+		assert.NoError(t, DeleteArticle(article.Slug, "alice", ""))
+		assert.EqualError(t, CleanupDeletedArticles(), "search store failed")
+
+		_, err := GetArticleBySlug(article.Slug)
+		assert.True(t, model.IsNotFound(err))
+		cleanups, err := store.QueryArticleCleanups(10)
+		assert.NoError(t, err)
+		assert.Len(t, cleanups, 1)
+
+		SetStore(store)
+		assert.NoError(t, CleanupDeletedArticles())
+
+		postings, err := store.QuerySearchPostings("body", 10)
+		assert.NoError(t, err)
+		assert.Empty(t, postings)
+
+		timeline, _, err := store.QueryTimeline("bob", Page{Limit: 10})
+		assert.NoError(t, err)
+		assert.Empty(t, timeline)
+
+		cleanups, err = store.QueryArticleCleanups(10)
+		assert.NoError(t, err)
+		assert.Empty(t, cleanups)
+	})
+}
@@ -0,0 +1,472 @@
+package service
+
+import (
+	"math"
+	"sort"
+
+	"realworld-go-nolambda/model"
+	"realworld-go-nolambda/util"
+)
+
+// maxQueryTags bounds the tags of a query, each of which may cost a store query.
+const maxQueryTags = 10
+
+// articleBatchSize is the number of candidates read per store call, within what a batch get accepts.
+const articleBatchSize = 100
+
+// maxScannedArticles bounds the candidates a streamed query examines per request. A page that isn't full by then
+// is returned as it is, with the position to resume from.
+const maxScannedArticles = 2000
+
+// maxCollectedArticles bounds the candidates a collected query sorts in memory.
+const maxCollectedArticles = 1000
+
+// estimatedArticles stands in for the number of articles of an author or favorites of a user, which aren't counted.
+const estimatedArticles = 100
+
+// Access paths driving a query.
+const (
+	planAll       = "all"       // every published article, in any order
+	planAuthor    = "author"    // the articles of the author, by CreatedAt
+	planTag       = "tag"       // the articles linked with a single tag, by CreatedAt
+	planTags      = "tags"      // the articles linked with any of several tags, collected only
+	planFavorited = "favorited" // the favorites of a user, collected only
+)
+
+// ArticleQuery selects the published articles matching all of its filters, in an order.
+type ArticleQuery struct {
+	Author         string
+	Tags           []string
+	AllTags        bool   // articles must have every tag of Tags rather than any of them
+	Favorited      string // username whose favorites to list
+	CreatedFrom    int64  // least CreatedAt, 0 for no bound
+	CreatedUntil   int64  // CreatedAt is before it, 0 for no bound
+	MinReadingTime int64  // least ReadingTime in minutes, 0 for no bound
+	MaxReadingTime int64  // greatest ReadingTime in minutes, 0 for no bound
+	Order          string // one of ArticleOrders, OrderNewest if empty
+}
+
+// articlePlan is how FindArticles reads a query: the access path driving it, and whether its candidates are
+// streamed from the store in order, or collected and sorted in memory. The other filters are applied to the candidates.
+type articlePlan struct {
+	Path     string
+	Tag      string // the tag of planTag
+	Collect  bool
+	Estimate int64 // estimated candidates, math.MaxInt64 if unknown
+}
+
+func (query ArticleQuery) scan(author string) ArticleScan {
+	return ArticleScan{
+		Author:       author,
+		Order:        query.Order,
+		CreatedFrom:  query.CreatedFrom,
+		CreatedUntil: query.CreatedUntil,
+	}
+}
+
+// GetArticles returns a page of articles newest first, filtered by any of author, tag and favorited,
+// and the position to resume from for the next page, nil if there is none.
+func GetArticles(page Page, author, tag, favorited string) ([]model.Article, *Cursor, error) {
+	query := ArticleQuery{Author: author, Favorited: favorited}
+	if tag != "" {
+		query.Tags = []string{tag}
+	}
+
+	return FindArticles(query, page)
+}
+
+// FindArticles returns a page of the articles matching query in its order,
+// and the position to resume from for the next page, nil if there is none.
+// Articles past their expiry are left out, even before the scheduler archives them.
+func FindArticles(query ArticleQuery, page Page) ([]model.Article, *Cursor, error) {
+	err := validatePage(page)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if query.Order == "" {
+		query.Order = OrderNewest
+	}
+
+	query.Tags, err = model.NormalizeTagList(query.Tags)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	query.Tags, err = resolveTagAliases(query.Tags)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = validateArticleQuery(query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	plan, err := planArticleQuery(query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if plan.Collect {
+		articles, complete, err := collectArticles(plan, query)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if complete {
+			return pageCollectedArticles(articles, query.Order, page)
+		}
+
+		// The estimate was off, every article can be streamed in any order instead
+		plan = articlePlan{Path: planAll, Estimate: math.MaxInt64}
+	}
+
+	return streamArticles(plan, query, page)
+}
+
+func validateArticleQuery(query ArticleQuery) error {
+	if !util.NewStringSetFromSlice(ArticleOrders)[query.Order] {
+		return model.NewInputError("sort", "must be one of newest, oldest, mostFavorited and recentlyUpdated")
+	}
+
+	if len(query.Tags) > maxQueryTags {
+		return model.NewInputError("tag", "can't be given more than 10 times")
+	}
+
+	if query.CreatedFrom != 0 && query.CreatedUntil != 0 && query.CreatedFrom >= query.CreatedUntil {
+		return model.NewInputError("from, until", "from must be before until")
+	}
+
+	if query.MinReadingTime != 0 && query.MaxReadingTime != 0 && query.MinReadingTime > query.MaxReadingTime {
+		return model.NewInputError("minReadingTime, maxReadingTime", "minReadingTime can't be greater than maxReadingTime")
+	}
+
+	return nil
+}
+
+// planArticleQuery chooses the access path with the fewest candidates. Tags are counted, authors and favorites
+// are estimated. A path that can't stream the order of the query is collected, provided it is small enough.
+// Every published article can be streamed in any order, so planAll is the fallback.
+func planArticleQuery(query ArticleQuery) (articlePlan, error) {
+	plans := make([]articlePlan, 0, 4)
+
+	if len(query.Tags) > 0 {
+		tags, err := GetStore().GetTags(query.Tags)
+		if err != nil {
+			return articlePlan{}, err
+		}
+
+		if len(query.Tags) == 1 || query.AllTags {
+			// The rarest tag of all of them, an unknown tag has no articles at all
+			rarest := articlePlan{Path: planTag, Estimate: math.MaxInt64}
+			for _, tag := range query.Tags {
+				if tags[tag].ArticleCount < rarest.Estimate {
+					rarest = articlePlan{Path: planTag, Tag: tag, Estimate: tags[tag].ArticleCount}
+				}
+			}
+			plans = append(plans, rarest)
+		} else {
+			union := articlePlan{Path: planTags, Collect: true}
+			for _, tag := range util.NewStringSetFromSlice(query.Tags).ToSlice() {
+				union.Estimate += tags[tag].ArticleCount
+			}
+			plans = append(plans, union)
+		}
+	}
+
+	if query.Author != "" {
+		plans = append(plans, articlePlan{Path: planAuthor, Estimate: estimatedArticles})
+	}
+
+	if query.Favorited != "" {
+		plans = append(plans, articlePlan{Path: planFavorited, Collect: true, Estimate: estimatedArticles})
+	}
+
+	best := articlePlan{Path: planAll, Estimate: math.MaxInt64}
+	for _, plan := range plans {
+		if (plan.Path == planTag || plan.Path == planAuthor) && query.Order != OrderNewest && query.Order != OrderOldest {
+			plan.Collect = true
+		}
+
+		if plan.Collect && plan.Estimate > maxCollectedArticles {
+			continue
+		}
+
+		if plan.Estimate < best.Estimate {
+			best = plan
+		}
+	}
+
+	return best, nil
+}
+
+// streamArticles reads the candidates of plan in the order of query, in batches, until the page is full,
+// the candidates run out or maxScannedArticles were examined.
+func streamArticles(plan articlePlan, query ArticleQuery, page Page) ([]model.Article, *Cursor, error) {
+	articles := make([]model.Article, 0, page.Limit)
+	skip := page.Offset
+	after := page.After
+
+	for scanned := 0; ; {
+		batch, numScanned, next, err := readArticleBatch(plan, query, after)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		batch, err = filterArticles(plan, query, batch)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, article := range batch {
+			if skip > 0 {
+				skip--
+				continue
+			}
+
+			articles = append(articles, article)
+			if len(articles) == page.Limit {
+				return articles, orderCursor(query.Order, article), nil
+			}
+		}
+
+		scanned += numScanned
+		if next == nil || scanned >= maxScannedArticles {
+			return articles, next, nil
+		}
+
+		after = next
+	}
+}
+
+// readArticleBatch reads the next batch of candidates of a streamed plan after a position. It also returns the number
+// of candidates examined, which includes the articles of tag links that are no longer published.
+func readArticleBatch(plan articlePlan, query ArticleQuery, after *Cursor) ([]model.Article, int, *Cursor, error) {
+	page := Page{Limit: articleBatchSize, After: after}
+
+	switch plan.Path {
+	case planTag:
+		articleIds, next, err := GetStore().ScanArticleIdsByTag(plan.Tag, query.scan(""), page)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+
+		articles, err := getArticlesByArticleIds(articleIds)
+		return articles, len(articleIds), next, err
+	case planAuthor:
+		articles, next, err := GetStore().ScanArticles(query.scan(query.Author), page)
+		return articles, len(articles), next, err
+	default:
+		articles, next, err := GetStore().ScanArticles(query.scan(""), page)
+		return articles, len(articles), next, err
+	}
+}
+
+// collectArticles reads every candidate of a collected plan, which is complete unless there were more than
+// maxCollectedArticles of them.
+func collectArticles(plan articlePlan, query ArticleQuery) ([]model.Article, bool, error) {
+	var articles []model.Article
+	var err error
+
+	switch plan.Path {
+	case planAuthor:
+		articles, err = collectPages(func(page Page) ([]model.Article, *Cursor, error) {
+			scan := query.scan(query.Author)
+			scan.Order = OrderNewest
+			return GetStore().ScanArticles(scan, page)
+		})
+	case planTag, planTags:
+		tags := []string{plan.Tag}
+		if plan.Path == planTags {
+			tags = util.NewStringSetFromSlice(query.Tags).ToSlice()
+		}
+
+		articleIds := make([]int64, 0)
+		for _, tag := range tags {
+			tagArticleIds, err := collectArticleIds(func(page Page) ([]int64, *Cursor, error) {
+				scan := query.scan("")
+				scan.Order = OrderNewest
+				return GetStore().ScanArticleIdsByTag(tag, scan, page)
+			})
+			if err != nil {
+				return nil, false, err
+			}
+
+			articleIds = append(articleIds, tagArticleIds...)
+		}
+
+		articles, err = getArticleBatches(articleIds)
+	case planFavorited:
+		var articleIds []int64
+		articleIds, err = collectArticleIds(func(page Page) ([]int64, *Cursor, error) {
+			return GetStore().QueryFavoriteArticleIds(query.Favorited, page)
+		})
+		if err == nil {
+			articles, err = getArticleBatches(articleIds)
+		}
+	}
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(articles) > maxCollectedArticles {
+		return nil, false, nil
+	}
+
+	articles, err = filterArticles(plan, query, articles)
+	return articles, true, err
+}
+
+// collectPages reads the pages of a listing until it ends or holds more than maxCollectedArticles.
+func collectPages(listing func(page Page) ([]model.Article, *Cursor, error)) ([]model.Article, error) {
+	articles := make([]model.Article, 0)
+	page := Page{Limit: articleBatchSize}
+
+	for {
+		batch, next, err := listing(page)
+		if err != nil {
+			return nil, err
+		}
+
+		articles = append(articles, batch...)
+		if next == nil || len(articles) > maxCollectedArticles {
+			return articles, nil
+		}
+
+		page.After = next
+	}
+}
+
+// collectArticleIds reads the pages of a listing of article ids until it ends or holds more than maxCollectedArticles.
+func collectArticleIds(listing func(page Page) ([]int64, *Cursor, error)) ([]int64, error) {
+	articleIds := make([]int64, 0)
+	page := Page{Limit: articleBatchSize}
+
+	for {
+		batch, next, err := listing(page)
+		if err != nil {
+			return nil, err
+		}
+
+		articleIds = append(articleIds, batch...)
+		if next == nil || len(articleIds) > maxCollectedArticles {
+			return articleIds, nil
+		}
+
+		page.After = next
+	}
+}
+
+// getArticleBatches gets the published articles of articleIds, once each, articleBatchSize at a time.
+func getArticleBatches(articleIds []int64) ([]model.Article, error) {
+	if len(articleIds) > maxCollectedArticles {
+		articleIds = articleIds[:maxCollectedArticles+1]
+	}
+
+	seen := make(map[int64]bool, len(articleIds))
+	unique := make([]int64, 0, len(articleIds))
+	for _, articleId := range articleIds {
+		if !seen[articleId] {
+			seen[articleId] = true
+			unique = append(unique, articleId)
+		}
+	}
+
+	articles := make([]model.Article, 0, len(unique))
+	for start := 0; start < len(unique); start += articleBatchSize {
+		batch, err := getArticlesByArticleIds(unique[start:util.MinInt(start+articleBatchSize, len(unique))])
+		if err != nil {
+			return nil, err
+		}
+
+		articles = append(articles, batch...)
+	}
+
+	return articles, nil
+}
+
+// filterArticles keeps the candidates of plan matching the filters of query that plan doesn't apply already, in order.
+func filterArticles(plan articlePlan, query ArticleQuery, articles []model.Article) ([]model.Article, error) {
+	var favorited map[int64]bool
+	if query.Favorited != "" && plan.Path != planFavorited && len(articles) > 0 {
+		articleIds := make([]int64, 0, len(articles))
+		for _, article := range articles {
+			articleIds = append(articleIds, article.ArticleId)
+		}
+
+		var err error
+		favorited, err = GetStore().GetFavoritedArticleIds(query.Favorited, articleIds)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	scan := query.scan(query.Author)
+	matching := make([]model.Article, 0, len(articles))
+
+	for _, article := range liveArticles(articles) {
+		if !scan.matches(article) || !matchesTags(query, article) || !matchesReadingTime(query, article) {
+			continue
+		}
+
+		if favorited != nil && !favorited[article.ArticleId] {
+			continue
+		}
+
+		matching = append(matching, article)
+	}
+
+	return matching, nil
+}
+
+func matchesTags(query ArticleQuery, article model.Article) bool {
+	if len(query.Tags) == 0 {
+		return true
+	}
+
+	tagList := util.NewStringSetFromSlice(article.TagList)
+	for _, tag := range query.Tags {
+		if tagList[tag] != query.AllTags {
+			return !query.AllTags
+		}
+	}
+
+	return query.AllTags
+}
+
+// matchesReadingTime reports whether the reading time of article is within the bounds of query.
+// Articles without words, including the ones not analyzed yet, only match without bounds.
+func matchesReadingTime(query ArticleQuery, article model.Article) bool {
+	if query.MinReadingTime == 0 && query.MaxReadingTime == 0 {
+		return true
+	}
+
+	return article.WordCount != 0 && article.ReadingTime >= query.MinReadingTime &&
+		(query.MaxReadingTime == 0 || article.ReadingTime <= query.MaxReadingTime)
+}
+
+// pageCollectedArticles sorts collected articles in order and returns the page of them.
+func pageCollectedArticles(articles []model.Article, order string, page Page) ([]model.Article, *Cursor, error) {
+	sort.Slice(articles, func(i, j int) bool {
+		return orderedBefore(order, orderSortKey(order, articles[i]), articles[i].ArticleId,
+			orderSortKey(order, articles[j]), articles[j].ArticleId)
+	})
+
+	after := make([]model.Article, 0, len(articles))
+	for _, article := range articles {
+		if page.After.isAfterIn(order, orderSortKey(order, article), article.ArticleId) {
+			after = append(after, article)
+		}
+	}
+
+	start := util.MinInt(page.Offset, len(after))
+	end := util.MinInt(page.Offset+page.Limit, len(after))
+
+	if end == len(after) || end == start {
+		return after[start:end], nil, nil
+	}
+
+	return after[start:end], orderCursor(order, after[end-1]), nil
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"testing"
+
+	"realworld-go-nolambda/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreFindArticles(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		newTestUser(t, "alice")
+		newTestUser(t, "bob")
+		newTestUser(t, "carol")
+
+		a1 := newTestArticle(t, "alice", 1, "go", "web")
+		a2 := newTestArticle(t, "alice", 2, "go")
+		b3 := newTestArticle(t, "bob", 3, "web")
+		b4 := newTestArticle(t, "bob", 4, "go", "web", "db")
+		a5 := newTestArticle(t, "alice", 5, "db")
+
+		for _, article := range []model.Article{a2, b4} {
+			favoriteTestArticle(t, "carol", article, 10)
+		}
+		favoriteTestArticle(t, "bob", b4, 11)
+
+		updated := a1
+		updated.Body = "Edited"
+		updated.UpdatedAt = 20
+		assert.NoError(t, UpdateArticle(a1, &updated, "alice", ""))
+
+		queries := []struct {
+			name     string
+			query    ArticleQuery
+			expected []model.Article
+		}{
+			{"all", ArticleQuery{}, []model.Article{a5, b4, b3, a2, a1}},
+			{"oldest", ArticleQuery{Order: OrderOldest}, []model.Article{a1, a2, b3, b4, a5}},
+			{"most favorited", ArticleQuery{Order: OrderMostFavorited}, []model.Article{b4, a2, a5, b3, a1}},
+			{"recently updated", ArticleQuery{Order: OrderRecentlyUpdated}, []model.Article{a1, a5, b4, b3, a2}},
+			{"any tag", ArticleQuery{Tags: []string{"db", "web"}}, []model.Article{a5, b4, b3, a1}},
+			{"all tags", ArticleQuery{Tags: []string{"go", "web"}, AllTags: true}, []model.Article{b4, a1}},
+			{"author and tag", ArticleQuery{Author: "alice", Tags: []string{"go"}, Order: OrderOldest}, []model.Article{a1, a2}},
+			{"favorited and tag", ArticleQuery{Favorited: "carol", Tags: []string{"web"}}, []model.Article{b4}},
+			{"favorited oldest", ArticleQuery{Favorited: "carol", Order: OrderOldest}, []model.Article{a2, b4}},
+			{"date range", ArticleQuery{CreatedFrom: 2, CreatedUntil: 5}, []model.Article{b4, b3, a2}},
+			{"author and range", ArticleQuery{Author: "bob", CreatedFrom: 4, Order: OrderMostFavorited}, []model.Article{b4}},
+			{"unknown tag", ArticleQuery{Tags: []string{"rust"}}, []model.Article{}},
+		}
+
+		for _, query := range queries {
+			slugs := readAllArticles(t, func(page Page) ([]model.Article, *Cursor, error) {
+				return FindArticles(query.query, page)
+			})
+			assert.Equal(t, articleSlugs(query.expected), slugs, query.name)
+		}
+
+		// Offsets skip matches, not candidates
+		articles, _, err := FindArticles(ArticleQuery{Tags: []string{"web"}, Order: OrderOldest}, Page{Offset: 1, Limit: 1})
+		assert.NoError(t, err)
+		assert.Equal(t, articleSlugs([]model.Article{b3}), articleSlugs(articles))
+
+		// The rarest tag drives a query for all of them, the others are filtered
+		plan, err := planArticleQuery(ArticleQuery{Tags: []string{"go", "db"}, AllTags: true, Order: OrderNewest})
+		assert.NoError(t, err)
+		assert.Equal(t, articlePlan{Path: planTag, Tag: "db", Estimate: 2}, plan)
+
+		plan, err = planArticleQuery(ArticleQuery{Author: "alice", Favorited: "carol", Order: OrderOldest})
+		assert.NoError(t, err)
+		assert.Equal(t, articlePlan{Path: planAuthor, Estimate: estimatedArticles}, plan)
+
+		plan, err = planArticleQuery(ArticleQuery{Tags: []string{"db", "web"}, Author: "alice", Order: OrderOldest})
+		assert.NoError(t, err)
+		assert.Equal(t, articlePlan{Path: planTags, Collect: true, Estimate: 5}, plan)
+
+		_, _, err = FindArticles(ArticleQuery{Order: "random"}, Page{Limit: 10})
+		assert.Error(t, err)
+
+		_, _, err = FindArticles(ArticleQuery{CreatedFrom: 5, CreatedUntil: 5}, Page{Limit: 10})
+		assert.Error(t, err)
+	})
+}
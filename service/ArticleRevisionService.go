@@ -0,0 +1,146 @@
+package service
+
+import (
+	"time"
+
+	"realworld-go-nolambda/model"
+	"realworld-go-nolambda/util"
+)
+
+// ArticleFieldDiff is the line-level diff of one field of an article between two revisions.
+type ArticleFieldDiff struct {
+	Field string
+	Lines []util.DiffLine
+}
+
+// firstArticleRevision is the revision an article that predates revisions was created with.
+// It isn't stored until the article is first edited.
+func firstArticleRevision(article model.Article) model.ArticleRevision {
+	article.Revision = 1
+	return model.NewArticleRevision(article, article.Author, model.ArticleFields)
+}
+
+// GetArticleRevisions returns a page of the revisions of an article newest first,
+// and the position to resume from for the next page, nil if there is none. Only articles visible to username have them.
+func GetArticleRevisions(slug string, username string, page Page) ([]model.ArticleRevision, *Cursor, error) {
+	err := validatePage(page)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	article, err := getVisibleArticle(slug, username)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if article.Revision == 0 {
+		revisions := []model.ArticleRevision{firstArticleRevision(article)}
+		if page.Offset > 0 || !page.After.isAfter(1, article.ArticleId) {
+			revisions = revisions[:0]
+		}
+		return revisions, nil, nil
+	}
+
+	return GetStore().QueryArticleRevisions(article.ArticleId, page)
+}
+
+// GetArticleRevision returns revision n of the article with the given slug, if it is visible to username.
+func GetArticleRevision(slug string, username string, n int64) (model.ArticleRevision, error) {
+	article, err := getVisibleArticle(slug, username)
+	if err != nil {
+		return model.ArticleRevision{}, err
+	}
+
+	return getArticleRevision(article, n)
+}
+
+func getArticleRevision(article model.Article, n int64) (model.ArticleRevision, error) {
+	if article.Revision == 0 && n == 1 {
+		return firstArticleRevision(article), nil
+	}
+
+	revision, found, err := GetStore().GetArticleRevision(model.ArticleRevisionKey{
+		ArticleId: article.ArticleId,
+		Revision:  n,
+	})
+	if err != nil {
+		return model.ArticleRevision{}, err
+	}
+
+	if !found {
+		return model.ArticleRevision{}, model.NewInputError("revision", "not found")
+	}
+
+	return revision, nil
+}
+
+// DiffArticleRevisions returns the diff of every field that changed from revision from to revision to
+// of the article with the given slug, if it is visible to username.
+// Revision 0 is an empty article, so diffing from it shows all the content.
+func DiffArticleRevisions(slug string, username string, from int64, to int64) ([]ArticleFieldDiff, error) {
+	article, err := getVisibleArticle(slug, username)
+	if err != nil {
+		return nil, err
+	}
+
+	fromRevision := model.ArticleRevision{}
+	if from != 0 {
+		fromRevision, err = getArticleRevision(article, from)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	toRevision, err := getArticleRevision(article, to)
+	if err != nil {
+		return nil, err
+	}
+
+	diffs := make([]ArticleFieldDiff, 0, len(model.ArticleFields))
+	for _, field := range model.ArticleFields {
+		oldText := fromRevision.Field(field)
+		newText := toRevision.Field(field)
+		if oldText == newText {
+			continue
+		}
+
+		diffs = append(diffs, ArticleFieldDiff{
+			Field: field,
+			Lines: util.DiffLines(oldText, newText),
+		})
+	}
+
+	return diffs, nil
+}
+
+// RestoreArticleRevision makes the content of revision n the current version of the article with the given slug,
+// as a new revision made by editor. A non-empty ifMatch must list the ETag of the article's current version.
+func RestoreArticleRevision(slug string, n int64, editor string, ifMatch string) (model.Article, error) {
+	oldArticle, err := GetArticleBySlug(slug)
+	if err != nil {
+		return model.Article{}, err
+	}
+
+	if ifMatch != "" && !util.MatchETag(ifMatch, oldArticle.ETag(), false) {
+		return model.Article{}, ErrArticleModified
+	}
+
+	revision, err := getArticleRevision(oldArticle, n)
+	if err != nil {
+		return model.Article{}, err
+	}
+
+	newArticle := oldArticle
+	newArticle.Title = revision.Title
+	newArticle.Description = revision.Description
+	newArticle.Body = revision.Body
+	newArticle.TagList = append([]string{}, revision.TagList...)
+	newArticle.UpdatedAt = time.Now().UTC().UnixNano()
+
+	err = updateArticle(oldArticle, &newArticle, editor, n)
+	if err != nil {
+		return model.Article{}, err
+	}
+
+	return newArticle, nil
+}
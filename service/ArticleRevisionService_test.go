@@ -0,0 +1,66 @@
+package service
+
+import (
+	"testing"
+
+	"realworld-go-nolambda/model"
+	"realworld-go-nolambda/util"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreArticleRevisions(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		newTestUser(t, "alice")
+		article := newTestArticle(t, "alice", 1, "go")
+
+		edited := article
+		edited.Body = "Body\nMore"
+		edited.TagList = []string{"go", "aws"}
+		edited.UpdatedAt = 2
+		assert.NoError(t, UpdateArticle(article, &edited, "alice", ""))
+		assert.Equal(t, int64(2), edited.Revision)
+
+		assert.Error(t, UpdateArticle(edited, &article, "bob", ""))
+
+		revisions, next, err := GetArticleRevisions(article.Slug, "", Page{Limit: 1})
+		assert.NoError(t, err)
+		assert.Len(t, revisions, 1)
+		assert.Equal(t, int64(2), revisions[0].Revision)
+		assert.Equal(t, []string{model.BodyField, model.TagListField}, revisions[0].ChangedFields)
+
+		revisions, next, err = GetArticleRevisions(article.Slug, "", Page{Limit: 1, After: next})
+		assert.NoError(t, err)
+		assert.Nil(t, next)
+		assert.Len(t, revisions, 1)
+		assert.Equal(t, "Body", revisions[0].Body)
+		assert.Equal(t, model.ArticleFields, revisions[0].ChangedFields)
+
+		diffs, err := DiffArticleRevisions(article.Slug, "", 1, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, []ArticleFieldDiff{
+			{Field: model.BodyField, Lines: []util.DiffLine{{Op: util.DiffEqual, Text: "Body"}, {Op: util.DiffInsert, Text: "More"}}},
+			{Field: model.TagListField, Lines: []util.DiffLine{{Op: util.DiffEqual, Text: "go"}, {Op: util.DiffInsert, Text: "aws"}}},
+		}, diffs)
+
+		_, err = RestoreArticleRevision(article.Slug, 1, "alice", article.ETag())
+		assert.Equal(t, ErrArticleModified, err)
+
+		restored, err := RestoreArticleRevision(article.Slug, 1, "alice", edited.ETag())
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), restored.Revision)
+		assert.Equal(t, "Body", restored.Body)
+
+		revision, err := GetArticleRevision(article.Slug, "", 3)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), revision.RestoredFrom)
+		assert.Equal(t, []string{"go"}, revision.TagList)
+
+		articles, _, err := GetArticles(Page{Limit: 10}, "", "aws", "")
+		assert.NoError(t, err)
+		assert.Empty(t, articles)
+
+		_, err = GetArticleRevision(article.Slug, "", 4)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,74 @@
+package service
+
+import "realworld-go-nolambda/model"
+
+// Orders of an article listing. Each sorts by a key, then by article id in the same direction.
+const (
+	OrderNewest          = "newest"          // by CreatedAt, descending
+	OrderOldest          = "oldest"          // by CreatedAt, ascending
+	OrderMostFavorited   = "mostFavorited"   // by FavoritesCount, descending
+	OrderRecentlyUpdated = "recentlyUpdated" // by UpdatedAt, descending
+)
+
+// ArticleOrders lists every order, the first being the default.
+var ArticleOrders = []string{OrderNewest, OrderOldest, OrderMostFavorited, OrderRecentlyUpdated}
+
+// ArticleScan selects published articles in an order, optionally of a single author and created within a range.
+type ArticleScan struct {
+	Author       string // all authors if empty. Only OrderNewest and OrderOldest are supported with an author.
+	Order        string
+	CreatedFrom  int64 // least CreatedAt, 0 for no bound
+	CreatedUntil int64 // CreatedAt is before it, 0 for no bound
+}
+
+// byCreatedAt reports whether the order of the scan is the order of the CreatedAt indexes.
+func (scan ArticleScan) byCreatedAt() bool {
+	return scan.Order == OrderNewest || scan.Order == OrderOldest
+}
+
+func (scan ArticleScan) isCreatedInRange(createdAt int64) bool {
+	return (scan.CreatedFrom == 0 || createdAt >= scan.CreatedFrom) && (scan.CreatedUntil == 0 || createdAt < scan.CreatedUntil)
+}
+
+func (scan ArticleScan) matches(article model.Article) bool {
+	return article.IsPublished() && (scan.Author == "" || article.Author == scan.Author) && scan.isCreatedInRange(article.CreatedAt)
+}
+
+// orderSortKey returns the key article is sorted by in order.
+func orderSortKey(order string, article model.Article) int64 {
+	switch order {
+	case OrderMostFavorited:
+		return article.FavoritesCount
+	case OrderRecentlyUpdated:
+		return article.UpdatedAt
+	default:
+		return article.CreatedAt
+	}
+}
+
+// orderCursor returns the position of article in order.
+func orderCursor(order string, article model.Article) *Cursor {
+	return &Cursor{
+		SortKey:   orderSortKey(order, article),
+		ArticleId: article.ArticleId,
+	}
+}
+
+// orderedBefore reports whether the item at (sortKey, articleId) comes before the other one in order.
+func orderedBefore(order string, sortKey, articleId, otherSortKey, otherArticleId int64) bool {
+	if order == OrderOldest {
+		return newerThan(otherSortKey, otherArticleId, sortKey, articleId)
+	}
+
+	return newerThan(sortKey, articleId, otherSortKey, otherArticleId)
+}
+
+// isAfterIn reports whether the item at (sortKey, articleId) comes after the cursor in order.
+// A nil cursor is before every item.
+func (c *Cursor) isAfterIn(order string, sortKey, articleId int64) bool {
+	if c == nil {
+		return true
+	}
+
+	return orderedBefore(order, c.SortKey, c.ArticleId, sortKey, articleId)
+}
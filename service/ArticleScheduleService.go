@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"time"
+
+	"realworld-go-nolambda/model"
+)
+
+// scheduleBatchSize bounds the reminders read per store call.
+const scheduleBatchSize = 25
+
+// maxScheduleWait bounds the time between two runs of the scheduler, so reminders written by
+// another server are picked up even when nothing wakes this one.
+const maxScheduleWait = time.Minute
+
+// maxScheduleAttempts bounds the retries of a transition that lost a race with an edit.
+const maxScheduleAttempts = 3
+
+var scheduleRequests = make(chan struct{}, 1)
+
+// requestArticleSchedule wakes up RunArticleScheduler without waiting for it, so it sees new reminders.
+func requestArticleSchedule() {
+	select {
+	case scheduleRequests <- struct{}{}:
+	default:
+	}
+}
+
+// RunArticleScheduler publishes and expires articles as they fall due. The reminders are kept in the store,
+// so whatever fell due while the server was down is caught up on start. It returns when ctx is done.
+func RunArticleScheduler(ctx context.Context) {
+	for {
+		wait, err := RunDueArticleSchedules(time.Now())
+		if err != nil {
+			log.Printf("article scheduler: %v", err)
+			wait = maxScheduleWait
+		}
+
+		timer := time.NewTimer(wait)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-scheduleRequests:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// RunDueArticleSchedules publishes and expires every article due at or before now,
+// and returns how long to wait for the next reminder, at most maxScheduleWait.
+func RunDueArticleSchedules(now time.Time) (time.Duration, error) {
+	nowNanos := now.UTC().UnixNano()
+
+	for {
+		schedules, err := GetStore().QueryArticleSchedules(nowNanos, scheduleBatchSize)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, schedule := range schedules {
+			err = runArticleSchedule(schedule, nowNanos)
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		if len(schedules) < scheduleBatchSize {
+			break
+		}
+	}
+
+	next, err := GetStore().QueryArticleSchedules(math.MaxInt64, 1)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(next) == 0 || next[0].DueAt-nowNanos > int64(maxScheduleWait) {
+		return maxScheduleWait, nil
+	}
+
+	if next[0].DueAt < nowNanos {
+		return 0, nil
+	}
+
+	return time.Duration(next[0].DueAt - nowNanos), nil
+}
+
+// runArticleSchedule carries out a due reminder, if the article still calls for it, then removes the reminder.
+func runArticleSchedule(schedule model.ArticleSchedule, now int64) error {
+	for attempt := 1; ; attempt++ {
+		article, found, err := GetStore().GetArticle(schedule.ArticleId)
+		if err != nil {
+			return err
+		}
+
+		if !found || !isArticleScheduleDue(article, schedule.Action, now) {
+			break
+		}
+
+		status := model.StatusPublished
+		if schedule.Action == model.ScheduleActionExpire {
+			status = model.StatusArchived
+		}
+
+		_, err = transitionArticle(article, status, now)
+		if errors.Is(err, ErrArticleModified) && attempt < maxScheduleAttempts {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		break
+	}
+
+	// A reminder rescheduled in the meantime stays for its new time
+	err := GetStore().DeleteArticleSchedule(schedule)
+	if errors.Is(err, ErrConditionFailed) {
+		return nil
+	}
+
+	return err
+}
+
+func isArticleScheduleDue(article model.Article, action string, now int64) bool {
+	switch action {
+	case model.ScheduleActionPublish:
+		return article.Status == model.StatusDraft && article.PublishAt != 0 && article.PublishAt <= now
+	case model.ScheduleActionExpire:
+		return article.IsPublished() && article.ExpireAt != 0 && article.ExpireAt <= now
+	default:
+		return false
+	}
+}
+
+// validateArticleSchedule checks the publication and expiry times of article against now.
+// Only the times that differ from oldArticle's are checked, so an article can still be edited once they have passed.
+func validateArticleSchedule(oldArticle model.Article, article model.Article, now int64) error {
+	if article.PublishAt != oldArticle.PublishAt && article.PublishAt != 0 {
+		if article.Status != model.StatusDraft {
+			return model.NewInputError("publishAt", "can only be set on a draft")
+		}
+	}
+
+	if article.ExpireAt != oldArticle.ExpireAt && article.ExpireAt != 0 {
+		if article.ExpireAt <= now {
+			return model.NewInputError("expireAt", "must be in the future")
+		}
+
+		if article.ExpireAt <= article.PublishAt {
+			return model.NewInputError("expireAt", "must be after publishAt")
+		}
+	}
+
+	return nil
+}
+
+// putArticleSchedules writes the reminders of article before the article itself,
+// so an interrupted write leaves at worst a reminder with nothing to do.
+func putArticleSchedules(article model.Article) error {
+	schedules := article.Schedules()
+	if len(schedules) == 0 {
+		return nil
+	}
+
+	err := GetStore().PutArticleSchedules(schedules)
+	if err != nil {
+		return err
+	}
+
+	requestArticleSchedule()
+	return nil
+}
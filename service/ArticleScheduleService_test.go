@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"realworld-go-nolambda/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreArticleSchedule(t *testing.T) {
+	SetFeedMode(FeedModeWrite)
+	defer SetFeedMode(FeedModeRead)
+
+	forEachStore(t, func(t *testing.T) {
+		newTestUser(t, "alice")
+		newTestUser(t, "bob")
+		assert.NoError(t, Follow("bob", "alice"))
+
+		now := time.Now().UTC()
+		article := model.Article{
+			Title:       "Title",
+			Description: "Description",
+			Body:        "Body",
+			TagList:     []string{"go"},
+			CreatedAt:   now.UnixNano(),
+			UpdatedAt:   now.UnixNano(),
+			Author:      "alice",
+			PublishAt:   now.Add(time.Hour).UnixNano(),
+			ExpireAt:    now.Add(2 * time.Hour).UnixNano(),
+		}
+
+		expired := article
+		expired.PublishAt = 0
+		expired.ExpireAt = now.Add(-time.Hour).UnixNano()
+		assert.Error(t, PutArticle(&expired))
+
+		// An article due to be published later starts out as a draft
+		assert.NoError(t, PutArticle(&article))
+		assert.Equal(t, model.StatusDraft, article.Status)
+
+		assertListed := func(expected []string) {
+			articles, _, err := GetArticles(Page{Limit: 10}, "", "go", "")
+			assert.NoError(t, err)
+			assert.Equal(t, expected, articleSlugs(articles))
+
+			articles, _, err = GetFeed(context.Background(), "bob", Page{Limit: 10})
+			assert.NoError(t, err)
+			assert.Equal(t, expected, articleSlugs(articles))
+		}
+
+		_, err := RunDueArticleSchedules(now.Add(30 * time.Minute))
+		assert.NoError(t, err)
+		assertListed([]string{})
+
+		// Publication is dated to when it was due, however late the scheduler runs
+		_, err = RunDueArticleSchedules(now.Add(90 * time.Minute))
+		assert.NoError(t, err)
+
+		published, err := GetArticleBySlug(article.Slug)
+		assert.NoError(t, err)
+		assert.Equal(t, model.StatusPublished, published.Status)
+		assert.Equal(t, article.PublishAt, published.CreatedAt)
+		assertListed([]string{article.Slug})
+
+		wait, err := RunDueArticleSchedules(now.Add(3 * time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, maxScheduleWait, wait)
+
+		archived, err := GetArticleBySlug(article.Slug)
+		assert.NoError(t, err)
+		assert.Equal(t, model.StatusArchived, archived.Status)
+		assertListed([]string{})
+
+		schedules, err := GetStore().QueryArticleSchedules(math.MaxInt64, 10)
+		assert.NoError(t, err)
+		assert.Empty(t, schedules)
+	})
+}
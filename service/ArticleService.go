@@ -1,267 +1,159 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
 
-	"realworld-go-nolambda/util"
 	"realworld-go-nolambda/model"
-	
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
-	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"realworld-go-nolambda/util"
 )
 
+// PutArticle creates an article, published unless its status is draft or it is due to be published later.
 func PutArticle(article *model.Article) error {
-	err := article.Validate()
-	if err != nil {
-		return err
-	}
-
-	const maxAttempt = 5
-
-	// Try to find a unique article id
-	for attempt := 0; ; attempt++ {
-		err := putArticleWithRandomId(article)
-
-		if err == nil {
-			return nil
-		}
-
-		if attempt >= maxAttempt {
-			return err
-		}
+	now := time.Now().UTC().UnixNano()
 
-		if !IsConditionalCheckFailed(err) {
-			return err
-		}
-
-		ArticleIdRand.RenewSeed()
+	if article.Status == "" {
+		article.Status = model.StatusPublished
 	}
-}
 
-func putArticleWithRandomId(article *model.Article) error {
-	article.ArticleId = 1 + ArticleIdRand.Get().Int63n(model.MaxArticleId-1) // range: [1, MaxArticleId)
-	article.MakeSlug()
+	if article.PublishAt > now {
+		article.Status = model.StatusDraft
+	}
 
-	articleItem, err := dynamodbattribute.MarshalMap(article)
+	err := article.NormalizeTags()
 	if err != nil {
 		return err
 	}
 
-	transactItems := make([]*dynamodb.TransactWriteItem, 0, 1+2*len(article.TagList))
-
-	// Put a new article
-	transactItems = append(transactItems, &dynamodb.TransactWriteItem{
-		Put: &dynamodb.Put{
-			TableName:           aws.String(ArticleTableName),
-			Item:                articleItem,
-			ConditionExpression: aws.String("attribute_not_exists(ArticleId)"),
-		},
-	})
-
-	for _, tag := range article.TagList {
-		articleTag := model.ArticleTag{
-			Tag:       tag,
-			ArticleId: article.ArticleId,
-			CreatedAt: article.CreatedAt,
-		}
-
-		item, err := dynamodbattribute.MarshalMap(articleTag)
-		if err != nil {
-			return err
-		}
-
-		// Link article with tag
-		transactItems = append(transactItems, &dynamodb.TransactWriteItem{
-			Put: &dynamodb.Put{
-				TableName: aws.String(ArticleTagTableName),
-				Item:      item,
-			},
-		})
-
-		// Update article count for each tag
-		transactItems = append(transactItems, &dynamodb.TransactWriteItem{
-			Update: &dynamodb.Update{
-				TableName:        aws.String(TagTableName),
-				Key:              StringKey("Tag", tag),
-				UpdateExpression: aws.String("ADD ArticleCount :one SET Dummy=:zero"),
-				ExpressionAttributeValues: AWSObject{
-					":one":  IntValue(1),
-					":zero": IntValue(0),
-				},
-			},
-		})
+	article.TagList, err = resolveTagAliases(article.TagList)
+	if err != nil {
+		return err
 	}
 
-	_, err = DynamoDB().TransactWriteItems(&dynamodb.TransactWriteItemsInput{
-		TransactItems: transactItems,
-	})
-
-	return err
-}
-
-func GetArticles(offset, limit int, author, tag, favorited string) ([]model.Article, error) {
-	if offset < 0 {
-		return nil, model.NewInputError("offset", "must be non-negative")
+	err = article.Validate()
+	if err != nil {
+		return err
 	}
 
-	if limit <= 0 {
-		return nil, model.NewInputError("limit", "must be positive")
+	if article.Status == model.StatusArchived {
+		return model.NewInputError("status", "can't be archived before it is published")
 	}
 
-	const maxDepth = 1000
-	if offset+limit > maxDepth {
-		return nil, model.NewInputError("offset + limit", fmt.Sprintf("must be smaller or equal to %d", maxDepth))
+	err = validateArticleSchedule(model.Article{}, *article, now)
+	if err != nil {
+		return err
 	}
 
-	numFilters := getNumFilters(author, tag, favorited)
-	if numFilters > 1 {
-		return nil, model.NewInputError("author, tag, favorited", "only one of these can be specified")
+	article.ArticleId, err = NextArticleId()
+	if err != nil {
+		return err
 	}
 
-	if numFilters == 0 {
-		return getAllArticles(offset, limit)
-	}
+	article.MakeSlug()
+	article.Revision = 1
+	analyzeArticleBody(article)
 
-	if author != "" {
-		return getArticlesByAuthor(author, offset, limit)
+	err = putArticleSchedules(*article)
+	if err != nil {
+		return err
 	}
 
-	if tag != "" {
-		return getArticlesByTag(tag, offset, limit)
+	err = GetStore().PutArticle(*article, model.NewArticleRevision(*article, article.Author, model.ArticleFields))
+	if err != nil {
+		return err
 	}
 
-	if favorited != "" {
-		return getFavoriteArticlesByUsername(favorited, offset, limit)
+	err = updateSearchIndex(model.Article{}, *article)
+	if err != nil {
+		return err
 	}
 
-	return nil, errors.New("unreachable code")
+	return fanOutArticle(*article)
 }
 
-func getNumFilters(author, tag, favorited string) int {
-	numFilters := 0
-	if author != "" {
-		numFilters++
-	}
-	if tag != "" {
-		numFilters++
-	}
-	if favorited != "" {
-		numFilters++
-	}
-	return numFilters
-}
+// analyzeArticleBody sets the word count, reading time and outline of the article from its body.
+// Any text takes at least a minute to read.
+func analyzeArticleBody(article *model.Article) {
+	wordCount, headings := util.AnalyzeMarkdown(article.Body)
 
-func getAllArticles(offset, limit int) ([]model.Article, error) {
-	queryArticles := dynamodb.QueryInput{
-		TableName:                 aws.String(ArticleTableName),
-		IndexName:                 aws.String("CreatedAt"),
-		KeyConditionExpression:    aws.String("Dummy=:zero"),
-		ExpressionAttributeValues: IntKey(":zero", 0),
-		Limit:                     aws.Int64(int64(offset + limit)),
-		ScanIndexForward:          aws.Bool(false),
-	}
+	article.WordCount = int64(wordCount)
+	article.ReadingTime = (article.WordCount + model.WordsPerMinute - 1) / model.WordsPerMinute
 
-	items, err := QueryItems(&queryArticles, offset, limit)
-	if err != nil {
-		return nil, err
+	if len(headings) > model.MaxOutlineHeadings {
+		headings = headings[:model.MaxOutlineHeadings]
 	}
 
-	articles := make([]model.Article, len(items))
-	err = dynamodbattribute.UnmarshalListOfMaps(items, &articles)
-	if err != nil {
-		return nil, err
+	article.Outline = make([]model.ArticleHeading, 0, len(headings))
+	for _, heading := range headings {
+		article.Outline = append(article.Outline, model.ArticleHeading{
+			Level:  heading.Level,
+			Text:   heading.Text,
+			Anchor: heading.Anchor,
+		})
 	}
-
-	return articles, nil
 }
 
-func getArticlesByAuthor(author string, offset, limit int) ([]model.Article, error) {
-	queryArticles := dynamodb.QueryInput{
-		TableName:                 aws.String(ArticleTableName),
-		IndexName:                 aws.String("Author"),
-		KeyConditionExpression:    aws.String("Author=:author"),
-		ExpressionAttributeValues: StringKey(":author", author),
-		Limit:                     aws.Int64(int64(offset + limit)),
-		ScanIndexForward:          aws.Bool(false),
+// validatePage bounds offset pagination, which reads and discards every skipped item.
+// Cursor pagination can go arbitrarily deep.
+func validatePage(page Page) error {
+	if page.Offset < 0 {
+		return model.NewInputError("offset", "must be non-negative")
 	}
 
-	items, err := QueryItems(&queryArticles, offset, limit)
-	if err != nil {
-		return nil, err
+	if page.Limit <= 0 {
+		return model.NewInputError("limit", "must be positive")
 	}
 
-	articles := make([]model.Article, len(items))
-	err = dynamodbattribute.UnmarshalListOfMaps(items, &articles)
-	if err != nil {
-		return nil, err
-	}
-
-	return articles, nil
-}
-
-func getArticlesByTag(tag string, offset, limit int) ([]model.Article, error) {
-	articleIds, err := GetArticleIdsByTag(tag, offset, limit)
-	if err != nil {
-		return nil, err
+	const maxDepth = 1000
+	if page.Offset+page.Limit > maxDepth {
+		return model.NewInputError("offset + limit", fmt.Sprintf("must be smaller or equal to %d", maxDepth))
 	}
 
-	return getArticlesByArticleIds(articleIds, limit)
+	return nil
 }
 
-func getFavoriteArticlesByUsername(username string, offset, limit int) ([]model.Article, error) {
-	articleIds, err := GetFavoriteArticleIdsByUsername(username, offset, limit)
-	if err != nil {
-		return nil, err
-	}
-
-	return getArticlesByArticleIds(articleIds, limit)
+func getArticlesByAuthor(author string, page Page) ([]model.Article, *Cursor, error) {
+	return GetStore().QueryArticlesByAuthor(author, page)
 }
 
-func getArticlesByArticleIds(articleIds []int64, limit int) ([]model.Article, error) {
+func getArticlesByArticleIds(articleIds []int64) ([]model.Article, error) {
 	if len(articleIds) == 0 {
 		return make([]model.Article, 0), nil
 	}
 
-	keys := make([]AWSObject, 0, len(articleIds))
-	for _, articleId := range articleIds {
-		keys = append(keys, Int64Key("ArticleId", articleId))
-	}
-
-	batchGetArticles := dynamodb.BatchGetItemInput{
-		RequestItems: map[string]*dynamodb.KeysAndAttributes{
-			ArticleTableName: {
-				Keys: keys,
-			},
-		},
-	}
-
-	responses, err := BatchGetItems(&batchGetArticles, limit)
+	articlesById, err := GetStore().GetArticlesByIds(articleIds)
 	if err != nil {
 		return nil, err
 	}
 
-	articles := make([]model.Article, len(articleIds))
-	articleIdToIndex := ReverseIndexInt64(articleIds)
+	// Favorites and timelines may still refer to unpublished articles
+	articles := make([]model.Article, 0, len(articleIds))
+	for _, articleId := range articleIds {
+		article, ok := articlesById[articleId]
+		if ok && article.IsPublished() {
+			articles = append(articles, article)
+		}
+	}
 
-	for _, response := range responses {
-		for _, items := range response {
-			for _, item := range items {
-				article := model.Article{}
-				err = dynamodbattribute.UnmarshalMap(item, &article)
-				if err != nil {
-					return nil, err
-				}
+	return articles, nil
+}
 
-				index := articleIdToIndex[article.ArticleId]
-				articles[index] = article
-			}
+// liveArticles filters out the articles that have expired, in place.
+func liveArticles(articles []model.Article) []model.Article {
+	now := time.Now().UTC().UnixNano()
+
+	live := articles[:0]
+	for _, article := range articles {
+		if article.IsLiveAt(now) {
+			live = append(live, article)
 		}
 	}
 
-	return articles, nil
+	return live
 }
 
 func GetArticleRelatedProperties(user *model.User, articles []model.Article, getFollowing bool) ([]bool, []model.User, []bool, error) {
@@ -301,9 +193,23 @@ func GetArticleBySlug(slug string) (model.Article, error) {
 	return GetArticleByArticleId(articleId)
 }
 
+// getVisibleArticle returns the article with the given slug, provided it is visible to username.
+// Articles hidden from the user aren't found, like missing ones.
+func getVisibleArticle(slug string, username string) (model.Article, error) {
+	article, err := GetArticleBySlug(slug)
+	if err != nil {
+		return model.Article{}, err
+	}
+
+	if !article.IsVisibleTo(username) {
+		return model.Article{}, model.NewInputError("slug", "not found")
+	}
+
+	return article, nil
+}
+
 func GetArticleByArticleId(articleId int64) (model.Article, error) {
-	article := model.Article{}
-	found, err := GetItemByKey(ArticleTableName, Int64Key("ArticleId", articleId), &article)
+	article, found, err := GetStore().GetArticle(articleId)
 
 	if err != nil {
 		return model.Article{}, err
@@ -316,237 +222,271 @@ func GetArticleByArticleId(articleId int64) (model.Article, error) {
 	return article, nil
 }
 
-func UpdateArticle(oldArticle model.Article, newArticle *model.Article) error {
-	err := newArticle.Validate()
-	if err != nil {
-		return err
+// ErrArticleModified is returned when an article was edited or deleted since the version a write is based on.
+var ErrArticleModified = errors.New("article was modified")
+
+// UpdateArticle replaces oldArticle with newArticle as edited by its author, provided oldArticle is still
+// the current version. A non-empty ifMatch must also list oldArticle's ETag.
+func UpdateArticle(oldArticle model.Article, newArticle *model.Article, editor string, ifMatch string) error {
+	if ifMatch != "" && !util.MatchETag(ifMatch, oldArticle.ETag(), false) {
+		return ErrArticleModified
 	}
 
-	newArticle.MakeSlug()
+	return updateArticle(oldArticle, newArticle, editor, 0)
+}
 
-	oldTagSet := util.NewStringSetFromSlice(oldArticle.TagList)
-	newTagSet := util.NewStringSetFromSlice(newArticle.TagList)
-	oldTags := oldTagSet.Difference(newTagSet)
-	newTags := newTagSet.Difference(oldTagSet)
+// updateArticle stores newArticle as the next revision of oldArticle, restoring revision restoredFrom unless it is 0.
+func updateArticle(oldArticle model.Article, newArticle *model.Article, editor string, restoredFrom int64) error {
+	if oldArticle.Author != editor {
+		return model.NewInputError("slug", "not written by you")
+	}
 
-	transactItems := make([]*dynamodb.TransactWriteItem, 0, 1+2*len(oldTags)+2*len(newTags))
+	err := newArticle.NormalizeTags()
+	if err != nil {
+		return err
+	}
 
-	expr, err := buildArticleUpdateExpression(oldArticle, *newArticle, len(oldTags) != 0 || len(newTags) != 0)
+	newArticle.TagList, err = resolveTagAliases(newArticle.TagList)
 	if err != nil {
 		return err
 	}
 
-	// No field changed
-	if expr.Update() == nil {
-		return nil
-	}
-
-	// Update article
-	transactItems = append(transactItems, &dynamodb.TransactWriteItem{
-		Update: &dynamodb.Update{
-			TableName:                 aws.String(ArticleTableName),
-			Key:                       Int64Key("ArticleId", oldArticle.ArticleId),
-			ConditionExpression:       aws.String("attribute_exists(ArticleId)"),
-			UpdateExpression:          expr.Update(),
-			ExpressionAttributeNames:  expr.Names(),
-			ExpressionAttributeValues: expr.Values(),
-		},
-	})
-
-	for tag := range oldTags {
-		// Unlink article from tag
-		transactItems = append(transactItems, &dynamodb.TransactWriteItem{
-			Delete: &dynamodb.Delete{
-				TableName: aws.String(ArticleTagTableName),
-				Key: AWSObject{
-					"Tag":       StringValue(tag),
-					"ArticleId": Int64Value(oldArticle.ArticleId),
-				},
-			},
-		})
+	err = newArticle.Validate()
+	if err != nil {
+		return err
+	}
 
-		// Update article count for each tag
-		transactItems = append(transactItems, &dynamodb.TransactWriteItem{
-			Update: &dynamodb.Update{
-				TableName:                 aws.String(TagTableName),
-				Key:                       StringKey("Tag", tag),
-				UpdateExpression:          aws.String("ADD ArticleCount :minus_one"),
-				ExpressionAttributeValues: IntKey(":minus_one", -1),
-			},
-		})
+	err = validateArticleSchedule(oldArticle, *newArticle, time.Now().UTC().UnixNano())
+	if err != nil {
+		return err
 	}
 
-	for tag := range newTags {
-		articleTag := model.ArticleTag{
-			Tag:       tag,
-			ArticleId: oldArticle.ArticleId,
-			CreatedAt: oldArticle.CreatedAt,
-		}
+	newArticle.MakeSlug()
+	analyzeArticleBody(newArticle)
 
-		item, err := dynamodbattribute.MarshalMap(articleTag)
+	if newArticle.PublishAt != oldArticle.PublishAt || newArticle.ExpireAt != oldArticle.ExpireAt {
+		err = putArticleSchedules(*newArticle)
 		if err != nil {
 			return err
 		}
+	}
 
-		// Link article with tag.
-		// Ignored benign race condition:
-		//   Current tag list: A B C
-		//   Request 1:        A B      (Delete C)
-		//   Request 2:        A B C D  (Add    D)
-		//   There's a small chance for both requests to get through, leading to inconsistent result A B D
-		transactItems = append(transactItems, &dynamodb.TransactWriteItem{
-			Put: &dynamodb.Put{
-				TableName: aws.String(ArticleTagTableName),
-				Item:      item,
-			},
-		})
+	revisions := make([]model.ArticleRevision, 0, 2)
 
-		// Update article count for each tag
-		transactItems = append(transactItems, &dynamodb.TransactWriteItem{
-			Update: &dynamodb.Update{
-				TableName:        aws.String(TagTableName),
-				Key:              StringKey("Tag", tag),
-				UpdateExpression: aws.String("ADD ArticleCount :one SET Dummy=:zero"),
-				ExpressionAttributeValues: AWSObject{
-					":one":  IntValue(1),
-					":zero": IntValue(0),
-				},
-			},
-		})
+	// The version an article was created with becomes a revision once it is first edited,
+	// if the article predates revisions
+	if oldArticle.Revision == 0 {
+		revisions = append(revisions, firstArticleRevision(oldArticle))
+		newArticle.Revision = 2
+	} else {
+		newArticle.Revision = oldArticle.Revision + 1
+	}
+
+	revision := model.NewArticleRevision(*newArticle, editor, model.ChangedArticleFields(oldArticle, *newArticle))
+	revision.RestoredFrom = restoredFrom
+	revisions = append(revisions, revision)
+
+	err = GetStore().UpdateArticle(oldArticle, *newArticle, revisions)
+	if errors.Is(err, ErrConditionFailed) {
+		return ErrArticleModified
 	}
 
-	_, err = DynamoDB().TransactWriteItems(&dynamodb.TransactWriteItemsInput{
-		TransactItems: transactItems,
-	})
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return updateSearchIndex(oldArticle, *newArticle)
 }
 
-func buildArticleUpdateExpression(oldArticle model.Article, newArticle model.Article, updateTagList bool) (expression.Expression, error) {
-	update := expression.UpdateBuilder{}
-
-	if oldArticle.Slug != newArticle.Slug {
-		update = update.Set(expression.Name("Slug"), expression.Value(newArticle.Slug))
+// DeleteArticle deletes the article with the given slug written by username.
+// A non-empty ifMatch must list the ETag of the article's current version.
+func DeleteArticle(slug string, username string, ifMatch string) error {
+	article, err := GetArticleBySlug(slug)
+	if err != nil {
+		return err
 	}
 
-	if oldArticle.Title != newArticle.Title {
-		update = update.Set(expression.Name("Title"), expression.Value(newArticle.Title))
+	if article.Author != username {
+		return model.NewInputError("slug", "not written by you")
 	}
 
-	if oldArticle.Description != newArticle.Description {
-		update = update.Set(expression.Name("Description"), expression.Value(newArticle.Description))
+	if ifMatch != "" && !util.MatchETag(ifMatch, article.ETag(), false) {
+		return ErrArticleModified
 	}
 
-	if oldArticle.Body != newArticle.Body {
-		update = update.Set(expression.Name("Body"), expression.Value(newArticle.Body))
+	searchTerms := make([]string, 0)
+	for _, posting := range newSearchPostings(article) {
+		searchTerms = append(searchTerms, posting.Term)
 	}
 
-	if updateTagList {
-		update = update.Set(expression.Name("TagList"), expression.Value(newArticle.TagList))
+	cleanup := model.ArticleCleanup{
+		ArticleId:   article.ArticleId,
+		DeletedAt:   time.Now().UTC().UnixNano(),
+		Author:      username,
+		SearchTerms: searchTerms,
 	}
 
-	if oldArticle.UpdatedAt != newArticle.UpdatedAt {
-		update = update.Set(expression.Name("UpdatedAt"), expression.Value(newArticle.UpdatedAt))
+	// The author can't change, so the condition can only fail on a concurrent edit or delete
+	err = GetStore().DeleteArticle(article, cleanup)
+	if errors.Is(err, ErrConditionFailed) {
+		return ErrArticleModified
 	}
 
-	if IsUpdateBuilderEmpty(update) {
-		return expression.Expression{}, nil
+	if err != nil {
+		return err
 	}
 
-	builder := expression.NewBuilder().WithUpdate(update)
-	return builder.Build()
+	// Search postings and timeline entries are removed by the article cleaner, which retries until they are gone
+	requestArticleCleanup()
+	return nil
 }
 
-func DeleteArticle(slug string, username string) error {
-	article, err := GetArticleBySlug(slug)
+// feedWorkers bounds the concurrent author queries of a single feed request.
+const feedWorkers = 8
+
+// feedTimeout bounds the time spent assembling a feed.
+const feedTimeout = 10 * time.Second
+
+// GetFeed returns a page of the articles of the authors followed by username, newest first,
+// and the position to resume from for the next page, nil if there is none.
+// Articles past their expiry are left out, even before the scheduler archives them, and the page is filled
+// from further down the feed instead, until maxScannedArticles were examined.
+func GetFeed(ctx context.Context, username string, page Page) ([]model.Article, *Cursor, error) {
+	err := validatePage(page)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
-	transactItems := make([]*dynamodb.TransactWriteItem, 0, 3+2*len(article.TagList))
-
-	transactItems = append(transactItems, &dynamodb.TransactWriteItem{
-		Delete: &dynamodb.Delete{
-			TableName:                 aws.String(ArticleTableName),
-			Key:                       Int64Key("ArticleId", article.ArticleId),
-			ConditionExpression:       aws.String("Author=:username"),
-			ExpressionAttributeValues: StringKey(":username", username),
-		},
-	})
-
-	// TODO: DynamoDB doesn't support deleting a whole partition by specifying just the partition key.
-	// https://stackoverflow.com/questions/34259358/dynamodb-delete-all-items-having-same-hash-key
-	// It's probably easier to delete related items in FavoriteArticleTable and CommentTable
-	// offline (despite potential article id overwrite).
-
-	for _, tag := range article.TagList {
-		transactItems = append(transactItems, &dynamodb.TransactWriteItem{
-			Delete: &dynamodb.Delete{
-				TableName: aws.String(ArticleTagTableName),
-				Key: AWSObject{
-					"Tag":       StringValue(tag),
-					"ArticleId": Int64Value(article.ArticleId),
-				},
-			},
-		})
+	articles := make([]model.Article, 0, page.Limit)
+	skip := page.Offset
+	after := page.After
 
-		transactItems = append(transactItems, &dynamodb.TransactWriteItem{
-			Update: &dynamodb.Update{
-				TableName:                 aws.String(TagTableName),
-				Key:                       StringKey("Tag", tag),
-				UpdateExpression:          aws.String("ADD ArticleCount :minus_one"),
-				ExpressionAttributeValues: IntKey(":minus_one", -1),
-			},
-		})
-	}
+	for scanned := 0; ; {
+		// The first batch is just what the page needs, later ones make up for expired articles
+		limit := skip + page.Limit - len(articles)
+		if scanned > 0 {
+			limit = util.MaxInt(limit, articleBatchSize)
+		}
 
-	_, err = DynamoDB().TransactWriteItems(&dynamodb.TransactWriteItemsInput{
-		TransactItems: transactItems,
-	})
-	if err != nil {
-		return err
-	}
+		batch, next, err := queryFeed(ctx, username, Page{Limit: limit, After: after})
+		if err != nil {
+			return nil, nil, err
+		}
 
-	return nil
+		scanned += limit
+		for _, article := range liveArticles(batch) {
+			if skip > 0 {
+				skip--
+				continue
+			}
+
+			articles = append(articles, article)
+			if len(articles) == page.Limit {
+				return articles, articleCursor(article), nil
+			}
+		}
+
+		if next == nil || scanned >= maxScannedArticles {
+			return articles, next, nil
+		}
+
+		after = next
+	}
 }
 
-func GetFeed(username string, offset, limit int) ([]model.Article, error) {
-	queryPublishers := dynamodb.QueryInput{
-		TableName:                 aws.String(FollowTableName),
-		KeyConditionExpression:    aws.String("Follower=:username"),
-		ExpressionAttributeValues: StringKey(":username", username),
-		ProjectionExpression:      aws.String("Publisher"),
+func queryFeed(ctx context.Context, username string, page Page) ([]model.Article, *Cursor, error) {
+	if feedMode == FeedModeWrite {
+		return getTimeline(username, page)
 	}
 
-	const queryInitialCapacity = 16
-	items, err := QueryItems(&queryPublishers, 0, queryInitialCapacity)
+	publishers, err := GetStore().QueryPublishers(username)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	follows := make([]model.Follow, 0, len(items))
-	err = dynamodbattribute.UnmarshalListOfMaps(items, &follows)
+	// Every author's articles after the cursor, down to the page's end, may end up on the page
+	authorPage := Page{
+		Limit: page.Offset + page.Limit,
+		After: page.After,
+	}
+
+	articlesByAuthor, err := queryArticlesByAuthors(ctx, publishers, authorPage)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	// TODO: DynamoDB doesn't support batch queries
-	// https://stackoverflow.com/questions/24953783/dynamodb-batch-execute-queryrequests
-	// Concurrent queries can probably improve the performance of the following operations.
+	articles := model.MergeArticles(articlesByAuthor, page.Offset, page.Limit)
+	if len(articles) < page.Limit {
+		return articles, nil, nil
+	}
 
-	articlesByAuthor := make(model.ArticlePriorityQueue, 0, len(follows))
+	return articles, articleCursor(articles[len(articles)-1]), nil
+}
 
-	for _, follow := range follows {
-		articles, err := getArticlesByAuthor(follow.Publisher, 0, limit)
-		if err != nil {
-			return nil, err
+// queryArticlesByAuthors queries a page of articles of every author concurrently, with at most feedWorkers
+// queries in flight. DynamoDB doesn't support batch queries, so this is the closest we get:
+// https://stackoverflow.com/questions/24953783/dynamodb-batch-execute-queryrequests
+// No more queries are started after the first error, or once ctx is done or feedTimeout has passed.
+// Store queries don't take a context, so those already in flight aren't cancelled: the feed waits for them to finish,
+// which can take up to a store query longer than feedTimeout. It only fails on timeout if a query was left out.
+func queryArticlesByAuthors(ctx context.Context, authors []string, page Page) (model.ArticlePriorityQueue, error) {
+	ctx, cancel := context.WithTimeout(ctx, feedTimeout)
+	defer cancel()
+
+	articlesByAuthor := make(model.ArticlePriorityQueue, len(authors))
+	jobs := make(chan int)
+	firstErr := make(chan error, 1)
+	var finished int64
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < util.MinInt(feedWorkers, len(authors)); worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+
+				articles, _, err := getArticlesByAuthor(authors[i], page)
+				if err != nil {
+					select {
+					case firstErr <- err:
+					default:
+					}
+					cancel()
+					return
+				}
+
+				articlesByAuthor[i] = articles
+				atomic.AddInt64(&finished, 1)
+			}
+		}()
+	}
+
+dispatch:
+	for i := range authors {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break dispatch
 		}
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	select {
+	case err := <-firstErr:
+		return nil, err
+	default:
+	}
 
-		articlesByAuthor = append(articlesByAuthor, articles)
+	// A deadline passing after the last query finished doesn't fail a complete feed
+	if int(finished) < len(authors) {
+		return nil, fmt.Errorf("feed of %d authors: %w", len(authors), ctx.Err())
 	}
 
-	return model.MergeArticles(articlesByAuthor, offset, limit), nil
+	return articlesByAuthor, nil
 }
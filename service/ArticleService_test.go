@@ -0,0 +1,270 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"realworld-go-nolambda/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreArticles(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		newTestUser(t, "alice")
+		newTestUser(t, "bob")
+
+		first := newTestArticle(t, "alice", 1, "go", "aws")
+		second := newTestArticle(t, "bob", 2, "go")
+
+		articles, _, err := GetArticles(Page{Limit: 10}, "", "", "")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{second.Slug, first.Slug}, articleSlugs(articles))
+
+		articles, _, err = GetArticles(Page{Limit: 10}, "", "aws", "")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{first.Slug}, articleSlugs(articles))
+
+		tags, err := GetTags()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"go", "aws"}, tags)
+
+		newArticle := first
+		newArticle.TagList = []string{"go"}
+		assert.NoError(t, UpdateArticle(first, &newArticle, first.Author, ""))
+
+		tags, err = GetTags()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"go"}, tags)
+
+		assert.Error(t, DeleteArticle(second.Slug, "alice", ""))
+		assert.NoError(t, DeleteArticle(second.Slug, "bob", ""))
+
+		articles, _, err = GetArticles(Page{Limit: 10}, "", "go", "")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{first.Slug}, articleSlugs(articles))
+	})
+}
+
+func TestStoreArticleConcurrency(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		newTestUser(t, "alice")
+		article := newTestArticle(t, "alice", 1, "go")
+
+		first := article
+		first.Title = "First"
+		first.UpdatedAt = 2
+		assert.NoError(t, UpdateArticle(article, &first, "alice", article.ETag()))
+
+		// Both a stale If-Match and a stale read of the article are rejected
+		second := article
+		second.Title = "Second"
+		second.UpdatedAt = 3
+		assert.Equal(t, ErrArticleModified, UpdateArticle(first, &second, "alice", article.ETag()))
+		assert.Equal(t, ErrArticleModified, UpdateArticle(article, &second, "alice", ""))
+		assert.Equal(t, ErrArticleModified, DeleteArticle(article.Slug, "alice", article.ETag()))
+
+		current, err := GetArticleBySlug(first.Slug)
+		assert.NoError(t, err)
+		assert.Equal(t, "First", current.Title)
+		assert.Equal(t, first.ETag(), current.ETag())
+
+		assert.NoError(t, DeleteArticle(first.Slug, "alice", `"other", `+first.ETag()))
+	})
+}
+
+func TestStoreCursorPagination(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		newTestUser(t, "alice")
+		newTestUser(t, "bob")
+		assert.NoError(t, Follow("bob", "alice"))
+
+		expected := make([]string, 0)
+		for i := 5; i >= 1; i-- {
+			article := newTestArticle(t, "alice", int64(i), "go")
+			expected = append(expected, article.Slug)
+
+			favoriteTestArticle(t, "bob", article, int64(i))
+		}
+
+		listings := map[string]func(page Page) ([]model.Article, *Cursor, error){
+			"all":       func(page Page) ([]model.Article, *Cursor, error) { return GetArticles(page, "", "", "") },
+			"author":    func(page Page) ([]model.Article, *Cursor, error) { return GetArticles(page, "alice", "", "") },
+			"tag":       func(page Page) ([]model.Article, *Cursor, error) { return GetArticles(page, "", "go", "") },
+			"favorited": func(page Page) ([]model.Article, *Cursor, error) { return GetArticles(page, "", "", "bob") },
+			"feed":      func(page Page) ([]model.Article, *Cursor, error) { return GetFeed(context.Background(), "bob", page) },
+		}
+
+		for name, listing := range listings {
+			assert.Equal(t, expected, readAllArticles(t, listing), name)
+		}
+
+		_, err := DecodeCursor("not a cursor")
+		assert.Error(t, err)
+	})
+}
+
+// failingAuthorStore fails the article queries of a single author.
+type failingAuthorStore struct {
+	Store
+	author string
+}
+
+func (s failingAuthorStore) QueryArticlesByAuthor(author string, page Page) ([]model.Article, *Cursor, error) {
+	if author == s.author {
+		return nil, nil, errors.New("query failed")
+	}
+	return s.Store.QueryArticlesByAuthor(author, page)
+}
+
+func TestStoreFeedFanOut(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		newTestUser(t, "reader")
+
+		// More authors than workers, and than a single page of follows
+		const numAuthors = 3*feedWorkers + 1
+		expected := make([]string, 0, numAuthors)
+		for i := numAuthors; i >= 1; i-- {
+			author := fmt.Sprintf("author%02d", i)
+			newTestUser(t, author)
+			assert.NoError(t, Follow("reader", author))
+
+			article := newTestArticle(t, author, int64(i))
+			expected = append(expected, article.Slug)
+		}
+
+		feed, next, err := GetFeed(context.Background(), "reader", Page{Limit: numAuthors})
+		assert.NoError(t, err)
+		assert.Equal(t, expected, articleSlugs(feed))
+		assert.NotNil(t, next)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, _, err = GetFeed(ctx, "reader", Page{Limit: 10})
+		assert.ErrorIs(t, err, context.Canceled)
+
+		SetStore(failingAuthorStore{Store: GetStore(), author: "author07"})
+		_, _, err = GetFeed(context.Background(), "reader", Page{Limit: 10})
+		assert.EqualError(t, err, "query failed")
+	})
+}
+
+// cancellingStore cancels a context during every article query of an author.
+type cancellingStore struct {
+	Store
+	cancel context.CancelFunc
+}
+
+func (s cancellingStore) QueryArticlesByAuthor(author string, page Page) ([]model.Article, *Cursor, error) {
+	s.cancel()
+	return s.Store.QueryArticlesByAuthor(author, page)
+}
+
+func TestStoreFeedCompleteAtDeadline(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		newTestUser(t, "alice")
+		article := newTestArticle(t, "alice", 1)
+
+		// The context ends during the last query, which still completes the feed
+		ctx, cancel := context.WithCancel(context.Background())
+		SetStore(cancellingStore{Store: GetStore(), cancel: cancel})
+		articlesByAuthor, err := queryArticlesByAuthors(ctx, []string{"alice"}, Page{Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{article.Slug}, articleSlugs(articlesByAuthor[0]))
+	})
+}
+
+func TestStoreExpiredArticlesPages(t *testing.T) {
+	defer SetFeedMode(FeedModeRead)
+
+	for _, mode := range []string{FeedModeRead, FeedModeWrite} {
+		SetFeedMode(mode)
+
+		forEachStore(t, func(t *testing.T) {
+			newTestUser(t, "alice")
+			newTestUser(t, "bob")
+			assert.NoError(t, Follow("bob", "alice"))
+
+			live := []model.Article{newTestArticle(t, "alice", 1), newTestArticle(t, "alice", 2)}
+
+			// Articles past their expiry that the scheduler didn't archive yet
+			expiredAt := time.Now().UTC().Add(-time.Hour).UnixNano()
+			for createdAt := int64(3); createdAt <= 5; createdAt++ {
+				article := newTestArticle(t, "alice", createdAt)
+				expired := article
+				expired.ExpireAt = expiredAt
+				assert.NoError(t, GetStore().UpdateArticle(article, expired, nil))
+			}
+
+			articles, next, err := GetFeed(context.Background(), "bob", Page{Limit: 2})
+			assert.NoError(t, err)
+			assert.Equal(t, []string{live[1].Slug, live[0].Slug}, articleSlugs(articles))
+			assert.Equal(t, articleCursor(live[0]), next)
+
+			results, _, err := SearchArticles("title", Page{Limit: 2})
+			assert.NoError(t, err)
+			assert.Len(t, results, 2)
+			for _, result := range results {
+				assert.Zero(t, result.Article.ExpireAt)
+			}
+		})
+	}
+}
+
+func TestStoreArticleReadingTime(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		newTestUser(t, "alice")
+
+		short := newTestArticle(t, "alice", 1)
+
+		long := model.Article{
+			Title:       "Long",
+			Description: "Description",
+			Body:        "# Intro\n\n" + strings.Repeat("word ", 450) + "\n\n## Intro\n\nmore",
+			CreatedAt:   2,
+			UpdatedAt:   2,
+			Author:      "alice",
+		}
+		assert.NoError(t, PutArticle(&long))
+
+		article, err := GetArticleBySlug(long.Slug)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(453), article.WordCount)
+		assert.Equal(t, int64(3), article.ReadingTime)
+		assert.Equal(t, []model.ArticleHeading{
+			{Level: 1, Text: "Intro", Anchor: "intro"},
+			{Level: 2, Text: "Intro", Anchor: "intro-1"},
+		}, article.Outline)
+
+		articles, _, err := FindArticles(ArticleQuery{MinReadingTime: 2}, Page{Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{long.Slug}, articleSlugs(articles))
+
+		articles, _, err = FindArticles(ArticleQuery{MaxReadingTime: 1}, Page{Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{short.Slug}, articleSlugs(articles))
+
+		// Edits analyze the new body
+		edited := article
+		edited.Body = "Just a few words"
+		edited.UpdatedAt = 3
+		assert.NoError(t, UpdateArticle(article, &edited, "alice", ""))
+
+		article, err = GetArticleBySlug(long.Slug)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(4), article.WordCount)
+		assert.Equal(t, int64(1), article.ReadingTime)
+		assert.Empty(t, article.Outline)
+
+		articles, _, err = FindArticles(ArticleQuery{MinReadingTime: 1, MaxReadingTime: 1, Order: OrderOldest}, Page{Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{short.Slug, long.Slug}, articleSlugs(articles))
+
+		_, _, err = FindArticles(ArticleQuery{MinReadingTime: 5, MaxReadingTime: 2}, Page{Limit: 10})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"realworld-go-nolambda/model"
+	"realworld-go-nolambda/util"
+)
+
+// PublishArticle lists a draft or archived article of username. An article published for the first time
+// is dated to now, so it shows up on top of the listings rather than where it was drafted.
+// A non-empty ifMatch must list the ETag of the article's current version.
+func PublishArticle(slug string, username string, ifMatch string) (model.Article, error) {
+	return setArticleStatus(slug, username, ifMatch, model.StatusPublished)
+}
+
+// UnpublishArticle archives a published article of username, taking it out of every listing and feed.
+// A non-empty ifMatch must list the ETag of the article's current version.
+func UnpublishArticle(slug string, username string, ifMatch string) (model.Article, error) {
+	return setArticleStatus(slug, username, ifMatch, model.StatusArchived)
+}
+
+func setArticleStatus(slug string, username string, ifMatch string, status string) (model.Article, error) {
+	oldArticle, err := GetArticleBySlug(slug)
+	if err != nil {
+		return model.Article{}, err
+	}
+
+	if oldArticle.Author != username {
+		return model.Article{}, model.NewInputError("slug", "not written by you")
+	}
+
+	if ifMatch != "" && !util.MatchETag(ifMatch, oldArticle.ETag(), false) {
+		return model.Article{}, ErrArticleModified
+	}
+
+	if status == model.StatusArchived && !oldArticle.IsPublished() {
+		return model.Article{}, model.NewInputError("status", "is not published")
+	}
+
+	if status == model.StatusPublished && oldArticle.IsPublished() {
+		return oldArticle, nil
+	}
+
+	return transitionArticle(oldArticle, status, time.Now().UTC().UnixNano())
+}
+
+// transitionArticle moves oldArticle to status at now, linking or unlinking its tags and adding it to
+// or removing it from timelines. A draft is dated to when it was due to be published, or now if it wasn't scheduled.
+// Republishing an article clears an expiry that has already passed.
+func transitionArticle(oldArticle model.Article, status string, now int64) (model.Article, error) {
+	newArticle := oldArticle
+	newArticle.Status = status
+	newArticle.UpdatedAt = now
+
+	if status == model.StatusPublished {
+		// Unpublished articles may still have tags from before tags were normalized or aliased,
+		// which would be linked as they are
+		canonical, _ := model.CanonicalTagList(oldArticle.TagList)
+		tagList, err := resolveTagAliases(canonical)
+		if err != nil {
+			return model.Article{}, err
+		}
+		newArticle.TagList = tagList
+
+		if oldArticle.Status == model.StatusDraft {
+			publishedAt := now
+			if oldArticle.PublishAt != 0 && oldArticle.PublishAt <= now {
+				publishedAt = oldArticle.PublishAt
+			}
+
+			newArticle.CreatedAt = publishedAt
+			newArticle.PublishAt = publishedAt
+		}
+
+		if newArticle.ExpireAt != 0 && newArticle.ExpireAt <= now {
+			newArticle.ExpireAt = 0
+		}
+
+		err = putArticleSchedules(newArticle)
+		if err != nil {
+			return model.Article{}, err
+		}
+	}
+
+	// The content doesn't change, so neither does the revision
+	err := GetStore().UpdateArticle(oldArticle, newArticle, nil)
+	if errors.Is(err, ErrConditionFailed) {
+		return model.Article{}, ErrArticleModified
+	}
+
+	if err != nil {
+		return model.Article{}, err
+	}
+
+	err = updateSearchIndex(oldArticle, newArticle)
+	if err != nil {
+		return model.Article{}, err
+	}
+
+	if newArticle.IsPublished() {
+		err = fanOutArticle(newArticle)
+	} else {
+		err = removeArticleFromTimelines(oldArticle)
+	}
+
+	return newArticle, err
+}
+
+// GetDrafts returns a page of the draft and archived articles of username newest first,
+// and the position to resume from for the next page, nil if there is none.
+func GetDrafts(username string, page Page) ([]model.Article, *Cursor, error) {
+	err := validatePage(page)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return GetStore().QueryDraftsByAuthor(username, page)
+}
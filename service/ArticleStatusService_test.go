@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"realworld-go-nolambda/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreArticleStatus(t *testing.T) {
+	SetFeedMode(FeedModeWrite)
+	defer SetFeedMode(FeedModeRead)
+
+	forEachStore(t, func(t *testing.T) {
+		newTestUser(t, "alice")
+		newTestUser(t, "bob")
+		assert.NoError(t, Follow("bob", "alice"))
+
+		draft := model.Article{
+			Title:       "Title",
+			Description: "Description",
+			Body:        "Body",
+			TagList:     []string{"go"},
+			CreatedAt:   1,
+			UpdatedAt:   1,
+			Author:      "alice",
+			Status:      model.StatusDraft,
+		}
+		assert.NoError(t, PutArticle(&draft))
+
+		// Drafts are only listed to their author
+		assertListed := func(expected []string) {
+			articles, _, err := GetArticles(Page{Limit: 10}, "", "", "")
+			assert.NoError(t, err)
+			assert.Equal(t, expected, articleSlugs(articles))
+
+			articles, _, err = GetArticles(Page{Limit: 10}, "alice", "", "")
+			assert.NoError(t, err)
+			assert.Equal(t, expected, articleSlugs(articles))
+
+			articles, _, err = GetArticles(Page{Limit: 10}, "", "go", "")
+			assert.NoError(t, err)
+			assert.Equal(t, expected, articleSlugs(articles))
+
+			articles, _, err = GetFeed(context.Background(), "bob", Page{Limit: 10})
+			assert.NoError(t, err)
+			assert.Equal(t, expected, articleSlugs(articles))
+		}
+
+		assertListed([]string{})
+		tags, err := GetTags()
+		assert.NoError(t, err)
+		assert.Empty(t, tags)
+
+		// So are their comments and revisions
+		_, _, _, err = GetComments(draft.Slug, "bob", "", Page{Limit: 10})
+		assert.True(t, model.IsNotFound(err))
+		_, _, _, err = GetComments(draft.Slug, "alice", "", Page{Limit: 10})
+		assert.NoError(t, err)
+
+		_, _, err = GetArticleRevisions(draft.Slug, "", Page{Limit: 10})
+		assert.Error(t, err)
+		_, err = GetArticleRevision(draft.Slug, "bob", 1)
+		assert.Error(t, err)
+		_, err = DiffArticleRevisions(draft.Slug, "bob", 0, 1)
+		assert.Error(t, err)
+		_, err = GetArticleRevision(draft.Slug, "alice", 1)
+		assert.NoError(t, err)
+
+		drafts, _, err := GetDrafts("alice", Page{Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{draft.Slug}, articleSlugs(drafts))
+
+		published, err := PublishArticle(draft.Slug, "alice", draft.ETag())
+		assert.NoError(t, err)
+		assert.Equal(t, model.StatusPublished, published.Status)
+		assert.Greater(t, published.CreatedAt, draft.CreatedAt)
+
+		assertListed([]string{draft.Slug})
+		tags, err = GetTags()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"go"}, tags)
+
+		_, err = UnpublishArticle(draft.Slug, "bob", "")
+		assert.Error(t, err)
+
+		archived, err := UnpublishArticle(draft.Slug, "alice", "")
+		assert.NoError(t, err)
+		assert.Equal(t, model.StatusArchived, archived.Status)
+
+		assertListed([]string{})
+		tags, err = GetTags()
+		assert.NoError(t, err)
+		assert.Empty(t, tags)
+
+		drafts, _, err = GetDrafts("alice", Page{Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{draft.Slug}, articleSlugs(drafts))
+
+		// Republishing keeps the date of the first publication
+		republished, err := PublishArticle(draft.Slug, "alice", "")
+		assert.NoError(t, err)
+		assert.Equal(t, published.CreatedAt, republished.CreatedAt)
+		assertListed([]string{draft.Slug})
+	})
+}
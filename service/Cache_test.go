@@ -0,0 +1,199 @@
+package service
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"realworld-go-nolambda/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUCacheEviction(t *testing.T) {
+	cache := NewLRUCache(2, time.Minute)
+
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+	cache.Get("a")
+	cache.Set("c", 3)
+
+	_, ok := cache.Get("b")
+	assert.False(t, ok, "least recently used entry is evicted")
+
+	value, ok := cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+	assert.Equal(t, 2, cache.Len())
+}
+
+func TestLRUCacheExpiry(t *testing.T) {
+	now := time.Unix(0, 0)
+	cache := NewLRUCache(10, time.Second)
+	cache.now = func() time.Time { return now }
+
+	cache.Set("a", 1)
+	now = now.Add(999 * time.Millisecond)
+	_, ok := cache.Get("a")
+	assert.True(t, ok)
+
+	now = now.Add(time.Millisecond)
+	_, ok = cache.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 0, cache.Len())
+}
+
+// countingStore counts the article reads reaching the store, and can hold them until release is closed.
+type countingStore struct {
+	Store
+	articleReads int64
+	release      chan struct{}
+}
+
+func (s *countingStore) GetArticle(articleId int64) (model.Article, bool, error) {
+	atomic.AddInt64(&s.articleReads, 1)
+	if s.release != nil {
+		<-s.release
+	}
+	return s.Store.GetArticle(articleId)
+}
+
+func (s *countingStore) GetArticlesByIds(articleIds []int64) (map[int64]model.Article, error) {
+	atomic.AddInt64(&s.articleReads, int64(len(articleIds)))
+	if s.release != nil {
+		<-s.release
+	}
+	return s.Store.GetArticlesByIds(articleIds)
+}
+
+func TestCachingStore(t *testing.T) {
+	counting := &countingStore{Store: NewMemoryStore()}
+	store := NewCachingStore(counting, NewLRUCache(100, time.Minute))
+	SetStore(store)
+
+	newTestUser(t, "alice")
+	article := newTestArticle(t, "alice", 1, "go")
+
+	for i := 0; i < 3; i++ {
+		_, err := GetArticleByArticleId(article.ArticleId)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int64(1), counting.articleReads)
+	assert.Equal(t, CacheStats{Hits: 2, Misses: 1}, store.Stats())
+
+	// Writes invalidate
+	favoriteTestArticle(t, "alice", article, 2)
+	cached, err := GetArticleByArticleId(article.ArticleId)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), cached.FavoritesCount)
+
+	updated := cached
+	updated.TagList = []string{"aws"}
+	assert.NoError(t, UpdateArticle(cached, &updated, cached.Author, ""))
+	cached, err = GetArticleByArticleId(article.ArticleId)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"aws"}, cached.TagList)
+
+	tags, err := GetTags()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"aws"}, tags)
+
+	// Concurrent misses share a single read
+	store.invalidate(articleCacheKey(article.ArticleId))
+	counting.articleReads = 0
+	counting.release = make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := GetArticleByArticleId(article.ArticleId)
+			assert.NoError(t, err)
+		}()
+	}
+
+	for atomic.LoadInt64(&counting.articleReads) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(counting.release)
+	wg.Wait()
+
+	assert.Equal(t, int64(1), atomic.LoadInt64(&counting.articleReads))
+}
+
+func TestCachingStoreBatches(t *testing.T) {
+	counting := &countingStore{Store: NewMemoryStore()}
+	store := NewCachingStore(counting, NewLRUCache(100, time.Minute))
+	SetStore(store)
+
+	alice := newTestUser(t, "alice")
+	newTestUser(t, "bob")
+	first := newTestArticle(t, "bob", 1)
+	second := newTestArticle(t, "bob", 2)
+
+	// Concurrent misses of batches and single reads share a read per article
+	counting.release = make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%2 == 0 {
+				_, err := GetArticleByArticleId(first.ArticleId)
+				assert.NoError(t, err)
+				return
+			}
+
+			articlesById, err := GetStore().GetArticlesByIds([]int64{first.ArticleId, second.ArticleId})
+			assert.NoError(t, err)
+			assert.Len(t, articlesById, 2)
+		}(i)
+	}
+
+	for atomic.LoadInt64(&counting.articleReads) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(counting.release)
+	wg.Wait()
+
+	assert.Equal(t, int64(2), atomic.LoadInt64(&counting.articleReads))
+	counting.release = nil
+
+	// Follows and favorites are cached, including their absence, until they change
+	following, err := IsFollowing(&alice, []string{"bob"})
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{false}, following)
+	assert.NoError(t, Follow("alice", "bob"))
+	following, err = IsFollowing(&alice, []string{"bob"})
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{true}, following)
+	assert.NoError(t, Unfollow("alice", "bob"))
+	following, err = IsFollowing(&alice, []string{"bob"})
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{false}, following)
+
+	articles := []model.Article{first, second}
+	favorited, err := IsArticleFavoritedByUser(&alice, articles)
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{false, false}, favorited)
+
+	favorite := favoriteTestArticle(t, "alice", second, 3)
+	favorited, err = IsArticleFavoritedByUser(&alice, articles)
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{false, true}, favorited)
+
+	hits := store.Stats().Hits
+	_, err = IsArticleFavoritedByUser(&alice, articles)
+	assert.NoError(t, err)
+	assert.Equal(t, hits+2, store.Stats().Hits)
+
+	assert.NoError(t, UnfavoriteArticle(favorite.FavoriteArticleKey))
+	favorited, err = IsArticleFavoritedByUser(&alice, articles)
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{false, false}, favorited)
+}
@@ -0,0 +1,614 @@
+package service
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"realworld-go-nolambda/model"
+)
+
+// CachingStore is a Store that reads users, articles, the tag cloud, follows and favorites through a Cache,
+// and invalidates them on the writes that change them. Other servers sharing the database don't see
+// these invalidations, so they may serve stale entries for up to the cache's TTL.
+type CachingStore struct {
+	Store
+	cache  Cache
+	flight singleFlight
+
+	// version is bumped by every invalidation, so that loads racing with a write don't cache what they read
+	version            uint64
+	tagGeneration      uint64
+	favoriteGeneration uint64
+	hits               uint64
+	misses             uint64
+}
+
+type CacheStats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+}
+
+func NewCachingStore(store Store, cache Cache) *CachingStore {
+	return &CachingStore{
+		Store: store,
+		cache: cache,
+	}
+}
+
+func (s *CachingStore) Stats() CacheStats {
+	return CacheStats{
+		Hits:   atomic.LoadUint64(&s.hits),
+		Misses: atomic.LoadUint64(&s.misses),
+	}
+}
+
+// GetCacheStats returns the statistics of the store in use, and false if it isn't cached.
+func GetCacheStats() (CacheStats, bool) {
+	cachingStore, ok := GetStore().(*CachingStore)
+	if !ok {
+		return CacheStats{}, false
+	}
+
+	return cachingStore.Stats(), true
+}
+
+func userCacheKey(username string) string {
+	return "user/" + username
+}
+
+func articleCacheKey(articleId int64) string {
+	return "article/" + strconv.FormatInt(articleId, 10)
+}
+
+// followCacheKey quotes follower, which may contain slashes, so that the key can't be read two ways.
+func followCacheKey(follower string, publisher string) string {
+	return "follow/" + strconv.Quote(follower) + "/" + publisher
+}
+
+// favoriteCacheKey is generational like tagsCacheKey, because the article cleaner removes favorites
+// without saying whose they were.
+func (s *CachingStore) favoriteCacheKey(username string, articleId int64) string {
+	return "favorite/" + strconv.FormatUint(atomic.LoadUint64(&s.favoriteGeneration), 10) + "/" +
+		strconv.FormatInt(articleId, 10) + "/" + username
+}
+
+func (s *CachingStore) tagsCacheKey(parts ...string) string {
+	return "tags/" + strconv.FormatUint(atomic.LoadUint64(&s.tagGeneration), 10) + "/" + strings.Join(parts, "/")
+}
+
+// get looks key up in the cache, counting the hit or miss.
+func (s *CachingStore) get(key string) (interface{}, bool) {
+	value, ok := s.cache.Get(key)
+	if ok {
+		atomic.AddUint64(&s.hits, 1)
+	} else {
+		atomic.AddUint64(&s.misses, 1)
+	}
+	return value, ok
+}
+
+// load reads a missing key through load, once for all concurrent callers, and caches the value if found.
+func (s *CachingStore) load(key string, load func() (interface{}, bool, error)) (interface{}, bool, error) {
+	result, err := s.flight.Do(key, func() (interface{}, error) {
+		version := atomic.LoadUint64(&s.version)
+
+		value, found, err := load()
+		if err != nil {
+			return nil, err
+		}
+
+		if found && atomic.LoadUint64(&s.version) == version {
+			s.cache.Set(key, value)
+		}
+
+		return cacheLookup{value, found}, nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	lookup := result.(cacheLookup)
+	return lookup.value, lookup.found, nil
+}
+
+// getBatch looks keys up in the cache, and reads those missing through load in one batch,
+// except for keys other callers are already reading. load returns the values it found by key,
+// which are cached like those of load. Only the values found are returned.
+func (s *CachingStore) getBatch(keys []string, load func(keys []string) (map[string]interface{}, error)) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(keys))
+	missing := make([]string, 0)
+
+	for _, key := range keys {
+		if value, ok := s.get(key); ok {
+			values[key] = value
+		} else {
+			missing = append(missing, key)
+		}
+	}
+
+	if len(missing) == 0 {
+		return values, nil
+	}
+
+	results, err := s.flight.DoBatch(missing, func(keys []string) (map[string]interface{}, error) {
+		version := atomic.LoadUint64(&s.version)
+
+		loaded, err := load(keys)
+		if err != nil {
+			return nil, err
+		}
+
+		unchanged := atomic.LoadUint64(&s.version) == version
+		lookups := make(map[string]interface{}, len(keys))
+		for _, key := range keys {
+			value, found := loaded[key]
+			if found && unchanged {
+				s.cache.Set(key, value)
+			}
+
+			lookups[key] = cacheLookup{value, found}
+		}
+
+		return lookups, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for key, result := range results {
+		if lookup := result.(cacheLookup); lookup.found {
+			values[key] = lookup.value
+		}
+	}
+
+	return values, nil
+}
+
+type cacheLookup struct {
+	value interface{}
+	found bool
+}
+
+func (s *CachingStore) invalidate(keys ...string) {
+	atomic.AddUint64(&s.version, 1)
+	for _, key := range keys {
+		s.cache.Delete(key)
+	}
+}
+
+func (s *CachingStore) invalidateTags() {
+	atomic.AddUint64(&s.version, 1)
+	atomic.AddUint64(&s.tagGeneration, 1)
+}
+
+func (s *CachingStore) invalidateFavorites() {
+	atomic.AddUint64(&s.version, 1)
+	atomic.AddUint64(&s.favoriteGeneration, 1)
+}
+
+func (s *CachingStore) GetUser(username string) (model.User, bool, error) {
+	key := userCacheKey(username)
+	if value, ok := s.get(key); ok {
+		return copyUser(value.(model.User)), true, nil
+	}
+
+	value, found, err := s.load(key, func() (interface{}, bool, error) {
+		return s.Store.GetUser(username)
+	})
+	if err != nil || !found {
+		return model.User{}, false, err
+	}
+
+	return copyUser(value.(model.User)), true, nil
+}
+
+func (s *CachingStore) GetUsers(usernames []string) (map[string]model.User, error) {
+	keys := make([]string, 0, len(usernames))
+	usernamesByKey := make(map[string]string, len(usernames))
+	for _, username := range usernames {
+		key := userCacheKey(username)
+		keys = append(keys, key)
+		usernamesByKey[key] = username
+	}
+
+	values, err := s.getBatch(keys, func(keys []string) (map[string]interface{}, error) {
+		missing := make([]string, 0, len(keys))
+		for _, key := range keys {
+			missing = append(missing, usernamesByKey[key])
+		}
+
+		loaded, err := s.Store.GetUsers(missing)
+		if err != nil {
+			return nil, err
+		}
+
+		values := make(map[string]interface{}, len(loaded))
+		for username, user := range loaded {
+			values[userCacheKey(username)] = user
+		}
+		return values, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	usersByUsername := make(map[string]model.User, len(values))
+	for key, value := range values {
+		usersByUsername[usernamesByKey[key]] = copyUser(value.(model.User))
+	}
+
+	return usersByUsername, nil
+}
+
+func (s *CachingStore) UpdateUser(oldUser model.User, newUser model.User) error {
+	defer s.invalidate(userCacheKey(oldUser.Username), userCacheKey(newUser.Username))
+	return s.Store.UpdateUser(oldUser, newUser)
+}
+
+func (s *CachingStore) GetArticle(articleId int64) (model.Article, bool, error) {
+	key := articleCacheKey(articleId)
+	if value, ok := s.get(key); ok {
+		return copyArticle(value.(model.Article)), true, nil
+	}
+
+	value, found, err := s.load(key, func() (interface{}, bool, error) {
+		return s.Store.GetArticle(articleId)
+	})
+	if err != nil || !found {
+		return model.Article{}, false, err
+	}
+
+	return copyArticle(value.(model.Article)), true, nil
+}
+
+func (s *CachingStore) GetArticlesByIds(articleIds []int64) (map[int64]model.Article, error) {
+	keys := make([]string, 0, len(articleIds))
+	articleIdsByKey := make(map[string]int64, len(articleIds))
+	for _, articleId := range articleIds {
+		key := articleCacheKey(articleId)
+		keys = append(keys, key)
+		articleIdsByKey[key] = articleId
+	}
+
+	values, err := s.getBatch(keys, func(keys []string) (map[string]interface{}, error) {
+		missing := make([]int64, 0, len(keys))
+		for _, key := range keys {
+			missing = append(missing, articleIdsByKey[key])
+		}
+
+		loaded, err := s.Store.GetArticlesByIds(missing)
+		if err != nil {
+			return nil, err
+		}
+
+		values := make(map[string]interface{}, len(loaded))
+		for articleId, article := range loaded {
+			values[articleCacheKey(articleId)] = article
+		}
+		return values, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	articlesById := make(map[int64]model.Article, len(values))
+	for key, value := range values {
+		articlesById[articleIdsByKey[key]] = copyArticle(value.(model.Article))
+	}
+
+	return articlesById, nil
+}
+
+func (s *CachingStore) PutArticle(article model.Article, revision model.ArticleRevision) error {
+	defer s.invalidateTags()
+	return s.Store.PutArticle(article, revision)
+}
+
+func (s *CachingStore) UpdateArticle(oldArticle model.Article, newArticle model.Article, revisions []model.ArticleRevision) error {
+	defer s.invalidateTags()
+	defer s.invalidate(articleCacheKey(oldArticle.ArticleId))
+	return s.Store.UpdateArticle(oldArticle, newArticle, revisions)
+}
+
+func (s *CachingStore) DeleteArticle(article model.Article, cleanup model.ArticleCleanup) error {
+	defer s.invalidateTags()
+	defer s.invalidate(articleCacheKey(article.ArticleId))
+	return s.Store.DeleteArticle(article, cleanup)
+}
+
+func (s *CachingStore) PutFavoriteArticle(favoriteArticle model.FavoriteArticle) error {
+	defer s.invalidate(articleCacheKey(favoriteArticle.ArticleId), s.favoriteCacheKey(favoriteArticle.Username, favoriteArticle.ArticleId))
+	return s.Store.PutFavoriteArticle(favoriteArticle)
+}
+
+func (s *CachingStore) DeleteFavoriteArticle(key model.FavoriteArticleKey) error {
+	defer s.invalidate(articleCacheKey(key.ArticleId), s.favoriteCacheKey(key.Username, key.ArticleId))
+	return s.Store.DeleteFavoriteArticle(key)
+}
+
+func (s *CachingStore) GetFavoritedArticleIds(username string, articleIds []int64) (map[int64]bool, error) {
+	keys := make([]string, 0, len(articleIds))
+	articleIdsByKey := make(map[string]int64, len(articleIds))
+	for _, articleId := range articleIds {
+		key := s.favoriteCacheKey(username, articleId)
+		keys = append(keys, key)
+		articleIdsByKey[key] = articleId
+	}
+
+	values, err := s.getBatch(keys, func(keys []string) (map[string]interface{}, error) {
+		missing := make([]int64, 0, len(keys))
+		for _, key := range keys {
+			missing = append(missing, articleIdsByKey[key])
+		}
+
+		favorited, err := s.Store.GetFavoritedArticleIds(username, missing)
+		if err != nil {
+			return nil, err
+		}
+
+		// Articles that aren't favorited are cached too, they are most of what a listing asks about
+		values := make(map[string]interface{}, len(keys))
+		for _, key := range keys {
+			values[key] = favorited[articleIdsByKey[key]]
+		}
+		return values, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	favorited := make(map[int64]bool)
+	for key, value := range values {
+		if value.(bool) {
+			favorited[articleIdsByKey[key]] = true
+		}
+	}
+
+	return favorited, nil
+}
+
+// DeleteArticleCleanup frees the id of an article whose favorites the cleaner removed,
+// so cached favorites of the id must not outlive it.
+func (s *CachingStore) DeleteArticleCleanup(articleId int64) error {
+	defer s.invalidateFavorites()
+	return s.Store.DeleteArticleCleanup(articleId)
+}
+
+func (s *CachingStore) PutFollow(follow model.Follow) error {
+	defer s.invalidate(followCacheKey(follow.Follower, follow.Publisher))
+	return s.Store.PutFollow(follow)
+}
+
+func (s *CachingStore) DeleteFollow(follow model.Follow) error {
+	defer s.invalidate(followCacheKey(follow.Follower, follow.Publisher))
+	return s.Store.DeleteFollow(follow)
+}
+
+func (s *CachingStore) GetFollowedPublishers(follower string, publishers []string) (map[string]bool, error) {
+	keys := make([]string, 0, len(publishers))
+	publishersByKey := make(map[string]string, len(publishers))
+	for _, publisher := range publishers {
+		key := followCacheKey(follower, publisher)
+		keys = append(keys, key)
+		publishersByKey[key] = publisher
+	}
+
+	values, err := s.getBatch(keys, func(keys []string) (map[string]interface{}, error) {
+		missing := make([]string, 0, len(keys))
+		for _, key := range keys {
+			missing = append(missing, publishersByKey[key])
+		}
+
+		followed, err := s.Store.GetFollowedPublishers(follower, missing)
+		if err != nil {
+			return nil, err
+		}
+
+		values := make(map[string]interface{}, len(keys))
+		for _, key := range keys {
+			values[key] = followed[publishersByKey[key]]
+		}
+		return values, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	followed := make(map[string]bool)
+	for key, value := range values {
+		if value.(bool) {
+			followed[publishersByKey[key]] = true
+		}
+	}
+
+	return followed, nil
+}
+
+// PutComment, DeleteComment and TombstoneComment change the CommentsCount of the article.
+func (s *CachingStore) PutComment(comment model.Comment) error {
+	defer s.invalidate(articleCacheKey(comment.ArticleId))
+	return s.Store.PutComment(comment)
+}
+
+func (s *CachingStore) DeleteComment(comment model.Comment) error {
+	defer s.invalidate(articleCacheKey(comment.ArticleId))
+	return s.Store.DeleteComment(comment)
+}
+
+func (s *CachingStore) TombstoneComment(key model.CommentKey, deletedAt int64) error {
+	defer s.invalidate(articleCacheKey(key.ArticleId))
+	return s.Store.TombstoneComment(key, deletedAt)
+}
+
+func (s *CachingStore) RecountComments() ([]int64, error) {
+	articleIds, err := s.Store.RecountComments()
+
+	keys := make([]string, 0, len(articleIds))
+	for _, articleId := range articleIds {
+		keys = append(keys, articleCacheKey(articleId))
+	}
+	s.invalidate(keys...)
+
+	return articleIds, err
+}
+
+// QueryTags caches the first page of every prefix, the only one most clients read.
+func (s *CachingStore) QueryTags(prefix string, page Page) ([]model.Tag, *Cursor, error) {
+	if page.Offset != 0 || page.After != nil {
+		return s.Store.QueryTags(prefix, page)
+	}
+
+	type tagPage struct {
+		tags []model.Tag
+		next *Cursor
+	}
+
+	key := s.tagsCacheKey("top", strconv.Itoa(page.Limit), prefix)
+	if value, ok := s.get(key); ok {
+		cached := value.(tagPage)
+		return append([]model.Tag{}, cached.tags...), cached.next, nil
+	}
+
+	value, _, err := s.load(key, func() (interface{}, bool, error) {
+		tags, next, err := s.Store.QueryTags(prefix, page)
+		return tagPage{tags, next}, err == nil, err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cached := value.(tagPage)
+	return append([]model.Tag{}, cached.tags...), cached.next, nil
+}
+
+func (s *CachingStore) QueryTrendingTags(since int64, limit int) ([]model.Tag, error) {
+	key := s.tagsCacheKey("trending", strconv.FormatInt(since, 10), strconv.Itoa(limit))
+	if value, ok := s.get(key); ok {
+		return append([]model.Tag{}, value.([]model.Tag)...), nil
+	}
+
+	value, _, err := s.load(key, func() (interface{}, bool, error) {
+		tags, err := s.Store.QueryTrendingTags(since, limit)
+		return tags, err == nil, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]model.Tag{}, value.([]model.Tag)...), nil
+}
+
+func (s *CachingStore) RecountTags() error {
+	defer s.invalidateTags()
+	return s.Store.RecountTags()
+}
+
+func (s *CachingStore) PutTagDescription(tag string, description string) error {
+	defer s.invalidateTags()
+	return s.Store.PutTagDescription(tag, description)
+}
+
+func (s *CachingStore) DeleteTag(tag string) error {
+	defer s.invalidateTags()
+	return s.Store.DeleteTag(tag)
+}
+
+// singleFlight runs a function once per key at a time. Concurrent callers with the same key share its result.
+type singleFlight struct {
+	mutex sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// DoBatch runs fn once for the keys no call is running for yet, and waits for the calls of the other keys.
+// fn returns a value for each key it was given, which callers of Do with that key share too.
+// It fails with the first error of any of the calls.
+func (g *singleFlight) DoBatch(keys []string, fn func(keys []string) (map[string]interface{}, error)) (map[string]interface{}, error) {
+	g.mutex.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+
+	owned := make(map[string]*flightCall)
+	ownedKeys := make([]string, 0, len(keys))
+	shared := make(map[string]*flightCall)
+	for _, key := range keys {
+		if _, ok := owned[key]; ok {
+			continue
+		}
+
+		if call, ok := g.calls[key]; ok {
+			shared[key] = call
+			continue
+		}
+
+		call := &flightCall{done: make(chan struct{})}
+		g.calls[key] = call
+		owned[key] = call
+		ownedKeys = append(ownedKeys, key)
+	}
+	g.mutex.Unlock()
+
+	// Run our own keys before waiting, so that two batches sharing keys never wait for each other
+	if len(ownedKeys) > 0 {
+		values, err := fn(ownedKeys)
+
+		g.mutex.Lock()
+		for key, call := range owned {
+			call.value, call.err = values[key], err
+			delete(g.calls, key)
+		}
+		g.mutex.Unlock()
+
+		for _, call := range owned {
+			close(call.done)
+		}
+	}
+
+	results := make(map[string]interface{}, len(owned)+len(shared))
+	for _, calls := range []map[string]*flightCall{owned, shared} {
+		for key, call := range calls {
+			<-call.done
+			if call.err != nil {
+				return nil, call.err
+			}
+
+			results[key] = call.value
+		}
+	}
+
+	return results, nil
+}
+
+func (g *singleFlight) Do(key string, fn func() (interface{}, error)) (interface{}, error) {
+	g.mutex.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+
+	if call, ok := g.calls[key]; ok {
+		g.mutex.Unlock()
+		<-call.done
+		return call.value, call.err
+	}
+
+	call := &flightCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mutex.Unlock()
+
+	call.value, call.err = fn()
+
+	g.mutex.Lock()
+	delete(g.calls, key)
+	g.mutex.Unlock()
+	close(call.done)
+
+	return call.value, call.err
+}
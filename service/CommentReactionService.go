@@ -0,0 +1,140 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"realworld-go-nolambda/model"
+)
+
+// ReactToComment adds a reaction of username to a comment, once per reaction, and returns the comment with its new counts.
+// Deleted comments and comments on articles the user can't read can't be reacted to.
+func ReactToComment(slug string, commentId int64, username string, reaction string) (model.Comment, error) {
+	key, err := getReactableComment(slug, commentId, username, reaction)
+	if err != nil {
+		return model.Comment{}, err
+	}
+
+	err = GetStore().PutCommentReaction(model.CommentReaction{
+		CommentReactionKey: model.CommentReactionKey{
+			ArticleId: key.ArticleId,
+			CommentId: key.CommentId,
+			Username:  username,
+			Reaction:  reaction,
+		},
+		ReactedAt: time.Now().UTC().UnixNano(),
+	})
+	if errors.Is(err, ErrConditionFailed) {
+		return model.Comment{}, model.NewInputError("reaction", "not found or already added")
+	}
+
+	if err != nil {
+		return model.Comment{}, err
+	}
+
+	return getReactedComment(key)
+}
+
+// UnreactToComment removes a reaction of username from a comment and returns the comment with its new counts.
+func UnreactToComment(slug string, commentId int64, username string, reaction string) (model.Comment, error) {
+	key, err := getReactableComment(slug, commentId, username, reaction)
+	if err != nil {
+		return model.Comment{}, err
+	}
+
+	err = GetStore().DeleteCommentReaction(model.CommentReactionKey{
+		ArticleId: key.ArticleId,
+		CommentId: key.CommentId,
+		Username:  username,
+		Reaction:  reaction,
+	})
+	if errors.Is(err, ErrConditionFailed) {
+		return model.Comment{}, model.NewInputError("reaction", "not found or not added")
+	}
+
+	if err != nil {
+		return model.Comment{}, err
+	}
+
+	return getReactedComment(key)
+}
+
+// getReactableComment checks that username may react to a comment with reaction, and returns the key of the comment.
+func getReactableComment(slug string, commentId int64, username string, reaction string) (model.CommentKey, error) {
+	if !model.IsReaction(reaction) {
+		return model.CommentKey{}, model.NewInputError("reaction", "must be one of "+strings.Join(model.Reactions, ", "))
+	}
+
+	article, err := getVisibleArticle(slug, username)
+	if err != nil {
+		return model.CommentKey{}, err
+	}
+
+	key := model.CommentKey{
+		ArticleId: article.ArticleId,
+		CommentId: commentId,
+	}
+
+	comment, found, err := GetStore().GetComment(key)
+	if err != nil {
+		return model.CommentKey{}, err
+	}
+
+	if !found || comment.IsDeleted() {
+		return model.CommentKey{}, errCommentNotFound
+	}
+
+	return key, nil
+}
+
+func getReactedComment(key model.CommentKey) (model.Comment, error) {
+	comment, found, err := GetStore().GetComment(key)
+	if err != nil {
+		return model.Comment{}, err
+	}
+
+	if !found {
+		return model.Comment{}, errCommentNotFound
+	}
+
+	return comment, nil
+}
+
+// GetOwnCommentReactions returns the reactions user added to each of the comments of an article, none for anonymous users.
+func GetOwnCommentReactions(user *model.User, comments []model.Comment) ([][]string, error) {
+	ownReactions := make([][]string, len(comments))
+	if user == nil || len(comments) == 0 {
+		return ownReactions, nil
+	}
+
+	commentIds := make([]int64, 0, len(comments))
+	for _, comment := range comments {
+		commentIds = append(commentIds, comment.CommentId)
+	}
+
+	reactions, err := GetStore().GetUserCommentReactions(user.Username, comments[0].ArticleId, commentIds)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, comment := range comments {
+		ownReactions[i] = reactions[comment.CommentId]
+	}
+
+	return ownReactions, nil
+}
+
+// orderReactions lists the reactions added to every comment in the order of model.Reactions.
+func orderReactions(added map[int64]map[string]bool) map[int64][]string {
+	reactions := make(map[int64][]string, len(added))
+	for commentId, commentReactions := range added {
+		for _, reaction := range model.Reactions {
+			if commentReactions[reaction] {
+				reactions[commentId] = append(reactions[commentId], reaction)
+			}
+		}
+	}
+
+	return reactions
+}
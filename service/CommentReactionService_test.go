@@ -0,0 +1,70 @@
+package service
+
+import (
+	"testing"
+
+	"realworld-go-nolambda/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreCommentReactions(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		alice := newTestUser(t, "alice")
+		newTestUser(t, "bob")
+		article := newTestArticle(t, "alice", 1)
+
+		older := newTestComment(t, article, "alice", 2, 0)
+
+		newer := older
+		newer.CreatedAt, newer.UpdatedAt = 3, 3
+		assert.NoError(t, PutComment(&newer))
+
+		_, err := ReactToComment(article.Slug, older.CommentId, "bob", model.ReactionUpvote)
+		assert.NoError(t, err)
+		_, err = ReactToComment(article.Slug, older.CommentId, "alice", model.ReactionHeart)
+		assert.NoError(t, err)
+		reacted, err := ReactToComment(article.Slug, older.CommentId, "alice", model.ReactionUpvote)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), reacted.ReactionCount(model.ReactionUpvote))
+		assert.Equal(t, int64(1), reacted.ReactionCount(model.ReactionHeart))
+		assert.Equal(t, int64(0), reacted.ReactionCount(model.ReactionEyes))
+
+		_, err = ReactToComment(article.Slug, older.CommentId, "alice", model.ReactionUpvote)
+		assert.Error(t, err)
+		_, err = ReactToComment(article.Slug, older.CommentId, "alice", "thumbsDown")
+		assert.Error(t, err)
+
+		comments, _, _, err := GetComments(article.Slug, "", OrderTop, Page{Limit: 10})
+		assert.NoError(t, err)
+		if assert.Len(t, comments, 2) {
+			assert.Equal(t, older.CommentId, comments[0].CommentId)
+		}
+
+		ownReactions, err := GetOwnCommentReactions(&alice, comments)
+		assert.NoError(t, err)
+		assert.Equal(t, [][]string{{model.ReactionUpvote, model.ReactionHeart}, nil}, ownReactions)
+
+		unreacted, err := UnreactToComment(article.Slug, older.CommentId, "bob", model.ReactionUpvote)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), unreacted.Upvotes)
+		_, err = UnreactToComment(article.Slug, older.CommentId, "bob", model.ReactionUpvote)
+		assert.Error(t, err)
+
+		// A tombstone loses its reactions and can't get new ones
+		newTestComment(t, article, "bob", 4, older.CommentId)
+		assert.NoError(t, DeleteComment(article.Slug, older.CommentId, "alice"))
+
+		tombstone, _, err := GetStore().GetComment(older.CommentKey)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), tombstone.ReactionCount(model.ReactionUpvote))
+		assert.Equal(t, int64(0), tombstone.ReactionCount(model.ReactionHeart))
+
+		reactions, err := GetStore().GetUserCommentReactions("alice", article.ArticleId, []int64{older.CommentId})
+		assert.NoError(t, err)
+		assert.Empty(t, reactions)
+
+		_, err = ReactToComment(article.Slug, older.CommentId, "bob", model.ReactionUpvote)
+		assert.Error(t, err)
+	})
+}
@@ -1,58 +1,153 @@
 package service
 
 import (
-	//"realworld-go-nolambda/model"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
 	"realworld-go-nolambda/model"
+)
+
+var errCommentNotFound = model.NewInputError("comment", "not found")
+
+// OrderTop lists comments with the most upvotes first, then by comment id, descending.
+const OrderTop = "top"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+// A page of comments comes with at most repliesPerComment replies of every comment, and maxPageReplies in all.
+// The rest are listed by GetCommentReplies.
+const (
+	repliesPerComment = 10
+	maxPageReplies    = 100
 )
 
+// PutComment adds a comment to an article, as a reply if its ParentId is set. Replies can't be nested deeper
+// than model.MaxCommentDepth, and deleted comments can't be replied to.
 func PutComment(comment *model.Comment) error {
 	err := comment.Validate()
 	if err != nil {
 		return err
 	}
 
-	const maxAttempt = 5
+	comment.Depth = 0
+	comment.ReplyCount = 0
+	comment.DeletedAt = 0
+
+	if comment.ParentId != 0 {
+		parent, found, err := GetStore().GetComment(model.CommentKey{ArticleId: comment.ArticleId, CommentId: comment.ParentId})
+		if err != nil {
+			return err
+		}
+
+		if !found || parent.IsDeleted() {
+			return model.NewInputError("parentId", "not found")
+		}
+
+		if parent.Depth >= model.MaxCommentDepth {
+			return model.NewInputError("parentId", fmt.Sprintf("can't have replies nested deeper than %d", model.MaxCommentDepth))
+		}
+
+		comment.Depth = parent.Depth + 1
+	}
+
+	comment.CommentId, err = NextCommentId()
+	if err != nil {
+		return err
+	}
+
+	err = GetStore().PutComment(*comment)
+	if errors.Is(err, ErrConditionFailed) && comment.ParentId != 0 {
+		// The parent was deleted meanwhile
+		return model.NewInputError("parentId", "not found")
+	}
+
+	return err
+}
+
+// UpdateComment replaces the body of a comment written by username, keeping the previous body as an edit.
+func UpdateComment(slug string, commentId int64, username string, body string) (model.Comment, error) {
+	comment, err := getOwnComment(slug, commentId, username)
+	if err != nil {
+		return model.Comment{}, err
+	}
+
+	for {
+		newComment := comment
+		newComment.Body = body
+		newComment.UpdatedAt = time.Now().UTC().UnixNano()
+		newComment.EditCount = comment.EditCount + 1
+
+		err = newComment.Validate()
+		if err != nil {
+			return model.Comment{}, err
+		}
+
+		if newComment.Body == comment.Body {
+			return comment, nil
+		}
 
-	// Try to find a unique comment id
-	for attempt := 0; ; attempt++ {
-		err := putCommentWithRandomId(comment)
+		editId, err := NextCommentEditId()
+		if err != nil {
+			return model.Comment{}, err
+		}
 
+		edit := model.CommentEdit{
+			ArticleId: comment.ArticleId,
+			EditId:    editId,
+			CommentId: comment.CommentId,
+			Body:      comment.Body,
+			WrittenAt: comment.UpdatedAt,
+			EditedAt:  newComment.UpdatedAt,
+		}
+
+		err = GetStore().UpdateComment(comment, newComment, edit)
 		if err == nil {
-			return nil
+			return newComment, nil
 		}
 
-		if attempt >= maxAttempt {
-			return err
+		if !errors.Is(err, ErrConditionFailed) {
+			return model.Comment{}, err
 		}
 
-		if !IsConditionalCheckFailed(err) {
-			return err
+		// Edited or deleted meanwhile, edit the current version
+		comment, err = getOwnComment(slug, commentId, username)
+		if err != nil {
+			return model.Comment{}, err
 		}
+	}
+}
 
-		CommentIdRand.RenewSeed()
+// GetCommentEdits returns the previous bodies of a comment, newest first. Only its author can see them.
+func GetCommentEdits(slug string, commentId int64, username string) ([]model.CommentEdit, error) {
+	comment, err := getOwnComment(slug, commentId, username)
+	if err != nil {
+		return nil, err
 	}
+
+	return GetStore().QueryCommentEdits(comment.CommentKey)
 }
 
-func putCommentWithRandomId(comment *model.Comment) error {
-	comment.CommentId = 1 + CommentIdRand.Get().Int63n(model.MaxCommentId-1) // range: [1, MaxCommentId)
+// getOwnComment returns a comment of the article with the given slug, unless it is deleted or written by someone else.
+func getOwnComment(slug string, commentId int64, username string) (model.Comment, error) {
+	articleId, err := model.SlugToArticleId(slug)
+	if err != nil {
+		return model.Comment{}, err
+	}
 
-	commentItem, err := dynamodbattribute.MarshalMap(comment)
+	comment, found, err := GetStore().GetComment(model.CommentKey{ArticleId: articleId, CommentId: commentId})
 	if err != nil {
-		return err
+		return model.Comment{}, err
 	}
 
-	// Put a new article
-	_, err = DynamoDB().PutItem(&dynamodb.PutItemInput{
-		TableName:           aws.String(CommentTableName),
-		Item:                commentItem,
-		ConditionExpression: aws.String("attribute_not_exists(CommentId)"),
-	})
+	if !found || comment.IsDeleted() {
+		return model.Comment{}, errCommentNotFound
+	}
 
-	return err
+	if comment.Author != username {
+		return model.Comment{}, model.NewInputError("comment", "not written by you")
+	}
+
+	return comment, nil
 }
 
 func GetCommentRelatedProperties(user *model.User, comments []model.Comment) ([]model.User, []bool, error) {
@@ -74,35 +169,143 @@ func GetCommentRelatedProperties(user *model.User, comments []model.Comment) ([]
 	return authors, following, nil
 }
 
-func GetComments(slug string) ([]model.Comment, error) {
-	articleId, err := model.SlugToArticleId(slug)
+// GetComments returns a page of the top-level comments of an article in order OrderNewest, OrderOldest or OrderTop,
+// OrderNewest if order is empty, each followed by its first replies oldest first, depth first.
+// It also returns the position to resume from for the next page, nil if there is none,
+// and the number of comments of the article, replies included. Only the comments of articles visible to username are listed.
+func GetComments(slug string, username string, order string, page Page) ([]model.Comment, *Cursor, int64, error) {
+	err := validatePage(page)
 	if err != nil {
-		return nil, err
+		return nil, nil, 0, err
 	}
 
-	queryComments := dynamodb.QueryInput{
-		TableName:                 aws.String(CommentTableName),
-		IndexName:                 aws.String("CreatedAt"),
-		KeyConditionExpression:    aws.String("ArticleId=:articleId"),
-		ExpressionAttributeValues: Int64Key(":articleId", articleId),
-		ScanIndexForward:          aws.Bool(false),
+	if order == "" {
+		order = OrderNewest
 	}
 
-	const queryInitialCapacity = 16
-	items, err := QueryItems(&queryComments, 0, queryInitialCapacity)
+	if order != OrderNewest && order != OrderOldest && order != OrderTop {
+		return nil, nil, 0, model.NewInputError("sort", "must be one of newest, oldest and top")
+	}
+
+	article, err := getVisibleArticle(slug, username)
 	if err != nil {
-		return nil, err
+		return nil, nil, 0, err
 	}
 
-	comments := make([]model.Comment, len(items))
-	err = dynamodbattribute.UnmarshalListOfMaps(items, &comments)
+	roots, next, err := GetStore().QueryComments(article.ArticleId, order, page)
 	if err != nil {
-		return nil, err
+		return nil, nil, 0, err
 	}
 
-	return comments, nil
+	replies, err := loadReplies(article.ArticleId, roots)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	return threadComments(roots, replies), next, article.CommentsCount, nil
 }
 
+// GetCommentReplies returns a page of the direct replies to a comment, oldest first, each followed by its first replies
+// like in GetComments. It also returns the position to resume from for the next page, nil if there is none,
+// and the number of direct replies to the comment. Only the comments of articles visible to username are listed.
+func GetCommentReplies(slug string, username string, commentId int64, page Page) ([]model.Comment, *Cursor, int64, error) {
+	err := validatePage(page)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	article, err := getVisibleArticle(slug, username)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	parent, found, err := GetStore().GetComment(model.CommentKey{ArticleId: article.ArticleId, CommentId: commentId})
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	if !found {
+		return nil, nil, 0, errCommentNotFound
+	}
+
+	roots, next, err := GetStore().QueryCommentReplies(article.ArticleId, parent.CommentId, page)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	replies, err := loadReplies(article.ArticleId, roots)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	return threadComments(roots, replies), next, parent.ReplyCount, nil
+}
+
+// loadReplies returns the replies below the given comments, up to repliesPerComment of every comment, oldest first,
+// and maxPageReplies in all. They are loaded a level at a time, only below the comments that have some.
+func loadReplies(articleId int64, comments []model.Comment) ([]model.Comment, error) {
+	replies := make([]model.Comment, 0)
+	level := comments
+	for len(level) != 0 {
+		nextLevel := make([]model.Comment, 0)
+		for _, comment := range level {
+			limit := maxPageReplies - len(replies) - len(nextLevel)
+			if limit <= 0 {
+				break
+			}
+
+			if comment.ReplyCount == 0 {
+				continue
+			}
+
+			if limit > repliesPerComment {
+				limit = repliesPerComment
+			}
+
+			commentReplies, _, err := GetStore().QueryCommentReplies(articleId, comment.CommentId, Page{Limit: limit})
+			if err != nil {
+				return nil, err
+			}
+
+			nextLevel = append(nextLevel, commentReplies...)
+		}
+
+		replies = append(replies, nextLevel...)
+		level = nextLevel
+	}
+
+	return replies, nil
+}
+
+// threadComments follows every top-level comment, in their given order, with its replies, oldest first, depth first.
+func threadComments(roots []model.Comment, replies []model.Comment) []model.Comment {
+	sort.SliceStable(replies, func(i, j int) bool {
+		return commentBefore(replies[i], replies[j])
+	})
+
+	repliesByParent := make(map[int64][]model.Comment)
+	for _, reply := range replies {
+		repliesByParent[reply.ParentId] = append(repliesByParent[reply.ParentId], reply)
+	}
+
+	threaded := make([]model.Comment, 0, len(roots)+len(replies))
+	var appendThread func(comment model.Comment)
+	appendThread = func(comment model.Comment) {
+		threaded = append(threaded, comment)
+		for _, reply := range repliesByParent[comment.CommentId] {
+			appendThread(reply)
+		}
+	}
+
+	for _, root := range roots {
+		appendThread(root)
+	}
+
+	return threaded
+}
+
+// DeleteComment deletes a comment written by username. A comment with replies is replaced by a tombstone,
+// which is deleted in turn once its last reply is.
 func DeleteComment(slug string, commentId int64, username string) error {
 	articleId, err := model.SlugToArticleId(slug)
 	if err != nil {
@@ -114,19 +317,77 @@ func DeleteComment(slug string, commentId int64, username string) error {
 		CommentId: commentId,
 	}
 
-	item, err := dynamodbattribute.MarshalMap(key)
+	for {
+		comment, found, err := GetStore().GetComment(key)
+		if err != nil {
+			return err
+		}
+
+		if !found || comment.IsDeleted() || comment.Author != username {
+			return errCommentNotFound
+		}
+
+		if comment.ReplyCount > 0 {
+			err = GetStore().TombstoneComment(key, time.Now().UTC().UnixNano())
+			if err == nil {
+				return deleteCommentHistory(key)
+			}
+		} else {
+			err = GetStore().DeleteComment(comment)
+			if err == nil {
+				err = deleteCommentHistory(key)
+				if err != nil {
+					return err
+				}
+
+				return deleteTombstones(comment)
+			}
+		}
+
+		// Retry if a reply was written or deleted meanwhile
+		if !errors.Is(err, ErrConditionFailed) {
+			return err
+		}
+	}
+}
+
+// deleteCommentHistory deletes the edits of a deleted comment and the reactions to it.
+func deleteCommentHistory(key model.CommentKey) error {
+	err := GetStore().DeleteCommentEdits(key)
 	if err != nil {
 		return err
 	}
 
-	deleteComment := dynamodb.DeleteItemInput{
-		TableName:                 aws.String(CommentTableName),
-		Key:                       item,
-		ConditionExpression:       aws.String("Author=:username"),
-		ExpressionAttributeValues: StringKey(":username", username),
+	return GetStore().DeleteCommentReactions(key)
+}
+
+// deleteTombstones deletes the tombstones among the ancestors of a deleted comment that have no replies left.
+func deleteTombstones(comment model.Comment) error {
+	for comment.ParentId != 0 {
+		parent, found, err := GetStore().GetComment(model.CommentKey{ArticleId: comment.ArticleId, CommentId: comment.ParentId})
+		if err != nil || !found || !parent.IsDeleted() || parent.ReplyCount > 0 {
+			return err
+		}
+
+		err = GetStore().DeleteComment(parent)
+		if errors.Is(err, ErrConditionFailed) {
+			// Deleted by someone else meanwhile
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		comment = parent
 	}
 
-	_, err = DynamoDB().DeleteItem(&deleteComment)
+	return nil
+}
 
-	return err
+// RecountComments sets the comment count of every article from its comments, e.g. after upgrading a DynamoDB store
+// from a version that didn't count them. It returns the number of articles whose count was off.
+func RecountComments() (int, error) {
+	articleIds, err := GetStore().RecountComments()
+	return len(articleIds), err
 }
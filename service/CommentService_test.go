@@ -0,0 +1,332 @@
+package service
+
+import (
+	"testing"
+
+	"realworld-go-nolambda/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreComments(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		newTestUser(t, "alice")
+		article := newTestArticle(t, "alice", 1)
+
+		comment := newTestComment(t, article, "alice", 2, 0)
+
+		comments, _, count, err := GetComments(article.Slug, "", "", Page{Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, []model.Comment{comment}, comments)
+		assert.Equal(t, int64(1), count)
+
+		assert.Error(t, DeleteComment(article.Slug, comment.CommentId, "bob"))
+		assert.NoError(t, DeleteComment(article.Slug, comment.CommentId, "alice"))
+
+		comments, _, count, err = GetComments(article.Slug, "", "", Page{Limit: 10})
+		assert.NoError(t, err)
+		assert.Empty(t, comments)
+		assert.Equal(t, int64(0), count)
+	})
+}
+
+func TestStoreCommentThreads(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		newTestUser(t, "alice")
+		newTestUser(t, "bob")
+		article := newTestArticle(t, "alice", 1)
+
+		commentIds := func() []int64 {
+			comments, _, _, err := GetComments(article.Slug, "", "", Page{Limit: 10})
+			assert.NoError(t, err)
+
+			ids := make([]int64, 0, len(comments))
+			for _, comment := range comments {
+				ids = append(ids, comment.CommentId)
+			}
+			return ids
+		}
+
+		first := newTestComment(t, article, "alice", 2, 0)
+		second := newTestComment(t, article, "bob", 3, 0)
+		reply := newTestComment(t, article, "bob", 4, first.CommentId)
+		nested := newTestComment(t, article, "alice", 5, reply.CommentId)
+		laterReply := newTestComment(t, article, "alice", 6, first.CommentId)
+		assert.Equal(t, 2, nested.Depth)
+
+		assert.Equal(t, []int64{second.CommentId, first.CommentId, reply.CommentId, nested.CommentId, laterReply.CommentId}, commentIds())
+
+		unknown := model.Comment{
+			CommentKey: model.CommentKey{ArticleId: article.ArticleId},
+			Body:       "Nice",
+			Author:     "bob",
+			ParentId:   12345,
+		}
+		assert.Error(t, PutComment(&unknown))
+
+		// A comment with replies leaves a tombstone
+		assert.NoError(t, DeleteComment(article.Slug, first.CommentId, "alice"))
+		tombstone, found, err := GetStore().GetComment(first.CommentKey)
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.True(t, tombstone.IsDeleted())
+		assert.Empty(t, tombstone.Body)
+		assert.Equal(t, int64(2), tombstone.ReplyCount)
+		assert.Equal(t, []int64{second.CommentId, first.CommentId, reply.CommentId, nested.CommentId, laterReply.CommentId}, commentIds())
+
+		deletedParent := model.Comment{
+			CommentKey: model.CommentKey{ArticleId: article.ArticleId},
+			Body:       "Nice",
+			Author:     "bob",
+			ParentId:   first.CommentId,
+		}
+		assert.Error(t, PutComment(&deletedParent))
+		assert.Error(t, DeleteComment(article.Slug, first.CommentId, "alice"))
+
+		// The tombstone goes with its last reply
+		assert.NoError(t, DeleteComment(article.Slug, laterReply.CommentId, "alice"))
+		assert.NoError(t, DeleteComment(article.Slug, reply.CommentId, "bob"))
+		assert.Equal(t, []int64{second.CommentId, first.CommentId, reply.CommentId, nested.CommentId}, commentIds())
+
+		assert.NoError(t, DeleteComment(article.Slug, nested.CommentId, "alice"))
+		assert.Equal(t, []int64{second.CommentId}, commentIds())
+
+		article, err = GetArticleByArticleId(article.ArticleId)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), article.CommentsCount)
+	})
+}
+
+func TestStoreRecountComments(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		newTestUser(t, "alice")
+		article := newTestArticle(t, "alice", 1)
+		other := newTestArticle(t, "alice", 2)
+
+		for createdAt := int64(2); createdAt <= 4; createdAt++ {
+			newTestComment(t, article, "alice", createdAt, 0)
+		}
+
+		numArticles, err := RecountComments()
+		assert.NoError(t, err)
+		assert.Equal(t, 0, numArticles)
+
+		// As on articles commented on before the count was kept
+		resetCommentsCount(t, article.ArticleId)
+
+		numArticles, err = RecountComments()
+		assert.NoError(t, err)
+		assert.Equal(t, 1, numArticles)
+
+		_, _, count, err := GetComments(article.Slug, "", "", Page{Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), count)
+
+		_, _, count, err = GetComments(other.Slug, "", "", Page{Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), count)
+	})
+}
+
+// resetCommentsCount zeroes the stored CommentsCount of an article behind the store's back.
+func resetCommentsCount(t *testing.T, articleId int64) {
+	store := GetStore()
+	if caching, ok := store.(*CachingStore); ok {
+		defer caching.invalidate(articleCacheKey(articleId))
+		store = caching.Store
+	}
+
+	switch store := store.(type) {
+	case *MemoryStore:
+		store.mutex.Lock()
+		article := store.articles[articleId]
+		article.CommentsCount = 0
+		store.articles[articleId] = article
+		store.mutex.Unlock()
+	case *SQLStore:
+		_, err := store.exec(store.db, "UPDATE articles SET comments_count = 0 WHERE article_id = ?", articleId)
+		assert.NoError(t, err)
+	default:
+		t.Fatalf("can't reset the comments count of a %T", store)
+	}
+}
+
+func TestStoreCommentReplyPages(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		newTestUser(t, "alice")
+		article := newTestArticle(t, "alice", 1)
+
+		createdAt := int64(1)
+		putComment := func(parentId int64) model.Comment {
+			createdAt++
+			return newTestComment(t, article, "alice", createdAt, parentId)
+		}
+
+		ids := func(comments []model.Comment) []int64 {
+			ids := make([]int64, 0, len(comments))
+			for _, comment := range comments {
+				ids = append(ids, comment.CommentId)
+			}
+			return ids
+		}
+
+		root := putComment(0)
+		replyIds := make([]int64, 0, repliesPerComment+2)
+		for i := 0; i < repliesPerComment+2; i++ {
+			replyIds = append(replyIds, putComment(root.CommentId).CommentId)
+		}
+		nested := putComment(replyIds[0])
+
+		// Only the first replies of a comment come with it
+		comments, _, _, err := GetComments(article.Slug, "", "", Page{Limit: 10})
+		assert.NoError(t, err)
+		expected := append([]int64{root.CommentId, replyIds[0], nested.CommentId}, replyIds[1:repliesPerComment]...)
+		assert.Equal(t, expected, ids(comments))
+
+		// The rest are paged through separately
+		var page Page
+		var all []model.Comment
+		for pages := 0; pages < 10; pages++ {
+			page.Limit = 5
+			replies, next, replyCount, err := GetCommentReplies(article.Slug, "", root.CommentId, page)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(repliesPerComment+2), replyCount)
+			all = append(all, replies...)
+			if next == nil {
+				break
+			}
+			page.After = next
+		}
+		assert.Equal(t, append([]int64{replyIds[0], nested.CommentId}, replyIds[1:]...), ids(all))
+
+		_, _, _, err = GetCommentReplies(article.Slug, "", 12345, Page{Limit: 5})
+		assert.True(t, model.IsNotFound(err))
+
+		// A page carries a bounded number of replies in all
+		for i := 0; i < maxPageReplies/repliesPerComment; i++ {
+			parent := putComment(0)
+			for j := 0; j < repliesPerComment; j++ {
+				putComment(parent.CommentId)
+			}
+		}
+
+		comments, _, _, err = GetComments(article.Slug, "", "", Page{Limit: 100})
+		assert.NoError(t, err)
+		replies := 0
+		for _, comment := range comments {
+			if comment.ParentId != 0 {
+				replies++
+			}
+		}
+		assert.Equal(t, maxPageReplies, replies)
+	})
+}
+
+func TestStoreCommentPagination(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		newTestUser(t, "alice")
+		article := newTestArticle(t, "alice", 1)
+
+		roots := make([]model.Comment, 0, 5)
+		for i := 0; i < 5; i++ {
+			roots = append(roots, newTestComment(t, article, "alice", int64(2+i), 0))
+		}
+
+		reply := newTestComment(t, article, "alice", 10, roots[1].CommentId)
+
+		readAll := func(order string) []int64 {
+			ids := make([]int64, 0)
+			page := Page{Limit: 2}
+			for {
+				comments, next, count, err := GetComments(article.Slug, "", order, page)
+				if !assert.NoError(t, err) {
+					return ids
+				}
+				assert.Equal(t, int64(6), count)
+
+				for _, comment := range comments {
+					ids = append(ids, comment.CommentId)
+				}
+
+				if next == nil {
+					return ids
+				}
+				page.After = next
+			}
+		}
+
+		// Replies come with their parent and don't count towards the limit
+		assert.Equal(t, []int64{roots[4].CommentId, roots[3].CommentId, roots[2].CommentId, roots[1].CommentId, reply.CommentId, roots[0].CommentId}, readAll(OrderNewest))
+		assert.Equal(t, []int64{roots[0].CommentId, roots[1].CommentId, reply.CommentId, roots[2].CommentId, roots[3].CommentId, roots[4].CommentId}, readAll(OrderOldest))
+
+		_, _, _, err := GetComments(article.Slug, "", "best", Page{Limit: 2})
+		assert.IsType(t, model.InputError{}, err)
+		assert.False(t, model.IsNotFound(err))
+		_, _, _, err = GetComments(article.Slug, "", "", Page{Limit: 0})
+		assert.Error(t, err)
+	})
+}
+
+func TestStoreCommentEdits(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		newTestUser(t, "alice")
+		newTestUser(t, "bob")
+		article := newTestArticle(t, "alice", 1)
+
+		comment := model.Comment{
+			CommentKey: model.CommentKey{ArticleId: article.ArticleId},
+			CreatedAt:  2,
+			UpdatedAt:  2,
+			Body:       "First",
+			Author:     "alice",
+		}
+		assert.NoError(t, PutComment(&comment))
+		assert.False(t, comment.IsEdited())
+
+		_, err := UpdateComment(article.Slug, comment.CommentId, "bob", "Mine")
+		assert.Error(t, err)
+		_, err = UpdateComment(article.Slug, comment.CommentId, "alice", "")
+		assert.Error(t, err)
+
+		edited, err := UpdateComment(article.Slug, comment.CommentId, "alice", "Second")
+		assert.NoError(t, err)
+		assert.True(t, edited.IsEdited())
+
+		edited, err = UpdateComment(article.Slug, comment.CommentId, "alice", "Third")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), edited.EditCount)
+
+		// Edits don't use up comment ids
+		nextCommentId, err := NextCommentId()
+		assert.NoError(t, err)
+		assert.Equal(t, comment.CommentId+1, nextCommentId)
+
+		// A stale version is rejected by the store
+		stale := comment
+		stale.Body = "Stale"
+		assert.True(t, IsConditionalCheckFailed(GetStore().UpdateComment(comment, stale, model.CommentEdit{ArticleId: article.ArticleId, EditId: 1})))
+
+		comments, _, _, err := GetComments(article.Slug, "", "", Page{Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, "Third", comments[0].Body)
+		assert.True(t, comments[0].IsEdited())
+
+		edits, err := GetCommentEdits(article.Slug, comment.CommentId, "alice")
+		assert.NoError(t, err)
+		if assert.Len(t, edits, 2) {
+			assert.Equal(t, "Second", edits[0].Body)
+			assert.Equal(t, "First", edits[1].Body)
+			assert.Equal(t, int64(2), edits[1].WrittenAt)
+			assert.Equal(t, edits[1].EditedAt, edits[0].WrittenAt)
+		}
+
+		_, err = GetCommentEdits(article.Slug, comment.CommentId, "bob")
+		assert.Error(t, err)
+
+		// The history goes with the comment
+		assert.NoError(t, DeleteComment(article.Slug, comment.CommentId, "alice"))
+		edits, err = GetStore().QueryCommentEdits(comment.CommentKey)
+		assert.NoError(t, err)
+		assert.Empty(t, edits)
+	})
+}
@@ -1,6 +1,9 @@
 package service
 
 import (
+	"fmt"
+	"time"
+
 	//"realworld-go-nolambda/util"
 	"realworld-go-nolambda/util"
 
@@ -57,6 +60,74 @@ func QueryItems(queryInput *dynamodb.QueryInput, offset, cap int) ([]AWSObject,
 	return items, nil
 }
 
+// QueryPage skips offset items of a query and returns the next limit ones, requesting no more items than needed.
+// It also returns the LastEvaluatedKey of the last request, nil if the query is exhausted.
+func QueryPage(queryInput *dynamodb.QueryInput, offset, limit int) ([]AWSObject, AWSObject, error) {
+	need := offset + limit
+	items := make([]AWSObject, 0, need)
+
+	for {
+		queryInput.Limit = aws.Int64(int64(need - len(items)))
+
+		output, err := DynamoDB().Query(queryInput)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		items = append(items, output.Items...)
+
+		if len(output.LastEvaluatedKey) == 0 {
+			return items[util.MinInt(offset, len(items)):], nil, nil
+		}
+
+		if len(items) >= need {
+			return items[offset:], output.LastEvaluatedKey, nil
+		}
+
+		queryInput.ExclusiveStartKey = output.LastEvaluatedKey
+	}
+}
+
+// keyCursor reads the position of an item from its key, e.g. a LastEvaluatedKey. It returns nil for a nil key.
+func keyCursor(key AWSObject, sortKeyName string) (*Cursor, error) {
+	if len(key) == 0 {
+		return nil, nil
+	}
+
+	cursor := Cursor{}
+
+	err := dynamodbattribute.Unmarshal(key["ArticleId"], &cursor.ArticleId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = dynamodbattribute.Unmarshal(key[sortKeyName], &cursor.SortKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cursor, nil
+}
+
+// cursorKey builds the ExclusiveStartKey of an index query resuming after cursor.
+// partitionKey holds the hash keys of the table and the index, sortKeyName is the range key of the index.
+func cursorKey(cursor *Cursor, partitionKey AWSObject, sortKeyName string) AWSObject {
+	if cursor == nil {
+		return nil
+	}
+
+	key := AWSObject{
+		"ArticleId": Int64Value(cursor.ArticleId),
+		sortKeyName: Int64Value(cursor.SortKey),
+	}
+
+	for name, value := range partitionKey {
+		key[name] = value
+	}
+
+	return key
+}
+
 func BatchGetItems(batchGetInput *dynamodb.BatchGetItemInput, cap int) ([]map[string][]AWSObject, error) {
 	responses := make([]map[string][]AWSObject, 0, cap)
 
@@ -71,3 +142,79 @@ func BatchGetItems(batchGetInput *dynamodb.BatchGetItemInput, cap int) ([]map[st
 
 	return responses, nil
 }
+
+// maxBatchWriteItems is the most items a single BatchWriteItem call accepts.
+const maxBatchWriteItems = 25
+
+// BatchDeleteItems deletes items of a table by key, see BatchWriteItems.
+func BatchDeleteItems(tableName string, keys []AWSObject) error {
+	writeRequests := make([]*dynamodb.WriteRequest, 0, len(keys))
+	for _, key := range keys {
+		writeRequests = append(writeRequests, &dynamodb.WriteRequest{
+			DeleteRequest: &dynamodb.DeleteRequest{
+				Key: key,
+			},
+		})
+	}
+
+	return BatchWriteItems(tableName, writeRequests)
+}
+
+// BatchPutItems puts items into a table, see BatchWriteItems.
+func BatchPutItems(tableName string, items []AWSObject) error {
+	writeRequests := make([]*dynamodb.WriteRequest, 0, len(items))
+	for _, item := range items {
+		writeRequests = append(writeRequests, &dynamodb.WriteRequest{
+			PutRequest: &dynamodb.PutRequest{
+				Item: item,
+			},
+		})
+	}
+
+	return BatchWriteItems(tableName, writeRequests)
+}
+
+// BatchWriteItems sends write requests of a table in batches of 25, retrying the requests DynamoDB leaves unprocessed.
+// Batches aren't transactions, a failure can leave earlier batches written.
+func BatchWriteItems(tableName string, writeRequests []*dynamodb.WriteRequest) error {
+	for start := 0; start < len(writeRequests); start += maxBatchWriteItems {
+		end := util.MinInt(start+maxBatchWriteItems, len(writeRequests))
+
+		err := batchWriteItems(tableName, writeRequests[start:end])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func batchWriteItems(tableName string, writeRequests []*dynamodb.WriteRequest) error {
+	requestItems := map[string][]*dynamodb.WriteRequest{
+		tableName: writeRequests,
+	}
+
+	const maxAttempt = 5
+	backoff := 50 * time.Millisecond
+
+	for attempt := 0; ; attempt++ {
+		output, err := DynamoDB().BatchWriteItem(&dynamodb.BatchWriteItemInput{
+			RequestItems: requestItems,
+		})
+		if err != nil {
+			return err
+		}
+
+		if len(output.UnprocessedItems) == 0 {
+			return nil
+		}
+
+		if attempt >= maxAttempt {
+			return fmt.Errorf("%d items of %s left unprocessed", len(output.UnprocessedItems[tableName]), tableName)
+		}
+
+		requestItems = output.UnprocessedItems
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
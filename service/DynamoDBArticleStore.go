@@ -0,0 +1,544 @@
+package service
+
+import (
+	"strings"
+
+	"realworld-go-nolambda/model"
+	"realworld-go-nolambda/util"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+func (s *DynamoDBStore) PutArticle(article model.Article, revision model.ArticleRevision) error {
+	articleItem, err := dynamodbattribute.MarshalMap(article)
+	if err != nil {
+		return err
+	}
+
+	// Index CreatedAt is sparse, unpublished articles stay out of it
+	if !article.IsPublished() {
+		delete(articleItem, "Dummy")
+	}
+
+	revisionItems, err := putArticleRevisionItems([]model.ArticleRevision{revision})
+	if err != nil {
+		return err
+	}
+
+	transactItems := make([]*dynamodb.TransactWriteItem, 0, 3+2*len(article.LinkedTags()))
+
+	// Put a new article
+	transactItems = append(transactItems, &dynamodb.TransactWriteItem{
+		Put: &dynamodb.Put{
+			TableName:           aws.String(ArticleTableName),
+			Item:                articleItem,
+			ConditionExpression: aws.String("attribute_not_exists(ArticleId)"),
+		},
+	})
+
+	// Don't reuse the id of a deleted article until its comments and favorites are gone
+	transactItems = append(transactItems, &dynamodb.TransactWriteItem{
+		ConditionCheck: &dynamodb.ConditionCheck{
+			TableName:           aws.String(ArticleCleanupTableName),
+			Key:                 Int64Key("ArticleId", article.ArticleId),
+			ConditionExpression: aws.String("attribute_not_exists(ArticleId)"),
+		},
+	})
+
+	transactItems = append(transactItems, revisionItems...)
+
+	for _, tag := range article.LinkedTags() {
+		articleTag := model.ArticleTag{
+			Tag:       tag,
+			ArticleId: article.ArticleId,
+			CreatedAt: article.CreatedAt,
+		}
+
+		item, err := dynamodbattribute.MarshalMap(articleTag)
+		if err != nil {
+			return err
+		}
+
+		// Link article with tag
+		transactItems = append(transactItems, &dynamodb.TransactWriteItem{
+			Put: &dynamodb.Put{
+				TableName: aws.String(ArticleTagTableName),
+				Item:      item,
+			},
+		})
+
+		// Update article count for each tag
+		transactItems = append(transactItems, &dynamodb.TransactWriteItem{
+			Update: &dynamodb.Update{
+				TableName:        aws.String(TagTableName),
+				Key:              StringKey("Tag", tag),
+				UpdateExpression: aws.String("ADD ArticleCount :one SET Dummy=:zero"),
+				ExpressionAttributeValues: AWSObject{
+					":one":  IntValue(1),
+					":zero": IntValue(0),
+				},
+			},
+		})
+	}
+
+	_, err = DynamoDB().TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+		TransactItems: transactItems,
+	})
+
+	return conditionError(err)
+}
+
+func (s *DynamoDBStore) UpdateArticle(oldArticle model.Article, newArticle model.Article, revisions []model.ArticleRevision) error {
+	oldTagSet := util.NewStringSetFromSlice(oldArticle.LinkedTags())
+	newTagSet := util.NewStringSetFromSlice(newArticle.LinkedTags())
+	oldTags := oldTagSet.Difference(newTagSet)
+	newTags := newTagSet.Difference(oldTagSet)
+
+	transactItems := make([]*dynamodb.TransactWriteItem, 0, 1+len(revisions)+2*len(oldTags)+2*len(newTags))
+
+	// Unpublished articles keep a tag list without being linked
+	tagListChanged := strings.Join(oldArticle.TagList, "\n") != strings.Join(newArticle.TagList, "\n")
+
+	expr, err := buildArticleUpdateExpression(oldArticle, newArticle, tagListChanged)
+	if err != nil {
+		return err
+	}
+
+	// No field changed
+	if expr.Update() == nil {
+		return nil
+	}
+
+	// Update article
+	transactItems = append(transactItems, &dynamodb.TransactWriteItem{
+		Update: &dynamodb.Update{
+			TableName:                 aws.String(ArticleTableName),
+			Key:                       Int64Key("ArticleId", oldArticle.ArticleId),
+			ConditionExpression:       expr.Condition(),
+			UpdateExpression:          expr.Update(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+		},
+	})
+
+	revisionItems, err := putArticleRevisionItems(revisions)
+	if err != nil {
+		return err
+	}
+
+	transactItems = append(transactItems, revisionItems...)
+
+	for tag := range oldTags {
+		// Unlink article from tag
+		transactItems = append(transactItems, &dynamodb.TransactWriteItem{
+			Delete: &dynamodb.Delete{
+				TableName: aws.String(ArticleTagTableName),
+				Key: AWSObject{
+					"Tag":       StringValue(tag),
+					"ArticleId": Int64Value(oldArticle.ArticleId),
+				},
+			},
+		})
+
+		// Update article count for each tag
+		transactItems = append(transactItems, &dynamodb.TransactWriteItem{
+			Update: &dynamodb.Update{
+				TableName:                 aws.String(TagTableName),
+				Key:                       StringKey("Tag", tag),
+				UpdateExpression:          aws.String("ADD ArticleCount :minus_one"),
+				ExpressionAttributeValues: IntKey(":minus_one", -1),
+			},
+		})
+	}
+
+	for tag := range newTags {
+		articleTag := model.ArticleTag{
+			Tag:       tag,
+			ArticleId: oldArticle.ArticleId,
+			CreatedAt: newArticle.CreatedAt,
+		}
+
+		item, err := dynamodbattribute.MarshalMap(articleTag)
+		if err != nil {
+			return err
+		}
+
+		// Link article with tag.
+		// Concurrent updates can't both relink tags, the article update is conditioned on UpdatedAt
+		transactItems = append(transactItems, &dynamodb.TransactWriteItem{
+			Put: &dynamodb.Put{
+				TableName: aws.String(ArticleTagTableName),
+				Item:      item,
+			},
+		})
+
+		// Update article count for each tag
+		transactItems = append(transactItems, &dynamodb.TransactWriteItem{
+			Update: &dynamodb.Update{
+				TableName:        aws.String(TagTableName),
+				Key:              StringKey("Tag", tag),
+				UpdateExpression: aws.String("ADD ArticleCount :one SET Dummy=:zero"),
+				ExpressionAttributeValues: AWSObject{
+					":one":  IntValue(1),
+					":zero": IntValue(0),
+				},
+			},
+		})
+	}
+
+	_, err = DynamoDB().TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+		TransactItems: transactItems,
+	})
+
+	return conditionError(err)
+}
+
+func buildArticleUpdateExpression(oldArticle model.Article, newArticle model.Article, updateTagList bool) (expression.Expression, error) {
+	update := expression.UpdateBuilder{}
+
+	if oldArticle.Slug != newArticle.Slug {
+		update = update.Set(expression.Name("Slug"), expression.Value(newArticle.Slug))
+	}
+
+	if oldArticle.Title != newArticle.Title {
+		update = update.Set(expression.Name("Title"), expression.Value(newArticle.Title))
+	}
+
+	if oldArticle.Description != newArticle.Description {
+		update = update.Set(expression.Name("Description"), expression.Value(newArticle.Description))
+	}
+
+	if oldArticle.Body != newArticle.Body {
+		update = update.Set(expression.Name("Body"), expression.Value(newArticle.Body))
+	}
+
+	if updateTagList {
+		update = update.Set(expression.Name("TagList"), expression.Value(newArticle.TagList))
+	}
+
+	if oldArticle.UpdatedAt != newArticle.UpdatedAt {
+		update = update.Set(expression.Name("UpdatedAt"), expression.Value(newArticle.UpdatedAt))
+	}
+
+	if oldArticle.Revision != newArticle.Revision {
+		update = update.Set(expression.Name("Revision"), expression.Value(newArticle.Revision))
+	}
+
+	if oldArticle.CreatedAt != newArticle.CreatedAt {
+		update = update.Set(expression.Name("CreatedAt"), expression.Value(newArticle.CreatedAt))
+	}
+
+	if oldArticle.Status != newArticle.Status {
+		update = update.Set(expression.Name("Status"), expression.Value(newArticle.Status))
+	}
+
+	if oldArticle.PublishAt != newArticle.PublishAt {
+		update = update.Set(expression.Name("PublishAt"), expression.Value(newArticle.PublishAt))
+	}
+
+	if oldArticle.ExpireAt != newArticle.ExpireAt {
+		update = update.Set(expression.Name("ExpireAt"), expression.Value(newArticle.ExpireAt))
+	}
+
+	if oldArticle.WordCount != newArticle.WordCount {
+		update = update.Set(expression.Name("WordCount"), expression.Value(newArticle.WordCount))
+	}
+
+	if oldArticle.ReadingTime != newArticle.ReadingTime {
+		update = update.Set(expression.Name("ReadingTime"), expression.Value(newArticle.ReadingTime))
+	}
+
+	// The outline follows the body, and is omitted when it is empty like on put
+	if oldArticle.Body != newArticle.Body || oldArticle.WordCount != newArticle.WordCount {
+		if len(newArticle.Outline) > 0 {
+			update = update.Set(expression.Name("Outline"), expression.Value(newArticle.Outline))
+		} else {
+			update = update.Remove(expression.Name("Outline"))
+		}
+	}
+
+	// Index CreatedAt is sparse, unpublished articles stay out of it
+	if !oldArticle.IsPublished() && newArticle.IsPublished() {
+		update = update.Set(expression.Name("Dummy"), expression.Value(0))
+	} else if oldArticle.IsPublished() && !newArticle.IsPublished() {
+		update = update.Remove(expression.Name("Dummy"))
+	}
+
+	if IsUpdateBuilderEmpty(update) {
+		return expression.Expression{}, nil
+	}
+
+	// Only update the version that was read, which also requires the article to exist
+	condition := expression.Name("UpdatedAt").Equal(expression.Value(oldArticle.UpdatedAt))
+
+	builder := expression.NewBuilder().WithUpdate(update).WithCondition(condition)
+	return builder.Build()
+}
+
+func (s *DynamoDBStore) DeleteArticle(article model.Article, cleanup model.ArticleCleanup) error {
+	cleanupItem, err := dynamodbattribute.MarshalMap(cleanup)
+	if err != nil {
+		return err
+	}
+
+	transactItems := make([]*dynamodb.TransactWriteItem, 0, 2+2*len(article.LinkedTags()))
+
+	transactItems = append(transactItems, &dynamodb.TransactWriteItem{
+		Delete: &dynamodb.Delete{
+			TableName:           aws.String(ArticleTableName),
+			Key:                 Int64Key("ArticleId", article.ArticleId),
+			ConditionExpression: aws.String("Author=:username AND UpdatedAt=:updatedAt"),
+			ExpressionAttributeValues: AWSObject{
+				":username":  StringValue(cleanup.Author),
+				":updatedAt": Int64Value(article.UpdatedAt),
+			},
+		},
+	})
+
+	// DynamoDB doesn't support deleting a whole partition by specifying just the partition key.
+	// https://stackoverflow.com/questions/34259358/dynamodb-delete-all-items-having-same-hash-key
+	// Related items in FavoriteArticleTable, CommentTable, ArticleRevisionTable, SearchPostingTable and TimelineTable
+	// are deleted offline by the article cleaner, the cleanup item keeps the article id from being reused meanwhile.
+	transactItems = append(transactItems, &dynamodb.TransactWriteItem{
+		Put: &dynamodb.Put{
+			TableName: aws.String(ArticleCleanupTableName),
+			Item:      cleanupItem,
+		},
+	})
+
+	for _, tag := range article.LinkedTags() {
+		transactItems = append(transactItems, &dynamodb.TransactWriteItem{
+			Delete: &dynamodb.Delete{
+				TableName: aws.String(ArticleTagTableName),
+				Key: AWSObject{
+					"Tag":       StringValue(tag),
+					"ArticleId": Int64Value(article.ArticleId),
+				},
+			},
+		})
+
+		transactItems = append(transactItems, &dynamodb.TransactWriteItem{
+			Update: &dynamodb.Update{
+				TableName:                 aws.String(TagTableName),
+				Key:                       StringKey("Tag", tag),
+				UpdateExpression:          aws.String("ADD ArticleCount :minus_one"),
+				ExpressionAttributeValues: IntKey(":minus_one", -1),
+			},
+		})
+	}
+
+	_, err = DynamoDB().TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+		TransactItems: transactItems,
+	})
+
+	return conditionError(err)
+}
+
+func (s *DynamoDBStore) GetArticle(articleId int64) (model.Article, bool, error) {
+	article := model.Article{}
+	found, err := GetItemByKey(ArticleTableName, Int64Key("ArticleId", articleId), &article)
+	return article, found, err
+}
+
+func (s *DynamoDBStore) GetArticlesByIds(articleIds []int64) (map[int64]model.Article, error) {
+	articlesById := make(map[int64]model.Article)
+	if len(articleIds) == 0 {
+		return articlesById, nil
+	}
+
+	keys := make([]AWSObject, 0, len(articleIds))
+	for _, articleId := range articleIds {
+		keys = append(keys, Int64Key("ArticleId", articleId))
+	}
+
+	batchGetArticles := dynamodb.BatchGetItemInput{
+		RequestItems: map[string]*dynamodb.KeysAndAttributes{
+			ArticleTableName: {
+				Keys: keys,
+			},
+		},
+	}
+
+	responses, err := BatchGetItems(&batchGetArticles, len(articleIds))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, response := range responses {
+		for _, items := range response {
+			for _, item := range items {
+				article := model.Article{}
+				err = dynamodbattribute.UnmarshalMap(item, &article)
+				if err != nil {
+					return nil, err
+				}
+
+				articlesById[article.ArticleId] = article
+			}
+		}
+	}
+
+	return articlesById, nil
+}
+
+func (s *DynamoDBStore) QueryArticles(page Page) ([]model.Article, *Cursor, error) {
+	queryArticles := dynamodb.QueryInput{
+		TableName:                 aws.String(ArticleTableName),
+		IndexName:                 aws.String("CreatedAt"),
+		KeyConditionExpression:    aws.String("Dummy=:zero"),
+		ExpressionAttributeValues: IntKey(":zero", 0),
+		ExclusiveStartKey:         cursorKey(page.After, IntKey("Dummy", 0), "CreatedAt"),
+		ScanIndexForward:          aws.Bool(false),
+	}
+
+	return queryArticleItems(&queryArticles, page)
+}
+
+func (s *DynamoDBStore) QueryArticlesByAuthor(author string, page Page) ([]model.Article, *Cursor, error) {
+	// Articles without a status predate statuses and are published
+	return queryArticlesOfAuthor(author, "attribute_not_exists(#status) OR #status=:published", page)
+}
+
+func (s *DynamoDBStore) QueryDraftsByAuthor(author string, page Page) ([]model.Article, *Cursor, error) {
+	return queryArticlesOfAuthor(author, "#status<>:published", page)
+}
+
+// queryArticlesOfAuthor queries index Author for the articles matching a filter on #status.
+// QueryPage keeps reading until the page is full, so filtered out articles only cost read capacity.
+func queryArticlesOfAuthor(author string, statusFilter string, page Page) ([]model.Article, *Cursor, error) {
+	queryArticles := dynamodb.QueryInput{
+		TableName:                aws.String(ArticleTableName),
+		IndexName:                aws.String("Author"),
+		KeyConditionExpression:   aws.String("Author=:author"),
+		FilterExpression:         aws.String(statusFilter),
+		ExpressionAttributeNames: map[string]*string{"#status": aws.String("Status")},
+		ExpressionAttributeValues: AWSObject{
+			":author":    StringValue(author),
+			":published": StringValue(model.StatusPublished),
+		},
+		ExclusiveStartKey: cursorKey(page.After, StringKey("Author", author), "CreatedAt"),
+		ScanIndexForward:  aws.Bool(false),
+	}
+
+	return queryArticleItems(&queryArticles, page)
+}
+
+// ScanArticles queries index Author for the articles of an author, and the sparse index of the order otherwise.
+// A range of CreatedAt is part of the key condition of the CreatedAt indexes, and filtered otherwise.
+func (s *DynamoDBStore) ScanArticles(scan ArticleScan, page Page) ([]model.Article, *Cursor, error) {
+	sortKeyName := dynamoDBOrderKey(scan.Order)
+	values := AWSObject{}
+	names := map[string]*string{}
+
+	queryArticles := dynamodb.QueryInput{
+		TableName:        aws.String(ArticleTableName),
+		IndexName:        aws.String(sortKeyName),
+		ScanIndexForward: aws.Bool(scan.Order == OrderOldest),
+	}
+
+	keyCondition := "Dummy=:zero"
+	partitionKey := IntKey("Dummy", 0)
+	filters := make([]string, 0, 2)
+
+	if scan.Author != "" {
+		// Articles without a status predate statuses and are published
+		queryArticles.IndexName = aws.String("Author")
+		keyCondition = "Author=:author"
+		partitionKey = StringKey("Author", scan.Author)
+		filters = append(filters, "(attribute_not_exists(#status) OR #status=:published)")
+		names["#status"] = aws.String("Status")
+		values[":author"] = StringValue(scan.Author)
+		values[":published"] = StringValue(model.StatusPublished)
+	} else {
+		values[":zero"] = IntValue(0)
+	}
+
+	createdRange := createdAtRangeExpression(scan, values)
+	if createdRange != "" && scan.byCreatedAt() {
+		keyCondition += " AND " + createdRange
+	} else if createdRange != "" {
+		filters = append(filters, createdRange)
+	}
+
+	queryArticles.KeyConditionExpression = aws.String(keyCondition)
+	queryArticles.ExpressionAttributeValues = values
+	queryArticles.ExclusiveStartKey = cursorKey(page.After, partitionKey, sortKeyName)
+	if len(filters) > 0 {
+		queryArticles.FilterExpression = aws.String(strings.Join(filters, " AND "))
+	}
+	if len(names) > 0 {
+		queryArticles.ExpressionAttributeNames = names
+	}
+
+	items, lastKey, err := QueryPage(&queryArticles, page.Offset, page.Limit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	articles := make([]model.Article, len(items))
+	err = dynamodbattribute.UnmarshalListOfMaps(items, &articles)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	next, err := keyCursor(lastKey, sortKeyName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return articles, next, nil
+}
+
+// dynamoDBOrderKey returns the attribute an order sorts by, which is also the name of its index of published articles.
+func dynamoDBOrderKey(order string) string {
+	switch order {
+	case OrderMostFavorited:
+		return "FavoritesCount"
+	case OrderRecentlyUpdated:
+		return "UpdatedAt"
+	default:
+		return "CreatedAt"
+	}
+}
+
+// createdAtRangeExpression returns a condition on CreatedAt selecting the range of scan, adding its values,
+// or an empty string if the range is unbounded. Key conditions allow a single comparison, hence BETWEEN.
+func createdAtRangeExpression(scan ArticleScan, values AWSObject) string {
+	switch {
+	case scan.CreatedFrom != 0 && scan.CreatedUntil != 0:
+		values[":createdFrom"] = Int64Value(scan.CreatedFrom)
+		values[":createdLast"] = Int64Value(scan.CreatedUntil - 1)
+		return "CreatedAt BETWEEN :createdFrom AND :createdLast"
+	case scan.CreatedFrom != 0:
+		values[":createdFrom"] = Int64Value(scan.CreatedFrom)
+		return "CreatedAt>=:createdFrom"
+	case scan.CreatedUntil != 0:
+		values[":createdUntil"] = Int64Value(scan.CreatedUntil)
+		return "CreatedAt<:createdUntil"
+	default:
+		return ""
+	}
+}
+
+func queryArticleItems(queryInput *dynamodb.QueryInput, page Page) ([]model.Article, *Cursor, error) {
+	items, lastKey, err := QueryPage(queryInput, page.Offset, page.Limit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	articles := make([]model.Article, len(items))
+	err = dynamodbattribute.UnmarshalListOfMaps(items, &articles)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	next, err := keyCursor(lastKey, "CreatedAt")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return articles, next, nil
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"realworld-go-nolambda/model"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+func (s *DynamoDBStore) QueryArticleCleanups(limit int) ([]model.ArticleCleanup, error) {
+	scanCleanups := dynamodb.ScanInput{
+		TableName: aws.String(ArticleCleanupTableName),
+		Limit:     aws.Int64(int64(limit)),
+	}
+
+	output, err := DynamoDB().Scan(&scanCleanups)
+	if err != nil {
+		return nil, err
+	}
+
+	cleanups := make([]model.ArticleCleanup, len(output.Items))
+	err = dynamodbattribute.UnmarshalListOfMaps(output.Items, &cleanups)
+	if err != nil {
+		return nil, err
+	}
+
+	return cleanups, nil
+}
+
+func (s *DynamoDBStore) DeleteArticleItems(articleId int64, limit int) (int, error) {
+	if limit > maxBatchWriteItems {
+		limit = maxBatchWriteItems
+	}
+
+	queryComments := dynamodb.QueryInput{
+		TableName:                 aws.String(CommentTableName),
+		KeyConditionExpression:    aws.String("ArticleId=:articleId"),
+		ExpressionAttributeValues: Int64Key(":articleId", articleId),
+		ProjectionExpression:      aws.String("ArticleId, CommentId"),
+		Limit:                     aws.Int64(int64(limit)),
+	}
+
+	numComments, err := deleteQueriedItems(CommentTableName, &queryComments)
+	if err != nil {
+		return 0, err
+	}
+
+	queryCommentEdits := dynamodb.QueryInput{
+		TableName:                 aws.String(CommentEditTableName),
+		KeyConditionExpression:    aws.String("ArticleId=:articleId"),
+		ExpressionAttributeValues: Int64Key(":articleId", articleId),
+		ProjectionExpression:      aws.String("ArticleId, EditId"),
+		Limit:                     aws.Int64(int64(limit)),
+	}
+
+	numCommentEdits, err := deleteQueriedItems(CommentEditTableName, &queryCommentEdits)
+	if err != nil {
+		return 0, err
+	}
+
+	queryCommentReactions := dynamodb.QueryInput{
+		TableName:                 aws.String(CommentReactionTableName),
+		KeyConditionExpression:    aws.String("ArticleId=:articleId"),
+		ExpressionAttributeValues: Int64Key(":articleId", articleId),
+		ProjectionExpression:      aws.String("ArticleId, ReactionId"),
+		Limit:                     aws.Int64(int64(limit)),
+	}
+
+	numCommentReactions, err := deleteQueriedItems(CommentReactionTableName, &queryCommentReactions)
+	if err != nil {
+		return 0, err
+	}
+
+	queryFavoriteArticles := dynamodb.QueryInput{
+		TableName:                 aws.String(FavoriteArticleTableName),
+		IndexName:                 aws.String("ArticleId"),
+		KeyConditionExpression:    aws.String("ArticleId=:articleId"),
+		ExpressionAttributeValues: Int64Key(":articleId", articleId),
+		ProjectionExpression:      aws.String("Username, ArticleId"),
+		Limit:                     aws.Int64(int64(limit)),
+	}
+
+	numFavoriteArticles, err := deleteQueriedItems(FavoriteArticleTableName, &queryFavoriteArticles)
+	if err != nil {
+		return 0, err
+	}
+
+	queryArticleRevisions := dynamodb.QueryInput{
+		TableName:                 aws.String(ArticleRevisionTableName),
+		KeyConditionExpression:    aws.String("ArticleId=:articleId"),
+		ExpressionAttributeValues: Int64Key(":articleId", articleId),
+		ProjectionExpression:      aws.String("ArticleId, Revision"),
+		Limit:                     aws.Int64(int64(limit)),
+	}
+
+	numArticleRevisions, err := deleteQueriedItems(ArticleRevisionTableName, &queryArticleRevisions)
+	if err != nil {
+		return 0, err
+	}
+
+	return numComments + numCommentEdits + numCommentReactions + numFavoriteArticles + numArticleRevisions, nil
+}
+
+// deleteQueriedItems deletes the first page of a query whose projection is the primary key of tableName.
+func deleteQueriedItems(tableName string, queryInput *dynamodb.QueryInput) (int, error) {
+	output, err := DynamoDB().Query(queryInput)
+	if err != nil {
+		return 0, err
+	}
+
+	err = BatchDeleteItems(tableName, output.Items)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(output.Items), nil
+}
+
+func (s *DynamoDBStore) DeleteArticleCleanup(articleId int64) error {
+	_, err := DynamoDB().DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(ArticleCleanupTableName),
+		Key:       Int64Key("ArticleId", articleId),
+	})
+
+	return err
+}
@@ -3,6 +3,8 @@ package service
 import (
 	"sync"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 )
@@ -10,18 +12,63 @@ import (
 var once sync.Once
 var svc *dynamodb.DynamoDB
 
+// DynamoDBOptions override the shared AWS config, e.g. to point the client at DynamoDB Local.
+// Empty fields fall back to the shared config and the default credential chain.
+type DynamoDBOptions struct {
+	Endpoint        string
+	Region          string
+	AccessKeyId     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+var dynamoDBOptions DynamoDBOptions
+
+// ConfigureDynamoDB sets the options of the DynamoDB() singleton. It must be called before the first DynamoDB() call.
+func ConfigureDynamoDB(options DynamoDBOptions) {
+	dynamoDBOptions = options
+}
+
 func initializeSingletons() {
+	config := aws.NewConfig()
+
+	if dynamoDBOptions.Endpoint != "" {
+		config = config.WithEndpoint(dynamoDBOptions.Endpoint)
+	}
+
+	if dynamoDBOptions.Region != "" {
+		config = config.WithRegion(dynamoDBOptions.Region)
+	}
+
+	if dynamoDBOptions.AccessKeyId != "" {
+		config = config.WithCredentials(credentials.NewStaticCredentials(
+			dynamoDBOptions.AccessKeyId, dynamoDBOptions.SecretAccessKey, dynamoDBOptions.SessionToken))
+	}
+
 	sess := session.Must(session.NewSessionWithOptions(session.Options{
+		Config:            *config,
 		SharedConfigState: session.SharedConfigEnable,
 	}))
 
 	svc = dynamodb.New(sess)
-
-	// table1 := ""
-	// table2 := ""
 }
 
 func DynamoDB() *dynamodb.DynamoDB {
 	once.Do(initializeSingletons)
 	return svc
 }
+
+// DynamoDBStore is the Store backed by the DynamoDB() singleton and the tables named in TableName.go.
+type DynamoDBStore struct{}
+
+func NewDynamoDBStore() *DynamoDBStore {
+	return &DynamoDBStore{}
+}
+
+// conditionError translates DynamoDB conditional check failures into ErrConditionFailed.
+func conditionError(err error) error {
+	if err != nil && IsConditionalCheckFailed(err) {
+		return ErrConditionFailed
+	}
+	return err
+}
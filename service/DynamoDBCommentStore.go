@@ -0,0 +1,649 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+
+	"realworld-go-nolambda/model"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+func (s *DynamoDBStore) PutComment(comment model.Comment) error {
+	commentItem, err := dynamodbattribute.MarshalMap(comment)
+	if err != nil {
+		return err
+	}
+
+	transactItems := make([]*dynamodb.TransactWriteItem, 0, 3)
+
+	// Put a new comment
+	transactItems = append(transactItems, &dynamodb.TransactWriteItem{
+		Put: &dynamodb.Put{
+			TableName:           aws.String(CommentTableName),
+			Item:                commentItem,
+			ConditionExpression: aws.String("attribute_not_exists(CommentId)"),
+		},
+	})
+
+	transactItems = append(transactItems, commentsCountUpdate(comment.ArticleId, 1))
+
+	// Update reply count of the parent, unless it was deleted
+	if comment.ParentId != 0 {
+		transactItems = append(transactItems, &dynamodb.TransactWriteItem{
+			Update: &dynamodb.Update{
+				TableName: aws.String(CommentTableName),
+				Key: AWSObject{
+					"ArticleId": Int64Value(comment.ArticleId),
+					"CommentId": Int64Value(comment.ParentId),
+				},
+				ConditionExpression: aws.String("attribute_exists(CommentId) AND (attribute_not_exists(DeletedAt) OR DeletedAt=:zero)"),
+				UpdateExpression:    aws.String("ADD ReplyCount :one"),
+				ExpressionAttributeValues: AWSObject{
+					":one":  IntValue(1),
+					":zero": IntValue(0),
+				},
+			},
+		})
+	}
+
+	_, err = DynamoDB().TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+		TransactItems: transactItems,
+	})
+
+	return conditionError(err)
+}
+
+func (s *DynamoDBStore) GetComment(key model.CommentKey) (model.Comment, bool, error) {
+	item, err := dynamodbattribute.MarshalMap(key)
+	if err != nil {
+		return model.Comment{}, false, err
+	}
+
+	comment := model.Comment{}
+	found, err := GetItemByKey(CommentTableName, item, &comment)
+	return comment, found, err
+}
+
+func (s *DynamoDBStore) QueryComments(articleId int64, order string, page Page) ([]model.Comment, *Cursor, error) {
+	// The Upvotes index is sparse: comments written before reactions existed lack the attribute,
+	// so they are left out of OrderTop until they are upvoted
+	sortKeyName := "CreatedAt"
+	if order == OrderTop {
+		sortKeyName = "Upvotes"
+	}
+
+	queryComments := dynamodb.QueryInput{
+		TableName:              aws.String(CommentTableName),
+		IndexName:              aws.String(sortKeyName),
+		KeyConditionExpression: aws.String("ArticleId=:articleId"),
+		FilterExpression:       aws.String("attribute_not_exists(ParentId) OR ParentId=:zero"),
+		ExpressionAttributeValues: AWSObject{
+			":articleId": Int64Value(articleId),
+			":zero":      IntValue(0),
+		},
+		ScanIndexForward: aws.Bool(order == OrderOldest),
+	}
+
+	if page.After != nil {
+		queryComments.ExclusiveStartKey = AWSObject{
+			"ArticleId": Int64Value(articleId),
+			"CommentId": Int64Value(page.After.CommentId),
+			sortKeyName: Int64Value(page.After.SortKey),
+		}
+	}
+
+	items, lastKey, err := QueryPage(&queryComments, page.Offset, page.Limit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	comments := make([]model.Comment, len(items))
+	err = dynamodbattribute.UnmarshalListOfMaps(items, &comments)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if lastKey == nil || len(comments) == 0 {
+		return comments, nil, nil
+	}
+
+	return comments, commentCursor(comments[len(comments)-1], order), nil
+}
+
+func (s *DynamoDBStore) QueryCommentReplies(articleId int64, parentId int64, page Page) ([]model.Comment, *Cursor, error) {
+	queryReplies := dynamodb.QueryInput{
+		TableName:              aws.String(CommentTableName),
+		IndexName:              aws.String("ParentId"),
+		KeyConditionExpression: aws.String("ParentId=:parentId"),
+		// Legacy comment ids are only unique within their article
+		FilterExpression: aws.String("ArticleId=:articleId"),
+		ExpressionAttributeValues: AWSObject{
+			":parentId":  Int64Value(parentId),
+			":articleId": Int64Value(articleId),
+		},
+		ScanIndexForward: aws.Bool(true),
+	}
+
+	if page.After != nil {
+		queryReplies.ExclusiveStartKey = AWSObject{
+			"ArticleId": Int64Value(page.After.ArticleId),
+			"CommentId": Int64Value(page.After.CommentId),
+			"ParentId":  Int64Value(parentId),
+			"CreatedAt": Int64Value(page.After.SortKey),
+		}
+	}
+
+	items, lastKey, err := QueryPage(&queryReplies, page.Offset, page.Limit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	replies := make([]model.Comment, len(items))
+	err = dynamodbattribute.UnmarshalListOfMaps(items, &replies)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if lastKey == nil || len(replies) == 0 {
+		return replies, nil, nil
+	}
+
+	return replies, commentCursor(replies[len(replies)-1], OrderOldest), nil
+}
+
+func (s *DynamoDBStore) DeleteComment(comment model.Comment) error {
+	item, err := dynamodbattribute.MarshalMap(comment.CommentKey)
+	if err != nil {
+		return err
+	}
+
+	transactItems := make([]*dynamodb.TransactWriteItem, 0, 3)
+
+	// Delete the comment, unless it got replies or was deleted meanwhile
+	values := AWSObject{
+		":username": StringValue(comment.Author),
+		":zero":     IntValue(0),
+	}
+
+	deletedCondition := "(attribute_not_exists(DeletedAt) OR DeletedAt=:zero)"
+	if comment.IsDeleted() {
+		deletedCondition = "DeletedAt=:deletedAt"
+		values[":deletedAt"] = Int64Value(comment.DeletedAt)
+	}
+
+	transactItems = append(transactItems, &dynamodb.TransactWriteItem{
+		Delete: &dynamodb.Delete{
+			TableName:                 aws.String(CommentTableName),
+			Key:                       item,
+			ConditionExpression:       aws.String("Author=:username AND (attribute_not_exists(ReplyCount) OR ReplyCount=:zero) AND " + deletedCondition),
+			ExpressionAttributeValues: values,
+		},
+	})
+
+	// Tombstones were uncounted already
+	if !comment.IsDeleted() {
+		decrement, err := commentsCountDecrement(comment.ArticleId)
+		if err != nil {
+			return err
+		}
+
+		if decrement != nil {
+			transactItems = append(transactItems, decrement)
+		}
+	}
+
+	// Update reply count of the parent
+	if comment.ParentId != 0 {
+		transactItems = append(transactItems, &dynamodb.TransactWriteItem{
+			Update: &dynamodb.Update{
+				TableName: aws.String(CommentTableName),
+				Key: AWSObject{
+					"ArticleId": Int64Value(comment.ArticleId),
+					"CommentId": Int64Value(comment.ParentId),
+				},
+				ConditionExpression:       aws.String("attribute_exists(CommentId)"),
+				UpdateExpression:          aws.String("ADD ReplyCount :minus_one"),
+				ExpressionAttributeValues: IntKey(":minus_one", -1),
+			},
+		})
+	}
+
+	_, err = DynamoDB().TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+		TransactItems: transactItems,
+	})
+
+	return conditionError(err)
+}
+
+func (s *DynamoDBStore) TombstoneComment(key model.CommentKey, deletedAt int64) error {
+	item, err := dynamodbattribute.MarshalMap(key)
+	if err != nil {
+		return err
+	}
+
+	transactItems := make([]*dynamodb.TransactWriteItem, 0, 2)
+
+	transactItems = append(transactItems, &dynamodb.TransactWriteItem{
+		Update: &dynamodb.Update{
+			TableName:           aws.String(CommentTableName),
+			Key:                 item,
+			ConditionExpression: aws.String("ReplyCount > :zero AND (attribute_not_exists(DeletedAt) OR DeletedAt=:zero)"),
+			UpdateExpression:    aws.String("SET Body=:empty, DeletedAt=:deletedAt, Upvotes=:zero REMOVE Reactions"),
+			ExpressionAttributeValues: AWSObject{
+				":zero":      IntValue(0),
+				":empty":     StringValue(""),
+				":deletedAt": Int64Value(deletedAt),
+			},
+		},
+	})
+
+	decrement, err := commentsCountDecrement(key.ArticleId)
+	if err != nil {
+		return err
+	}
+
+	if decrement != nil {
+		transactItems = append(transactItems, decrement)
+	}
+
+	_, err = DynamoDB().TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+		TransactItems: transactItems,
+	})
+
+	return conditionError(err)
+}
+
+// commentsCountDecrement subtracts one from the CommentsCount of an article, provided it is positive. Articles commented on
+// before the count was kept start from 0 until RecountComments runs, and their count mustn't go negative meanwhile.
+// It returns nil if there is nothing to subtract from. A count that drops to 0 concurrently fails the transaction.
+func commentsCountDecrement(articleId int64) (*dynamodb.TransactWriteItem, error) {
+	article := model.Article{}
+	found, err := GetItemByKey(ArticleTableName, Int64Key("ArticleId", articleId), &article)
+	if err != nil || !found || article.CommentsCount <= 0 {
+		return nil, err
+	}
+
+	decrement := commentsCountUpdate(articleId, -1)
+	decrement.Update.ConditionExpression = aws.String("CommentsCount > :zero")
+	decrement.Update.ExpressionAttributeValues[":zero"] = IntValue(0)
+	return decrement, nil
+}
+
+// RecountComments scans every comment, so it is meant to be run once after upgrading, see recount-comments.
+func (s *DynamoDBStore) RecountComments() ([]int64, error) {
+	counts := make(map[int64]int64)
+
+	scanComments := dynamodb.ScanInput{
+		TableName:            aws.String(CommentTableName),
+		ProjectionExpression: aws.String("ArticleId, DeletedAt"),
+	}
+
+	var marshalErr error
+	err := DynamoDB().ScanPages(&scanComments, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			comment := model.Comment{}
+			marshalErr = dynamodbattribute.UnmarshalMap(item, &comment)
+			if marshalErr != nil {
+				return false
+			}
+
+			if !comment.IsDeleted() {
+				counts[comment.ArticleId]++
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if marshalErr != nil {
+		return nil, marshalErr
+	}
+
+	scanArticles := dynamodb.ScanInput{
+		TableName:            aws.String(ArticleTableName),
+		ProjectionExpression: aws.String("ArticleId, CommentsCount"),
+	}
+
+	stale := make(map[int64]int64)
+	err = DynamoDB().ScanPages(&scanArticles, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			article := model.Article{}
+			marshalErr = dynamodbattribute.UnmarshalMap(item, &article)
+			if marshalErr != nil {
+				return false
+			}
+
+			if count := counts[article.ArticleId]; count != article.CommentsCount {
+				stale[article.ArticleId] = count
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if marshalErr != nil {
+		return nil, marshalErr
+	}
+
+	changed := make([]int64, 0, len(stale))
+	for articleId, count := range stale {
+		_, err = DynamoDB().UpdateItem(&dynamodb.UpdateItemInput{
+			TableName:                 aws.String(ArticleTableName),
+			Key:                       Int64Key("ArticleId", articleId),
+			ConditionExpression:       aws.String("attribute_exists(ArticleId)"),
+			UpdateExpression:          aws.String("SET CommentsCount=:count"),
+			ExpressionAttributeValues: AWSObject{":count": Int64Value(count)},
+		})
+
+		// Deleted meanwhile
+		if err != nil && IsConditionalCheckFailed(err) {
+			continue
+		}
+
+		if err != nil {
+			return changed, err
+		}
+
+		changed = append(changed, articleId)
+	}
+
+	return changed, nil
+}
+
+// commentsCountUpdate adds delta to the CommentsCount of an article, which must exist.
+func commentsCountUpdate(articleId int64, delta int) *dynamodb.TransactWriteItem {
+	return &dynamodb.TransactWriteItem{
+		Update: &dynamodb.Update{
+			TableName:                 aws.String(ArticleTableName),
+			Key:                       Int64Key("ArticleId", articleId),
+			ConditionExpression:       aws.String("attribute_exists(ArticleId)"),
+			UpdateExpression:          aws.String("ADD CommentsCount :delta"),
+			ExpressionAttributeValues: IntKey(":delta", delta),
+		},
+	}
+}
+
+func (s *DynamoDBStore) UpdateComment(oldComment model.Comment, newComment model.Comment, edit model.CommentEdit) error {
+	editItem, err := dynamodbattribute.MarshalMap(edit)
+	if err != nil {
+		return err
+	}
+
+	key, err := dynamodbattribute.MarshalMap(oldComment.CommentKey)
+	if err != nil {
+		return err
+	}
+
+	transactItems := make([]*dynamodb.TransactWriteItem, 0, 2)
+
+	// Update the comment, unless it was edited or deleted meanwhile
+	transactItems = append(transactItems, &dynamodb.TransactWriteItem{
+		Update: &dynamodb.Update{
+			TableName:           aws.String(CommentTableName),
+			Key:                 key,
+			ConditionExpression: aws.String("Author=:username AND UpdatedAt=:oldUpdatedAt AND (attribute_not_exists(DeletedAt) OR DeletedAt=:zero)"),
+			UpdateExpression:    aws.String("SET Body=:body, UpdatedAt=:updatedAt, EditCount=:editCount"),
+			ExpressionAttributeValues: AWSObject{
+				":username":     StringValue(oldComment.Author),
+				":oldUpdatedAt": Int64Value(oldComment.UpdatedAt),
+				":zero":         IntValue(0),
+				":body":         StringValue(newComment.Body),
+				":updatedAt":    Int64Value(newComment.UpdatedAt),
+				":editCount":    Int64Value(newComment.EditCount),
+			},
+		},
+	})
+
+	// Keep the replaced body
+	transactItems = append(transactItems, &dynamodb.TransactWriteItem{
+		Put: &dynamodb.Put{
+			TableName:           aws.String(CommentEditTableName),
+			Item:                editItem,
+			ConditionExpression: aws.String("attribute_not_exists(EditId)"),
+		},
+	})
+
+	_, err = DynamoDB().TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+		TransactItems: transactItems,
+	})
+
+	return conditionError(err)
+}
+
+func (s *DynamoDBStore) QueryCommentEdits(key model.CommentKey) ([]model.CommentEdit, error) {
+	queryEdits := dynamodb.QueryInput{
+		TableName:              aws.String(CommentEditTableName),
+		IndexName:              aws.String("CommentId"),
+		KeyConditionExpression: aws.String("CommentId=:commentId"),
+		// Legacy comment ids are only unique within their article
+		FilterExpression: aws.String("ArticleId=:articleId"),
+		ExpressionAttributeValues: AWSObject{
+			":commentId": Int64Value(key.CommentId),
+			":articleId": Int64Value(key.ArticleId),
+		},
+		ScanIndexForward: aws.Bool(false),
+	}
+
+	const queryInitialCapacity = 16
+	items, err := QueryItems(&queryEdits, 0, queryInitialCapacity)
+	if err != nil {
+		return nil, err
+	}
+
+	edits := make([]model.CommentEdit, len(items))
+	err = dynamodbattribute.UnmarshalListOfMaps(items, &edits)
+	if err != nil {
+		return nil, err
+	}
+
+	return edits, nil
+}
+
+func (s *DynamoDBStore) DeleteCommentEdits(key model.CommentKey) error {
+	edits, err := s.QueryCommentEdits(key)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]AWSObject, 0, len(edits))
+	for _, edit := range edits {
+		keys = append(keys, AWSObject{
+			"ArticleId": Int64Value(edit.ArticleId),
+			"EditId":    Int64Value(edit.EditId),
+		})
+	}
+
+	return BatchDeleteItems(CommentEditTableName, keys)
+}
+
+// commentReactionId is the range key of a reaction. It starts with the comment id, to query the reactions to a comment.
+func commentReactionId(key model.CommentReactionKey) string {
+	return strconv.FormatInt(key.CommentId, 10) + "/" + key.Reaction + "/" + key.Username
+}
+
+func (s *DynamoDBStore) PutCommentReaction(reaction model.CommentReaction) error {
+	item, err := dynamodbattribute.MarshalMap(reaction)
+	if err != nil {
+		return err
+	}
+	item["ReactionId"] = StringValue(commentReactionId(reaction.CommentReactionKey))
+
+	commentKey := AWSObject{
+		"ArticleId": Int64Value(reaction.ArticleId),
+		"CommentId": Int64Value(reaction.CommentId),
+	}
+
+	if reaction.Reaction != model.ReactionUpvote {
+		err = initCommentReactions(commentKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	transactItems := make([]*dynamodb.TransactWriteItem, 0, 2)
+
+	// Add the reaction
+	transactItems = append(transactItems, &dynamodb.TransactWriteItem{
+		Put: &dynamodb.Put{
+			TableName:           aws.String(CommentReactionTableName),
+			Item:                item,
+			ConditionExpression: aws.String("attribute_not_exists(ReactionId)"),
+		},
+	})
+
+	transactItems = append(transactItems, commentReactionCountUpdate(commentKey, reaction.Reaction, 1))
+
+	_, err = DynamoDB().TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+		TransactItems: transactItems,
+	})
+
+	return conditionError(err)
+}
+
+// initCommentReactions adds an empty Reactions map to a comment that has none, so that counts can be set in it.
+// Comments get it with their first emoji reaction.
+func initCommentReactions(commentKey AWSObject) error {
+	_, err := DynamoDB().UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:           aws.String(CommentTableName),
+		Key:                 commentKey,
+		ConditionExpression: aws.String("attribute_exists(CommentId) AND attribute_not_exists(Reactions)"),
+		UpdateExpression:    aws.String("SET Reactions=:empty"),
+		ExpressionAttributeValues: AWSObject{
+			":empty": {M: AWSObject{}},
+		},
+	})
+
+	err = conditionError(err)
+	if errors.Is(err, ErrConditionFailed) {
+		// Missing comments are left to the transaction to report
+		return nil
+	}
+
+	return err
+}
+
+func (s *DynamoDBStore) DeleteCommentReaction(key model.CommentReactionKey) error {
+	commentKey := AWSObject{
+		"ArticleId": Int64Value(key.ArticleId),
+		"CommentId": Int64Value(key.CommentId),
+	}
+
+	transactItems := make([]*dynamodb.TransactWriteItem, 0, 2)
+
+	// Remove the reaction
+	transactItems = append(transactItems, &dynamodb.TransactWriteItem{
+		Delete: &dynamodb.Delete{
+			TableName: aws.String(CommentReactionTableName),
+			Key: AWSObject{
+				"ArticleId":  Int64Value(key.ArticleId),
+				"ReactionId": StringValue(commentReactionId(key)),
+			},
+			ConditionExpression: aws.String("attribute_exists(ReactionId)"),
+		},
+	})
+
+	transactItems = append(transactItems, commentReactionCountUpdate(commentKey, key.Reaction, -1))
+
+	_, err := DynamoDB().TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+		TransactItems: transactItems,
+	})
+
+	return conditionError(err)
+}
+
+// commentReactionCountUpdate adds delta to the count of a reaction on a comment, unless the comment was deleted.
+func commentReactionCountUpdate(commentKey AWSObject, reaction string, delta int) *dynamodb.TransactWriteItem {
+	update := dynamodb.Update{
+		TableName:           aws.String(CommentTableName),
+		Key:                 commentKey,
+		ConditionExpression: aws.String("attribute_exists(CommentId) AND (attribute_not_exists(DeletedAt) OR DeletedAt=:zero)"),
+		UpdateExpression:    aws.String("ADD Upvotes :delta"),
+		ExpressionAttributeValues: AWSObject{
+			":zero":  IntValue(0),
+			":delta": IntValue(delta),
+		},
+	}
+
+	if reaction != model.ReactionUpvote {
+		update.UpdateExpression = aws.String("SET Reactions.#reaction = if_not_exists(Reactions.#reaction, :zero) + :delta")
+		update.ExpressionAttributeNames = map[string]*string{"#reaction": aws.String(reaction)}
+	}
+
+	return &dynamodb.TransactWriteItem{Update: &update}
+}
+
+func (s *DynamoDBStore) GetUserCommentReactions(username string, articleId int64, commentIds []int64) (map[int64][]string, error) {
+	reactions := make(map[int64][]string)
+	if len(commentIds) == 0 {
+		return reactions, nil
+	}
+
+	queryReactions := dynamodb.QueryInput{
+		TableName:              aws.String(CommentReactionTableName),
+		IndexName:              aws.String("Username"),
+		KeyConditionExpression: aws.String("Username=:username AND ArticleId=:articleId"),
+		ExpressionAttributeValues: AWSObject{
+			":username":  StringValue(username),
+			":articleId": Int64Value(articleId),
+		},
+		ProjectionExpression: aws.String("CommentId, Reaction"),
+	}
+
+	const queryInitialCapacity = 16
+	items, err := QueryItems(&queryReactions, 0, queryInitialCapacity)
+	if err != nil {
+		return nil, err
+	}
+
+	commentReactions := make([]model.CommentReaction, len(items))
+	err = dynamodbattribute.UnmarshalListOfMaps(items, &commentReactions)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[int64]bool, len(commentIds))
+	for _, commentId := range commentIds {
+		wanted[commentId] = true
+	}
+
+	added := make(map[int64]map[string]bool)
+	for _, reaction := range commentReactions {
+		if !wanted[reaction.CommentId] {
+			continue
+		}
+
+		if added[reaction.CommentId] == nil {
+			added[reaction.CommentId] = make(map[string]bool)
+		}
+		added[reaction.CommentId][reaction.Reaction] = true
+	}
+
+	return orderReactions(added), nil
+}
+
+func (s *DynamoDBStore) DeleteCommentReactions(key model.CommentKey) error {
+	queryReactions := dynamodb.QueryInput{
+		TableName:              aws.String(CommentReactionTableName),
+		KeyConditionExpression: aws.String("ArticleId=:articleId AND begins_with(ReactionId, :prefix)"),
+		ExpressionAttributeValues: AWSObject{
+			":articleId": Int64Value(key.ArticleId),
+			":prefix":    StringValue(strconv.FormatInt(key.CommentId, 10) + "/"),
+		},
+		ProjectionExpression: aws.String("ArticleId, ReactionId"),
+	}
+
+	const queryInitialCapacity = 16
+	items, err := QueryItems(&queryReactions, 0, queryInitialCapacity)
+	if err != nil {
+		return err
+	}
+
+	return BatchDeleteItems(CommentReactionTableName, items)
+}
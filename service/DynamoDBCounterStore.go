@@ -0,0 +1,24 @@
+package service
+
+import (
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func (s *DynamoDBStore) IncrementCounter(name string) (int64, error) {
+	output, err := DynamoDB().UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:                 aws.String(CounterTableName),
+		Key:                       StringKey("Name", name),
+		UpdateExpression:          aws.String("ADD #value :one"),
+		ExpressionAttributeNames:  map[string]*string{"#value": aws.String("Value")},
+		ExpressionAttributeValues: IntKey(":one", 1),
+		ReturnValues:              aws.String(dynamodb.ReturnValueUpdatedNew),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseInt(aws.StringValue(output.Attributes["Value"].N), 10, 64)
+}
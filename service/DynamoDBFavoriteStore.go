@@ -0,0 +1,160 @@
+package service
+
+import (
+	"realworld-go-nolambda/model"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+func (s *DynamoDBStore) PutFavoriteArticle(favoriteArticle model.FavoriteArticle) error {
+	item, err := dynamodbattribute.MarshalMap(favoriteArticle)
+	if err != nil {
+		return err
+	}
+
+	transactItems := make([]*dynamodb.TransactWriteItem, 0, 2)
+
+	// Favorite the article
+	transactItems = append(transactItems, &dynamodb.TransactWriteItem{
+		Put: &dynamodb.Put{
+			TableName:           aws.String(FavoriteArticleTableName),
+			Item:                item,
+			ConditionExpression: aws.String("attribute_not_exists(Username) AND attribute_not_exists(ArticleId)"),
+		},
+	})
+
+	// Update favorites count
+	transactItems = append(transactItems, &dynamodb.TransactWriteItem{
+		Update: &dynamodb.Update{
+			TableName:                 aws.String(ArticleTableName),
+			Key:                       Int64Key("ArticleId", favoriteArticle.ArticleId),
+			ConditionExpression:       aws.String("attribute_exists(ArticleId)"),
+			UpdateExpression:          aws.String("ADD FavoritesCount :one"),
+			ExpressionAttributeValues: IntKey(":one", 1),
+		},
+	})
+
+	_, err = DynamoDB().TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+		TransactItems: transactItems,
+	})
+
+	return conditionError(err)
+}
+
+func (s *DynamoDBStore) DeleteFavoriteArticle(key model.FavoriteArticleKey) error {
+	item, err := dynamodbattribute.MarshalMap(key)
+	if err != nil {
+		return err
+	}
+
+	transactItems := make([]*dynamodb.TransactWriteItem, 0, 2)
+
+	// Unfavorite the article
+	transactItems = append(transactItems, &dynamodb.TransactWriteItem{
+		Delete: &dynamodb.Delete{
+			TableName:           aws.String(FavoriteArticleTableName),
+			Key:                 item,
+			ConditionExpression: aws.String("attribute_exists(Username) AND attribute_exists(ArticleId)"),
+		},
+	})
+
+	// Update favorites count
+	transactItems = append(transactItems, &dynamodb.TransactWriteItem{
+		Update: &dynamodb.Update{
+			TableName:                 aws.String(ArticleTableName),
+			Key:                       Int64Key("ArticleId", key.ArticleId),
+			ConditionExpression:       aws.String("attribute_exists(ArticleId)"),
+			UpdateExpression:          aws.String("ADD FavoritesCount :minus_one"),
+			ExpressionAttributeValues: IntKey(":minus_one", -1),
+		},
+	})
+
+	_, err = DynamoDB().TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+		TransactItems: transactItems,
+	})
+
+	return conditionError(err)
+}
+
+func (s *DynamoDBStore) QueryFavoriteArticleIds(username string, page Page) ([]int64, *Cursor, error) {
+	queryArticleIds := dynamodb.QueryInput{
+		TableName:                 aws.String(FavoriteArticleTableName),
+		IndexName:                 aws.String("FavoritedAt"),
+		KeyConditionExpression:    aws.String("Username=:username"),
+		ExpressionAttributeValues: StringKey(":username", username),
+		ExclusiveStartKey:         cursorKey(page.After, StringKey("Username", username), "FavoritedAt"),
+		ScanIndexForward:          aws.Bool(false),
+		ProjectionExpression:      aws.String("ArticleId"),
+	}
+
+	items, lastKey, err := QueryPage(&queryArticleIds, page.Offset, page.Limit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	favoriteArticles := make([]model.FavoriteArticle, len(items))
+	err = dynamodbattribute.UnmarshalListOfMaps(items, &favoriteArticles)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	articleIds := make([]int64, 0, len(items))
+
+	for _, favoriteArticle := range favoriteArticles {
+		articleIds = append(articleIds, favoriteArticle.ArticleId)
+	}
+
+	next, err := keyCursor(lastKey, "FavoritedAt")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return articleIds, next, nil
+}
+
+func (s *DynamoDBStore) GetFavoritedArticleIds(username string, articleIds []int64) (map[int64]bool, error) {
+	favorited := make(map[int64]bool)
+	if len(articleIds) == 0 {
+		return favorited, nil
+	}
+
+	keys := make([]AWSObject, 0, len(articleIds))
+	for _, articleId := range articleIds {
+		keys = append(keys, AWSObject{
+			"Username":  StringValue(username),
+			"ArticleId": Int64Value(articleId),
+		})
+	}
+
+	batchGetFavoriteArticles := dynamodb.BatchGetItemInput{
+		RequestItems: map[string]*dynamodb.KeysAndAttributes{
+			FavoriteArticleTableName: {
+				Keys:                 keys,
+				ProjectionExpression: aws.String("ArticleId"),
+			},
+		},
+	}
+
+	responses, err := BatchGetItems(&batchGetFavoriteArticles, len(articleIds))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, response := range responses {
+		for _, items := range response {
+			for _, item := range items {
+				favoriteArticle := model.FavoriteArticle{}
+				err = dynamodbattribute.UnmarshalMap(item, &favoriteArticle)
+				if err != nil {
+					return nil, err
+				}
+
+				favorited[favoriteArticle.ArticleId] = true
+			}
+		}
+	}
+
+	return favorited, nil
+}
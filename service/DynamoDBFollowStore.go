@@ -0,0 +1,149 @@
+package service
+
+import (
+	"realworld-go-nolambda/model"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+func (s *DynamoDBStore) PutFollow(follow model.Follow) error {
+	item, err := dynamodbattribute.MarshalMap(follow)
+	if err != nil {
+		return err
+	}
+
+	putFollow := dynamodb.PutItemInput{
+		TableName: aws.String(FollowTableName),
+		Item:      item,
+	}
+
+	_, err = DynamoDB().PutItem(&putFollow)
+
+	return err
+}
+
+func (s *DynamoDBStore) DeleteFollow(follow model.Follow) error {
+	item, err := dynamodbattribute.MarshalMap(follow)
+	if err != nil {
+		return err
+	}
+
+	deleteFollow := dynamodb.DeleteItemInput{
+		TableName: aws.String(FollowTableName),
+		Key:       item,
+	}
+
+	_, err = DynamoDB().DeleteItem(&deleteFollow)
+
+	return err
+}
+
+func (s *DynamoDBStore) QueryPublishers(follower string) ([]string, error) {
+	queryPublishers := dynamodb.QueryInput{
+		TableName:                 aws.String(FollowTableName),
+		KeyConditionExpression:    aws.String("Follower=:username"),
+		ExpressionAttributeValues: StringKey(":username", follower),
+		ProjectionExpression:      aws.String("Publisher"),
+	}
+
+	// QueryItems follows LastEvaluatedKey through every page of follows, the capacity is only a hint
+	const queryInitialCapacity = 16
+	items, err := QueryItems(&queryPublishers, 0, queryInitialCapacity)
+	if err != nil {
+		return nil, err
+	}
+
+	follows := make([]model.Follow, 0, len(items))
+	err = dynamodbattribute.UnmarshalListOfMaps(items, &follows)
+	if err != nil {
+		return nil, err
+	}
+
+	publishers := make([]string, 0, len(follows))
+	for _, follow := range follows {
+		publishers = append(publishers, follow.Publisher)
+	}
+
+	return publishers, nil
+}
+
+func (s *DynamoDBStore) QueryFollowers(publisher string) ([]string, error) {
+	queryFollowers := dynamodb.QueryInput{
+		TableName:                 aws.String(FollowTableName),
+		IndexName:                 aws.String("Publisher"),
+		KeyConditionExpression:    aws.String("Publisher=:username"),
+		ExpressionAttributeValues: StringKey(":username", publisher),
+		ProjectionExpression:      aws.String("Follower"),
+	}
+
+	const queryInitialCapacity = 16
+	items, err := QueryItems(&queryFollowers, 0, queryInitialCapacity)
+	if err != nil {
+		return nil, err
+	}
+
+	follows := make([]model.Follow, 0, len(items))
+	err = dynamodbattribute.UnmarshalListOfMaps(items, &follows)
+	if err != nil {
+		return nil, err
+	}
+
+	followers := make([]string, 0, len(follows))
+	for _, follow := range follows {
+		followers = append(followers, follow.Follower)
+	}
+
+	return followers, nil
+}
+
+func (s *DynamoDBStore) GetFollowedPublishers(follower string, publishers []string) (map[string]bool, error) {
+	followingUser := make(map[string]bool)
+	if len(publishers) == 0 {
+		return followingUser, nil
+	}
+
+	publisherSet := make(map[string]bool)
+	for _, publisher := range publishers {
+		publisherSet[publisher] = true
+	}
+
+	keys := make([]AWSObject, 0, len(publisherSet))
+	for publisher := range publisherSet {
+		keys = append(keys, AWSObject{
+			"Follower":  StringValue(follower),
+			"Publisher": StringValue(publisher),
+		})
+	}
+
+	batchGetFollows := dynamodb.BatchGetItemInput{
+		RequestItems: map[string]*dynamodb.KeysAndAttributes{
+			FollowTableName: {
+				Keys:                 keys,
+				ProjectionExpression: aws.String("Publisher"),
+			},
+		},
+	}
+
+	responses, err := BatchGetItems(&batchGetFollows, len(publisherSet))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, response := range responses {
+		for _, items := range response {
+			for _, item := range items {
+				follow := model.Follow{}
+				err = dynamodbattribute.UnmarshalMap(item, &follow)
+				if err != nil {
+					return nil, err
+				}
+
+				followingUser[follow.Publisher] = true
+			}
+		}
+	}
+
+	return followingUser, nil
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"realworld-go-nolambda/model"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// putArticleRevisionItems builds the transaction items inserting revisions, which must not exist yet.
+func putArticleRevisionItems(revisions []model.ArticleRevision) ([]*dynamodb.TransactWriteItem, error) {
+	transactItems := make([]*dynamodb.TransactWriteItem, 0, len(revisions))
+
+	for _, revision := range revisions {
+		item, err := dynamodbattribute.MarshalMap(revision)
+		if err != nil {
+			return nil, err
+		}
+
+		transactItems = append(transactItems, &dynamodb.TransactWriteItem{
+			Put: &dynamodb.Put{
+				TableName:           aws.String(ArticleRevisionTableName),
+				Item:                item,
+				ConditionExpression: aws.String("attribute_not_exists(ArticleId)"),
+			},
+		})
+	}
+
+	return transactItems, nil
+}
+
+func (s *DynamoDBStore) GetArticleRevision(key model.ArticleRevisionKey) (model.ArticleRevision, bool, error) {
+	revision := model.ArticleRevision{}
+	found, err := GetItemByKey(ArticleRevisionTableName, AWSObject{
+		"ArticleId": Int64Value(key.ArticleId),
+		"Revision":  Int64Value(key.Revision),
+	}, &revision)
+	return revision, found, err
+}
+
+func (s *DynamoDBStore) QueryArticleRevisions(articleId int64, page Page) ([]model.ArticleRevision, *Cursor, error) {
+	queryRevisions := dynamodb.QueryInput{
+		TableName:                 aws.String(ArticleRevisionTableName),
+		KeyConditionExpression:    aws.String("ArticleId=:articleId"),
+		ExpressionAttributeValues: Int64Key(":articleId", articleId),
+		ExclusiveStartKey:         cursorKey(page.After, nil, "Revision"),
+		ScanIndexForward:          aws.Bool(false),
+	}
+
+	items, lastKey, err := QueryPage(&queryRevisions, page.Offset, page.Limit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	revisions := make([]model.ArticleRevision, len(items))
+	err = dynamodbattribute.UnmarshalListOfMaps(items, &revisions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	next, err := keyCursor(lastKey, "Revision")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return revisions, next, nil
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"realworld-go-nolambda/model"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+func (s *DynamoDBStore) PutArticleSchedules(schedules []model.ArticleSchedule) error {
+	items := make([]AWSObject, 0, len(schedules))
+	for _, schedule := range schedules {
+		item, err := dynamodbattribute.MarshalMap(schedule)
+		if err != nil {
+			return err
+		}
+
+		items = append(items, item)
+	}
+
+	return BatchPutItems(ArticleScheduleTableName, items)
+}
+
+func (s *DynamoDBStore) QueryArticleSchedules(until int64, limit int) ([]model.ArticleSchedule, error) {
+	querySchedules := dynamodb.QueryInput{
+		TableName:              aws.String(ArticleScheduleTableName),
+		IndexName:              aws.String("DueAt"),
+		KeyConditionExpression: aws.String("Dummy=:zero AND DueAt<=:until"),
+		ExpressionAttributeValues: AWSObject{
+			":zero":  IntValue(0),
+			":until": Int64Value(until),
+		},
+		Limit: aws.Int64(int64(limit)),
+	}
+
+	output, err := DynamoDB().Query(&querySchedules)
+	if err != nil {
+		return nil, err
+	}
+
+	schedules := make([]model.ArticleSchedule, len(output.Items))
+	err = dynamodbattribute.UnmarshalListOfMaps(output.Items, &schedules)
+	if err != nil {
+		return nil, err
+	}
+
+	return schedules, nil
+}
+
+func (s *DynamoDBStore) DeleteArticleSchedule(schedule model.ArticleSchedule) error {
+	_, err := DynamoDB().DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(ArticleScheduleTableName),
+		Key: AWSObject{
+			"ArticleId": Int64Value(schedule.ArticleId),
+			"Action":    StringValue(schedule.Action),
+		},
+		ConditionExpression:       aws.String("DueAt=:dueAt"),
+		ExpressionAttributeValues: Int64Key(":dueAt", schedule.DueAt),
+	})
+
+	return conditionError(err)
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"realworld-go-nolambda/model"
+	"realworld-go-nolambda/util"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+func (s *DynamoDBStore) PutSearchPostings(postings []model.SearchPosting) error {
+	items := make([]AWSObject, 0, len(postings))
+	for _, posting := range postings {
+		item, err := dynamodbattribute.MarshalMap(posting)
+		if err != nil {
+			return err
+		}
+
+		items = append(items, item)
+	}
+
+	return BatchPutItems(SearchPostingTableName, items)
+}
+
+func (s *DynamoDBStore) DeleteSearchPostings(keys []model.SearchPostingKey) error {
+	itemKeys := make([]AWSObject, 0, len(keys))
+	for _, key := range keys {
+		itemKeys = append(itemKeys, AWSObject{
+			"Term":      StringValue(key.Term),
+			"ArticleId": Int64Value(key.ArticleId),
+		})
+	}
+
+	return BatchDeleteItems(SearchPostingTableName, itemKeys)
+}
+
+func (s *DynamoDBStore) QuerySearchPostings(term string, limit int) ([]model.SearchPosting, error) {
+	queryPostings := dynamodb.QueryInput{
+		TableName:                 aws.String(SearchPostingTableName),
+		KeyConditionExpression:    aws.String("Term=:term"),
+		ExpressionAttributeValues: StringKey(":term", term),
+		ScanIndexForward:          aws.Bool(false),
+	}
+
+	return querySearchPostings(&queryPostings, limit)
+}
+
+// QuerySearchPostingsByPrefix queries the partition of index TermHead of the first character of prefix.
+func (s *DynamoDBStore) QuerySearchPostingsByPrefix(prefix string, limit int) ([]model.SearchPosting, error) {
+	queryPostings := dynamodb.QueryInput{
+		TableName:              aws.String(SearchPostingTableName),
+		IndexName:              aws.String("TermHead"),
+		KeyConditionExpression: aws.String("TermHead=:termHead AND begins_with(Term, :prefix)"),
+		ExpressionAttributeValues: AWSObject{
+			":termHead": StringValue(util.FirstRune(prefix)),
+			":prefix":   StringValue(prefix),
+		},
+	}
+
+	return querySearchPostings(&queryPostings, limit)
+}
+
+func querySearchPostings(queryInput *dynamodb.QueryInput, limit int) ([]model.SearchPosting, error) {
+	items, _, err := QueryPage(queryInput, 0, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	postings := make([]model.SearchPosting, len(items))
+	err = dynamodbattribute.UnmarshalListOfMaps(items, &postings)
+	if err != nil {
+		return nil, err
+	}
+
+	return postings, nil
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"realworld-go-nolambda/model"
+	"realworld-go-nolambda/util"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+func (s *DynamoDBStore) PutTagAlias(alias model.TagAlias) error {
+	item, err := dynamodbattribute.MarshalMap(alias)
+	if err != nil {
+		return err
+	}
+
+	_, err = DynamoDB().PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(TagAliasTableName),
+		Item:      item,
+	})
+
+	return err
+}
+
+func (s *DynamoDBStore) DeleteTagAlias(alias string) error {
+	_, err := DynamoDB().DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(TagAliasTableName),
+		Key:       StringKey("Alias", alias),
+	})
+
+	return err
+}
+
+func (s *DynamoDBStore) GetTagAliases(aliases []string) (map[string]model.TagAlias, error) {
+	found := make(map[string]model.TagAlias)
+	if len(aliases) == 0 {
+		return found, nil
+	}
+
+	keys := make([]AWSObject, 0, len(aliases))
+	for _, alias := range util.NewStringSetFromSlice(aliases).ToSlice() {
+		keys = append(keys, StringKey("Alias", alias))
+	}
+
+	batchGetAliases := dynamodb.BatchGetItemInput{
+		RequestItems: map[string]*dynamodb.KeysAndAttributes{
+			TagAliasTableName: {
+				Keys: keys,
+			},
+		},
+	}
+
+	responses, err := BatchGetItems(&batchGetAliases, len(keys))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, response := range responses {
+		for _, items := range response {
+			for _, item := range items {
+				alias := model.TagAlias{}
+				err = dynamodbattribute.UnmarshalMap(item, &alias)
+				if err != nil {
+					return nil, err
+				}
+
+				found[alias.Alias] = alias
+			}
+		}
+	}
+
+	return found, nil
+}
+
+func (s *DynamoDBStore) QueryTagAliases(tag string) ([]model.TagAlias, error) {
+	queryAliases := dynamodb.QueryInput{
+		TableName:                 aws.String(TagAliasTableName),
+		IndexName:                 aws.String("Tag"),
+		KeyConditionExpression:    aws.String("Tag=:tag"),
+		ExpressionAttributeValues: StringKey(":tag", tag),
+	}
+
+	items, err := QueryItems(&queryAliases, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	aliases := make([]model.TagAlias, len(items))
+	err = dynamodbattribute.UnmarshalListOfMaps(items, &aliases)
+	if err != nil {
+		return nil, err
+	}
+
+	return aliases, nil
+}
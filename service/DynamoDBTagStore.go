@@ -0,0 +1,318 @@
+package service
+
+import (
+	"realworld-go-nolambda/model"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+func (s *DynamoDBStore) QueryArticleIdsByTag(tag string, page Page) ([]int64, *Cursor, error) {
+	queryArticleIds := dynamodb.QueryInput{
+		TableName:                 aws.String(ArticleTagTableName),
+		IndexName:                 aws.String("CreatedAt"),
+		KeyConditionExpression:    aws.String("Tag=:tag"),
+		ExpressionAttributeValues: StringKey(":tag", tag),
+		ExclusiveStartKey:         cursorKey(page.After, StringKey("Tag", tag), "CreatedAt"),
+		ScanIndexForward:          aws.Bool(false),
+		ProjectionExpression:      aws.String("ArticleId"),
+	}
+
+	items, lastKey, err := QueryPage(&queryArticleIds, page.Offset, page.Limit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	articleTags := make([]model.ArticleTag, len(items))
+	err = dynamodbattribute.UnmarshalListOfMaps(items, &articleTags)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	articleIds := make([]int64, 0, len(items))
+
+	for _, articleTag := range articleTags {
+		articleIds = append(articleIds, articleTag.ArticleId)
+	}
+
+	next, err := keyCursor(lastKey, "CreatedAt")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return articleIds, next, nil
+}
+
+func (s *DynamoDBStore) ScanArticleIdsByTag(tag string, scan ArticleScan, page Page) ([]int64, *Cursor, error) {
+	values := StringKey(":tag", tag)
+	keyCondition := "Tag=:tag"
+
+	createdRange := createdAtRangeExpression(scan, values)
+	if createdRange != "" {
+		keyCondition += " AND " + createdRange
+	}
+
+	queryArticleIds := dynamodb.QueryInput{
+		TableName:                 aws.String(ArticleTagTableName),
+		IndexName:                 aws.String("CreatedAt"),
+		KeyConditionExpression:    aws.String(keyCondition),
+		ExpressionAttributeValues: values,
+		ExclusiveStartKey:         cursorKey(page.After, StringKey("Tag", tag), "CreatedAt"),
+		ScanIndexForward:          aws.Bool(scan.Order == OrderOldest),
+		ProjectionExpression:      aws.String("ArticleId"),
+	}
+
+	items, lastKey, err := QueryPage(&queryArticleIds, page.Offset, page.Limit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	articleTags := make([]model.ArticleTag, len(items))
+	err = dynamodbattribute.UnmarshalListOfMaps(items, &articleTags)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	articleIds := make([]int64, 0, len(items))
+	for _, articleTag := range articleTags {
+		articleIds = append(articleIds, articleTag.ArticleId)
+	}
+
+	next, err := keyCursor(lastKey, "CreatedAt")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return articleIds, next, nil
+}
+
+func (s *DynamoDBStore) GetTags(tags []string) (map[string]model.Tag, error) {
+	tagsByName := make(map[string]model.Tag)
+	if len(tags) == 0 {
+		return tagsByName, nil
+	}
+
+	tagSet := make(map[string]bool)
+	for _, tag := range tags {
+		tagSet[tag] = true
+	}
+
+	keys := make([]AWSObject, 0, len(tagSet))
+	for tag := range tagSet {
+		keys = append(keys, StringKey("Tag", tag))
+	}
+
+	batchGetTags := dynamodb.BatchGetItemInput{
+		RequestItems: map[string]*dynamodb.KeysAndAttributes{
+			TagTableName: {
+				Keys: keys,
+			},
+		},
+	}
+
+	responses, err := BatchGetItems(&batchGetTags, len(tags))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, response := range responses {
+		for _, items := range response {
+			for _, item := range items {
+				tag := model.Tag{}
+				err = dynamodbattribute.UnmarshalMap(item, &tag)
+				if err != nil {
+					return nil, err
+				}
+
+				tagsByName[tag.Tag] = tag
+			}
+		}
+	}
+
+	return tagsByName, nil
+}
+
+func (s *DynamoDBStore) QueryTags(prefix string, page Page) ([]model.Tag, *Cursor, error) {
+	queryTags := dynamodb.QueryInput{
+		TableName:              aws.String(TagTableName),
+		IndexName:              aws.String("ArticleCount"),
+		KeyConditionExpression: aws.String("Dummy=:zero AND ArticleCount > :zero"),
+		ExpressionAttributeValues: AWSObject{
+			":zero": IntValue(0),
+		},
+		ScanIndexForward: aws.Bool(false),
+	}
+
+	if prefix != "" {
+		queryTags.FilterExpression = aws.String("begins_with(Tag, :prefix)")
+		queryTags.ExpressionAttributeValues[":prefix"] = StringValue(prefix)
+	}
+
+	if page.After != nil {
+		queryTags.ExclusiveStartKey = AWSObject{
+			"Tag":          StringValue(page.After.Tag),
+			"Dummy":        IntValue(0),
+			"ArticleCount": Int64Value(page.After.SortKey),
+		}
+	}
+
+	items, lastKey, err := QueryPage(&queryTags, page.Offset, page.Limit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tags := make([]model.Tag, len(items))
+	err = dynamodbattribute.UnmarshalListOfMaps(items, &tags)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if lastKey == nil || len(tags) == 0 {
+		return tags, nil, nil
+	}
+
+	return tags, tagCursor(tags[len(tags)-1]), nil
+}
+
+// maxTrendingLinks bounds the links read by QueryTrendingTags, newest first, so that a long window stays cheap.
+// Links written by older versions lack the Dummy key of the Recent index, so they aren't counted.
+const maxTrendingLinks = 10000
+
+func (s *DynamoDBStore) QueryTrendingTags(since int64, limit int) ([]model.Tag, error) {
+	queryLinks := dynamodb.QueryInput{
+		TableName:              aws.String(ArticleTagTableName),
+		IndexName:              aws.String("Recent"),
+		KeyConditionExpression: aws.String("Dummy=:zero AND CreatedAt >= :since"),
+		ExpressionAttributeValues: AWSObject{
+			":zero":  IntValue(0),
+			":since": Int64Value(since),
+		},
+		ProjectionExpression: aws.String("Tag"),
+		ScanIndexForward:     aws.Bool(false),
+	}
+
+	items, _, err := QueryPage(&queryLinks, 0, maxTrendingLinks)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64)
+	for _, item := range items {
+		counts[aws.StringValue(item["Tag"].S)]++
+	}
+
+	tags := make([]model.Tag, 0, len(counts))
+	for tag, count := range counts {
+		tags = append(tags, model.Tag{Tag: tag, ArticleCount: count})
+	}
+
+	sortTags(tags)
+
+	if len(tags) > limit {
+		tags = tags[:limit]
+	}
+
+	return tags, nil
+}
+
+func (s *DynamoDBStore) RecountTags() error {
+	counts := make(map[string]int64)
+
+	scanArticleTags := dynamodb.ScanInput{
+		TableName:            aws.String(ArticleTagTableName),
+		ProjectionExpression: aws.String("Tag"),
+	}
+
+	err := DynamoDB().ScanPages(&scanArticleTags, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			counts[aws.StringValue(item["Tag"].S)]++
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	scanTags := dynamodb.ScanInput{
+		TableName: aws.String(TagTableName),
+	}
+
+	staleTags := make([]AWSObject, 0)
+	emptyTags := make([]AWSObject, 0)
+	var marshalErr error
+
+	err = DynamoDB().ScanPages(&scanTags, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			tag := model.Tag{}
+			marshalErr = dynamodbattribute.UnmarshalMap(item, &tag)
+			if marshalErr != nil {
+				return false
+			}
+
+			count := counts[tag.Tag]
+			delete(counts, tag.Tag)
+
+			if count == 0 && tag.Description == "" {
+				emptyTags = append(emptyTags, StringKey("Tag", tag.Tag))
+			} else if count != tag.ArticleCount {
+				tag.ArticleCount = count
+				item, marshalErr = dynamodbattribute.MarshalMap(tag)
+				if marshalErr != nil {
+					return false
+				}
+				staleTags = append(staleTags, item)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	if marshalErr != nil {
+		return marshalErr
+	}
+
+	// Tags left in counts are linked but missing
+	for tag, count := range counts {
+		item, err := dynamodbattribute.MarshalMap(model.Tag{Tag: tag, ArticleCount: count})
+		if err != nil {
+			return err
+		}
+
+		staleTags = append(staleTags, item)
+	}
+
+	err = BatchPutItems(TagTableName, staleTags)
+	if err != nil {
+		return err
+	}
+
+	return BatchDeleteItems(TagTableName, emptyTags)
+}
+
+func (s *DynamoDBStore) PutTagDescription(tag string, description string) error {
+	_, err := DynamoDB().UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:        aws.String(TagTableName),
+		Key:              StringKey("Tag", tag),
+		UpdateExpression: aws.String("SET Description=:description, Dummy=:zero ADD ArticleCount :zero"),
+		ExpressionAttributeValues: AWSObject{
+			":description": StringValue(description),
+			":zero":        IntValue(0),
+		},
+	})
+
+	return err
+}
+
+func (s *DynamoDBStore) DeleteTag(tag string) error {
+	_, err := DynamoDB().DeleteItem(&dynamodb.DeleteItemInput{
+		TableName:                 aws.String(TagTableName),
+		Key:                       StringKey("Tag", tag),
+		ConditionExpression:       aws.String("attribute_not_exists(ArticleCount) OR ArticleCount<=:zero"),
+		ExpressionAttributeValues: IntKey(":zero", 0),
+	})
+
+	return conditionError(err)
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"realworld-go-nolambda/model"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+func (s *DynamoDBStore) PutTimelineEntries(entries []model.TimelineEntry) error {
+	items := make([]AWSObject, 0, len(entries))
+	for _, entry := range entries {
+		item, err := dynamodbattribute.MarshalMap(entry)
+		if err != nil {
+			return err
+		}
+
+		items = append(items, item)
+	}
+
+	return BatchPutItems(TimelineTableName, items)
+}
+
+func (s *DynamoDBStore) DeleteTimelineEntries(keys []model.TimelineKey) error {
+	items := make([]AWSObject, 0, len(keys))
+	for _, key := range keys {
+		item, err := dynamodbattribute.MarshalMap(key)
+		if err != nil {
+			return err
+		}
+
+		items = append(items, item)
+	}
+
+	return BatchDeleteItems(TimelineTableName, items)
+}
+
+func (s *DynamoDBStore) QueryTimeline(username string, page Page) ([]int64, *Cursor, error) {
+	queryTimeline := dynamodb.QueryInput{
+		TableName:                 aws.String(TimelineTableName),
+		IndexName:                 aws.String("CreatedAt"),
+		KeyConditionExpression:    aws.String("Username=:username"),
+		ExpressionAttributeValues: StringKey(":username", username),
+		ExclusiveStartKey:         cursorKey(page.After, StringKey("Username", username), "CreatedAt"),
+		ScanIndexForward:          aws.Bool(false),
+		ProjectionExpression:      aws.String("ArticleId"),
+	}
+
+	items, lastKey, err := QueryPage(&queryTimeline, page.Offset, page.Limit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entries := make([]model.TimelineEntry, len(items))
+	err = dynamodbattribute.UnmarshalListOfMaps(items, &entries)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	articleIds := make([]int64, 0, len(entries))
+	for _, entry := range entries {
+		articleIds = append(articleIds, entry.ArticleId)
+	}
+
+	next, err := keyCursor(lastKey, "CreatedAt")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return articleIds, next, nil
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"realworld-go-nolambda/model"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+func (s *DynamoDBStore) PutUser(user model.User) error {
+	userItem, err := dynamodbattribute.MarshalMap(user)
+	if err != nil {
+		return err
+	}
+
+	emailUser := model.EmailUser{
+		Email:    user.Email,
+		Username: user.Username,
+	}
+
+	emailUserItem, err := dynamodbattribute.MarshalMap(emailUser)
+	if err != nil {
+		return err
+	}
+
+	// Put a new user, make sure username and email are unique
+	transaction := dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			{
+				Put: &dynamodb.Put{
+					TableName:           aws.String(UserTableName),
+					Item:                userItem,
+					ConditionExpression: aws.String("attribute_not_exists(Username)"),
+				},
+			},
+			{
+				Put: &dynamodb.Put{
+					TableName:           aws.String(EmailUserTableName),
+					Item:                emailUserItem,
+					ConditionExpression: aws.String("attribute_not_exists(Email)"),
+				},
+			},
+		},
+	}
+
+	_, err = DynamoDB().TransactWriteItems(&transaction)
+	return conditionError(err)
+}
+
+func (s *DynamoDBStore) UpdateUser(oldUser model.User, newUser model.User) error {
+	transactItems := make([]*dynamodb.TransactWriteItem, 0, 3)
+
+	if oldUser.Email != newUser.Email {
+		newEmailUser := model.EmailUser{
+			Email:    newUser.Email,
+			Username: newUser.Username,
+		}
+
+		newEmailUserItem, err := dynamodbattribute.MarshalMap(newEmailUser)
+		if err != nil {
+			return err
+		}
+
+		// Link user with the new email
+		transactItems = append(transactItems, &dynamodb.TransactWriteItem{
+			Put: &dynamodb.Put{
+				TableName:           aws.String(EmailUserTableName),
+				Item:                newEmailUserItem,
+				ConditionExpression: aws.String("attribute_not_exists(Email)"),
+			},
+		})
+
+		// Unlink user from the old email
+		transactItems = append(transactItems, &dynamodb.TransactWriteItem{
+			Delete: &dynamodb.Delete{
+				TableName:           aws.String(EmailUserTableName),
+				Key:                 StringKey("Email", oldUser.Email),
+				ConditionExpression: aws.String("attribute_exists(Email)"),
+			},
+		})
+	}
+
+	newUserItem, err := dynamodbattribute.MarshalMap(newUser)
+	if err != nil {
+		return err
+	}
+
+	// Update user info
+	transactItems = append(transactItems, &dynamodb.TransactWriteItem{
+		Put: &dynamodb.Put{
+			TableName:                 aws.String(UserTableName),
+			Item:                      newUserItem,
+			ConditionExpression:       aws.String("Email = :email"),
+			ExpressionAttributeValues: StringKey(":email", oldUser.Email),
+		},
+	})
+
+	_, err = DynamoDB().TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+		TransactItems: transactItems,
+	})
+	return conditionError(err)
+}
+
+func (s *DynamoDBStore) GetUser(username string) (model.User, bool, error) {
+	user := model.User{}
+	found, err := GetItemByKey(UserTableName, StringKey("Username", username), &user)
+	return user, found, err
+}
+
+func (s *DynamoDBStore) GetUsernameByEmail(email string) (string, bool, error) {
+	emailUser := model.EmailUser{}
+	found, err := GetItemByKey(EmailUserTableName, StringKey("Email", email), &emailUser)
+	return emailUser.Username, found, err
+}
+
+func (s *DynamoDBStore) GetUsers(usernames []string) (map[string]model.User, error) {
+	usersByUsername := make(map[string]model.User)
+	if len(usernames) == 0 {
+		return usersByUsername, nil
+	}
+
+	usernameSet := make(map[string]bool)
+	for _, username := range usernames {
+		usernameSet[username] = true
+	}
+
+	keys := make([]AWSObject, 0, len(usernameSet))
+	for username := range usernameSet {
+		keys = append(keys, StringKey("Username", username))
+	}
+
+	batchGetUsers := dynamodb.BatchGetItemInput{
+		RequestItems: map[string]*dynamodb.KeysAndAttributes{
+			UserTableName: {
+				Keys: keys,
+			},
+		},
+	}
+
+	responses, err := BatchGetItems(&batchGetUsers, len(usernames))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, response := range responses {
+		for _, items := range response {
+			for _, item := range items {
+				user := model.User{}
+				err = dynamodbattribute.UnmarshalMap(item, &user)
+				if err != nil {
+					return nil, err
+				}
+
+				usersByUsername[user.Username] = user
+			}
+		}
+	}
+
+	return usersByUsername, nil
+}
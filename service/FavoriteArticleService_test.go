@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"realworld-go-nolambda/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreFavoritesAndFeed(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		alice := newTestUser(t, "alice")
+		newTestUser(t, "bob")
+		newTestUser(t, "carol")
+
+		fromBob := newTestArticle(t, "bob", 1)
+		fromCarol := newTestArticle(t, "carol", 2)
+		newTestArticle(t, "alice", 3)
+
+		favorite := favoriteTestArticle(t, "alice", fromBob, 4)
+		assert.Error(t, SetFavoriteArticle(favorite))
+
+		article, err := GetArticleByArticleId(fromBob.ArticleId)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), article.FavoritesCount)
+
+		isFavorited, err := IsArticleFavoritedByUser(&alice, []model.Article{fromCarol, fromBob})
+		assert.NoError(t, err)
+		assert.Equal(t, []bool{false, true}, isFavorited)
+
+		assert.NoError(t, Follow("alice", "bob"))
+		assert.NoError(t, Follow("alice", "carol"))
+
+		feed, _, err := GetFeed(context.Background(), "alice", Page{Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{fromCarol.Slug, fromBob.Slug}, articleSlugs(feed))
+
+		assert.NoError(t, Unfollow("alice", "carol"))
+		following, err := IsFollowing(&alice, []string{"bob", "carol"})
+		assert.NoError(t, err)
+		assert.Equal(t, []bool{true, false}, following)
+
+		assert.NoError(t, UnfavoriteArticle(favorite.FavoriteArticleKey))
+		article, err = GetArticleByArticleId(fromBob.ArticleId)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), article.FavoritesCount)
+	})
+}
@@ -1,12 +1,7 @@
 package service
 
 import (
-	//"realworld-go-nolambda/model"
 	"realworld-go-nolambda/model"
-
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 )
 
 func IsFollowing(follower *model.User, publishers []string) ([]bool, error) {
@@ -14,49 +9,11 @@ func IsFollowing(follower *model.User, publishers []string) ([]bool, error) {
 		return make([]bool, len(publishers)), nil
 	}
 
-	publisherSet := make(map[string]bool)
-	for _, publisher := range publishers {
-		publisherSet[publisher] = true
-	}
-
-	keys := make([]AWSObject, 0, len(publisherSet))
-	for publisher := range publisherSet {
-		keys = append(keys, AWSObject{
-			"Follower":  StringValue(follower.Username),
-			"Publisher": StringValue(publisher),
-		})
-	}
-
-	batchGetFollows := dynamodb.BatchGetItemInput{
-		RequestItems: map[string]*dynamodb.KeysAndAttributes{
-			FollowTableName: {
-				Keys:                 keys,
-				ProjectionExpression: aws.String("Publisher"),
-			},
-		},
-	}
-
-	responses, err := BatchGetItems(&batchGetFollows, len(publisherSet))
+	followingUser, err := GetStore().GetFollowedPublishers(follower.Username, publishers)
 	if err != nil {
 		return nil, err
 	}
 
-	followingUser := make(map[string]bool)
-
-	for _, response := range responses {
-		for _, items := range response {
-			for _, item := range items {
-				follow := model.Follow{}
-				err = dynamodbattribute.UnmarshalMap(item, &follow)
-				if err != nil {
-					return nil, err
-				}
-
-				followingUser[follow.Publisher] = true
-			}
-		}
-	}
-
 	following := make([]bool, 0, len(publishers))
 	for _, username := range publishers {
 		following = append(following, followingUser[username])
@@ -71,19 +28,12 @@ func Follow(follower string, publisher string) error {
 		Publisher: publisher,
 	}
 
-	item, err := dynamodbattribute.MarshalMap(follow)
+	err := GetStore().PutFollow(follow)
 	if err != nil {
 		return err
 	}
 
-	putFollow := dynamodb.PutItemInput{
-		TableName: aws.String(FollowTableName),
-		Item:      item,
-	}
-
-	_, err = DynamoDB().PutItem(&putFollow)
-
-	return err
+	return backfillTimeline(follower, publisher)
 }
 
 func Unfollow(follower string, publisher string) error {
@@ -92,17 +42,10 @@ func Unfollow(follower string, publisher string) error {
 		Publisher: publisher,
 	}
 
-	item, err := dynamodbattribute.MarshalMap(follow)
+	err := GetStore().DeleteFollow(follow)
 	if err != nil {
 		return err
 	}
 
-	deleteFollow := dynamodb.DeleteItemInput{
-		TableName: aws.String(FollowTableName),
-		Key:       item,
-	}
-
-	_, err = DynamoDB().DeleteItem(&deleteFollow)
-
-	return err
+	return pruneTimeline(follower, publisher)
 }
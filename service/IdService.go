@@ -0,0 +1,37 @@
+package service
+
+import (
+	"realworld-go-nolambda/model"
+)
+
+const (
+	articleIdCounter     = "ArticleId"
+	commentIdCounter     = "CommentId"
+	commentEditIdCounter = "CommentEditId"
+)
+
+// NextArticleId allocates an unused article id from a counter.
+// Allocated ids start above the random ids of older articles, so both keep resolving from their slugs.
+func NextArticleId() (int64, error) {
+	n, err := GetStore().IncrementCounter(articleIdCounter)
+	if err != nil {
+		return 0, err
+	}
+
+	return model.MaxArticleId + n, nil
+}
+
+// NextCommentId allocates an unused comment id from a counter shared by all articles.
+func NextCommentId() (int64, error) {
+	n, err := GetStore().IncrementCounter(commentIdCounter)
+	if err != nil {
+		return 0, err
+	}
+
+	return model.MaxCommentId + n, nil
+}
+
+// NextCommentEditId allocates an unused comment edit id from a counter shared by all articles, apart from comment ids.
+func NextCommentEditId() (int64, error) {
+	return GetStore().IncrementCounter(commentEditIdCounter)
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"sync"
+	"testing"
+
+	"realworld-go-nolambda/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreIdAllocation(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		newTestUser(t, "alice")
+
+		const n = 20
+		ids := make(chan int64, n)
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				id, err := NextArticleId()
+				assert.NoError(t, err)
+				ids <- id
+			}()
+		}
+		wg.Wait()
+		close(ids)
+
+		seen := make(map[int64]bool)
+		for id := range ids {
+			assert.False(t, seen[id], "duplicate id %x", id)
+			assert.Greater(t, id, int64(model.MaxArticleId))
+			seen[id] = true
+		}
+
+		article := newTestArticle(t, "alice", 1)
+		assert.Greater(t, article.ArticleId, int64(model.MaxArticleId))
+
+		found, err := GetArticleBySlug(article.Slug)
+		assert.NoError(t, err)
+		assert.Equal(t, article.ArticleId, found.ArticleId)
+	})
+}
@@ -0,0 +1,1415 @@
+package service
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"realworld-go-nolambda/model"
+	"realworld-go-nolambda/util"
+)
+
+// MemoryStore is a Store that keeps everything in process memory.
+// It mirrors the conditions and counters of DynamoDBStore, so the whole API can run without outside services.
+type MemoryStore struct {
+	mutex sync.RWMutex
+
+	users            map[string]model.User
+	emailUsers       map[string]string
+	articles         map[int64]model.Article
+	articleRevisions map[int64]map[int64]model.ArticleRevision
+	articleTags      map[string]map[int64]model.ArticleTag
+	tags             map[string]model.Tag
+	tagAliases       map[string]model.TagAlias
+	comments         map[int64]map[int64]model.Comment
+	commentEdits     map[int64]map[int64]model.CommentEdit
+	commentReactions map[int64]map[model.CommentReactionKey]model.CommentReaction
+	follows          map[string]map[string]bool
+	favoriteArticles map[string]map[int64]model.FavoriteArticle
+	articleCleanups  map[int64]model.ArticleCleanup
+	counters         map[string]int64
+	timelines        map[string]map[int64]model.TimelineEntry
+	articleSchedules map[model.ArticleScheduleKey]model.ArticleSchedule
+	searchPostings   map[string]map[int64]model.SearchPosting
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users:            make(map[string]model.User),
+		emailUsers:       make(map[string]string),
+		articles:         make(map[int64]model.Article),
+		articleRevisions: make(map[int64]map[int64]model.ArticleRevision),
+		articleTags:      make(map[string]map[int64]model.ArticleTag),
+		tags:             make(map[string]model.Tag),
+		tagAliases:       make(map[string]model.TagAlias),
+		comments:         make(map[int64]map[int64]model.Comment),
+		commentEdits:     make(map[int64]map[int64]model.CommentEdit),
+		commentReactions: make(map[int64]map[model.CommentReactionKey]model.CommentReaction),
+		follows:          make(map[string]map[string]bool),
+		favoriteArticles: make(map[string]map[int64]model.FavoriteArticle),
+		articleCleanups:  make(map[int64]model.ArticleCleanup),
+		counters:         make(map[string]int64),
+		timelines:        make(map[string]map[int64]model.TimelineEntry),
+		articleSchedules: make(map[model.ArticleScheduleKey]model.ArticleSchedule),
+		searchPostings:   make(map[string]map[int64]model.SearchPosting),
+	}
+}
+
+func (s *MemoryStore) PutUser(user model.User) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.users[user.Username]; ok {
+		return ErrConditionFailed
+	}
+
+	if _, ok := s.emailUsers[user.Email]; ok {
+		return ErrConditionFailed
+	}
+
+	s.users[user.Username] = copyUser(user)
+	s.emailUsers[user.Email] = user.Username
+	return nil
+}
+
+func (s *MemoryStore) UpdateUser(oldUser model.User, newUser model.User) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	current, ok := s.users[newUser.Username]
+	if !ok || current.Email != oldUser.Email {
+		return ErrConditionFailed
+	}
+
+	if oldUser.Email != newUser.Email {
+		if _, ok := s.emailUsers[newUser.Email]; ok {
+			return ErrConditionFailed
+		}
+
+		if _, ok := s.emailUsers[oldUser.Email]; !ok {
+			return ErrConditionFailed
+		}
+
+		delete(s.emailUsers, oldUser.Email)
+		s.emailUsers[newUser.Email] = newUser.Username
+	}
+
+	s.users[newUser.Username] = copyUser(newUser)
+	return nil
+}
+
+func (s *MemoryStore) GetUser(username string) (model.User, bool, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	user, ok := s.users[username]
+	return copyUser(user), ok, nil
+}
+
+func (s *MemoryStore) GetUsernameByEmail(email string) (string, bool, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	username, ok := s.emailUsers[email]
+	return username, ok, nil
+}
+
+func (s *MemoryStore) GetUsers(usernames []string) (map[string]model.User, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	usersByUsername := make(map[string]model.User)
+	for _, username := range usernames {
+		if user, ok := s.users[username]; ok {
+			usersByUsername[username] = copyUser(user)
+		}
+	}
+
+	return usersByUsername, nil
+}
+
+func (s *MemoryStore) PutArticle(article model.Article, revision model.ArticleRevision) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.articles[article.ArticleId]; ok {
+		return ErrConditionFailed
+	}
+
+	if _, ok := s.articleCleanups[article.ArticleId]; ok {
+		return ErrConditionFailed
+	}
+
+	if s.hasArticleRevisions([]model.ArticleRevision{revision}) {
+		return ErrConditionFailed
+	}
+
+	s.articles[article.ArticleId] = copyArticle(article)
+	s.putArticleRevisions([]model.ArticleRevision{revision})
+
+	for _, tag := range article.LinkedTags() {
+		s.linkArticleTag(tag, article.ArticleId, article.CreatedAt)
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) UpdateArticle(oldArticle model.Article, newArticle model.Article, revisions []model.ArticleRevision) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	current, ok := s.articles[oldArticle.ArticleId]
+	if !ok || current.UpdatedAt != oldArticle.UpdatedAt {
+		return ErrConditionFailed
+	}
+
+	if s.hasArticleRevisions(revisions) {
+		return ErrConditionFailed
+	}
+
+	s.putArticleRevisions(revisions)
+
+	oldTagSet := util.NewStringSetFromSlice(oldArticle.LinkedTags())
+	newTagSet := util.NewStringSetFromSlice(newArticle.LinkedTags())
+
+	for tag := range oldTagSet.Difference(newTagSet) {
+		s.unlinkArticleTag(tag, oldArticle.ArticleId)
+	}
+
+	for tag := range newTagSet.Difference(oldTagSet) {
+		s.linkArticleTag(tag, oldArticle.ArticleId, newArticle.CreatedAt)
+	}
+
+	// Counters are owned by the store, not by the caller's copy
+	updated := copyArticle(newArticle)
+	updated.FavoritesCount = current.FavoritesCount
+	updated.CommentsCount = current.CommentsCount
+	s.articles[oldArticle.ArticleId] = updated
+
+	return nil
+}
+
+func (s *MemoryStore) DeleteArticle(article model.Article, cleanup model.ArticleCleanup) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	current, ok := s.articles[article.ArticleId]
+	if !ok || current.Author != cleanup.Author || current.UpdatedAt != article.UpdatedAt {
+		return ErrConditionFailed
+	}
+
+	delete(s.articles, article.ArticleId)
+
+	for _, tag := range article.LinkedTags() {
+		s.unlinkArticleTag(tag, article.ArticleId)
+	}
+
+	s.articleCleanups[article.ArticleId] = cleanup
+
+	return nil
+}
+
+func (s *MemoryStore) hasArticleRevisions(revisions []model.ArticleRevision) bool {
+	for _, revision := range revisions {
+		if _, ok := s.articleRevisions[revision.ArticleId][revision.Revision]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *MemoryStore) putArticleRevisions(revisions []model.ArticleRevision) {
+	for _, revision := range revisions {
+		if s.articleRevisions[revision.ArticleId] == nil {
+			s.articleRevisions[revision.ArticleId] = make(map[int64]model.ArticleRevision)
+		}
+
+		s.articleRevisions[revision.ArticleId][revision.Revision] = copyArticleRevision(revision)
+	}
+}
+
+func (s *MemoryStore) GetArticleRevision(key model.ArticleRevisionKey) (model.ArticleRevision, bool, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	revision, ok := s.articleRevisions[key.ArticleId][key.Revision]
+	return copyArticleRevision(revision), ok, nil
+}
+
+func (s *MemoryStore) QueryArticleRevisions(articleId int64, page Page) ([]model.ArticleRevision, *Cursor, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	revisions := make([]model.ArticleRevision, 0, len(s.articleRevisions[articleId]))
+	for _, revision := range s.articleRevisions[articleId] {
+		if page.After.isAfter(revision.Revision, revision.ArticleId) {
+			revisions = append(revisions, copyArticleRevision(revision))
+		}
+	}
+
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Revision > revisions[j].Revision
+	})
+
+	start, end := pageBounds(len(revisions), page.Offset, page.Limit)
+	if end == len(revisions) || end == start {
+		return revisions[start:end], nil, nil
+	}
+
+	return revisions[start:end], revisionCursor(revisions[end-1]), nil
+}
+
+func (s *MemoryStore) linkArticleTag(tag string, articleId int64, createdAt int64) {
+	if s.articleTags[tag] == nil {
+		s.articleTags[tag] = make(map[int64]model.ArticleTag)
+	}
+
+	s.articleTags[tag][articleId] = model.ArticleTag{
+		Tag:       tag,
+		ArticleId: articleId,
+		CreatedAt: createdAt,
+	}
+
+	tagObject := s.tags[tag]
+	tagObject.Tag = tag
+	tagObject.ArticleCount++
+	s.tags[tag] = tagObject
+}
+
+func (s *MemoryStore) unlinkArticleTag(tag string, articleId int64) {
+	delete(s.articleTags[tag], articleId)
+
+	tagObject := s.tags[tag]
+	tagObject.Tag = tag
+	tagObject.ArticleCount--
+	s.tags[tag] = tagObject
+}
+
+func (s *MemoryStore) GetArticle(articleId int64) (model.Article, bool, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	article, ok := s.articles[articleId]
+	return copyArticle(article), ok, nil
+}
+
+func (s *MemoryStore) GetArticlesByIds(articleIds []int64) (map[int64]model.Article, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	articlesById := make(map[int64]model.Article)
+	for _, articleId := range articleIds {
+		if article, ok := s.articles[articleId]; ok {
+			articlesById[articleId] = copyArticle(article)
+		}
+	}
+
+	return articlesById, nil
+}
+
+func (s *MemoryStore) QueryArticles(page Page) ([]model.Article, *Cursor, error) {
+	articles, next := s.queryArticles(func(article model.Article) bool { return article.IsPublished() }, page)
+	return articles, next, nil
+}
+
+func (s *MemoryStore) QueryArticlesByAuthor(author string, page Page) ([]model.Article, *Cursor, error) {
+	articles, next := s.queryArticles(func(article model.Article) bool {
+		return article.Author == author && article.IsPublished()
+	}, page)
+	return articles, next, nil
+}
+
+func (s *MemoryStore) QueryDraftsByAuthor(author string, page Page) ([]model.Article, *Cursor, error) {
+	articles, next := s.queryArticles(func(article model.Article) bool {
+		return article.Author == author && !article.IsPublished()
+	}, page)
+	return articles, next, nil
+}
+
+func (s *MemoryStore) queryArticles(match func(model.Article) bool, page Page) ([]model.Article, *Cursor) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	articles := make([]model.Article, 0)
+	for _, article := range s.articles {
+		if match(article) && page.After.isAfter(article.CreatedAt, article.ArticleId) {
+			articles = append(articles, article)
+		}
+	}
+
+	sort.Slice(articles, func(i, j int) bool {
+		return newerThan(articles[i].CreatedAt, articles[i].ArticleId, articles[j].CreatedAt, articles[j].ArticleId)
+	})
+
+	start, end := pageBounds(len(articles), page.Offset, page.Limit)
+	result := make([]model.Article, 0, end-start)
+	for _, article := range articles[start:end] {
+		result = append(result, copyArticle(article))
+	}
+
+	if end == len(articles) || end == start {
+		return result, nil
+	}
+
+	return result, articleCursor(articles[end-1])
+}
+
+func (s *MemoryStore) ScanArticles(scan ArticleScan, page Page) ([]model.Article, *Cursor, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	articles := make([]model.Article, 0)
+	for _, article := range s.articles {
+		if scan.matches(article) && page.After.isAfterIn(scan.Order, orderSortKey(scan.Order, article), article.ArticleId) {
+			articles = append(articles, article)
+		}
+	}
+
+	sort.Slice(articles, func(i, j int) bool {
+		return orderedBefore(scan.Order, orderSortKey(scan.Order, articles[i]), articles[i].ArticleId,
+			orderSortKey(scan.Order, articles[j]), articles[j].ArticleId)
+	})
+
+	start, end := pageBounds(len(articles), page.Offset, page.Limit)
+	result := make([]model.Article, 0, end-start)
+	for _, article := range articles[start:end] {
+		result = append(result, copyArticle(article))
+	}
+
+	if end == len(articles) || end == start {
+		return result, nil, nil
+	}
+
+	return result, orderCursor(scan.Order, articles[end-1]), nil
+}
+
+func (s *MemoryStore) QueryArticleIdsByTag(tag string, page Page) ([]int64, *Cursor, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	articleTags := make([]model.ArticleTag, 0, len(s.articleTags[tag]))
+	for _, articleTag := range s.articleTags[tag] {
+		if page.After.isAfter(articleTag.CreatedAt, articleTag.ArticleId) {
+			articleTags = append(articleTags, articleTag)
+		}
+	}
+
+	sort.Slice(articleTags, func(i, j int) bool {
+		return newerThan(articleTags[i].CreatedAt, articleTags[i].ArticleId, articleTags[j].CreatedAt, articleTags[j].ArticleId)
+	})
+
+	start, end := pageBounds(len(articleTags), page.Offset, page.Limit)
+	articleIds := make([]int64, 0, end-start)
+	for _, articleTag := range articleTags[start:end] {
+		articleIds = append(articleIds, articleTag.ArticleId)
+	}
+
+	if end == len(articleTags) || end == start {
+		return articleIds, nil, nil
+	}
+
+	last := articleTags[end-1]
+	return articleIds, &Cursor{SortKey: last.CreatedAt, ArticleId: last.ArticleId}, nil
+}
+
+func (s *MemoryStore) ScanArticleIdsByTag(tag string, scan ArticleScan, page Page) ([]int64, *Cursor, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	articleTags := make([]model.ArticleTag, 0, len(s.articleTags[tag]))
+	for _, articleTag := range s.articleTags[tag] {
+		if scan.isCreatedInRange(articleTag.CreatedAt) && page.After.isAfterIn(scan.Order, articleTag.CreatedAt, articleTag.ArticleId) {
+			articleTags = append(articleTags, articleTag)
+		}
+	}
+
+	sort.Slice(articleTags, func(i, j int) bool {
+		return orderedBefore(scan.Order, articleTags[i].CreatedAt, articleTags[i].ArticleId, articleTags[j].CreatedAt, articleTags[j].ArticleId)
+	})
+
+	start, end := pageBounds(len(articleTags), page.Offset, page.Limit)
+	articleIds := make([]int64, 0, end-start)
+	for _, articleTag := range articleTags[start:end] {
+		articleIds = append(articleIds, articleTag.ArticleId)
+	}
+
+	if end == len(articleTags) || end == start {
+		return articleIds, nil, nil
+	}
+
+	last := articleTags[end-1]
+	return articleIds, &Cursor{SortKey: last.CreatedAt, ArticleId: last.ArticleId}, nil
+}
+
+func (s *MemoryStore) GetTags(tags []string) (map[string]model.Tag, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	found := make(map[string]model.Tag, len(tags))
+	for _, tag := range tags {
+		tagObject, ok := s.tags[tag]
+		if ok {
+			found[tag] = tagObject
+		}
+	}
+
+	return found, nil
+}
+
+func (s *MemoryStore) RecountTags() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	tags := make(map[string]model.Tag, len(s.articleTags))
+	for tag, articleTags := range s.articleTags {
+		if len(articleTags) > 0 {
+			tags[tag] = model.Tag{Tag: tag, ArticleCount: int64(len(articleTags))}
+		}
+	}
+
+	for _, tag := range s.tags {
+		if tag.Description != "" {
+			counted := tags[tag.Tag]
+			counted.Tag = tag.Tag
+			counted.Description = tag.Description
+			tags[tag.Tag] = counted
+		}
+	}
+
+	s.tags = tags
+	return nil
+}
+
+func (s *MemoryStore) PutTagDescription(tag string, description string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	tagObject := s.tags[tag]
+	tagObject.Tag = tag
+	tagObject.Description = description
+	s.tags[tag] = tagObject
+	return nil
+}
+
+func (s *MemoryStore) DeleteTag(tag string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.tags[tag].ArticleCount > 0 {
+		return ErrConditionFailed
+	}
+
+	delete(s.tags, tag)
+	return nil
+}
+
+func (s *MemoryStore) PutTagAlias(alias model.TagAlias) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.tagAliases[alias.Alias] = alias
+	return nil
+}
+
+func (s *MemoryStore) DeleteTagAlias(alias string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.tagAliases, alias)
+	return nil
+}
+
+func (s *MemoryStore) GetTagAliases(aliases []string) (map[string]model.TagAlias, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	found := make(map[string]model.TagAlias)
+	for _, alias := range aliases {
+		if tagAlias, ok := s.tagAliases[alias]; ok {
+			found[alias] = tagAlias
+		}
+	}
+
+	return found, nil
+}
+
+func (s *MemoryStore) QueryTagAliases(tag string) ([]model.TagAlias, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	aliases := make([]model.TagAlias, 0)
+	for _, alias := range s.tagAliases {
+		if alias.Tag == tag {
+			aliases = append(aliases, alias)
+		}
+	}
+
+	return aliases, nil
+}
+
+func (s *MemoryStore) QueryTags(prefix string, page Page) ([]model.Tag, *Cursor, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	tags := make([]model.Tag, 0, len(s.tags))
+	for _, tag := range s.tags {
+		if tag.ArticleCount > 0 && strings.HasPrefix(tag.Tag, prefix) && page.After.isAfterTag(tag) {
+			tags = append(tags, tag)
+		}
+	}
+
+	sortTags(tags)
+
+	start, end := pageBounds(len(tags), page.Offset, page.Limit)
+	var next *Cursor
+	if end < len(tags) {
+		next = tagCursor(tags[end-1])
+	}
+
+	return tags[start:end], next, nil
+}
+
+func (s *MemoryStore) QueryTrendingTags(since int64, limit int) ([]model.Tag, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	tags := make([]model.Tag, 0)
+	for tag, articleTags := range s.articleTags {
+		count := int64(0)
+		for _, articleTag := range articleTags {
+			if articleTag.CreatedAt >= since {
+				count++
+			}
+		}
+
+		if count > 0 {
+			tags = append(tags, model.Tag{Tag: tag, ArticleCount: count})
+		}
+	}
+
+	sortTags(tags)
+
+	_, end := pageBounds(len(tags), 0, limit)
+	return tags[:end], nil
+}
+
+// sortTags sorts tags by ArticleCount, most first, then by tag.
+func sortTags(tags []model.Tag) {
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].ArticleCount != tags[j].ArticleCount {
+			return tags[i].ArticleCount > tags[j].ArticleCount
+		}
+		return tags[i].Tag < tags[j].Tag
+	})
+}
+
+func (s *MemoryStore) PutComment(comment model.Comment) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.comments[comment.ArticleId] == nil {
+		s.comments[comment.ArticleId] = make(map[int64]model.Comment)
+	}
+
+	if _, ok := s.comments[comment.ArticleId][comment.CommentId]; ok {
+		return ErrConditionFailed
+	}
+
+	article, ok := s.articles[comment.ArticleId]
+	if !ok {
+		return ErrConditionFailed
+	}
+
+	if comment.ParentId != 0 {
+		parent, ok := s.comments[comment.ArticleId][comment.ParentId]
+		if !ok || parent.IsDeleted() {
+			return ErrConditionFailed
+		}
+
+		parent.ReplyCount++
+		s.comments[comment.ArticleId][comment.ParentId] = parent
+	}
+
+	article.CommentsCount++
+	s.articles[comment.ArticleId] = article
+	s.comments[comment.ArticleId][comment.CommentId] = comment
+	return nil
+}
+
+func (s *MemoryStore) GetComment(key model.CommentKey) (model.Comment, bool, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	comment, ok := s.comments[key.ArticleId][key.CommentId]
+	return comment, ok, nil
+}
+
+func (s *MemoryStore) QueryComments(articleId int64, order string, page Page) ([]model.Comment, *Cursor, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	comments := make([]model.Comment, 0, len(s.comments[articleId]))
+	for _, comment := range s.comments[articleId] {
+		if comment.ParentId == 0 && page.After.isAfterComment(comment, order) {
+			comments = append(comments, comment)
+		}
+	}
+
+	sort.Slice(comments, func(i, j int) bool {
+		a, b := comments[i], comments[j]
+		if order != OrderOldest {
+			a, b = b, a
+		}
+
+		if keyA, keyB := commentSortKey(a, order), commentSortKey(b, order); keyA != keyB {
+			return keyA < keyB
+		}
+		return a.CommentId < b.CommentId
+	})
+
+	start, end := pageBounds(len(comments), page.Offset, page.Limit)
+	var next *Cursor
+	if end < len(comments) {
+		next = commentCursor(comments[end-1], order)
+	}
+
+	return comments[start:end], next, nil
+}
+
+func (s *MemoryStore) QueryCommentReplies(articleId int64, parentId int64, page Page) ([]model.Comment, *Cursor, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	replies := make([]model.Comment, 0)
+	for _, comment := range s.comments[articleId] {
+		if comment.ParentId == parentId && page.After.isAfterComment(comment, OrderOldest) {
+			replies = append(replies, comment)
+		}
+	}
+
+	sort.Slice(replies, func(i, j int) bool {
+		return commentBefore(replies[i], replies[j])
+	})
+
+	start, end := pageBounds(len(replies), page.Offset, page.Limit)
+	var next *Cursor
+	if end < len(replies) {
+		next = commentCursor(replies[end-1], OrderOldest)
+	}
+
+	return replies[start:end], next, nil
+}
+
+func (s *MemoryStore) DeleteComment(comment model.Comment) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	found, ok := s.comments[comment.ArticleId][comment.CommentId]
+	if !ok || found.Author != comment.Author || found.ReplyCount > 0 {
+		return ErrConditionFailed
+	}
+
+	article, ok := s.articles[comment.ArticleId]
+	if !ok {
+		return ErrConditionFailed
+	}
+
+	delete(s.comments[comment.ArticleId], comment.CommentId)
+
+	if parent, ok := s.comments[comment.ArticleId][found.ParentId]; ok {
+		parent.ReplyCount--
+		s.comments[comment.ArticleId][found.ParentId] = parent
+	}
+
+	if !found.IsDeleted() {
+		article.CommentsCount--
+		s.articles[comment.ArticleId] = article
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) TombstoneComment(key model.CommentKey, deletedAt int64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	comment, ok := s.comments[key.ArticleId][key.CommentId]
+	if !ok || comment.ReplyCount == 0 || comment.IsDeleted() {
+		return ErrConditionFailed
+	}
+
+	article, ok := s.articles[key.ArticleId]
+	if !ok {
+		return ErrConditionFailed
+	}
+
+	article.CommentsCount--
+	s.articles[key.ArticleId] = article
+
+	comment.Body = ""
+	comment.DeletedAt = deletedAt
+	comment.Upvotes = 0
+	comment.Reactions = nil
+	s.comments[key.ArticleId][key.CommentId] = comment
+	return nil
+}
+
+func (s *MemoryStore) RecountComments() ([]int64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	changed := make([]int64, 0)
+	for articleId, article := range s.articles {
+		count := int64(0)
+		for _, comment := range s.comments[articleId] {
+			if !comment.IsDeleted() {
+				count++
+			}
+		}
+
+		if count != article.CommentsCount {
+			article.CommentsCount = count
+			s.articles[articleId] = article
+			changed = append(changed, articleId)
+		}
+	}
+
+	return changed, nil
+}
+
+func (s *MemoryStore) UpdateComment(oldComment model.Comment, newComment model.Comment, edit model.CommentEdit) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	found, ok := s.comments[oldComment.ArticleId][oldComment.CommentId]
+	if !ok || found.Author != oldComment.Author || found.UpdatedAt != oldComment.UpdatedAt || found.IsDeleted() {
+		return ErrConditionFailed
+	}
+
+	if _, ok := s.commentEdits[edit.ArticleId][edit.EditId]; ok {
+		return ErrConditionFailed
+	}
+
+	if s.commentEdits[edit.ArticleId] == nil {
+		s.commentEdits[edit.ArticleId] = make(map[int64]model.CommentEdit)
+	}
+
+	found.Body = newComment.Body
+	found.UpdatedAt = newComment.UpdatedAt
+	found.EditCount = newComment.EditCount
+	s.comments[oldComment.ArticleId][oldComment.CommentId] = found
+	s.commentEdits[edit.ArticleId][edit.EditId] = edit
+	return nil
+}
+
+func (s *MemoryStore) QueryCommentEdits(key model.CommentKey) ([]model.CommentEdit, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	edits := make([]model.CommentEdit, 0)
+	for _, edit := range s.commentEdits[key.ArticleId] {
+		if edit.CommentId == key.CommentId {
+			edits = append(edits, edit)
+		}
+	}
+
+	sort.Slice(edits, func(i, j int) bool {
+		return edits[i].EditId > edits[j].EditId
+	})
+
+	return edits, nil
+}
+
+func (s *MemoryStore) DeleteCommentEdits(key model.CommentKey) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for editId, edit := range s.commentEdits[key.ArticleId] {
+		if edit.CommentId == key.CommentId {
+			delete(s.commentEdits[key.ArticleId], editId)
+		}
+	}
+
+	if len(s.commentEdits[key.ArticleId]) == 0 {
+		delete(s.commentEdits, key.ArticleId)
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) PutCommentReaction(reaction model.CommentReaction) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	comment, ok := s.comments[reaction.ArticleId][reaction.CommentId]
+	if !ok || comment.IsDeleted() {
+		return ErrConditionFailed
+	}
+
+	if _, ok := s.commentReactions[reaction.ArticleId][reaction.CommentReactionKey]; ok {
+		return ErrConditionFailed
+	}
+
+	if s.commentReactions[reaction.ArticleId] == nil {
+		s.commentReactions[reaction.ArticleId] = make(map[model.CommentReactionKey]model.CommentReaction)
+	}
+
+	s.commentReactions[reaction.ArticleId][reaction.CommentReactionKey] = reaction
+	s.comments[reaction.ArticleId][reaction.CommentId] = countCommentReaction(comment, reaction.Reaction, 1)
+	return nil
+}
+
+func (s *MemoryStore) DeleteCommentReaction(key model.CommentReactionKey) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	comment, ok := s.comments[key.ArticleId][key.CommentId]
+	if !ok || comment.IsDeleted() {
+		return ErrConditionFailed
+	}
+
+	if _, ok := s.commentReactions[key.ArticleId][key]; !ok {
+		return ErrConditionFailed
+	}
+
+	delete(s.commentReactions[key.ArticleId], key)
+	s.comments[key.ArticleId][key.CommentId] = countCommentReaction(comment, key.Reaction, -1)
+	return nil
+}
+
+// countCommentReaction adds delta to the count of a reaction on a copy of the comment,
+// leaving the Reactions of the stored comment, which readers may hold, untouched.
+func countCommentReaction(comment model.Comment, reaction string, delta int64) model.Comment {
+	if reaction == model.ReactionUpvote {
+		comment.Upvotes += delta
+		return comment
+	}
+
+	reactions := make(map[string]int64, len(comment.Reactions)+1)
+	for name, count := range comment.Reactions {
+		reactions[name] = count
+	}
+
+	reactions[reaction] += delta
+	comment.Reactions = reactions
+	return comment
+}
+
+func (s *MemoryStore) GetUserCommentReactions(username string, articleId int64, commentIds []int64) (map[int64][]string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	reactions := make(map[int64][]string)
+	for _, commentId := range commentIds {
+		for _, reaction := range model.Reactions {
+			key := model.CommentReactionKey{ArticleId: articleId, CommentId: commentId, Username: username, Reaction: reaction}
+			if _, ok := s.commentReactions[articleId][key]; ok {
+				reactions[commentId] = append(reactions[commentId], reaction)
+			}
+		}
+	}
+
+	return reactions, nil
+}
+
+func (s *MemoryStore) DeleteCommentReactions(key model.CommentKey) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for reactionKey := range s.commentReactions[key.ArticleId] {
+		if reactionKey.CommentId == key.CommentId {
+			delete(s.commentReactions[key.ArticleId], reactionKey)
+		}
+	}
+
+	if len(s.commentReactions[key.ArticleId]) == 0 {
+		delete(s.commentReactions, key.ArticleId)
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) PutFollow(follow model.Follow) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.follows[follow.Follower] == nil {
+		s.follows[follow.Follower] = make(map[string]bool)
+	}
+
+	s.follows[follow.Follower][follow.Publisher] = true
+	return nil
+}
+
+func (s *MemoryStore) DeleteFollow(follow model.Follow) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.follows[follow.Follower], follow.Publisher)
+	return nil
+}
+
+func (s *MemoryStore) QueryPublishers(follower string) ([]string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	publishers := make([]string, 0, len(s.follows[follower]))
+	for publisher := range s.follows[follower] {
+		publishers = append(publishers, publisher)
+	}
+
+	sort.Strings(publishers)
+	return publishers, nil
+}
+
+func (s *MemoryStore) QueryFollowers(publisher string) ([]string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	followers := make([]string, 0)
+	for follower, publishers := range s.follows {
+		if publishers[publisher] {
+			followers = append(followers, follower)
+		}
+	}
+
+	sort.Strings(followers)
+	return followers, nil
+}
+
+func (s *MemoryStore) GetFollowedPublishers(follower string, publishers []string) (map[string]bool, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	followingUser := make(map[string]bool)
+	for _, publisher := range publishers {
+		if s.follows[follower][publisher] {
+			followingUser[publisher] = true
+		}
+	}
+
+	return followingUser, nil
+}
+
+func (s *MemoryStore) PutFavoriteArticle(favoriteArticle model.FavoriteArticle) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	article, ok := s.articles[favoriteArticle.ArticleId]
+	if !ok {
+		return ErrConditionFailed
+	}
+
+	if _, ok := s.favoriteArticles[favoriteArticle.Username][favoriteArticle.ArticleId]; ok {
+		return ErrConditionFailed
+	}
+
+	if s.favoriteArticles[favoriteArticle.Username] == nil {
+		s.favoriteArticles[favoriteArticle.Username] = make(map[int64]model.FavoriteArticle)
+	}
+
+	s.favoriteArticles[favoriteArticle.Username][favoriteArticle.ArticleId] = favoriteArticle
+	article.FavoritesCount++
+	s.articles[article.ArticleId] = article
+	return nil
+}
+
+func (s *MemoryStore) DeleteFavoriteArticle(key model.FavoriteArticleKey) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	article, ok := s.articles[key.ArticleId]
+	if !ok {
+		return ErrConditionFailed
+	}
+
+	if _, ok := s.favoriteArticles[key.Username][key.ArticleId]; !ok {
+		return ErrConditionFailed
+	}
+
+	delete(s.favoriteArticles[key.Username], key.ArticleId)
+	article.FavoritesCount--
+	s.articles[article.ArticleId] = article
+	return nil
+}
+
+func (s *MemoryStore) QueryFavoriteArticleIds(username string, page Page) ([]int64, *Cursor, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	favoriteArticles := make([]model.FavoriteArticle, 0, len(s.favoriteArticles[username]))
+	for _, favoriteArticle := range s.favoriteArticles[username] {
+		if page.After.isAfter(favoriteArticle.FavoritedAt, favoriteArticle.ArticleId) {
+			favoriteArticles = append(favoriteArticles, favoriteArticle)
+		}
+	}
+
+	sort.Slice(favoriteArticles, func(i, j int) bool {
+		return newerThan(favoriteArticles[i].FavoritedAt, favoriteArticles[i].ArticleId, favoriteArticles[j].FavoritedAt, favoriteArticles[j].ArticleId)
+	})
+
+	start, end := pageBounds(len(favoriteArticles), page.Offset, page.Limit)
+	articleIds := make([]int64, 0, end-start)
+	for _, favoriteArticle := range favoriteArticles[start:end] {
+		articleIds = append(articleIds, favoriteArticle.ArticleId)
+	}
+
+	if end == len(favoriteArticles) || end == start {
+		return articleIds, nil, nil
+	}
+
+	last := favoriteArticles[end-1]
+	return articleIds, &Cursor{SortKey: last.FavoritedAt, ArticleId: last.ArticleId}, nil
+}
+
+func (s *MemoryStore) GetFavoritedArticleIds(username string, articleIds []int64) (map[int64]bool, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	favorited := make(map[int64]bool)
+	for _, articleId := range articleIds {
+		if _, ok := s.favoriteArticles[username][articleId]; ok {
+			favorited[articleId] = true
+		}
+	}
+
+	return favorited, nil
+}
+
+func (s *MemoryStore) QueryArticleCleanups(limit int) ([]model.ArticleCleanup, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	cleanups := make([]model.ArticleCleanup, 0, len(s.articleCleanups))
+	for _, cleanup := range s.articleCleanups {
+		cleanups = append(cleanups, cleanup)
+	}
+
+	sort.Slice(cleanups, func(i, j int) bool {
+		return cleanups[i].DeletedAt < cleanups[j].DeletedAt
+	})
+
+	_, end := pageBounds(len(cleanups), 0, limit)
+	return cleanups[:end], nil
+}
+
+func (s *MemoryStore) DeleteArticleItems(articleId int64, limit int) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	numComments := 0
+	for commentId := range s.comments[articleId] {
+		if numComments >= limit {
+			break
+		}
+
+		delete(s.comments[articleId], commentId)
+		numComments++
+	}
+
+	if len(s.comments[articleId]) == 0 {
+		delete(s.comments, articleId)
+	}
+
+	numCommentEdits := 0
+	for editId := range s.commentEdits[articleId] {
+		if numCommentEdits >= limit {
+			break
+		}
+
+		delete(s.commentEdits[articleId], editId)
+		numCommentEdits++
+	}
+
+	if len(s.commentEdits[articleId]) == 0 {
+		delete(s.commentEdits, articleId)
+	}
+
+	numCommentReactions := 0
+	for key := range s.commentReactions[articleId] {
+		if numCommentReactions >= limit {
+			break
+		}
+
+		delete(s.commentReactions[articleId], key)
+		numCommentReactions++
+	}
+
+	if len(s.commentReactions[articleId]) == 0 {
+		delete(s.commentReactions, articleId)
+	}
+
+	numArticleRevisions := 0
+	for revision := range s.articleRevisions[articleId] {
+		if numArticleRevisions >= limit {
+			break
+		}
+
+		delete(s.articleRevisions[articleId], revision)
+		numArticleRevisions++
+	}
+
+	if len(s.articleRevisions[articleId]) == 0 {
+		delete(s.articleRevisions, articleId)
+	}
+
+	numFavoriteArticles := 0
+	for username, favoriteArticles := range s.favoriteArticles {
+		if numFavoriteArticles >= limit {
+			break
+		}
+
+		if _, ok := favoriteArticles[articleId]; ok {
+			delete(favoriteArticles, articleId)
+			numFavoriteArticles++
+		}
+
+		if len(favoriteArticles) == 0 {
+			delete(s.favoriteArticles, username)
+		}
+	}
+
+	return numComments + numCommentEdits + numCommentReactions + numFavoriteArticles + numArticleRevisions, nil
+}
+
+func (s *MemoryStore) DeleteArticleCleanup(articleId int64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.articleCleanups, articleId)
+	return nil
+}
+
+func (s *MemoryStore) IncrementCounter(name string) (int64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.counters[name]++
+	return s.counters[name], nil
+}
+
+func (s *MemoryStore) PutTimelineEntries(entries []model.TimelineEntry) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, entry := range entries {
+		if s.timelines[entry.Username] == nil {
+			s.timelines[entry.Username] = make(map[int64]model.TimelineEntry)
+		}
+
+		s.timelines[entry.Username][entry.ArticleId] = entry
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) DeleteTimelineEntries(keys []model.TimelineKey) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, key := range keys {
+		delete(s.timelines[key.Username], key.ArticleId)
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) QueryTimeline(username string, page Page) ([]int64, *Cursor, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	entries := make([]model.TimelineEntry, 0, len(s.timelines[username]))
+	for _, entry := range s.timelines[username] {
+		if page.After.isAfter(entry.CreatedAt, entry.ArticleId) {
+			entries = append(entries, entry)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return newerThan(entries[i].CreatedAt, entries[i].ArticleId, entries[j].CreatedAt, entries[j].ArticleId)
+	})
+
+	start, end := pageBounds(len(entries), page.Offset, page.Limit)
+	articleIds := make([]int64, 0, end-start)
+	for _, entry := range entries[start:end] {
+		articleIds = append(articleIds, entry.ArticleId)
+	}
+
+	if end == len(entries) || end == start {
+		return articleIds, nil, nil
+	}
+
+	last := entries[end-1]
+	return articleIds, &Cursor{SortKey: last.CreatedAt, ArticleId: last.ArticleId}, nil
+}
+
+func (s *MemoryStore) PutArticleSchedules(schedules []model.ArticleSchedule) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, schedule := range schedules {
+		s.articleSchedules[schedule.ArticleScheduleKey] = schedule
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) QueryArticleSchedules(until int64, limit int) ([]model.ArticleSchedule, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	schedules := make([]model.ArticleSchedule, 0)
+	for _, schedule := range s.articleSchedules {
+		if schedule.DueAt <= until {
+			schedules = append(schedules, schedule)
+		}
+	}
+
+	sort.Slice(schedules, func(i, j int) bool {
+		return schedules[i].DueAt < schedules[j].DueAt
+	})
+
+	if len(schedules) > limit {
+		schedules = schedules[:limit]
+	}
+
+	return schedules, nil
+}
+
+func (s *MemoryStore) DeleteArticleSchedule(schedule model.ArticleSchedule) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	current, ok := s.articleSchedules[schedule.ArticleScheduleKey]
+	if !ok || current.DueAt != schedule.DueAt {
+		return ErrConditionFailed
+	}
+
+	delete(s.articleSchedules, schedule.ArticleScheduleKey)
+	return nil
+}
+
+func (s *MemoryStore) PutSearchPostings(postings []model.SearchPosting) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, posting := range postings {
+		if s.searchPostings[posting.Term] == nil {
+			s.searchPostings[posting.Term] = make(map[int64]model.SearchPosting)
+		}
+		s.searchPostings[posting.Term][posting.ArticleId] = posting
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) DeleteSearchPostings(keys []model.SearchPostingKey) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, key := range keys {
+		delete(s.searchPostings[key.Term], key.ArticleId)
+		if len(s.searchPostings[key.Term]) == 0 {
+			delete(s.searchPostings, key.Term)
+		}
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) QuerySearchPostings(term string, limit int) ([]model.SearchPosting, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	postings := make([]model.SearchPosting, 0, len(s.searchPostings[term]))
+	for _, posting := range s.searchPostings[term] {
+		postings = append(postings, posting)
+	}
+
+	sort.Slice(postings, func(i, j int) bool {
+		return postings[i].ArticleId > postings[j].ArticleId
+	})
+
+	if len(postings) > limit {
+		postings = postings[:limit]
+	}
+
+	return postings, nil
+}
+
+func (s *MemoryStore) QuerySearchPostingsByPrefix(prefix string, limit int) ([]model.SearchPosting, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	postings := make([]model.SearchPosting, 0)
+	for term, termPostings := range s.searchPostings {
+		if strings.HasPrefix(term, prefix) {
+			for _, posting := range termPostings {
+				postings = append(postings, posting)
+			}
+		}
+	}
+
+	sort.Slice(postings, func(i, j int) bool {
+		if postings[i].Term != postings[j].Term {
+			return postings[i].Term < postings[j].Term
+		}
+		return postings[i].ArticleId < postings[j].ArticleId
+	})
+
+	if len(postings) > limit {
+		postings = postings[:limit]
+	}
+
+	return postings, nil
+}
+
+// newerThan orders items newest first by sort key, then by article id.
+func newerThan(sortKey, articleId, otherSortKey, otherArticleId int64) bool {
+	if sortKey != otherSortKey {
+		return sortKey > otherSortKey
+	}
+	return articleId > otherArticleId
+}
+
+// pageBounds clamps [offset, offset+limit) to a slice of length n.
+func pageBounds(n, offset, limit int) (int, int) {
+	start := offset
+	if start > n {
+		start = n
+	}
+
+	end := start + limit
+	if end > n {
+		end = n
+	}
+
+	return start, end
+}
+
+func copyUser(user model.User) model.User {
+	if user.PasswordHash != nil {
+		user.PasswordHash = append([]byte(nil), user.PasswordHash...)
+	}
+	return user
+}
+
+func copyArticle(article model.Article) model.Article {
+	if article.TagList != nil {
+		article.TagList = append([]string{}, article.TagList...)
+	}
+	if article.Outline != nil {
+		article.Outline = append([]model.ArticleHeading{}, article.Outline...)
+	}
+	return article
+}
+
+func copyArticleRevision(revision model.ArticleRevision) model.ArticleRevision {
+	if revision.TagList != nil {
+		revision.TagList = append([]string{}, revision.TagList...)
+	}
+	if revision.ChangedFields != nil {
+		revision.ChangedFields = append([]string{}, revision.ChangedFields...)
+	}
+	return revision
+}
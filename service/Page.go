@@ -0,0 +1,129 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"realworld-go-nolambda/model"
+)
+
+// Cursor is the position of an item in a newest first listing: its sort key, e.g. CreatedAt, FavoritedAt or Revision,
+// and its article id to break ties. Tags are listed by ArticleCount, with the tag breaking ties instead,
+// and comments by CreatedAt or Upvotes, with their article and comment id. Clients only see it encoded, as an opaque token.
+type Cursor struct {
+	SortKey   int64  `json:"k"`
+	ArticleId int64  `json:"a"`
+	Tag       string `json:"t,omitempty"`
+	CommentId int64  `json:"c,omitempty"`
+}
+
+// Page selects a page of a listing. Offset items are skipped, after the After position if it is set.
+type Page struct {
+	Offset int
+	Limit  int
+	After  *Cursor
+}
+
+func (c Cursor) Encode() string {
+	js, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+// DecodeCursor parses a token made by Cursor.Encode. An empty token decodes to nil.
+func DecodeCursor(token string) (*Cursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	js, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, model.NewInputError("cursor", "invalid")
+	}
+
+	cursor := Cursor{}
+	err = json.Unmarshal(js, &cursor)
+	if err != nil {
+		return nil, model.NewInputError("cursor", "invalid")
+	}
+
+	return &cursor, nil
+}
+
+// isAfter reports whether the item at (sortKey, articleId) comes after the cursor in a newest first listing.
+// A nil cursor is before every item.
+func (c *Cursor) isAfter(sortKey, articleId int64) bool {
+	if c == nil {
+		return true
+	}
+
+	return sortKey < c.SortKey || (sortKey == c.SortKey && articleId < c.ArticleId)
+}
+
+// isAfterTag reports whether the tag comes after the cursor in a listing of tags by ArticleCount, most first.
+// A nil cursor is before every tag.
+func (c *Cursor) isAfterTag(tag model.Tag) bool {
+	if c == nil {
+		return true
+	}
+
+	return tag.ArticleCount < c.SortKey || (tag.ArticleCount == c.SortKey && tag.Tag > c.Tag)
+}
+
+func tagCursor(tag model.Tag) *Cursor {
+	return &Cursor{
+		SortKey: tag.ArticleCount,
+		Tag:     tag.Tag,
+	}
+}
+
+// commentSortKey returns the key comments are listed by in order: Upvotes for OrderTop, CreatedAt otherwise.
+func commentSortKey(comment model.Comment, order string) int64 {
+	if order == OrderTop {
+		return comment.Upvotes
+	}
+	return comment.CreatedAt
+}
+
+// isAfterComment reports whether the comment comes after the cursor in a listing of comments in order,
+// by their sort key and then CommentId, descending unless order is OrderOldest. A nil cursor is before every comment.
+func (c *Cursor) isAfterComment(comment model.Comment, order string) bool {
+	if c == nil {
+		return true
+	}
+
+	sortKey := commentSortKey(comment, order)
+	if order == OrderOldest {
+		return sortKey > c.SortKey || (sortKey == c.SortKey && comment.CommentId > c.CommentId)
+	}
+	return sortKey < c.SortKey || (sortKey == c.SortKey && comment.CommentId < c.CommentId)
+}
+
+func commentCursor(comment model.Comment, order string) *Cursor {
+	return &Cursor{
+		SortKey:   commentSortKey(comment, order),
+		ArticleId: comment.ArticleId,
+		CommentId: comment.CommentId,
+	}
+}
+
+// commentBefore reports whether comment a was written before comment b, by CreatedAt and then CommentId.
+func commentBefore(a, b model.Comment) bool {
+	if a.CreatedAt != b.CreatedAt {
+		return a.CreatedAt < b.CreatedAt
+	}
+	return a.CommentId < b.CommentId
+}
+
+func articleCursor(article model.Article) *Cursor {
+	return &Cursor{
+		SortKey:   article.CreatedAt,
+		ArticleId: article.ArticleId,
+	}
+}
+
+func revisionCursor(revision model.ArticleRevision) *Cursor {
+	return &Cursor{
+		SortKey:   revision.Revision,
+		ArticleId: revision.ArticleId,
+	}
+}
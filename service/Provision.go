@@ -0,0 +1,191 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+const provisionPollInterval = 2 * time.Second
+const provisionTimeout = 15 * time.Minute
+
+// ProvisionTables creates every table in TableSchemas, and adds the secondary indexes missing from existing tables.
+// It can be run repeatedly; tables that are already up to date are left alone.
+func ProvisionTables() error {
+	for _, schema := range TableSchemas() {
+		err := provisionTable(schema)
+		if err != nil {
+			return fmt.Errorf("%s: %w", schema.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func provisionTable(schema TableSchema) error {
+	description, err := describeTable(schema.Name)
+	if err != nil {
+		return err
+	}
+
+	if description == nil {
+		_, err = DynamoDB().CreateTable(schema.createTableInput())
+		if err != nil {
+			return err
+		}
+
+		log.Printf("creating table %s", schema.Name)
+		return waitUntilTableActive(schema.Name)
+	}
+
+	err = checkKeySchema(description.KeySchema, keySchema(schema.HashKey, schema.RangeKey))
+	if err != nil {
+		return err
+	}
+
+	existingIndexes := make(map[string]*dynamodb.GlobalSecondaryIndexDescription)
+	for _, index := range description.GlobalSecondaryIndexes {
+		existingIndexes[aws.StringValue(index.IndexName)] = index
+	}
+
+	for _, index := range schema.Indexes {
+		existing, ok := existingIndexes[index.Name]
+		if ok {
+			err = checkKeySchema(existing.KeySchema, keySchema(index.HashKey, index.RangeKey))
+			if err != nil {
+				return fmt.Errorf("index %s: %w", index.Name, err)
+			}
+			continue
+		}
+
+		// DynamoDB accepts a single index creation per UpdateTable call
+		_, err = DynamoDB().UpdateTable(&dynamodb.UpdateTableInput{
+			TableName:            aws.String(schema.Name),
+			AttributeDefinitions: schema.attributeDefinitions(),
+			GlobalSecondaryIndexUpdates: []*dynamodb.GlobalSecondaryIndexUpdate{
+				{
+					Create: &dynamodb.CreateGlobalSecondaryIndexAction{
+						IndexName:  aws.String(index.Name),
+						KeySchema:  keySchema(index.HashKey, index.RangeKey),
+						Projection: index.globalSecondaryIndex().Projection,
+					},
+				},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("index %s: %w", index.Name, err)
+		}
+
+		log.Printf("creating index %s on table %s", index.Name, schema.Name)
+		err = waitUntilTableActive(schema.Name)
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Printf("table %s is up to date", schema.Name)
+	return nil
+}
+
+// TeardownTables deletes every table in TableSchemas that exists. All data of the stage is lost.
+func TeardownTables() error {
+	for _, schema := range TableSchemas() {
+		description, err := describeTable(schema.Name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", schema.Name, err)
+		}
+
+		if description == nil {
+			continue
+		}
+
+		_, err = DynamoDB().DeleteTable(&dynamodb.DeleteTableInput{
+			TableName: aws.String(schema.Name),
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", schema.Name, err)
+		}
+
+		log.Printf("deleting table %s", schema.Name)
+		err = DynamoDB().WaitUntilTableNotExists(&dynamodb.DescribeTableInput{
+			TableName: aws.String(schema.Name),
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", schema.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// describeTable returns nil if the table doesn't exist.
+func describeTable(tableName string) (*dynamodb.TableDescription, error) {
+	output, err := DynamoDB().DescribeTable(&dynamodb.DescribeTableInput{
+		TableName: aws.String(tableName),
+	})
+
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeResourceNotFoundException {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return output.Table, nil
+}
+
+// waitUntilTableActive waits for the table and all of its indexes to become active.
+func waitUntilTableActive(tableName string) error {
+	deadline := time.Now().Add(provisionTimeout)
+
+	for {
+		description, err := describeTable(tableName)
+		if err != nil {
+			return err
+		}
+
+		if description != nil && isTableActive(description) {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("table %s is not active after %s", tableName, provisionTimeout)
+		}
+
+		time.Sleep(provisionPollInterval)
+	}
+}
+
+func isTableActive(description *dynamodb.TableDescription) bool {
+	if aws.StringValue(description.TableStatus) != dynamodb.TableStatusActive {
+		return false
+	}
+
+	for _, index := range description.GlobalSecondaryIndexes {
+		if aws.StringValue(index.IndexStatus) != dynamodb.IndexStatusActive {
+			return false
+		}
+	}
+
+	return true
+}
+
+func checkKeySchema(actual, expected []*dynamodb.KeySchemaElement) error {
+	if len(actual) != len(expected) {
+		return fmt.Errorf("key schema %v differs from %v and can't be updated in place", actual, expected)
+	}
+
+	for i := range actual {
+		if aws.StringValue(actual[i].AttributeName) != aws.StringValue(expected[i].AttributeName) ||
+			aws.StringValue(actual[i].KeyType) != aws.StringValue(expected[i].KeyType) {
+			return fmt.Errorf("key schema %v differs from %v and can't be updated in place", actual, expected)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"realworld-go-nolambda/model"
+	"realworld-go-nolambda/util"
+)
+
+// MaxPreviewLength bounds the Markdown rendered by RenderPreview, in bytes.
+const MaxPreviewLength = 100000
+
+// Rendered bodies are cached per version, keyed by UpdatedAt, so entries never go stale and are only dropped
+// to make room or after renderCacheTTL.
+const (
+	renderCacheSize = 1000
+	renderCacheTTL  = time.Hour
+)
+
+var renderCache Cache = NewLRUCache(renderCacheSize, renderCacheTTL)
+
+// RenderArticleBody returns the body of the article rendered from Markdown to sanitized HTML.
+func RenderArticleBody(article model.Article) string {
+	key := "article/" + strconv.FormatInt(article.ArticleId, 36) + "/" + strconv.FormatInt(article.UpdatedAt, 36)
+	return renderCached(key, article.Body)
+}
+
+// RenderCommentBody returns the body of the comment rendered from Markdown to sanitized HTML, empty for deleted comments.
+func RenderCommentBody(comment model.Comment) string {
+	if comment.IsDeleted() {
+		return ""
+	}
+
+	key := "comment/" + strconv.FormatInt(comment.ArticleId, 36) + "/" + strconv.FormatInt(comment.CommentId, 36) +
+		"/" + strconv.FormatInt(comment.UpdatedAt, 36)
+	return renderCached(key, comment.Body)
+}
+
+// RenderPreview renders Markdown the way article and comment bodies are, for editors to preview a draft.
+func RenderPreview(body string) (string, error) {
+	if len(body) > MaxPreviewLength {
+		return "", model.NewInputError("body", fmt.Sprintf("can't be longer than %d bytes", MaxPreviewLength))
+	}
+
+	return util.RenderMarkdown(body), nil
+}
+
+func renderCached(key string, body string) string {
+	if rendered, ok := renderCache.Get(key); ok {
+		return rendered.(string)
+	}
+
+	rendered := util.RenderMarkdown(body)
+	renderCache.Set(key, rendered)
+	return rendered
+}
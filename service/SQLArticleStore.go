@@ -0,0 +1,306 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"realworld-go-nolambda/model"
+	"realworld-go-nolambda/util"
+)
+
+const sqlArticleColumns = "article_id, slug, title, description, body, tag_list, created_at, updated_at, favorites_count, author, revision, status, publish_at, expire_at, comments_count, word_count, reading_time, outline"
+
+func scanArticle(row interface{ Scan(...interface{}) error }) (model.Article, error) {
+	article := model.Article{}
+	var tagList, outline string
+
+	err := row.Scan(&article.ArticleId, &article.Slug, &article.Title, &article.Description, &article.Body,
+		&tagList, &article.CreatedAt, &article.UpdatedAt, &article.FavoritesCount, &article.Author, &article.Revision, &article.Status,
+		&article.PublishAt, &article.ExpireAt, &article.CommentsCount, &article.WordCount, &article.ReadingTime, &outline)
+	if err != nil {
+		return model.Article{}, err
+	}
+
+	article.TagList, err = decodeStringList(tagList)
+	if err != nil {
+		return model.Article{}, err
+	}
+
+	err = json.Unmarshal([]byte(outline), &article.Outline)
+	return article, err
+}
+
+// encodeArticleOutline encodes the outline of an article as JSON, like a string list.
+func encodeArticleOutline(outline []model.ArticleHeading) (string, error) {
+	if outline == nil {
+		outline = make([]model.ArticleHeading, 0)
+	}
+
+	js, err := json.Marshal(outline)
+	if err != nil {
+		return "", err
+	}
+
+	return string(js), nil
+}
+
+func (s *SQLStore) scanArticles(rows *sql.Rows, err error) ([]model.Article, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	articles := make([]model.Article, 0)
+	for rows.Next() {
+		article, err := scanArticle(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		articles = append(articles, article)
+	}
+
+	return articles, rows.Err()
+}
+
+func (s *SQLStore) PutArticle(article model.Article, revision model.ArticleRevision) error {
+	tagList, err := encodeStringList(article.TagList)
+	if err != nil {
+		return err
+	}
+
+	outline, err := encodeArticleOutline(article.Outline)
+	if err != nil {
+		return err
+	}
+
+	return s.transact(func(tx *sql.Tx) error {
+		// Don't reuse the id of a deleted article until its comments and favorites are gone
+		var cleanups int
+		err := s.queryRow(tx, "SELECT COUNT(*) FROM article_cleanups WHERE article_id = ?", article.ArticleId).Scan(&cleanups)
+		if err != nil {
+			return err
+		}
+
+		if cleanups != 0 {
+			return ErrConditionFailed
+		}
+
+		// Put a new article
+		err = s.execAffectingOne(tx, "INSERT INTO articles ("+sqlArticleColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT DO NOTHING",
+			article.ArticleId, article.Slug, article.Title, article.Description, article.Body,
+			tagList, article.CreatedAt, article.UpdatedAt, article.FavoritesCount, article.Author, article.Revision, article.EffectiveStatus(),
+			article.PublishAt, article.ExpireAt, article.CommentsCount, article.WordCount, article.ReadingTime, outline)
+		if err != nil {
+			return err
+		}
+
+		err = s.putArticleRevisions(tx, []model.ArticleRevision{revision})
+		if err != nil {
+			return err
+		}
+
+		for _, tag := range article.LinkedTags() {
+			err = s.linkArticleTag(tx, tag, article.ArticleId, article.CreatedAt)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *SQLStore) UpdateArticle(oldArticle model.Article, newArticle model.Article, revisions []model.ArticleRevision) error {
+	tagList, err := encodeStringList(newArticle.TagList)
+	if err != nil {
+		return err
+	}
+
+	outline, err := encodeArticleOutline(newArticle.Outline)
+	if err != nil {
+		return err
+	}
+
+	oldTagSet := util.NewStringSetFromSlice(oldArticle.LinkedTags())
+	newTagSet := util.NewStringSetFromSlice(newArticle.LinkedTags())
+
+	return s.transact(func(tx *sql.Tx) error {
+		err := s.execAffectingOne(tx, "UPDATE articles SET slug = ?, title = ?, description = ?, body = ?, tag_list = ?, created_at = ?, updated_at = ?, revision = ?, status = ?, publish_at = ?, expire_at = ?, word_count = ?, reading_time = ?, outline = ? WHERE article_id = ? AND updated_at = ?",
+			newArticle.Slug, newArticle.Title, newArticle.Description, newArticle.Body, tagList, newArticle.CreatedAt, newArticle.UpdatedAt,
+			newArticle.Revision, newArticle.EffectiveStatus(), newArticle.PublishAt, newArticle.ExpireAt,
+			newArticle.WordCount, newArticle.ReadingTime, outline, oldArticle.ArticleId, oldArticle.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		err = s.putArticleRevisions(tx, revisions)
+		if err != nil {
+			return err
+		}
+
+		for tag := range oldTagSet.Difference(newTagSet) {
+			err = s.unlinkArticleTag(tx, tag, oldArticle.ArticleId)
+			if err != nil {
+				return err
+			}
+		}
+
+		for tag := range newTagSet.Difference(oldTagSet) {
+			err = s.linkArticleTag(tx, tag, oldArticle.ArticleId, newArticle.CreatedAt)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *SQLStore) DeleteArticle(article model.Article, cleanup model.ArticleCleanup) error {
+	searchTerms, err := json.Marshal(cleanup.SearchTerms)
+	if err != nil {
+		return err
+	}
+
+	return s.transact(func(tx *sql.Tx) error {
+		err := s.execAffectingOne(tx, "DELETE FROM articles WHERE article_id = ? AND author = ? AND updated_at = ?",
+			article.ArticleId, cleanup.Author, article.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		for _, tag := range article.LinkedTags() {
+			err = s.unlinkArticleTag(tx, tag, article.ArticleId)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Comments, favorites, search postings and timeline entries are deleted in batches by the article cleaner
+		_, err = s.exec(tx, "INSERT INTO article_cleanups (article_id, deleted_at, author, search_terms) VALUES (?, ?, ?, ?) ON CONFLICT DO NOTHING",
+			cleanup.ArticleId, cleanup.DeletedAt, cleanup.Author, string(searchTerms))
+		return err
+	})
+}
+
+// linkArticleTag links an article with a tag and increments the tag's article count.
+func (s *SQLStore) linkArticleTag(tx *sql.Tx, tag string, articleId int64, createdAt int64) error {
+	_, err := s.exec(tx, "INSERT INTO article_tags (tag, article_id, created_at) VALUES (?, ?, ?) ON CONFLICT DO NOTHING",
+		tag, articleId, createdAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.exec(tx, "INSERT INTO tags (tag, article_count) VALUES (?, 1) ON CONFLICT (tag) DO UPDATE SET article_count = tags.article_count + 1", tag)
+	return err
+}
+
+// unlinkArticleTag unlinks an article from a tag and decrements the tag's article count.
+func (s *SQLStore) unlinkArticleTag(tx *sql.Tx, tag string, articleId int64) error {
+	_, err := s.exec(tx, "DELETE FROM article_tags WHERE tag = ? AND article_id = ?", tag, articleId)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.exec(tx, "UPDATE tags SET article_count = article_count - 1 WHERE tag = ?", tag)
+	return err
+}
+
+func (s *SQLStore) GetArticle(articleId int64) (model.Article, bool, error) {
+	article, err := scanArticle(s.queryRow(s.db, "SELECT "+sqlArticleColumns+" FROM articles WHERE article_id = ?", articleId))
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Article{}, false, nil
+	}
+
+	if err != nil {
+		return model.Article{}, false, err
+	}
+
+	return article, true, nil
+}
+
+func (s *SQLStore) GetArticlesByIds(articleIds []int64) (map[int64]model.Article, error) {
+	articlesById := make(map[int64]model.Article)
+	if len(articleIds) == 0 {
+		return articlesById, nil
+	}
+
+	args := make([]interface{}, 0, len(articleIds))
+	for _, articleId := range articleIds {
+		args = append(args, articleId)
+	}
+
+	articles, err := s.scanArticles(s.query(s.db, "SELECT "+sqlArticleColumns+" FROM articles WHERE article_id IN ("+placeholders(len(args))+")", args...))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, article := range articles {
+		articlesById[article.ArticleId] = article
+	}
+
+	return articlesById, nil
+}
+
+func (s *SQLStore) QueryArticles(page Page) ([]model.Article, *Cursor, error) {
+	return s.queryArticles("status = ?", []interface{}{model.StatusPublished}, page)
+}
+
+func (s *SQLStore) QueryArticlesByAuthor(author string, page Page) ([]model.Article, *Cursor, error) {
+	return s.queryArticles("author = ? AND status = ?", []interface{}{author, model.StatusPublished}, page)
+}
+
+func (s *SQLStore) QueryDraftsByAuthor(author string, page Page) ([]model.Article, *Cursor, error) {
+	return s.queryArticles("author = ? AND status <> ?", []interface{}{author, model.StatusPublished}, page)
+}
+
+func (s *SQLStore) ScanArticles(scan ArticleScan, page Page) ([]model.Article, *Cursor, error) {
+	condition, args := createdRangeCondition(scan)
+	condition += " AND status = ?"
+	args = append(args, model.StatusPublished)
+
+	if scan.Author != "" {
+		condition += " AND author = ?"
+		args = append(args, scan.Author)
+	}
+
+	sortColumn, direction := sqlOrder(scan.Order)
+	after, afterArgs := orderedKeysetCondition(page.After, sortColumn, direction == "ASC")
+	args = append(args, afterArgs...)
+	args = append(args, page.Limit+1, page.Offset)
+
+	articles, err := s.scanArticles(s.query(s.db, "SELECT "+sqlArticleColumns+" FROM articles WHERE "+condition+" AND "+after+
+		" ORDER BY "+sortColumn+" "+direction+", article_id "+direction+" LIMIT ? OFFSET ?", args...))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(articles) <= page.Limit {
+		return articles, nil, nil
+	}
+
+	articles = articles[:page.Limit]
+	return articles, orderCursor(scan.Order, articles[len(articles)-1]), nil
+}
+
+// queryArticles returns a page of the articles matching condition, reading one more row to tell if more follow.
+func (s *SQLStore) queryArticles(condition string, args []interface{}, page Page) ([]model.Article, *Cursor, error) {
+	after, afterArgs := keysetCondition(page.After, "created_at")
+	args = append(args, afterArgs...)
+	args = append(args, page.Limit+1, page.Offset)
+
+	articles, err := s.scanArticles(s.query(s.db, "SELECT "+sqlArticleColumns+" FROM articles WHERE "+condition+" AND "+after+
+		" ORDER BY created_at DESC, article_id DESC LIMIT ? OFFSET ?", args...))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(articles) <= page.Limit {
+		return articles, nil, nil
+	}
+
+	articles = articles[:page.Limit]
+	return articles, articleCursor(articles[len(articles)-1]), nil
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"realworld-go-nolambda/model"
+)
+
+func (s *SQLStore) QueryArticleCleanups(limit int) ([]model.ArticleCleanup, error) {
+	rows, err := s.query(s.db, "SELECT article_id, deleted_at, author, search_terms FROM article_cleanups ORDER BY deleted_at, article_id LIMIT ?", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cleanups := make([]model.ArticleCleanup, 0)
+	for rows.Next() {
+		cleanup := model.ArticleCleanup{}
+		var searchTerms string
+		err = rows.Scan(&cleanup.ArticleId, &cleanup.DeletedAt, &cleanup.Author, &searchTerms)
+		if err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal([]byte(searchTerms), &cleanup.SearchTerms)
+		if err != nil {
+			return nil, err
+		}
+
+		cleanups = append(cleanups, cleanup)
+	}
+
+	return cleanups, rows.Err()
+}
+
+func (s *SQLStore) DeleteArticleItems(articleId int64, limit int) (int, error) {
+	removed := 0
+
+	err := s.transact(func(tx *sql.Tx) error {
+		result, err := s.exec(tx, "DELETE FROM comments WHERE article_id = ? AND comment_id IN (SELECT comment_id FROM comments WHERE article_id = ? LIMIT ?)",
+			articleId, articleId, limit)
+		if err != nil {
+			return err
+		}
+
+		numComments, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		result, err = s.exec(tx, "DELETE FROM comment_edits WHERE article_id = ? AND edit_id IN (SELECT edit_id FROM comment_edits WHERE article_id = ? LIMIT ?)",
+			articleId, articleId, limit)
+		if err != nil {
+			return err
+		}
+
+		numCommentEdits, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		// Every reaction to up to limit comments
+		result, err = s.exec(tx, "DELETE FROM comment_reactions WHERE article_id = ? AND comment_id IN (SELECT comment_id FROM comment_reactions WHERE article_id = ? LIMIT ?)",
+			articleId, articleId, limit)
+		if err != nil {
+			return err
+		}
+
+		numCommentReactions, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		result, err = s.exec(tx, "DELETE FROM comment_reaction_counts WHERE article_id = ? AND comment_id IN (SELECT comment_id FROM comment_reaction_counts WHERE article_id = ? LIMIT ?)",
+			articleId, articleId, limit)
+		if err != nil {
+			return err
+		}
+
+		numCommentReactionCounts, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		result, err = s.exec(tx, "DELETE FROM favorite_articles WHERE article_id = ? AND username IN (SELECT username FROM favorite_articles WHERE article_id = ? LIMIT ?)",
+			articleId, articleId, limit)
+		if err != nil {
+			return err
+		}
+
+		numFavoriteArticles, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		result, err = s.exec(tx, "DELETE FROM article_revisions WHERE article_id = ? AND revision IN (SELECT revision FROM article_revisions WHERE article_id = ? LIMIT ?)",
+			articleId, articleId, limit)
+		if err != nil {
+			return err
+		}
+
+		numArticleRevisions, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		removed = int(numComments + numCommentEdits + numCommentReactions + numCommentReactionCounts + numFavoriteArticles + numArticleRevisions)
+		return nil
+	})
+
+	return removed, err
+}
+
+func (s *SQLStore) DeleteArticleCleanup(articleId int64) error {
+	_, err := s.exec(s.db, "DELETE FROM article_cleanups WHERE article_id = ?", articleId)
+	return err
+}
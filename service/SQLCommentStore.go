@@ -0,0 +1,414 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+
+	"realworld-go-nolambda/model"
+)
+
+const sqlCommentColumns = "article_id, comment_id, created_at, updated_at, body, author, parent_id, depth, reply_count, deleted_at, edit_count, upvotes"
+
+func scanComment(row interface{ Scan(...interface{}) error }) (model.Comment, error) {
+	comment := model.Comment{}
+	err := row.Scan(&comment.ArticleId, &comment.CommentId, &comment.CreatedAt, &comment.UpdatedAt, &comment.Body, &comment.Author,
+		&comment.ParentId, &comment.Depth, &comment.ReplyCount, &comment.DeletedAt, &comment.EditCount, &comment.Upvotes)
+	return comment, err
+}
+
+func (s *SQLStore) PutComment(comment model.Comment) error {
+	return s.transact(func(tx *sql.Tx) error {
+		err := s.execAffectingOne(tx, "INSERT INTO comments ("+sqlCommentColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT DO NOTHING",
+			comment.ArticleId, comment.CommentId, comment.CreatedAt, comment.UpdatedAt, comment.Body, comment.Author,
+			comment.ParentId, comment.Depth, comment.ReplyCount, comment.DeletedAt, comment.EditCount, comment.Upvotes)
+		if err != nil {
+			return err
+		}
+
+		err = s.execAffectingOne(tx, "UPDATE articles SET comments_count = comments_count + 1 WHERE article_id = ?", comment.ArticleId)
+		if err != nil || comment.ParentId == 0 {
+			return err
+		}
+
+		return s.execAffectingOne(tx, "UPDATE comments SET reply_count = reply_count + 1 WHERE article_id = ? AND comment_id = ? AND deleted_at = 0",
+			comment.ArticleId, comment.ParentId)
+	})
+}
+
+func (s *SQLStore) GetComment(key model.CommentKey) (model.Comment, bool, error) {
+	comment, err := scanComment(s.queryRow(s.db, "SELECT "+sqlCommentColumns+" FROM comments WHERE article_id = ? AND comment_id = ?",
+		key.ArticleId, key.CommentId))
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Comment{}, false, nil
+	}
+
+	if err != nil {
+		return model.Comment{}, false, err
+	}
+
+	comments := []model.Comment{comment}
+	err = s.loadCommentReactionCounts(key.ArticleId, comments)
+	if err != nil {
+		return model.Comment{}, false, err
+	}
+
+	return comments[0], true, nil
+}
+
+func (s *SQLStore) QueryComments(articleId int64, order string, page Page) ([]model.Comment, *Cursor, error) {
+	sortColumn := "created_at"
+	if order == OrderTop {
+		sortColumn = "upvotes"
+	}
+
+	direction, comparison := "DESC", " < "
+	if order == OrderOldest {
+		direction, comparison = "ASC", " > "
+	}
+
+	after, args := "1 = 1", []interface{}{articleId}
+	if page.After != nil {
+		after = "(" + sortColumn + comparison + "? OR (" + sortColumn + " = ? AND comment_id" + comparison + "?))"
+		args = append(args, page.After.SortKey, page.After.SortKey, page.After.CommentId)
+	}
+	args = append(args, page.Limit+1, page.Offset)
+
+	comments, err := s.queryComments(articleId, "SELECT "+sqlCommentColumns+" FROM comments WHERE article_id = ? AND parent_id = 0 AND "+after+
+		" ORDER BY "+sortColumn+" "+direction+", comment_id "+direction+" LIMIT ? OFFSET ?", args...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(comments) > page.Limit {
+		comments = comments[:page.Limit]
+		return comments, commentCursor(comments[len(comments)-1], order), nil
+	}
+
+	return comments, nil, nil
+}
+
+func (s *SQLStore) QueryCommentReplies(articleId int64, parentId int64, page Page) ([]model.Comment, *Cursor, error) {
+	after, args := "1 = 1", []interface{}{articleId, parentId}
+	if page.After != nil {
+		after = "(created_at > ? OR (created_at = ? AND comment_id > ?))"
+		args = append(args, page.After.SortKey, page.After.SortKey, page.After.CommentId)
+	}
+	args = append(args, page.Limit+1, page.Offset)
+
+	replies, err := s.queryComments(articleId, "SELECT "+sqlCommentColumns+" FROM comments WHERE article_id = ? AND parent_id = ? AND "+after+
+		" ORDER BY created_at, comment_id LIMIT ? OFFSET ?", args...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(replies) > page.Limit {
+		replies = replies[:page.Limit]
+		return replies, commentCursor(replies[len(replies)-1], OrderOldest), nil
+	}
+
+	return replies, nil, nil
+}
+
+// queryComments returns the comments of an article selected by query, with their reaction counts.
+func (s *SQLStore) queryComments(articleId int64, query string, args ...interface{}) ([]model.Comment, error) {
+	comments, err := s.scanComments(s.query(s.db, query, args...))
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.loadCommentReactionCounts(articleId, comments)
+	if err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
+
+func (s *SQLStore) scanComments(rows *sql.Rows, err error) ([]model.Comment, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := make([]model.Comment, 0)
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		comments = append(comments, comment)
+	}
+
+	return comments, rows.Err()
+}
+
+// loadCommentReactionCounts sets the Reactions of comments of an article, which are counted in their own table.
+func (s *SQLStore) loadCommentReactionCounts(articleId int64, comments []model.Comment) error {
+	if len(comments) == 0 {
+		return nil
+	}
+
+	args := make([]interface{}, 0, 1+len(comments))
+	args = append(args, articleId)
+	for _, comment := range comments {
+		args = append(args, comment.CommentId)
+	}
+
+	rows, err := s.query(s.db, "SELECT comment_id, reaction, count FROM comment_reaction_counts "+
+		"WHERE article_id = ? AND comment_id IN ("+placeholders(len(comments))+") AND count > 0", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	counts := make(map[int64]map[string]int64)
+	for rows.Next() {
+		var commentId, count int64
+		var reaction string
+		err = rows.Scan(&commentId, &reaction, &count)
+		if err != nil {
+			return err
+		}
+
+		if counts[commentId] == nil {
+			counts[commentId] = make(map[string]int64)
+		}
+		counts[commentId][reaction] = count
+	}
+
+	for i := range comments {
+		comments[i].Reactions = counts[comments[i].CommentId]
+	}
+
+	return rows.Err()
+}
+
+func (s *SQLStore) DeleteComment(comment model.Comment) error {
+	return s.transact(func(tx *sql.Tx) error {
+		err := s.execAffectingOne(tx, "DELETE FROM comments WHERE article_id = ? AND comment_id = ? AND author = ? AND reply_count = 0 AND deleted_at = ?",
+			comment.ArticleId, comment.CommentId, comment.Author, comment.DeletedAt)
+		if err != nil {
+			return err
+		}
+
+		_, err = s.exec(tx, "DELETE FROM comment_reaction_counts WHERE article_id = ? AND comment_id = ?", comment.ArticleId, comment.CommentId)
+		if err != nil {
+			return err
+		}
+
+		if !comment.IsDeleted() {
+			err = s.execAffectingOne(tx, "UPDATE articles SET comments_count = comments_count - 1 WHERE article_id = ?", comment.ArticleId)
+			if err != nil {
+				return err
+			}
+		}
+
+		if comment.ParentId == 0 {
+			return nil
+		}
+
+		_, err = s.exec(tx, "UPDATE comments SET reply_count = reply_count - 1 WHERE article_id = ? AND comment_id = ?",
+			comment.ArticleId, comment.ParentId)
+		return err
+	})
+}
+
+func (s *SQLStore) TombstoneComment(key model.CommentKey, deletedAt int64) error {
+	return s.transact(func(tx *sql.Tx) error {
+		err := s.execAffectingOne(tx, "UPDATE comments SET body = '', deleted_at = ?, upvotes = 0 WHERE article_id = ? AND comment_id = ? AND reply_count > 0 AND deleted_at = 0",
+			deletedAt, key.ArticleId, key.CommentId)
+		if err != nil {
+			return err
+		}
+
+		_, err = s.exec(tx, "DELETE FROM comment_reaction_counts WHERE article_id = ? AND comment_id = ?", key.ArticleId, key.CommentId)
+		if err != nil {
+			return err
+		}
+
+		return s.execAffectingOne(tx, "UPDATE articles SET comments_count = comments_count - 1 WHERE article_id = ?", key.ArticleId)
+	})
+}
+
+func (s *SQLStore) RecountComments() ([]int64, error) {
+	changed := make([]int64, 0)
+
+	err := s.transact(func(tx *sql.Tx) error {
+		rows, err := s.query(tx, "SELECT article_id, counted FROM (SELECT article_id, comments_count,"+
+			" (SELECT COUNT(*) FROM comments WHERE comments.article_id = articles.article_id AND comments.deleted_at = 0) AS counted"+
+			" FROM articles) AS recounted WHERE counted <> comments_count")
+		if err != nil {
+			return err
+		}
+
+		counts := make(map[int64]int64)
+		for rows.Next() {
+			var articleId, count int64
+			err = rows.Scan(&articleId, &count)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			counts[articleId] = count
+		}
+		rows.Close()
+
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		for articleId, count := range counts {
+			_, err = s.exec(tx, "UPDATE articles SET comments_count = ? WHERE article_id = ?", count, articleId)
+			if err != nil {
+				return err
+			}
+			changed = append(changed, articleId)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return changed, nil
+}
+
+func (s *SQLStore) UpdateComment(oldComment model.Comment, newComment model.Comment, edit model.CommentEdit) error {
+	return s.transact(func(tx *sql.Tx) error {
+		err := s.execAffectingOne(tx, "UPDATE comments SET body = ?, updated_at = ?, edit_count = ? "+
+			"WHERE article_id = ? AND comment_id = ? AND author = ? AND updated_at = ? AND deleted_at = 0",
+			newComment.Body, newComment.UpdatedAt, newComment.EditCount,
+			oldComment.ArticleId, oldComment.CommentId, oldComment.Author, oldComment.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		return s.execAffectingOne(tx, "INSERT INTO comment_edits (article_id, edit_id, comment_id, body, written_at, edited_at) "+
+			"VALUES (?, ?, ?, ?, ?, ?) ON CONFLICT DO NOTHING",
+			edit.ArticleId, edit.EditId, edit.CommentId, edit.Body, edit.WrittenAt, edit.EditedAt)
+	})
+}
+
+func (s *SQLStore) QueryCommentEdits(key model.CommentKey) ([]model.CommentEdit, error) {
+	rows, err := s.query(s.db, "SELECT article_id, edit_id, comment_id, body, written_at, edited_at FROM comment_edits "+
+		"WHERE article_id = ? AND comment_id = ? ORDER BY edit_id DESC", key.ArticleId, key.CommentId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	edits := make([]model.CommentEdit, 0)
+	for rows.Next() {
+		edit := model.CommentEdit{}
+		err = rows.Scan(&edit.ArticleId, &edit.EditId, &edit.CommentId, &edit.Body, &edit.WrittenAt, &edit.EditedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		edits = append(edits, edit)
+	}
+
+	return edits, rows.Err()
+}
+
+func (s *SQLStore) DeleteCommentEdits(key model.CommentKey) error {
+	_, err := s.exec(s.db, "DELETE FROM comment_edits WHERE article_id = ? AND comment_id = ?", key.ArticleId, key.CommentId)
+	return err
+}
+
+func (s *SQLStore) PutCommentReaction(reaction model.CommentReaction) error {
+	return s.transact(func(tx *sql.Tx) error {
+		// Update the upvotes, or just lock the comment, unless it was deleted
+		err := s.execAffectingOne(tx, "UPDATE comments SET upvotes = upvotes + ? WHERE article_id = ? AND comment_id = ? AND deleted_at = 0",
+			upvoteDelta(reaction.Reaction, 1), reaction.ArticleId, reaction.CommentId)
+		if err != nil {
+			return err
+		}
+
+		err = s.execAffectingOne(tx, "INSERT INTO comment_reactions (article_id, comment_id, username, reaction, reacted_at) "+
+			"VALUES (?, ?, ?, ?, ?) ON CONFLICT DO NOTHING",
+			reaction.ArticleId, reaction.CommentId, reaction.Username, reaction.Reaction, reaction.ReactedAt)
+		if err != nil || reaction.Reaction == model.ReactionUpvote {
+			return err
+		}
+
+		_, err = s.exec(tx, "INSERT INTO comment_reaction_counts (article_id, comment_id, reaction, count) VALUES (?, ?, ?, 1) "+
+			"ON CONFLICT (article_id, comment_id, reaction) DO UPDATE SET count = comment_reaction_counts.count + 1",
+			reaction.ArticleId, reaction.CommentId, reaction.Reaction)
+		return err
+	})
+}
+
+func (s *SQLStore) DeleteCommentReaction(key model.CommentReactionKey) error {
+	return s.transact(func(tx *sql.Tx) error {
+		err := s.execAffectingOne(tx, "UPDATE comments SET upvotes = upvotes + ? WHERE article_id = ? AND comment_id = ? AND deleted_at = 0",
+			upvoteDelta(key.Reaction, -1), key.ArticleId, key.CommentId)
+		if err != nil {
+			return err
+		}
+
+		err = s.execAffectingOne(tx, "DELETE FROM comment_reactions WHERE article_id = ? AND comment_id = ? AND username = ? AND reaction = ?",
+			key.ArticleId, key.CommentId, key.Username, key.Reaction)
+		if err != nil || key.Reaction == model.ReactionUpvote {
+			return err
+		}
+
+		return s.execAffectingOne(tx, "UPDATE comment_reaction_counts SET count = count - 1 WHERE article_id = ? AND comment_id = ? AND reaction = ?",
+			key.ArticleId, key.CommentId, key.Reaction)
+	})
+}
+
+// upvoteDelta returns delta for upvotes and 0 for the other reactions, which are counted apart.
+func upvoteDelta(reaction string, delta int) int {
+	if reaction == model.ReactionUpvote {
+		return delta
+	}
+	return 0
+}
+
+func (s *SQLStore) GetUserCommentReactions(username string, articleId int64, commentIds []int64) (map[int64][]string, error) {
+	if len(commentIds) == 0 {
+		return make(map[int64][]string), nil
+	}
+
+	args := make([]interface{}, 0, 2+len(commentIds))
+	args = append(args, articleId, username)
+	for _, commentId := range commentIds {
+		args = append(args, commentId)
+	}
+
+	rows, err := s.query(s.db, "SELECT comment_id, reaction FROM comment_reactions "+
+		"WHERE article_id = ? AND username = ? AND comment_id IN ("+placeholders(len(commentIds))+")", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	added := make(map[int64]map[string]bool)
+	for rows.Next() {
+		var commentId int64
+		var reaction string
+		err = rows.Scan(&commentId, &reaction)
+		if err != nil {
+			return nil, err
+		}
+
+		if added[commentId] == nil {
+			added[commentId] = make(map[string]bool)
+		}
+		added[commentId][reaction] = true
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return orderReactions(added), nil
+}
+
+func (s *SQLStore) DeleteCommentReactions(key model.CommentKey) error {
+	_, err := s.exec(s.db, "DELETE FROM comment_reactions WHERE article_id = ? AND comment_id = ?", key.ArticleId, key.CommentId)
+	return err
+}
@@ -0,0 +1,8 @@
+package service
+
+func (s *SQLStore) IncrementCounter(name string) (int64, error) {
+	var value int64
+	err := s.queryRow(s.db, "INSERT INTO counters (name, value) VALUES (?, 1) ON CONFLICT (name) DO UPDATE SET value = counters.value + 1 RETURNING value",
+		name).Scan(&value)
+	return value, err
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"database/sql"
+
+	"realworld-go-nolambda/model"
+)
+
+func (s *SQLStore) PutFavoriteArticle(favoriteArticle model.FavoriteArticle) error {
+	return s.transact(func(tx *sql.Tx) error {
+		// Favorite the article
+		err := s.execAffectingOne(tx, "INSERT INTO favorite_articles (username, article_id, favorited_at) VALUES (?, ?, ?) ON CONFLICT DO NOTHING",
+			favoriteArticle.Username, favoriteArticle.ArticleId, favoriteArticle.FavoritedAt)
+		if err != nil {
+			return err
+		}
+
+		// Update favorites count
+		return s.execAffectingOne(tx, "UPDATE articles SET favorites_count = favorites_count + 1 WHERE article_id = ?", favoriteArticle.ArticleId)
+	})
+}
+
+func (s *SQLStore) DeleteFavoriteArticle(key model.FavoriteArticleKey) error {
+	return s.transact(func(tx *sql.Tx) error {
+		// Unfavorite the article
+		err := s.execAffectingOne(tx, "DELETE FROM favorite_articles WHERE username = ? AND article_id = ?", key.Username, key.ArticleId)
+		if err != nil {
+			return err
+		}
+
+		// Update favorites count
+		return s.execAffectingOne(tx, "UPDATE articles SET favorites_count = favorites_count - 1 WHERE article_id = ?", key.ArticleId)
+	})
+}
+
+func (s *SQLStore) QueryFavoriteArticleIds(username string, page Page) ([]int64, *Cursor, error) {
+	after, afterArgs := keysetCondition(page.After, "favorited_at")
+	args := append([]interface{}{username}, afterArgs...)
+	args = append(args, page.Limit+1, page.Offset)
+
+	rows, err := s.query(s.db, "SELECT article_id, favorited_at FROM favorite_articles WHERE username = ? AND "+after+
+		" ORDER BY favorited_at DESC, article_id DESC LIMIT ? OFFSET ?", args...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return scanPositionPage(rows, page.Limit)
+}
+
+func (s *SQLStore) GetFavoritedArticleIds(username string, articleIds []int64) (map[int64]bool, error) {
+	favorited := make(map[int64]bool)
+	if len(articleIds) == 0 {
+		return favorited, nil
+	}
+
+	args := make([]interface{}, 0, 1+len(articleIds))
+	args = append(args, username)
+	for _, articleId := range articleIds {
+		args = append(args, articleId)
+	}
+
+	rows, err := s.query(s.db, "SELECT article_id FROM favorite_articles WHERE username = ? AND article_id IN ("+placeholders(len(articleIds))+")", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	favoritedIds, err := scanInt64s(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, articleId := range favoritedIds {
+		favorited[articleId] = true
+	}
+
+	return favorited, nil
+}
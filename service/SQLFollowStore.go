@@ -0,0 +1,88 @@
+package service
+
+import (
+	"realworld-go-nolambda/model"
+)
+
+func (s *SQLStore) PutFollow(follow model.Follow) error {
+	_, err := s.exec(s.db, "INSERT INTO follows (follower, publisher) VALUES (?, ?) ON CONFLICT DO NOTHING", follow.Follower, follow.Publisher)
+	return err
+}
+
+func (s *SQLStore) DeleteFollow(follow model.Follow) error {
+	_, err := s.exec(s.db, "DELETE FROM follows WHERE follower = ? AND publisher = ?", follow.Follower, follow.Publisher)
+	return err
+}
+
+func (s *SQLStore) QueryPublishers(follower string) ([]string, error) {
+	rows, err := s.query(s.db, "SELECT publisher FROM follows WHERE follower = ? ORDER BY publisher", follower)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	publishers := make([]string, 0)
+	for rows.Next() {
+		var publisher string
+		err = rows.Scan(&publisher)
+		if err != nil {
+			return nil, err
+		}
+
+		publishers = append(publishers, publisher)
+	}
+
+	return publishers, rows.Err()
+}
+
+func (s *SQLStore) QueryFollowers(publisher string) ([]string, error) {
+	rows, err := s.query(s.db, "SELECT follower FROM follows WHERE publisher = ? ORDER BY follower", publisher)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	followers := make([]string, 0)
+	for rows.Next() {
+		var follower string
+		err = rows.Scan(&follower)
+		if err != nil {
+			return nil, err
+		}
+
+		followers = append(followers, follower)
+	}
+
+	return followers, rows.Err()
+}
+
+func (s *SQLStore) GetFollowedPublishers(follower string, publishers []string) (map[string]bool, error) {
+	followingUser := make(map[string]bool)
+	if len(publishers) == 0 {
+		return followingUser, nil
+	}
+
+	args := make([]interface{}, 0, 1+len(publishers))
+	args = append(args, follower)
+	for _, publisher := range publishers {
+		args = append(args, publisher)
+	}
+
+	rows, err := s.query(s.db, "SELECT publisher FROM follows WHERE follower = ? AND publisher IN ("+placeholders(len(publishers))+")", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var publisher string
+		err = rows.Scan(&publisher)
+		if err != nil {
+			return nil, err
+		}
+
+		followingUser[publisher] = true
+	}
+
+	return followingUser, rows.Err()
+}
@@ -0,0 +1,327 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type sqlMigration struct {
+	Version    int
+	Name       string
+	Statements []string
+}
+
+// sqlMigrations are applied in order, each exactly once. Never edit a released migration, append a new one.
+// Column types are written for SQLite and translated by SQLStore.ddl for PostgreSQL.
+var sqlMigrations = []sqlMigration{
+	{
+		Version: 1,
+		Name:    "initial schema",
+		Statements: []string{
+			`CREATE TABLE users (
+				username      TEXT PRIMARY KEY,
+				email         TEXT NOT NULL UNIQUE,
+				password_hash BLOB,
+				image         TEXT NOT NULL DEFAULT '',
+				bio           TEXT NOT NULL DEFAULT ''
+			)`,
+			`CREATE TABLE articles (
+				article_id      BIGINT PRIMARY KEY,
+				slug            TEXT NOT NULL,
+				title           TEXT NOT NULL,
+				description     TEXT NOT NULL,
+				body            TEXT NOT NULL,
+				tag_list        TEXT NOT NULL,
+				created_at      BIGINT NOT NULL,
+				updated_at      BIGINT NOT NULL,
+				favorites_count BIGINT NOT NULL DEFAULT 0,
+				author          TEXT NOT NULL
+			)`,
+			`CREATE INDEX articles_created_at ON articles (created_at)`,
+			`CREATE INDEX articles_author ON articles (author, created_at)`,
+			`CREATE TABLE article_tags (
+				tag        TEXT NOT NULL,
+				article_id BIGINT NOT NULL,
+				created_at BIGINT NOT NULL,
+				PRIMARY KEY (tag, article_id)
+			)`,
+			`CREATE INDEX article_tags_created_at ON article_tags (tag, created_at)`,
+			`CREATE TABLE tags (
+				tag           TEXT PRIMARY KEY,
+				article_count BIGINT NOT NULL DEFAULT 0
+			)`,
+			`CREATE INDEX tags_article_count ON tags (article_count)`,
+			`CREATE TABLE comments (
+				article_id BIGINT NOT NULL,
+				comment_id BIGINT NOT NULL,
+				created_at BIGINT NOT NULL,
+				updated_at BIGINT NOT NULL,
+				body       TEXT NOT NULL,
+				author     TEXT NOT NULL,
+				PRIMARY KEY (article_id, comment_id)
+			)`,
+			`CREATE INDEX comments_created_at ON comments (article_id, created_at)`,
+			`CREATE TABLE follows (
+				follower  TEXT NOT NULL,
+				publisher TEXT NOT NULL,
+				PRIMARY KEY (follower, publisher)
+			)`,
+			`CREATE TABLE favorite_articles (
+				username     TEXT NOT NULL,
+				article_id   BIGINT NOT NULL,
+				favorited_at BIGINT NOT NULL,
+				PRIMARY KEY (username, article_id)
+			)`,
+			`CREATE INDEX favorite_articles_favorited_at ON favorite_articles (username, favorited_at)`,
+		},
+	},
+	{
+		Version: 2,
+		Name:    "article cleanups",
+		Statements: []string{
+			`CREATE TABLE article_cleanups (
+				article_id BIGINT PRIMARY KEY,
+				deleted_at BIGINT NOT NULL
+			)`,
+			`CREATE INDEX favorite_articles_article_id ON favorite_articles (article_id)`,
+		},
+	},
+	{
+		Version: 3,
+		Name:    "id counters",
+		Statements: []string{
+			`CREATE TABLE counters (
+				name  TEXT PRIMARY KEY,
+				value BIGINT NOT NULL
+			)`,
+		},
+	},
+	{
+		Version: 4,
+		Name:    "timelines",
+		Statements: []string{
+			`CREATE INDEX follows_publisher ON follows (publisher, follower)`,
+			`CREATE TABLE timelines (
+				username   TEXT NOT NULL,
+				article_id BIGINT NOT NULL,
+				created_at BIGINT NOT NULL,
+				author     TEXT NOT NULL,
+				PRIMARY KEY (username, article_id)
+			)`,
+			`CREATE INDEX timelines_created_at ON timelines (username, created_at)`,
+		},
+	},
+	{
+		Version: 5,
+		Name:    "article revisions",
+		Statements: []string{
+			`ALTER TABLE articles ADD COLUMN revision BIGINT NOT NULL DEFAULT 0`,
+			`CREATE TABLE article_revisions (
+				article_id     BIGINT NOT NULL,
+				revision       BIGINT NOT NULL,
+				title          TEXT NOT NULL,
+				description    TEXT NOT NULL,
+				body           TEXT NOT NULL,
+				tag_list       TEXT NOT NULL,
+				created_at     BIGINT NOT NULL,
+				editor         TEXT NOT NULL,
+				changed_fields TEXT NOT NULL,
+				restored_from  BIGINT NOT NULL DEFAULT 0,
+				PRIMARY KEY (article_id, revision)
+			)`,
+		},
+	},
+	{
+		Version: 6,
+		Name:    "article status",
+		Statements: []string{
+			`ALTER TABLE articles ADD COLUMN status TEXT NOT NULL DEFAULT 'published'`,
+			`CREATE INDEX articles_status_created_at ON articles (status, created_at)`,
+			`CREATE INDEX articles_author_status ON articles (author, status, created_at)`,
+		},
+	},
+	{
+		Version: 7,
+		Name:    "article schedules",
+		Statements: []string{
+			`ALTER TABLE articles ADD COLUMN publish_at BIGINT NOT NULL DEFAULT 0`,
+			`ALTER TABLE articles ADD COLUMN expire_at BIGINT NOT NULL DEFAULT 0`,
+			`CREATE TABLE article_schedules (
+				article_id BIGINT NOT NULL,
+				action     TEXT NOT NULL,
+				due_at     BIGINT NOT NULL,
+				PRIMARY KEY (article_id, action)
+			)`,
+			`CREATE INDEX article_schedules_due_at ON article_schedules (due_at)`,
+		},
+	},
+	{
+		Version: 8,
+		Name:    "search postings",
+		Statements: []string{
+			`CREATE TABLE search_postings (
+				term       TEXT NOT NULL,
+				article_id BIGINT NOT NULL,
+				positions  TEXT NOT NULL,
+				PRIMARY KEY (term, article_id)
+			)`,
+		},
+	},
+	{
+		Version: 9,
+		Name:    "article orders",
+		Statements: []string{
+			`CREATE INDEX articles_status_favorites_count ON articles (status, favorites_count)`,
+			`CREATE INDEX articles_status_updated_at ON articles (status, updated_at)`,
+		},
+	},
+	{
+		Version: 10,
+		Name:    "tag descriptions and aliases",
+		Statements: []string{
+			`ALTER TABLE tags ADD COLUMN description TEXT NOT NULL DEFAULT ''`,
+			`CREATE TABLE tag_aliases (
+				alias TEXT PRIMARY KEY,
+				tag   TEXT NOT NULL
+			)`,
+			`CREATE INDEX tag_aliases_tag ON tag_aliases (tag)`,
+		},
+	},
+	{
+		Version: 11,
+		Name:    "trending tags",
+		Statements: []string{
+			`CREATE INDEX article_tags_recent ON article_tags (created_at)`,
+		},
+	},
+	{
+		Version: 12,
+		Name:    "comment threads",
+		Statements: []string{
+			`ALTER TABLE comments ADD COLUMN parent_id BIGINT NOT NULL DEFAULT 0`,
+			`ALTER TABLE comments ADD COLUMN depth INTEGER NOT NULL DEFAULT 0`,
+			`ALTER TABLE comments ADD COLUMN reply_count BIGINT NOT NULL DEFAULT 0`,
+			`ALTER TABLE comments ADD COLUMN deleted_at BIGINT NOT NULL DEFAULT 0`,
+		},
+	},
+	{
+		Version: 13,
+		Name:    "comment edits",
+		Statements: []string{
+			`ALTER TABLE comments ADD COLUMN edit_count BIGINT NOT NULL DEFAULT 0`,
+			`CREATE TABLE comment_edits (
+				article_id BIGINT NOT NULL,
+				edit_id    BIGINT NOT NULL,
+				comment_id BIGINT NOT NULL,
+				body       TEXT NOT NULL,
+				written_at BIGINT NOT NULL,
+				edited_at  BIGINT NOT NULL,
+				PRIMARY KEY (article_id, edit_id)
+			)`,
+			`CREATE INDEX comment_edits_comment_id ON comment_edits (article_id, comment_id, edit_id)`,
+		},
+	},
+	{
+		Version: 14,
+		Name:    "comment counts and pages",
+		Statements: []string{
+			`ALTER TABLE articles ADD COLUMN comments_count BIGINT NOT NULL DEFAULT 0`,
+			`UPDATE articles SET comments_count =
+				(SELECT COUNT(*) FROM comments WHERE comments.article_id = articles.article_id AND comments.deleted_at = 0)`,
+			`CREATE INDEX comments_parent_id ON comments (article_id, parent_id, created_at, comment_id)`,
+		},
+	},
+	{
+		Version: 15,
+		Name:    "comment reactions",
+		Statements: []string{
+			`ALTER TABLE comments ADD COLUMN upvotes BIGINT NOT NULL DEFAULT 0`,
+			`CREATE INDEX comments_upvotes ON comments (article_id, parent_id, upvotes, comment_id)`,
+			`CREATE TABLE comment_reactions (
+				article_id BIGINT NOT NULL,
+				comment_id BIGINT NOT NULL,
+				username   TEXT NOT NULL,
+				reaction   TEXT NOT NULL,
+				reacted_at BIGINT NOT NULL,
+				PRIMARY KEY (article_id, comment_id, username, reaction)
+			)`,
+			`CREATE TABLE comment_reaction_counts (
+				article_id BIGINT NOT NULL,
+				comment_id BIGINT NOT NULL,
+				reaction   TEXT NOT NULL,
+				count      BIGINT NOT NULL,
+				PRIMARY KEY (article_id, comment_id, reaction)
+			)`,
+		},
+	},
+	{
+		Version: 16,
+		Name:    "article reading time",
+		Statements: []string{
+			`ALTER TABLE articles ADD COLUMN word_count BIGINT NOT NULL DEFAULT 0`,
+			`ALTER TABLE articles ADD COLUMN reading_time BIGINT NOT NULL DEFAULT 0`,
+			`ALTER TABLE articles ADD COLUMN outline TEXT NOT NULL DEFAULT '[]'`,
+		},
+	},
+	{
+		Version: 17,
+		Name:    "article cleanup of search postings and timelines",
+		Statements: []string{
+			`ALTER TABLE article_cleanups ADD COLUMN author TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE article_cleanups ADD COLUMN search_terms TEXT NOT NULL DEFAULT '[]'`,
+		},
+	},
+}
+
+// Migrate brings the schema up to the latest version in sqlMigrations.
+func (s *SQLStore) Migrate() error {
+	_, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    BIGINT PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at BIGINT NOT NULL
+	)`)
+	if err != nil {
+		return err
+	}
+
+	version, err := s.SchemaVersion()
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range sqlMigrations {
+		if migration.Version <= version {
+			continue
+		}
+
+		err = s.transact(func(tx *sql.Tx) error {
+			for _, statement := range migration.Statements {
+				_, err := tx.Exec(s.ddl(statement))
+				if err != nil {
+					return err
+				}
+			}
+
+			_, err := s.exec(tx, "INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+				migration.Version, migration.Name, time.Now().UTC().UnixNano())
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d (%s): %w", migration.Version, migration.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// SchemaVersion returns the version of the last applied migration, 0 if none.
+func (s *SQLStore) SchemaVersion() (int, error) {
+	var version sql.NullInt64
+	err := s.db.QueryRow("SELECT MAX(version) FROM schema_migrations").Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(version.Int64), nil
+}
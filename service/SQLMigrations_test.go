@@ -0,0 +1,22 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLStoreMigrate(t *testing.T) {
+	store, err := NewSQLStore(DialectSQLite, ":memory:")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer store.Close()
+
+	// Migrating an up-to-date schema is a no-op
+	assert.NoError(t, store.Migrate())
+
+	version, err := store.SchemaVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, sqlMigrations[len(sqlMigrations)-1].Version, version)
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+
+	"realworld-go-nolambda/model"
+)
+
+const sqlArticleRevisionColumns = "article_id, revision, title, description, body, tag_list, created_at, editor, changed_fields, restored_from"
+
+func scanArticleRevision(row interface{ Scan(...interface{}) error }) (model.ArticleRevision, error) {
+	revision := model.ArticleRevision{}
+	var tagList, changedFields string
+
+	err := row.Scan(&revision.ArticleId, &revision.Revision, &revision.Title, &revision.Description, &revision.Body,
+		&tagList, &revision.CreatedAt, &revision.Editor, &changedFields, &revision.RestoredFrom)
+	if err != nil {
+		return model.ArticleRevision{}, err
+	}
+
+	revision.TagList, err = decodeStringList(tagList)
+	if err != nil {
+		return model.ArticleRevision{}, err
+	}
+
+	revision.ChangedFields, err = decodeStringList(changedFields)
+	return revision, err
+}
+
+// putArticleRevisions inserts revisions, failing with ErrConditionFailed if one of them exists.
+func (s *SQLStore) putArticleRevisions(tx *sql.Tx, revisions []model.ArticleRevision) error {
+	for _, revision := range revisions {
+		tagList, err := encodeStringList(revision.TagList)
+		if err != nil {
+			return err
+		}
+
+		changedFields, err := encodeStringList(revision.ChangedFields)
+		if err != nil {
+			return err
+		}
+
+		err = s.execAffectingOne(tx, "INSERT INTO article_revisions ("+sqlArticleRevisionColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT DO NOTHING",
+			revision.ArticleId, revision.Revision, revision.Title, revision.Description, revision.Body,
+			tagList, revision.CreatedAt, revision.Editor, changedFields, revision.RestoredFrom)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *SQLStore) GetArticleRevision(key model.ArticleRevisionKey) (model.ArticleRevision, bool, error) {
+	revision, err := scanArticleRevision(s.queryRow(s.db, "SELECT "+sqlArticleRevisionColumns+" FROM article_revisions WHERE article_id = ? AND revision = ?",
+		key.ArticleId, key.Revision))
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.ArticleRevision{}, false, nil
+	}
+
+	if err != nil {
+		return model.ArticleRevision{}, false, err
+	}
+
+	return revision, true, nil
+}
+
+func (s *SQLStore) QueryArticleRevisions(articleId int64, page Page) ([]model.ArticleRevision, *Cursor, error) {
+	after, afterArgs := keysetCondition(page.After, "revision")
+	args := append([]interface{}{articleId}, afterArgs...)
+	args = append(args, page.Limit+1, page.Offset)
+
+	rows, err := s.query(s.db, "SELECT "+sqlArticleRevisionColumns+" FROM article_revisions WHERE article_id = ? AND "+after+
+		" ORDER BY revision DESC LIMIT ? OFFSET ?", args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	revisions := make([]model.ArticleRevision, 0)
+	for rows.Next() {
+		revision, err := scanArticleRevision(rows)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		revisions = append(revisions, revision)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	if len(revisions) <= page.Limit {
+		return revisions, nil, nil
+	}
+
+	revisions = revisions[:page.Limit]
+	return revisions, revisionCursor(revisions[len(revisions)-1]), nil
+}
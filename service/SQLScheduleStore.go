@@ -0,0 +1,47 @@
+package service
+
+import (
+	"database/sql"
+
+	"realworld-go-nolambda/model"
+)
+
+func (s *SQLStore) PutArticleSchedules(schedules []model.ArticleSchedule) error {
+	return s.transact(func(tx *sql.Tx) error {
+		for _, schedule := range schedules {
+			_, err := s.exec(tx, "INSERT INTO article_schedules (article_id, action, due_at) VALUES (?, ?, ?) ON CONFLICT (article_id, action) DO UPDATE SET due_at = excluded.due_at",
+				schedule.ArticleId, schedule.Action, schedule.DueAt)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *SQLStore) QueryArticleSchedules(until int64, limit int) ([]model.ArticleSchedule, error) {
+	rows, err := s.query(s.db, "SELECT article_id, action, due_at FROM article_schedules WHERE due_at <= ? ORDER BY due_at LIMIT ?", until, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedules := make([]model.ArticleSchedule, 0)
+	for rows.Next() {
+		schedule := model.ArticleSchedule{}
+		err = rows.Scan(&schedule.ArticleId, &schedule.Action, &schedule.DueAt)
+		if err != nil {
+			return nil, err
+		}
+
+		schedules = append(schedules, schedule)
+	}
+
+	return schedules, rows.Err()
+}
+
+func (s *SQLStore) DeleteArticleSchedule(schedule model.ArticleSchedule) error {
+	return s.execAffectingOne(s.db, "DELETE FROM article_schedules WHERE article_id = ? AND action = ? AND due_at = ?",
+		schedule.ArticleId, schedule.Action, schedule.DueAt)
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"realworld-go-nolambda/model"
+	"realworld-go-nolambda/util"
+)
+
+func (s *SQLStore) PutSearchPostings(postings []model.SearchPosting) error {
+	return s.transact(func(tx *sql.Tx) error {
+		for _, posting := range postings {
+			positions, err := json.Marshal(posting.Positions)
+			if err != nil {
+				return err
+			}
+
+			_, err = s.exec(tx, "INSERT INTO search_postings (term, article_id, positions) VALUES (?, ?, ?) "+
+				"ON CONFLICT (term, article_id) DO UPDATE SET positions = excluded.positions",
+				posting.Term, posting.ArticleId, string(positions))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *SQLStore) DeleteSearchPostings(keys []model.SearchPostingKey) error {
+	return s.transact(func(tx *sql.Tx) error {
+		for _, key := range keys {
+			_, err := s.exec(tx, "DELETE FROM search_postings WHERE term = ? AND article_id = ?", key.Term, key.ArticleId)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *SQLStore) QuerySearchPostings(term string, limit int) ([]model.SearchPosting, error) {
+	rows, err := s.query(s.db, "SELECT term, article_id, positions FROM search_postings WHERE term = ? "+
+		"ORDER BY article_id DESC LIMIT ?", term, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanSearchPostings(rows)
+}
+
+// QuerySearchPostingsByPrefix relies on terms being made of letters and digits only, which LIKE doesn't treat specially.
+func (s *SQLStore) QuerySearchPostingsByPrefix(prefix string, limit int) ([]model.SearchPosting, error) {
+	rows, err := s.query(s.db, "SELECT term, article_id, positions FROM search_postings WHERE term LIKE ? "+
+		"ORDER BY term, article_id LIMIT ?", prefix+"%", limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanSearchPostings(rows)
+}
+
+func scanSearchPostings(rows *sql.Rows) ([]model.SearchPosting, error) {
+	defer rows.Close()
+
+	postings := make([]model.SearchPosting, 0)
+	for rows.Next() {
+		posting := model.SearchPosting{}
+		var positions string
+
+		err := rows.Scan(&posting.Term, &posting.ArticleId, &positions)
+		if err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal([]byte(positions), &posting.Positions)
+		if err != nil {
+			return nil, err
+		}
+
+		posting.TermHead = util.FirstRune(posting.Term)
+		postings = append(postings, posting)
+	}
+
+	return postings, rows.Err()
+}
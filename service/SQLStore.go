@@ -0,0 +1,270 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	DialectSQLite   = "sqlite"
+	DialectPostgres = "postgres"
+)
+
+// SQLStore is the Store backed by a SQLite or PostgreSQL database through database/sql.
+// DynamoDB transactions are mapped to SQL transactions, conditional puts to INSERT ... ON CONFLICT DO NOTHING.
+type SQLStore struct {
+	db      *sql.DB
+	dialect string
+}
+
+// NewSQLStore opens a database of the given dialect and migrates its schema to the latest version.
+func NewSQLStore(dialect, dataSource string) (*SQLStore, error) {
+	if dialect != DialectSQLite && dialect != DialectPostgres {
+		return nil, fmt.Errorf("unsupported SQL dialect %q", dialect)
+	}
+
+	db, err := sql.Open(dialect, dataSource)
+	if err != nil {
+		return nil, err
+	}
+
+	if dialect == DialectSQLite {
+		// SQLite allows a single writer. One connection also keeps ":memory:" databases alive and shared.
+		db.SetMaxOpenConns(1)
+	}
+
+	s := &SQLStore{
+		db:      db,
+		dialect: dialect,
+	}
+
+	err = s.Migrate()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
+
+// rebind rewrites ? placeholders into the dialect's placeholder syntax.
+func (s *SQLStore) rebind(query string) string {
+	if s.dialect != DialectPostgres {
+		return query
+	}
+
+	var builder strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			builder.WriteString("$" + strconv.Itoa(n))
+		} else {
+			builder.WriteRune(c)
+		}
+	}
+	return builder.String()
+}
+
+// ddl rewrites portable column types into the dialect's types.
+func (s *SQLStore) ddl(statement string) string {
+	if s.dialect != DialectPostgres {
+		return statement
+	}
+	return strings.ReplaceAll(statement, " BLOB", " BYTEA")
+}
+
+type sqlExecer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func (s *SQLStore) exec(db sqlExecer, query string, args ...interface{}) (sql.Result, error) {
+	return db.Exec(s.rebind(query), args...)
+}
+
+func (s *SQLStore) query(db sqlExecer, query string, args ...interface{}) (*sql.Rows, error) {
+	return db.Query(s.rebind(query), args...)
+}
+
+func (s *SQLStore) queryRow(db sqlExecer, query string, args ...interface{}) *sql.Row {
+	return db.QueryRow(s.rebind(query), args...)
+}
+
+// execAffectingOne runs a conditional statement, returning ErrConditionFailed if no row was affected.
+func (s *SQLStore) execAffectingOne(db sqlExecer, query string, args ...interface{}) error {
+	result, err := s.exec(db, query, args...)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrConditionFailed
+	}
+
+	return nil
+}
+
+// transact runs fn in a transaction, committing only if fn succeeds.
+func (s *SQLStore) transact(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	err = fn(tx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// placeholders returns "?, ?, ..." with n placeholders.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func encodeStringList(values []string) (string, error) {
+	if values == nil {
+		values = make([]string, 0)
+	}
+
+	js, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+
+	return string(js), nil
+}
+
+func decodeStringList(js string) ([]string, error) {
+	values := make([]string, 0)
+	if js == "" {
+		return values, nil
+	}
+
+	err := json.Unmarshal([]byte(js), &values)
+	return values, err
+}
+
+// scanInt64s reads a single integer column from every row and closes rows.
+func scanInt64s(rows *sql.Rows) ([]int64, error) {
+	defer rows.Close()
+
+	values := make([]int64, 0)
+	for rows.Next() {
+		var value int64
+		err := rows.Scan(&value)
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, value)
+	}
+
+	return values, rows.Err()
+}
+
+// keysetCondition selects the rows after cursor in a listing ordered by sortColumn and article_id, both descending.
+func keysetCondition(cursor *Cursor, sortColumn string) (string, []interface{}) {
+	return orderedKeysetCondition(cursor, sortColumn, false)
+}
+
+// orderedKeysetCondition is keysetCondition for a listing ordered by sortColumn and article_id, both ascending if ascending is set.
+func orderedKeysetCondition(cursor *Cursor, sortColumn string, ascending bool) (string, []interface{}) {
+	if cursor == nil {
+		return "1 = 1", nil
+	}
+
+	comparison := " < "
+	if ascending {
+		comparison = " > "
+	}
+
+	return "(" + sortColumn + comparison + "? OR (" + sortColumn + " = ? AND article_id" + comparison + "?))",
+		[]interface{}{cursor.SortKey, cursor.SortKey, cursor.ArticleId}
+}
+
+// createdRangeCondition selects the rows whose created_at is within the range of scan.
+func createdRangeCondition(scan ArticleScan) (string, []interface{}) {
+	condition := "1 = 1"
+	args := make([]interface{}, 0, 2)
+
+	if scan.CreatedFrom != 0 {
+		condition += " AND created_at >= ?"
+		args = append(args, scan.CreatedFrom)
+	}
+
+	if scan.CreatedUntil != 0 {
+		condition += " AND created_at < ?"
+		args = append(args, scan.CreatedUntil)
+	}
+
+	return condition, args
+}
+
+// sqlOrder returns the column and direction an order sorts by.
+func sqlOrder(order string) (string, string) {
+	switch order {
+	case OrderOldest:
+		return "created_at", "ASC"
+	case OrderMostFavorited:
+		return "favorites_count", "DESC"
+	case OrderRecentlyUpdated:
+		return "updated_at", "DESC"
+	default:
+		return "created_at", "DESC"
+	}
+}
+
+// scanPositionPage reads (article_id, sort key) rows of a query for limit+1 rows, and closes rows.
+// It returns the first limit article ids, and the position of the last one if there was one more row.
+func scanPositionPage(rows *sql.Rows, limit int) ([]int64, *Cursor, error) {
+	defer rows.Close()
+
+	positions := make([]Cursor, 0)
+	for rows.Next() {
+		position := Cursor{}
+		err := rows.Scan(&position.ArticleId, &position.SortKey)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		positions = append(positions, position)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var next *Cursor
+	if len(positions) > limit {
+		positions = positions[:limit]
+		next = &positions[limit-1]
+	}
+
+	articleIds := make([]int64, 0, len(positions))
+	for _, position := range positions {
+		articleIds = append(articleIds, position.ArticleId)
+	}
+
+	return articleIds, next, nil
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"database/sql"
+
+	"realworld-go-nolambda/model"
+)
+
+func (s *SQLStore) PutTagAlias(alias model.TagAlias) error {
+	_, err := s.exec(s.db, "INSERT INTO tag_aliases (alias, tag) VALUES (?, ?) ON CONFLICT (alias) DO UPDATE SET tag = excluded.tag",
+		alias.Alias, alias.Tag)
+	return err
+}
+
+func (s *SQLStore) DeleteTagAlias(alias string) error {
+	_, err := s.exec(s.db, "DELETE FROM tag_aliases WHERE alias = ?", alias)
+	return err
+}
+
+func (s *SQLStore) GetTagAliases(aliases []string) (map[string]model.TagAlias, error) {
+	if len(aliases) == 0 {
+		return make(map[string]model.TagAlias), nil
+	}
+
+	args := make([]interface{}, 0, len(aliases))
+	for _, alias := range aliases {
+		args = append(args, alias)
+	}
+
+	return scanTagAliases(s.query(s.db, "SELECT alias, tag FROM tag_aliases WHERE alias IN ("+placeholders(len(args))+")", args...))
+}
+
+func (s *SQLStore) QueryTagAliases(tag string) ([]model.TagAlias, error) {
+	found, err := scanTagAliases(s.query(s.db, "SELECT alias, tag FROM tag_aliases WHERE tag = ?", tag))
+	if err != nil {
+		return nil, err
+	}
+
+	aliases := make([]model.TagAlias, 0, len(found))
+	for _, alias := range found {
+		aliases = append(aliases, alias)
+	}
+
+	return aliases, nil
+}
+
+func scanTagAliases(rows *sql.Rows, err error) (map[string]model.TagAlias, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	aliases := make(map[string]model.TagAlias)
+	for rows.Next() {
+		alias := model.TagAlias{}
+		err = rows.Scan(&alias.Alias, &alias.Tag)
+		if err != nil {
+			return nil, err
+		}
+
+		aliases[alias.Alias] = alias
+	}
+
+	return aliases, rows.Err()
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"realworld-go-nolambda/model"
+)
+
+func (s *SQLStore) QueryArticleIdsByTag(tag string, page Page) ([]int64, *Cursor, error) {
+	after, afterArgs := keysetCondition(page.After, "created_at")
+	args := append([]interface{}{tag}, afterArgs...)
+	args = append(args, page.Limit+1, page.Offset)
+
+	rows, err := s.query(s.db, "SELECT article_id, created_at FROM article_tags WHERE tag = ? AND "+after+
+		" ORDER BY created_at DESC, article_id DESC LIMIT ? OFFSET ?", args...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return scanPositionPage(rows, page.Limit)
+}
+
+func (s *SQLStore) ScanArticleIdsByTag(tag string, scan ArticleScan, page Page) ([]int64, *Cursor, error) {
+	condition, args := createdRangeCondition(scan)
+	args = append([]interface{}{tag}, args...)
+
+	_, direction := sqlOrder(scan.Order)
+	after, afterArgs := orderedKeysetCondition(page.After, "created_at", direction == "ASC")
+	args = append(args, afterArgs...)
+	args = append(args, page.Limit+1, page.Offset)
+
+	rows, err := s.query(s.db, "SELECT article_id, created_at FROM article_tags WHERE tag = ? AND "+condition+" AND "+after+
+		" ORDER BY created_at "+direction+", article_id "+direction+" LIMIT ? OFFSET ?", args...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return scanPositionPage(rows, page.Limit)
+}
+
+func (s *SQLStore) GetTags(tags []string) (map[string]model.Tag, error) {
+	found := make(map[string]model.Tag, len(tags))
+	if len(tags) == 0 {
+		return found, nil
+	}
+
+	args := make([]interface{}, 0, len(tags))
+	for _, tag := range tags {
+		args = append(args, tag)
+	}
+
+	rows, err := s.query(s.db, "SELECT tag, article_count, description FROM tags WHERE tag IN ("+placeholders(len(tags))+")", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		tag := model.Tag{}
+		err = rows.Scan(&tag.Tag, &tag.ArticleCount, &tag.Description)
+		if err != nil {
+			return nil, err
+		}
+
+		found[tag.Tag] = tag
+	}
+
+	return found, rows.Err()
+}
+
+func (s *SQLStore) QueryTags(prefix string, page Page) ([]model.Tag, *Cursor, error) {
+	after, args := "1 = 1", []interface{}{likePrefix(prefix)}
+	if page.After != nil {
+		after = "(article_count < ? OR (article_count = ? AND tag > ?))"
+		args = append(args, page.After.SortKey, page.After.SortKey, page.After.Tag)
+	}
+	args = append(args, page.Limit+1, page.Offset)
+
+	rows, err := s.query(s.db, "SELECT tag, article_count, description FROM tags WHERE article_count > 0 AND tag LIKE ? ESCAPE '!' AND "+
+		after+" ORDER BY article_count DESC, tag LIMIT ? OFFSET ?", args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	tags := make([]model.Tag, 0, page.Limit+1)
+	for rows.Next() {
+		tag := model.Tag{}
+		err = rows.Scan(&tag.Tag, &tag.ArticleCount, &tag.Description)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		tags = append(tags, tag)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	if len(tags) > page.Limit {
+		tags = tags[:page.Limit]
+		return tags, tagCursor(tags[len(tags)-1]), nil
+	}
+
+	return tags, nil, nil
+}
+
+func (s *SQLStore) QueryTrendingTags(since int64, limit int) ([]model.Tag, error) {
+	rows, err := s.query(s.db, "SELECT tag, COUNT(*) FROM article_tags WHERE created_at >= ? GROUP BY tag ORDER BY COUNT(*) DESC, tag LIMIT ?",
+		since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make([]model.Tag, 0, limit)
+	for rows.Next() {
+		tag := model.Tag{}
+		err = rows.Scan(&tag.Tag, &tag.ArticleCount)
+		if err != nil {
+			return nil, err
+		}
+
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
+
+// likePrefix makes a LIKE pattern with escape character ! matching the strings that start with prefix.
+// Tags may contain _, which LIKE would take for a wildcard.
+func likePrefix(prefix string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(prefix) + "%"
+}
+
+func (s *SQLStore) RecountTags() error {
+	return s.transact(func(tx *sql.Tx) error {
+		_, err := s.exec(tx, "UPDATE tags SET article_count = (SELECT COUNT(*) FROM article_tags WHERE article_tags.tag = tags.tag)")
+		if err != nil {
+			return err
+		}
+
+		_, err = s.exec(tx, "INSERT INTO tags (tag, article_count) SELECT tag, COUNT(*) FROM article_tags"+
+			" WHERE tag NOT IN (SELECT tag FROM tags) GROUP BY tag")
+		if err != nil {
+			return err
+		}
+
+		_, err = s.exec(tx, "DELETE FROM tags WHERE article_count = 0 AND description = ''")
+		return err
+	})
+}
+
+func (s *SQLStore) PutTagDescription(tag string, description string) error {
+	_, err := s.exec(s.db, "INSERT INTO tags (tag, article_count, description) VALUES (?, 0, ?)"+
+		" ON CONFLICT (tag) DO UPDATE SET description = excluded.description", tag, description)
+	return err
+}
+
+func (s *SQLStore) DeleteTag(tag string) error {
+	_, err := s.exec(s.db, "DELETE FROM tags WHERE tag = ? AND article_count <= 0", tag)
+	if err != nil {
+		return err
+	}
+
+	var articleCount int64
+	err = s.queryRow(s.db, "SELECT article_count FROM tags WHERE tag = ?", tag).Scan(&articleCount)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return ErrConditionFailed
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"database/sql"
+
+	"realworld-go-nolambda/model"
+)
+
+func (s *SQLStore) PutTimelineEntries(entries []model.TimelineEntry) error {
+	return s.transact(func(tx *sql.Tx) error {
+		for _, entry := range entries {
+			_, err := s.exec(tx, "INSERT INTO timelines (username, article_id, created_at, author) VALUES (?, ?, ?, ?) "+
+				"ON CONFLICT (username, article_id) DO UPDATE SET created_at = excluded.created_at, author = excluded.author",
+				entry.Username, entry.ArticleId, entry.CreatedAt, entry.Author)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *SQLStore) DeleteTimelineEntries(keys []model.TimelineKey) error {
+	return s.transact(func(tx *sql.Tx) error {
+		for _, key := range keys {
+			_, err := s.exec(tx, "DELETE FROM timelines WHERE username = ? AND article_id = ?", key.Username, key.ArticleId)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *SQLStore) QueryTimeline(username string, page Page) ([]int64, *Cursor, error) {
+	after, afterArgs := keysetCondition(page.After, "created_at")
+	args := append([]interface{}{username}, afterArgs...)
+	args = append(args, page.Limit+1, page.Offset)
+
+	rows, err := s.query(s.db, "SELECT article_id, created_at FROM timelines WHERE username = ? AND "+after+
+		" ORDER BY created_at DESC, article_id DESC LIMIT ? OFFSET ?", args...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return scanPositionPage(rows, page.Limit)
+}
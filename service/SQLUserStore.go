@@ -0,0 +1,97 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+
+	"realworld-go-nolambda/model"
+)
+
+const sqlUserColumns = "username, email, password_hash, image, bio"
+
+func scanUser(row interface{ Scan(...interface{}) error }) (model.User, error) {
+	user := model.User{}
+	err := row.Scan(&user.Username, &user.Email, &user.PasswordHash, &user.Image, &user.Bio)
+	return user, err
+}
+
+func (s *SQLStore) PutUser(user model.User) error {
+	// Put a new user, make sure username and email are unique
+	return s.execAffectingOne(s.db, "INSERT INTO users ("+sqlUserColumns+") VALUES (?, ?, ?, ?, ?) ON CONFLICT DO NOTHING",
+		user.Username, user.Email, user.PasswordHash, user.Image, user.Bio)
+}
+
+func (s *SQLStore) UpdateUser(oldUser model.User, newUser model.User) error {
+	return s.transact(func(tx *sql.Tx) error {
+		if oldUser.Email != newUser.Email {
+			var taken int
+			err := s.queryRow(tx, "SELECT COUNT(*) FROM users WHERE email = ?", newUser.Email).Scan(&taken)
+			if err != nil {
+				return err
+			}
+
+			if taken != 0 {
+				return ErrConditionFailed
+			}
+		}
+
+		return s.execAffectingOne(tx, "UPDATE users SET email = ?, password_hash = ?, image = ?, bio = ? WHERE username = ? AND email = ?",
+			newUser.Email, newUser.PasswordHash, newUser.Image, newUser.Bio, oldUser.Username, oldUser.Email)
+	})
+}
+
+func (s *SQLStore) GetUser(username string) (model.User, bool, error) {
+	user, err := scanUser(s.queryRow(s.db, "SELECT "+sqlUserColumns+" FROM users WHERE username = ?", username))
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.User{}, false, nil
+	}
+
+	if err != nil {
+		return model.User{}, false, err
+	}
+
+	return user, true, nil
+}
+
+func (s *SQLStore) GetUsernameByEmail(email string) (string, bool, error) {
+	var username string
+	err := s.queryRow(s.db, "SELECT username FROM users WHERE email = ?", email).Scan(&username)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+
+	if err != nil {
+		return "", false, err
+	}
+
+	return username, true, nil
+}
+
+func (s *SQLStore) GetUsers(usernames []string) (map[string]model.User, error) {
+	usersByUsername := make(map[string]model.User)
+	if len(usernames) == 0 {
+		return usersByUsername, nil
+	}
+
+	args := make([]interface{}, 0, len(usernames))
+	for _, username := range usernames {
+		args = append(args, username)
+	}
+
+	rows, err := s.query(s.db, "SELECT "+sqlUserColumns+" FROM users WHERE username IN ("+placeholders(len(args))+")", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		usersByUsername[user.Username] = user
+	}
+
+	return usersByUsername, rows.Err()
+}
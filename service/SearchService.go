@@ -0,0 +1,469 @@
+package service
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"realworld-go-nolambda/model"
+	"realworld-go-nolambda/util"
+)
+
+// maxSearchClauses bounds the clauses of a query, each of which costs at least one store query.
+const maxSearchClauses = 10
+
+// minSearchPrefixLength is the fewest characters of a prefix, shorter ones match too many terms to be useful.
+const minSearchPrefixLength = 2
+
+// maxSearchPostings bounds the postings read per term or prefix. Only the newest articles of a common term are ranked.
+const maxSearchPostings = 1000
+
+// searchSnippetLength is the length in bytes a highlighted snippet aims for.
+const searchSnippetLength = 160
+
+// searchFieldWeights weighs an occurrence of a term by the field it occurs in.
+var searchFieldWeights = map[string]float64{
+	model.TitleField:       4,
+	model.TagListField:     3,
+	model.DescriptionField: 2,
+	model.BodyField:        1,
+}
+
+// Bonuses and penalties of the clauses that match more or less precisely than a single term.
+const (
+	searchPhraseWeight = 2
+	searchPrefixWeight = 0.5
+)
+
+// SearchClause is a part of a search query every result must match: a term, a phrase of consecutive terms
+// in a single field, or, if Prefix is set, any term starting with its single term.
+type SearchClause struct {
+	Terms  []string
+	Prefix bool
+}
+
+// SearchResult is an article matching a search, with its relevance and HTML snippets of the fields that match,
+// by field name, the matching words wrapped in util.HighlightStart and util.HighlightEnd.
+type SearchResult struct {
+	Article    model.Article
+	Score      float64
+	Highlights map[string]string
+}
+
+// ParseSearchQuery splits a query into clauses: words, "quoted phrases" and word* prefixes.
+// Words are split and lowercased the way articles are indexed, so "e-mail" is the phrase "e mail".
+func ParseSearchQuery(query string) ([]SearchClause, error) {
+	clauses := make([]SearchClause, 0)
+
+	for rest := strings.TrimSpace(query); rest != ""; rest = strings.TrimSpace(rest) {
+		var part string
+		prefix := false
+
+		if rest[0] == '"' {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				part, rest = rest[1:], ""
+			} else {
+				part, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else {
+			end := strings.IndexAny(rest, " \t\r\n\"")
+			if end < 0 {
+				end = len(rest)
+			}
+			part, rest = rest[:end], rest[end:]
+
+			prefix = strings.HasSuffix(part, "*")
+		}
+
+		terms := util.Terms(part)
+		if len(terms) == 0 {
+			continue
+		}
+
+		clause := SearchClause{Terms: terms, Prefix: prefix && len(terms) == 1}
+		if clause.Prefix && utf8.RuneCountInString(terms[0]) < minSearchPrefixLength {
+			return nil, model.NewInputError("q", "a prefix needs at least 2 characters")
+		}
+
+		clauses = append(clauses, clause)
+	}
+
+	if len(clauses) == 0 {
+		return nil, model.NewInputError("q", "can't be empty")
+	}
+
+	if len(clauses) > maxSearchClauses {
+		return nil, model.NewInputError("q", "can't have more than 10 words, phrases and prefixes")
+	}
+
+	return clauses, nil
+}
+
+// SearchArticles returns a page of the published articles matching every clause of query, most relevant first,
+// and the position to resume from for the next page, nil if there is none.
+// The sort key of the position is the bits of the score, which order like the score as it is never negative.
+func SearchArticles(query string, page Page) ([]SearchResult, *Cursor, error) {
+	err := validatePage(page)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	clauses, err := ParseSearchQuery(query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var scores map[int64]float64
+	for _, clause := range clauses {
+		clauseScores, err := matchSearchClause(clause)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if scores == nil {
+			scores = clauseScores
+			continue
+		}
+
+		for articleId, score := range scores {
+			clauseScore, ok := clauseScores[articleId]
+			if ok {
+				scores[articleId] = score + clauseScore
+			} else {
+				delete(scores, articleId)
+			}
+		}
+	}
+
+	ranked := make([]Cursor, 0, len(scores))
+	for articleId, score := range scores {
+		position := Cursor{SortKey: int64(math.Float64bits(score)), ArticleId: articleId}
+		if page.After.isAfter(position.SortKey, position.ArticleId) {
+			ranked = append(ranked, position)
+		}
+	}
+
+	sort.Slice(ranked, func(i, j int) bool {
+		return newerThan(ranked[i].SortKey, ranked[i].ArticleId, ranked[j].SortKey, ranked[j].ArticleId)
+	})
+
+	matches := searchMatcher(clauses)
+	results := make([]SearchResult, 0, page.Limit)
+
+	// Articles that are no longer live are skipped, and the page is filled from further down the ranking instead
+	start := util.MinInt(page.Offset, len(ranked))
+	for start < len(ranked) && len(results) < page.Limit {
+		end := util.MinInt(start+page.Limit-len(results), len(ranked))
+
+		articleIds := make([]int64, 0, end-start)
+		for _, position := range ranked[start:end] {
+			articleIds = append(articleIds, position.ArticleId)
+		}
+
+		articles, err := getArticlesByArticleIds(articleIds)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, article := range liveArticles(articles) {
+			results = append(results, SearchResult{
+				Article:    article,
+				Score:      scores[article.ArticleId],
+				Highlights: highlightArticle(article, matches),
+			})
+		}
+
+		start = end
+	}
+
+	var next *Cursor
+	if start < len(ranked) {
+		next = &ranked[start-1]
+	}
+
+	return results, next, nil
+}
+
+// matchSearchClause returns the score of every article matching clause.
+func matchSearchClause(clause SearchClause) (map[int64]float64, error) {
+	if clause.Prefix {
+		return matchSearchPrefix(clause.Terms[0])
+	}
+
+	postingsByTerm := make([]map[int64]model.SearchPosting, 0, len(clause.Terms))
+	idf := 0.0
+
+	for _, term := range clause.Terms {
+		postings, err := GetStore().QuerySearchPostings(term, maxSearchPostings)
+		if err != nil {
+			return nil, err
+		}
+
+		postingsByArticle := make(map[int64]model.SearchPosting, len(postings))
+		for _, posting := range postings {
+			postingsByArticle[posting.ArticleId] = posting
+		}
+
+		postingsByTerm = append(postingsByTerm, postingsByArticle)
+		idf += searchIdf(len(postings))
+	}
+
+	scores := make(map[int64]float64)
+	for articleId, posting := range postingsByTerm[0] {
+		if len(clause.Terms) == 1 {
+			scores[articleId] = idf * searchTermFrequency(posting.Positions)
+			continue
+		}
+
+		phrasePositions := matchSearchPhrase(articleId, postingsByTerm)
+		if len(phrasePositions) > 0 {
+			scores[articleId] = searchPhraseWeight * idf * searchTermFrequency(phrasePositions)
+		}
+	}
+
+	return scores, nil
+}
+
+// matchSearchPhrase returns the positions of the phrase made of the terms of postingsByTerm in each field of an article.
+func matchSearchPhrase(articleId int64, postingsByTerm []map[int64]model.SearchPosting) map[string][]int {
+	phrasePositions := make(map[string][]int)
+
+	for field, positions := range postingsByTerm[0][articleId].Positions {
+	nextPosition:
+		for _, position := range positions {
+			for i, postingsByArticle := range postingsByTerm[1:] {
+				posting, ok := postingsByArticle[articleId]
+				if !ok || !containsInt(posting.Positions[field], position+i+1) {
+					continue nextPosition
+				}
+			}
+
+			phrasePositions[field] = append(phrasePositions[field], position)
+		}
+	}
+
+	return phrasePositions
+}
+
+// matchSearchPrefix scores an article by the best of its terms starting with prefix, prefix itself counting in full.
+func matchSearchPrefix(prefix string) (map[int64]float64, error) {
+	postings, err := GetStore().QuerySearchPostingsByPrefix(prefix, maxSearchPostings)
+	if err != nil {
+		return nil, err
+	}
+
+	documentFrequencies := make(map[string]int)
+	for _, posting := range postings {
+		documentFrequencies[posting.Term]++
+	}
+
+	scores := make(map[int64]float64)
+	for _, posting := range postings {
+		score := searchIdf(documentFrequencies[posting.Term]) * searchTermFrequency(posting.Positions)
+		if posting.Term != prefix {
+			score *= searchPrefixWeight
+		}
+
+		scores[posting.ArticleId] = math.Max(scores[posting.ArticleId], score)
+	}
+
+	return scores, nil
+}
+
+// searchIdf weighs a term by its rarity, given the number of articles it occurs in.
+// The index doesn't count articles, so maxSearchPostings stands in for their number.
+func searchIdf(documentFrequency int) float64 {
+	return math.Log(1 + float64(maxSearchPostings)/float64(util.MaxInt(documentFrequency, 1)))
+}
+
+// searchTermFrequency weighs the occurrences of a term in each field of an article, with diminishing returns.
+func searchTermFrequency(positions map[string][]int) float64 {
+	frequency := 0.0
+	for field, fieldPositions := range positions {
+		if len(fieldPositions) > 0 {
+			frequency += searchFieldWeights[field] * (1 + math.Log(float64(len(fieldPositions))))
+		}
+	}
+
+	return frequency
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// searchMatcher returns whether a term is one of the terms of clauses, or starts with one of their prefixes.
+func searchMatcher(clauses []SearchClause) func(term string) bool {
+	terms := make(map[string]bool)
+	prefixes := make([]string, 0)
+
+	for _, clause := range clauses {
+		if clause.Prefix {
+			prefixes = append(prefixes, clause.Terms[0])
+			continue
+		}
+
+		for _, term := range clause.Terms {
+			terms[term] = true
+		}
+	}
+
+	return func(term string) bool {
+		if terms[term] {
+			return true
+		}
+
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(term, prefix) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+func highlightArticle(article model.Article, matches func(term string) bool) map[string]string {
+	highlights := make(map[string]string)
+
+	fields := map[string]string{
+		model.TitleField:       article.Title,
+		model.DescriptionField: article.Description,
+		model.BodyField:        article.Body,
+		model.TagListField:     strings.Join(article.TagList, ", "),
+	}
+
+	for field, text := range fields {
+		snippet := util.Highlight(text, matches, searchSnippetLength)
+		if snippet != "" {
+			highlights[field] = snippet
+		}
+	}
+
+	return highlights
+}
+
+// newSearchPostings indexes the fields of a published article, one posting per term, in term order.
+// Tags are separated by a position, so a phrase never spans two tags. Unpublished articles aren't indexed.
+func newSearchPostings(article model.Article) []model.SearchPosting {
+	if article.ArticleId == 0 || !article.IsPublished() {
+		return nil
+	}
+
+	postingsByTerm := make(map[string]*model.SearchPosting)
+	addField := func(field string, text string, position int) int {
+		for _, term := range util.Terms(text) {
+			posting, ok := postingsByTerm[term]
+			if !ok {
+				posting = &model.SearchPosting{
+					SearchPostingKey: model.SearchPostingKey{Term: term, ArticleId: article.ArticleId},
+					Positions:        make(map[string][]int),
+					TermHead:         util.FirstRune(term),
+				}
+				postingsByTerm[term] = posting
+			}
+
+			posting.Positions[field] = append(posting.Positions[field], position)
+			position++
+		}
+
+		return position
+	}
+
+	addField(model.TitleField, article.Title, 0)
+	addField(model.DescriptionField, article.Description, 0)
+	addField(model.BodyField, article.Body, 0)
+
+	position := 0
+	for _, tag := range article.TagList {
+		position = addField(model.TagListField, tag, position) + 1
+	}
+
+	postings := make([]model.SearchPosting, 0, len(postingsByTerm))
+	for _, posting := range postingsByTerm {
+		postings = append(postings, *posting)
+	}
+
+	sort.Slice(postings, func(i, j int) bool {
+		return postings[i].Term < postings[j].Term
+	})
+
+	return postings
+}
+
+// updateSearchIndex replaces the postings of oldArticle with those of newArticle, writing only the ones that changed.
+// The zero Article stands for an article that doesn't exist. The index isn't updated in the same transaction as
+// the article, ReindexArticles repairs what an interrupted update leaves behind.
+func updateSearchIndex(oldArticle model.Article, newArticle model.Article) error {
+	oldPostings := make(map[string]model.SearchPosting)
+	for _, posting := range newSearchPostings(oldArticle) {
+		oldPostings[posting.Term] = posting
+	}
+
+	changedPostings := make([]model.SearchPosting, 0)
+	for _, posting := range newSearchPostings(newArticle) {
+		oldPosting, ok := oldPostings[posting.Term]
+		if !ok || !reflect.DeepEqual(oldPosting.Positions, posting.Positions) {
+			changedPostings = append(changedPostings, posting)
+		}
+
+		delete(oldPostings, posting.Term)
+	}
+
+	if len(changedPostings) > 0 {
+		err := GetStore().PutSearchPostings(changedPostings)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(oldPostings) == 0 {
+		return nil
+	}
+
+	staleKeys := make([]model.SearchPostingKey, 0, len(oldPostings))
+	for _, posting := range oldPostings {
+		staleKeys = append(staleKeys, posting.SearchPostingKey)
+	}
+
+	return GetStore().DeleteSearchPostings(staleKeys)
+}
+
+// ReindexArticles writes the postings of every published article, e.g. for articles written before search existed,
+// and returns the number of articles indexed. Postings of terms an article no longer contains can't be found
+// from the article, so they are left behind.
+func ReindexArticles() (int, error) {
+	numArticles := 0
+	page := Page{Limit: 100}
+
+	for {
+		articles, next, err := GetStore().QueryArticles(page)
+		if err != nil {
+			return numArticles, err
+		}
+
+		for _, article := range articles {
+			err = GetStore().PutSearchPostings(newSearchPostings(article))
+			if err != nil {
+				return numArticles, err
+			}
+		}
+
+		numArticles += len(articles)
+
+		if next == nil {
+			return numArticles, nil
+		}
+
+		page.After = next
+	}
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"testing"
+
+	"realworld-go-nolambda/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreSearchArticles(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		newTestUser(t, "alice")
+
+		putArticle := func(title, body string, tags ...string) model.Article {
+			article := model.Article{
+				Title:       title,
+				Description: "Description",
+				Body:        body,
+				TagList:     tags,
+				CreatedAt:   1,
+				UpdatedAt:   1,
+				Author:      "alice",
+			}
+			assert.NoError(t, PutArticle(&article))
+			return article
+		}
+
+		goTitle := putArticle("Concurrency in Go", "Goroutines and channels.", "go")
+		goBody := putArticle("Channels", "Go channels are typed conduits. Unlike queues, channels block.")
+		rust := putArticle("Ownership in Rust", "Borrowing without a garbage collector.", "rust")
+
+		search := func(query string) []string {
+			results, _, err := SearchArticles(query, Page{Limit: 10})
+			assert.NoError(t, err, query)
+
+			slugs := make([]string, 0, len(results))
+			for _, result := range results {
+				slugs = append(slugs, result.Article.Slug)
+			}
+			return slugs
+		}
+
+		// A match in the title outweighs one in the body
+		assert.Equal(t, []string{goTitle.Slug, goBody.Slug}, search("go"))
+		assert.Equal(t, []string{goBody.Slug}, search("GO typed"))
+		assert.Equal(t, []string{goBody.Slug}, search(`"channels are typed"`))
+		assert.Empty(t, search(`"typed channels"`))
+		assert.Equal(t, []string{goTitle.Slug}, search("gorout*"))
+		assert.Equal(t, []string{rust.Slug}, search("rust"))
+		assert.Empty(t, search("python"))
+
+		_, _, err := SearchArticles("  ", Page{Limit: 10})
+		assert.Error(t, err)
+		_, _, err = SearchArticles("g*", Page{Limit: 10})
+		assert.Error(t, err)
+
+		results, _, err := SearchArticles("typed", Page{Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, "Go channels are <mark>typed</mark> conduits. Unlike queues, channels block.", results[0].Highlights[model.BodyField])
+
+		// Pages resume after the last result
+		results, next, err := SearchArticles("channels", Page{Limit: 1})
+		assert.NoError(t, err)
+		assert.Len(t, results, 1)
+		assert.NotNil(t, next)
+		rest, next, err := SearchArticles("channels", Page{Limit: 1, After: next})
+		assert.NoError(t, err)
+		assert.Len(t, rest, 1)
+		assert.Nil(t, next)
+		assert.NotEqual(t, results[0].Article.Slug, rest[0].Article.Slug)
+
+		// Edits, unpublishing and deletes keep the index up to date
+		edited := rust
+		edited.Body = "Lifetimes."
+		assert.NoError(t, UpdateArticle(rust, &edited, "alice", ""))
+		assert.Empty(t, search("borrowing"))
+		assert.Equal(t, []string{rust.Slug}, search("lifetimes"))
+
+		_, err = UnpublishArticle(goBody.Slug, "alice", "")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{goTitle.Slug}, search("channels"))
+
+		assert.NoError(t, DeleteArticle(goTitle.Slug, "alice", ""))
+		assert.Empty(t, search("channels"))
+
+		assert.NoError(t, CleanupDeletedArticles())
+		postings, err := GetStore().QuerySearchPostingsByPrefix("chan", 10)
+		assert.NoError(t, err)
+		assert.Empty(t, postings)
+	})
+}
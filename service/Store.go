@@ -0,0 +1,279 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"realworld-go-nolambda/model"
+)
+
+// ErrConditionFailed is returned by a store when a conditional write is rejected,
+// e.g. a primary key is already taken or the item to update doesn't exist.
+var ErrConditionFailed = errors.New("condition failed")
+
+type UserStore interface {
+	// PutUser inserts a new user. Both username and email must be unused.
+	PutUser(user model.User) error
+	// UpdateUser replaces oldUser with newUser, moving the email link if it changed.
+	UpdateUser(oldUser model.User, newUser model.User) error
+	GetUser(username string) (model.User, bool, error)
+	GetUsernameByEmail(email string) (string, bool, error)
+	// GetUsers returns the users found, keyed by username.
+	GetUsers(usernames []string) (map[string]model.User, error)
+}
+
+type ArticleStore interface {
+	// PutArticle inserts a new article together with its first revision, its tag links and tag counts.
+	// Only published articles are linked with their tags, see model.Article.LinkedTags.
+	// The article id must be unused.
+	PutArticle(article model.Article, revision model.ArticleRevision) error
+	// UpdateArticle replaces oldArticle with newArticle, relinking tags that changed, and adds revisions.
+	// The stored article's UpdatedAt must still equal oldArticle's, and the revision numbers must be unused.
+	UpdateArticle(oldArticle model.Article, newArticle model.Article, revisions []model.ArticleRevision) error
+	// DeleteArticle removes an article written by cleanup.Author, together with its tag links,
+	// and adds cleanup to remove its other items. The id stays taken until CleanupStore finishes.
+	// The stored article's UpdatedAt must still equal article's.
+	DeleteArticle(article model.Article, cleanup model.ArticleCleanup) error
+	GetArticle(articleId int64) (model.Article, bool, error)
+	// GetArticlesByIds returns the articles found, keyed by article id.
+	GetArticlesByIds(articleIds []int64) (map[int64]model.Article, error)
+	// QueryArticles returns a page of published articles newest first,
+	// and the position of the last one if more may follow, nil otherwise.
+	QueryArticles(page Page) ([]model.Article, *Cursor, error)
+	// QueryArticlesByAuthor returns a page of published articles of an author newest first, like QueryArticles.
+	QueryArticlesByAuthor(author string, page Page) ([]model.Article, *Cursor, error)
+	// QueryDraftsByAuthor returns a page of the draft and archived articles of an author newest first, like QueryArticles.
+	QueryDraftsByAuthor(author string, page Page) ([]model.Article, *Cursor, error)
+	// ScanArticles returns a page of the published articles selected by scan in its order,
+	// and the position of the last one if more may follow. The sort key of the position is the key of the order.
+	ScanArticles(scan ArticleScan, page Page) ([]model.Article, *Cursor, error)
+}
+
+// RevisionStore reads the revisions written by ArticleStore.
+type RevisionStore interface {
+	GetArticleRevision(key model.ArticleRevisionKey) (model.ArticleRevision, bool, error)
+	// QueryArticleRevisions returns a page of revisions of an article newest first,
+	// and the position of the last one if more may follow. The sort key of the position is Revision.
+	QueryArticleRevisions(articleId int64, page Page) ([]model.ArticleRevision, *Cursor, error)
+}
+
+type TagStore interface {
+	// QueryArticleIdsByTag returns a page of ids of the articles linked with a tag newest first,
+	// and the position of the last one if more may follow. The sort key of the position is CreatedAt.
+	QueryArticleIdsByTag(tag string, page Page) ([]int64, *Cursor, error)
+	// ScanArticleIdsByTag returns a page of ids of the articles linked with a tag created within the range of scan,
+	// like QueryArticleIdsByTag. The order of scan must be OrderNewest or OrderOldest, and its author is ignored.
+	ScanArticleIdsByTag(tag string, scan ArticleScan, page Page) ([]int64, *Cursor, error)
+	// GetTags returns the tags found, keyed by tag.
+	GetTags(tags []string) (map[string]model.Tag, error)
+	// QueryTags returns a page of the tags with articles whose name starts with prefix, most articles first,
+	// and the position of the last one if more may follow. The sort key of the position is ArticleCount.
+	// Ties are ordered by tag, except in DynamoDB where their order is stable but arbitrary.
+	QueryTags(prefix string, page Page) ([]model.Tag, *Cursor, error)
+	// QueryTrendingTags returns up to limit tags linked with the most articles created since the given time,
+	// most first then by tag, with ArticleCount set to the number of those articles.
+	QueryTrendingTags(since int64, limit int) ([]model.Tag, error)
+	// RecountTags sets the article count of every tag to the number of articles linked with it,
+	// and deletes the tags without articles or description. Links written concurrently may be miscounted.
+	RecountTags() error
+	// PutTagDescription sets the description of a tag, creating the tag without articles if it doesn't exist.
+	PutTagDescription(tag string, description string) error
+	// DeleteTag deletes a tag unless articles are linked with it, in which case it returns ErrConditionFailed.
+	DeleteTag(tag string) error
+}
+
+type TagAliasStore interface {
+	// PutTagAlias creates or replaces an alias.
+	PutTagAlias(alias model.TagAlias) error
+	// DeleteTagAlias deletes an alias, if it exists.
+	DeleteTagAlias(alias string) error
+	// GetTagAliases returns the aliases found, keyed by alias.
+	GetTagAliases(aliases []string) (map[string]model.TagAlias, error)
+	// QueryTagAliases returns the aliases of a tag, in no particular order.
+	QueryTagAliases(tag string) ([]model.TagAlias, error)
+}
+
+type CommentStore interface {
+	// PutComment inserts a new comment and increments the CommentsCount of its article, which must exist.
+	// The comment id must be unused. A reply increments the ReplyCount of its parent, which must exist and not be deleted.
+	PutComment(comment model.Comment) error
+	// GetComment returns a comment and whether it was found.
+	GetComment(key model.CommentKey) (model.Comment, bool, error)
+	// QueryComments returns a page of the top-level comments of an article, in order OrderNewest, OrderOldest or OrderTop,
+	// and the position of the last one if more may follow. The sort key of the position is Upvotes for OrderTop,
+	// CreatedAt otherwise.
+	QueryComments(articleId int64, order string, page Page) ([]model.Comment, *Cursor, error)
+	// QueryCommentReplies returns a page of the direct replies to a comment of an article, oldest first,
+	// and the position of the last one if more may follow. The sort key of the position is CreatedAt.
+	QueryCommentReplies(articleId int64, parentId int64, page Page) ([]model.Comment, *Cursor, error)
+	// DeleteComment removes a comment as read, unless its author changed or it has replies,
+	// and decrements the ReplyCount of its parent and, unless it is a tombstone, the CommentsCount of its article.
+	DeleteComment(comment model.Comment) error
+	// TombstoneComment clears the body and reaction counts of a comment with replies, marks it deleted at deletedAt and
+	// decrements the CommentsCount of its article. It returns ErrConditionFailed if the comment has no replies or is deleted already.
+	TombstoneComment(key model.CommentKey, deletedAt int64) error
+	// RecountComments sets the CommentsCount of every article to the number of its comments that aren't deleted,
+	// and returns the ids of the articles whose count changed. Comments written concurrently may be miscounted.
+	RecountComments() ([]int64, error)
+	// UpdateComment replaces a comment as read with newComment and keeps the replaced body as edit,
+	// unless the comment was edited or deleted meanwhile.
+	UpdateComment(oldComment model.Comment, newComment model.Comment, edit model.CommentEdit) error
+	// QueryCommentEdits returns the edits of a comment, newest first.
+	QueryCommentEdits(key model.CommentKey) ([]model.CommentEdit, error)
+	// DeleteCommentEdits removes the edits of a comment.
+	DeleteCommentEdits(key model.CommentKey) error
+	// PutCommentReaction adds a reaction to a comment that isn't deleted and increments its count on the comment,
+	// Upvotes or Reactions. The user must not have added the same reaction to the comment yet.
+	PutCommentReaction(reaction model.CommentReaction) error
+	// DeleteCommentReaction removes a reaction from a comment that isn't deleted and decrements its count.
+	DeleteCommentReaction(key model.CommentReactionKey) error
+	// GetUserCommentReactions returns the reactions username added to any of the given comments of an article,
+	// by comment id, in the order of model.Reactions.
+	GetUserCommentReactions(username string, articleId int64, commentIds []int64) (map[int64][]string, error)
+	// DeleteCommentReactions removes the reactions to a comment, leaving its counts as they are.
+	DeleteCommentReactions(key model.CommentKey) error
+}
+
+type FollowStore interface {
+	PutFollow(follow model.Follow) error
+	DeleteFollow(follow model.Follow) error
+	// QueryPublishers returns the usernames followed by follower.
+	QueryPublishers(follower string) ([]string, error)
+	// QueryFollowers returns the usernames following publisher.
+	QueryFollowers(publisher string) ([]string, error)
+	// GetFollowedPublishers returns the subset of publishers followed by follower.
+	GetFollowedPublishers(follower string, publishers []string) (map[string]bool, error)
+}
+
+type FavoriteStore interface {
+	// PutFavoriteArticle favorites an existing article and increments its favorites count.
+	PutFavoriteArticle(favoriteArticle model.FavoriteArticle) error
+	// DeleteFavoriteArticle unfavorites an article and decrements its favorites count.
+	DeleteFavoriteArticle(key model.FavoriteArticleKey) error
+	// QueryFavoriteArticleIds returns a page of ids of the articles favorited by username latest first,
+	// and the position of the last one if more may follow. The sort key of the position is FavoritedAt.
+	QueryFavoriteArticleIds(username string, page Page) ([]int64, *Cursor, error)
+	// GetFavoritedArticleIds returns the subset of articleIds favorited by username.
+	GetFavoritedArticleIds(username string, articleIds []int64) (map[int64]bool, error)
+}
+
+// CleanupStore removes what deleted articles leave behind, in batches that can be resumed after a crash.
+type CleanupStore interface {
+	// QueryArticleCleanups returns up to limit articles marked for cleanup.
+	QueryArticleCleanups(limit int) ([]model.ArticleCleanup, error)
+	// DeleteArticleItems removes up to limit comments, comment edits, favorites and revisions each of a deleted article,
+	// returning how many items were removed.
+	DeleteArticleItems(articleId int64, limit int) (int, error)
+	// DeleteArticleCleanup unmarks an article whose items are all removed, freeing its id.
+	DeleteArticleCleanup(articleId int64) error
+}
+
+// TimelineStore keeps the personal feeds built on write, see FeedModeWrite.
+type TimelineStore interface {
+	// PutTimelineEntries adds articles to timelines, overwriting entries that already exist.
+	PutTimelineEntries(entries []model.TimelineEntry) error
+	// DeleteTimelineEntries removes articles from timelines. Missing entries are ignored.
+	DeleteTimelineEntries(keys []model.TimelineKey) error
+	// QueryTimeline returns a page of article ids of a timeline newest first,
+	// and the position of the last one if more may follow. The sort key of the position is CreatedAt.
+	QueryTimeline(username string, page Page) ([]int64, *Cursor, error)
+}
+
+// ScheduleStore keeps the reminders of RunArticleScheduler.
+type ScheduleStore interface {
+	// PutArticleSchedules adds reminders, rescheduling those that already exist.
+	PutArticleSchedules(schedules []model.ArticleSchedule) error
+	// QueryArticleSchedules returns up to limit reminders due at or before until, earliest first.
+	QueryArticleSchedules(until int64, limit int) ([]model.ArticleSchedule, error)
+	// DeleteArticleSchedule removes a reminder, failing if it was rescheduled or removed since it was read.
+	DeleteArticleSchedule(schedule model.ArticleSchedule) error
+}
+
+// SearchStore keeps the inverted index of published articles searched by SearchArticles.
+type SearchStore interface {
+	// PutSearchPostings adds postings, replacing those with the same term and article.
+	PutSearchPostings(postings []model.SearchPosting) error
+	// DeleteSearchPostings removes postings. Missing postings are ignored.
+	DeleteSearchPostings(keys []model.SearchPostingKey) error
+	// QuerySearchPostings returns up to limit postings of term, newest article first.
+	QuerySearchPostings(term string, limit int) ([]model.SearchPosting, error)
+	// QuerySearchPostingsByPrefix returns up to limit postings of the terms starting with prefix, in term order.
+	QuerySearchPostingsByPrefix(prefix string, limit int) ([]model.SearchPosting, error)
+}
+
+type CounterStore interface {
+	// IncrementCounter atomically adds one to a named counter and returns the new value, starting at 1.
+	IncrementCounter(name string) (int64, error)
+}
+
+// Store is everything the service layer needs from a persistence backend.
+type Store interface {
+	UserStore
+	ArticleStore
+	RevisionStore
+	TagStore
+	TagAliasStore
+	CommentStore
+	FollowStore
+	FavoriteStore
+	CleanupStore
+	CounterStore
+	TimelineStore
+	ScheduleStore
+	SearchStore
+}
+
+var storeMutex sync.RWMutex
+var currentStore Store
+
+// SetStore replaces the backend used by the service layer.
+func SetStore(store Store) {
+	storeMutex.Lock()
+	defer storeMutex.Unlock()
+	currentStore = store
+}
+
+// GetStore returns the backend used by the service layer, DynamoDB unless SetStore was called.
+func GetStore() Store {
+	storeMutex.RLock()
+	store := currentStore
+	storeMutex.RUnlock()
+
+	if store != nil {
+		return store
+	}
+
+	storeMutex.Lock()
+	defer storeMutex.Unlock()
+	if currentStore == nil {
+		currentStore = NewDynamoDBStore()
+	}
+	return currentStore
+}
+
+const (
+	BackendDynamoDB = "dynamodb"
+	BackendMemory   = "memory"
+	BackendSQLite   = "sqlite"
+	BackendPostgres = "postgres"
+)
+
+// OpenStore creates the backend named by backend. dataSource is the database file or connection string for SQL backends.
+func OpenStore(backend, dataSource string) (Store, error) {
+	switch backend {
+	case "", BackendDynamoDB:
+		return NewDynamoDBStore(), nil
+	case BackendMemory:
+		return NewMemoryStore(), nil
+	case BackendSQLite:
+		if dataSource == "" {
+			dataSource = "realworld.db"
+		}
+		return NewSQLStore(DialectSQLite, dataSource)
+	case BackendPostgres:
+		return NewSQLStore(DialectPostgres, dataSource)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", backend)
+	}
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"realworld-go-nolambda/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// forEachStore runs test against every backend that needs no outside services.
+func forEachStore(t *testing.T, test func(t *testing.T)) {
+	t.Run("memory", func(t *testing.T) {
+		SetStore(NewMemoryStore())
+		test(t)
+	})
+
+	t.Run("cached", func(t *testing.T) {
+		SetStore(NewCachingStore(NewMemoryStore(), NewLRUCache(1000, time.Minute)))
+		test(t)
+	})
+
+	t.Run("sqlite", func(t *testing.T) {
+		store, err := NewSQLStore(DialectSQLite, ":memory:")
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer store.Close()
+
+		SetStore(store)
+		test(t)
+	})
+}
+
+func newTestUser(t *testing.T, username string) model.User {
+	user := model.User{
+		Username:     username,
+		Email:        username + "@example.com",
+		PasswordHash: make([]byte, model.PasswordKeyLength),
+	}
+	assert.NoError(t, PutUser(user))
+	return user
+}
+
+func newTestArticle(t *testing.T, author string, createdAt int64, tags ...string) model.Article {
+	article := model.Article{
+		Title:       "Title",
+		Description: "Description",
+		Body:        "Body",
+		TagList:     tags,
+		CreatedAt:   createdAt,
+		UpdatedAt:   createdAt,
+		Author:      author,
+	}
+	assert.NoError(t, PutArticle(&article))
+	return article
+}
+
+// newTestComment comments on an article, replying to parentId unless it is 0.
+func newTestComment(t *testing.T, article model.Article, author string, createdAt int64, parentId int64) model.Comment {
+	comment := model.Comment{
+		CommentKey: model.CommentKey{ArticleId: article.ArticleId},
+		CreatedAt:  createdAt,
+		UpdatedAt:  createdAt,
+		Body:       "Nice",
+		Author:     author,
+		ParentId:   parentId,
+	}
+	assert.NoError(t, PutComment(&comment))
+	return comment
+}
+
+func favoriteTestArticle(t *testing.T, username string, article model.Article, favoritedAt int64) model.FavoriteArticle {
+	favorite := model.FavoriteArticle{
+		FavoriteArticleKey: model.FavoriteArticleKey{Username: username, ArticleId: article.ArticleId},
+		FavoritedAt:        favoritedAt,
+	}
+	assert.NoError(t, SetFavoriteArticle(favorite))
+	return favorite
+}
+
+// readAllArticles reads a listing two articles at a time and returns the slugs of all pages.
+// Cursors are passed back through their token, as clients do.
+func readAllArticles(t *testing.T, listing func(page Page) ([]model.Article, *Cursor, error)) []string {
+	slugs := make([]string, 0)
+	page := Page{Limit: 2}
+
+	for {
+		articles, next, err := listing(page)
+		if !assert.NoError(t, err) {
+			return slugs
+		}
+
+		slugs = append(slugs, articleSlugs(articles)...)
+		if next == nil {
+			return slugs
+		}
+
+		page.After, err = DecodeCursor(next.Encode())
+		assert.NoError(t, err)
+	}
+}
+
+func articleSlugs(articles []model.Article) []string {
+	slugs := make([]string, 0, len(articles))
+	for _, article := range articles {
+		slugs = append(slugs, article.Slug)
+	}
+	return slugs
+}
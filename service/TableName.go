@@ -5,17 +5,56 @@ import (
 	"os"
 )
 
+var TablePrefix = "realworld"
 var Stage = os.Getenv("STAGE")
 
 var UserTableName = makeTableName("user")
 var EmailUserTableName = makeTableName("email-user")
 var FollowTableName = makeTableName("follow")
 var ArticleTableName = makeTableName("article")
+var ArticleRevisionTableName = makeTableName("article-revision")
+var ArticleScheduleTableName = makeTableName("article-schedule")
 var ArticleTagTableName = makeTableName("article-tag")
 var TagTableName = makeTableName("tag")
+var TagAliasTableName = makeTableName("tag-alias")
 var FavoriteArticleTableName = makeTableName("favorite-article")
 var CommentTableName = makeTableName("comment")
+var CommentEditTableName = makeTableName("comment-edit")
+var CommentReactionTableName = makeTableName("comment-reaction")
+var ArticleCleanupTableName = makeTableName("article-cleanup")
+var CounterTableName = makeTableName("counter")
+var TimelineTableName = makeTableName("timeline")
+var SearchPostingTableName = makeTableName("search-posting")
 
 func makeTableName(suffix string) string {
-	return fmt.Sprintf("realworld-%s-%s", Stage, suffix)
+	return fmt.Sprintf("%s-%s-%s", TablePrefix, Stage, suffix)
+}
+
+// SetStage renames every table after stage. It must be called before the tables are used.
+func SetStage(stage string) {
+	SetTableNames(TablePrefix, stage)
+}
+
+// SetTableNames renames every table after prefix and stage. It must be called before the tables are used.
+func SetTableNames(prefix, stage string) {
+	TablePrefix = prefix
+	Stage = stage
+
+	UserTableName = makeTableName("user")
+	EmailUserTableName = makeTableName("email-user")
+	FollowTableName = makeTableName("follow")
+	ArticleTableName = makeTableName("article")
+	ArticleRevisionTableName = makeTableName("article-revision")
+	ArticleScheduleTableName = makeTableName("article-schedule")
+	ArticleTagTableName = makeTableName("article-tag")
+	TagTableName = makeTableName("tag")
+	TagAliasTableName = makeTableName("tag-alias")
+	FavoriteArticleTableName = makeTableName("favorite-article")
+	CommentTableName = makeTableName("comment")
+	CommentEditTableName = makeTableName("comment-edit")
+	CommentReactionTableName = makeTableName("comment-reaction")
+	ArticleCleanupTableName = makeTableName("article-cleanup")
+	CounterTableName = makeTableName("counter")
+	TimelineTableName = makeTableName("timeline")
+	SearchPostingTableName = makeTableName("search-posting")
 }
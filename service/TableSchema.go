@@ -0,0 +1,250 @@
+package service
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// KeyAttribute is a key attribute of a table or index. Type is a DynamoDB scalar type: S, N or B.
+type KeyAttribute struct {
+	Name string
+	Type string
+}
+
+type IndexSchema struct {
+	Name     string
+	HashKey  KeyAttribute
+	RangeKey *KeyAttribute
+}
+
+type TableSchema struct {
+	Name     string
+	HashKey  KeyAttribute
+	RangeKey *KeyAttribute
+	Indexes  []IndexSchema
+}
+
+var (
+	usernameAttribute       = KeyAttribute{"Username", dynamodb.ScalarAttributeTypeS}
+	emailAttribute          = KeyAttribute{"Email", dynamodb.ScalarAttributeTypeS}
+	followerAttribute       = KeyAttribute{"Follower", dynamodb.ScalarAttributeTypeS}
+	publisherAttribute      = KeyAttribute{"Publisher", dynamodb.ScalarAttributeTypeS}
+	articleIdAttribute      = KeyAttribute{"ArticleId", dynamodb.ScalarAttributeTypeN}
+	createdAtAttribute      = KeyAttribute{"CreatedAt", dynamodb.ScalarAttributeTypeN}
+	dummyAttribute          = KeyAttribute{"Dummy", dynamodb.ScalarAttributeTypeN}
+	authorAttribute         = KeyAttribute{"Author", dynamodb.ScalarAttributeTypeS}
+	tagAttribute            = KeyAttribute{"Tag", dynamodb.ScalarAttributeTypeS}
+	articleCountAttribute   = KeyAttribute{"ArticleCount", dynamodb.ScalarAttributeTypeN}
+	favoritedAtAttribute    = KeyAttribute{"FavoritedAt", dynamodb.ScalarAttributeTypeN}
+	commentIdAttribute      = KeyAttribute{"CommentId", dynamodb.ScalarAttributeTypeN}
+	nameAttribute           = KeyAttribute{"Name", dynamodb.ScalarAttributeTypeS}
+	revisionAttribute       = KeyAttribute{"Revision", dynamodb.ScalarAttributeTypeN}
+	actionAttribute         = KeyAttribute{"Action", dynamodb.ScalarAttributeTypeS}
+	dueAtAttribute          = KeyAttribute{"DueAt", dynamodb.ScalarAttributeTypeN}
+	termAttribute           = KeyAttribute{"Term", dynamodb.ScalarAttributeTypeS}
+	termHeadAttribute       = KeyAttribute{"TermHead", dynamodb.ScalarAttributeTypeS}
+	favoritesCountAttribute = KeyAttribute{"FavoritesCount", dynamodb.ScalarAttributeTypeN}
+	updatedAtAttribute      = KeyAttribute{"UpdatedAt", dynamodb.ScalarAttributeTypeN}
+	aliasAttribute          = KeyAttribute{"Alias", dynamodb.ScalarAttributeTypeS}
+	editIdAttribute         = KeyAttribute{"EditId", dynamodb.ScalarAttributeTypeN}
+	parentIdAttribute       = KeyAttribute{"ParentId", dynamodb.ScalarAttributeTypeN}
+	upvotesAttribute        = KeyAttribute{"Upvotes", dynamodb.ScalarAttributeTypeN}
+	reactionIdAttribute     = KeyAttribute{"ReactionId", dynamodb.ScalarAttributeTypeS}
+)
+
+// TableSchemas describes every table of the current stage, with the secondary indexes the queries depend on.
+func TableSchemas() []TableSchema {
+	return []TableSchema{
+		{
+			Name:    UserTableName,
+			HashKey: usernameAttribute,
+		},
+		{
+			Name:    EmailUserTableName,
+			HashKey: emailAttribute,
+		},
+		{
+			Name:     FollowTableName,
+			HashKey:  followerAttribute,
+			RangeKey: &publisherAttribute,
+			Indexes: []IndexSchema{
+				{Name: "Publisher", HashKey: publisherAttribute, RangeKey: &followerAttribute},
+			},
+		},
+		{
+			Name:    ArticleTableName,
+			HashKey: articleIdAttribute,
+			Indexes: []IndexSchema{
+				{Name: "CreatedAt", HashKey: dummyAttribute, RangeKey: &createdAtAttribute},
+				{Name: "Author", HashKey: authorAttribute, RangeKey: &createdAtAttribute},
+				{Name: "FavoritesCount", HashKey: dummyAttribute, RangeKey: &favoritesCountAttribute},
+				{Name: "UpdatedAt", HashKey: dummyAttribute, RangeKey: &updatedAtAttribute},
+			},
+		},
+		{
+			Name:     ArticleRevisionTableName,
+			HashKey:  articleIdAttribute,
+			RangeKey: &revisionAttribute,
+		},
+		{
+			Name:     ArticleScheduleTableName,
+			HashKey:  articleIdAttribute,
+			RangeKey: &actionAttribute,
+			Indexes: []IndexSchema{
+				{Name: "DueAt", HashKey: dummyAttribute, RangeKey: &dueAtAttribute},
+			},
+		},
+		{
+			Name:     ArticleTagTableName,
+			HashKey:  tagAttribute,
+			RangeKey: &articleIdAttribute,
+			Indexes: []IndexSchema{
+				{Name: "CreatedAt", HashKey: tagAttribute, RangeKey: &createdAtAttribute},
+				{Name: "Recent", HashKey: dummyAttribute, RangeKey: &createdAtAttribute},
+			},
+		},
+		{
+			Name:    TagTableName,
+			HashKey: tagAttribute,
+			Indexes: []IndexSchema{
+				{Name: "ArticleCount", HashKey: dummyAttribute, RangeKey: &articleCountAttribute},
+			},
+		},
+		{
+			Name:    TagAliasTableName,
+			HashKey: aliasAttribute,
+			Indexes: []IndexSchema{
+				{Name: "Tag", HashKey: tagAttribute, RangeKey: &aliasAttribute},
+			},
+		},
+		{
+			Name:     FavoriteArticleTableName,
+			HashKey:  usernameAttribute,
+			RangeKey: &articleIdAttribute,
+			Indexes: []IndexSchema{
+				{Name: "FavoritedAt", HashKey: usernameAttribute, RangeKey: &favoritedAtAttribute},
+				{Name: "ArticleId", HashKey: articleIdAttribute, RangeKey: &usernameAttribute},
+			},
+		},
+		{
+			Name:     CommentTableName,
+			HashKey:  articleIdAttribute,
+			RangeKey: &commentIdAttribute,
+			Indexes: []IndexSchema{
+				{Name: "CreatedAt", HashKey: articleIdAttribute, RangeKey: &createdAtAttribute},
+				{Name: "ParentId", HashKey: parentIdAttribute, RangeKey: &createdAtAttribute},
+				{Name: "Upvotes", HashKey: articleIdAttribute, RangeKey: &upvotesAttribute},
+			},
+		},
+		{
+			Name:     CommentEditTableName,
+			HashKey:  articleIdAttribute,
+			RangeKey: &editIdAttribute,
+			Indexes: []IndexSchema{
+				{Name: "CommentId", HashKey: commentIdAttribute, RangeKey: &editIdAttribute},
+			},
+		},
+		{
+			Name:     CommentReactionTableName,
+			HashKey:  articleIdAttribute,
+			RangeKey: &reactionIdAttribute,
+			Indexes: []IndexSchema{
+				{Name: "Username", HashKey: usernameAttribute, RangeKey: &articleIdAttribute},
+			},
+		},
+		{
+			Name:    ArticleCleanupTableName,
+			HashKey: articleIdAttribute,
+		},
+		{
+			Name:    CounterTableName,
+			HashKey: nameAttribute,
+		},
+		{
+			Name:     TimelineTableName,
+			HashKey:  usernameAttribute,
+			RangeKey: &articleIdAttribute,
+			Indexes: []IndexSchema{
+				{Name: "CreatedAt", HashKey: usernameAttribute, RangeKey: &createdAtAttribute},
+			},
+		},
+		{
+			Name:     SearchPostingTableName,
+			HashKey:  termAttribute,
+			RangeKey: &articleIdAttribute,
+			Indexes: []IndexSchema{
+				{Name: "TermHead", HashKey: termHeadAttribute, RangeKey: &termAttribute},
+			},
+		},
+	}
+}
+
+func keySchema(hashKey KeyAttribute, rangeKey *KeyAttribute) []*dynamodb.KeySchemaElement {
+	elements := []*dynamodb.KeySchemaElement{
+		{
+			AttributeName: aws.String(hashKey.Name),
+			KeyType:       aws.String(dynamodb.KeyTypeHash),
+		},
+	}
+
+	if rangeKey != nil {
+		elements = append(elements, &dynamodb.KeySchemaElement{
+			AttributeName: aws.String(rangeKey.Name),
+			KeyType:       aws.String(dynamodb.KeyTypeRange),
+		})
+	}
+
+	return elements
+}
+
+// attributeDefinitions declares every attribute used in the keys of the table or any of its indexes, once.
+func (schema TableSchema) attributeDefinitions() []*dynamodb.AttributeDefinition {
+	definitions := make([]*dynamodb.AttributeDefinition, 0)
+	declared := make(map[string]bool)
+
+	declare := func(attribute *KeyAttribute) {
+		if attribute == nil || declared[attribute.Name] {
+			return
+		}
+
+		declared[attribute.Name] = true
+		definitions = append(definitions, &dynamodb.AttributeDefinition{
+			AttributeName: aws.String(attribute.Name),
+			AttributeType: aws.String(attribute.Type),
+		})
+	}
+
+	declare(&schema.HashKey)
+	declare(schema.RangeKey)
+	for _, index := range schema.Indexes {
+		declare(&index.HashKey)
+		declare(index.RangeKey)
+	}
+
+	return definitions
+}
+
+func (index IndexSchema) globalSecondaryIndex() *dynamodb.GlobalSecondaryIndex {
+	return &dynamodb.GlobalSecondaryIndex{
+		IndexName: aws.String(index.Name),
+		KeySchema: keySchema(index.HashKey, index.RangeKey),
+		Projection: &dynamodb.Projection{
+			ProjectionType: aws.String(dynamodb.ProjectionTypeAll),
+		},
+	}
+}
+
+func (schema TableSchema) createTableInput() *dynamodb.CreateTableInput {
+	input := &dynamodb.CreateTableInput{
+		TableName:            aws.String(schema.Name),
+		KeySchema:            keySchema(schema.HashKey, schema.RangeKey),
+		AttributeDefinitions: schema.attributeDefinitions(),
+		BillingMode:          aws.String(dynamodb.BillingModePayPerRequest),
+	}
+
+	for _, index := range schema.Indexes {
+		input.GlobalSecondaryIndexes = append(input.GlobalSecondaryIndexes, index.globalSecondaryIndex())
+	}
+
+	return input
+}
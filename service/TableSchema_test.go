@@ -0,0 +1,44 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateTableInputDeclaresKeyAttributesOnce(t *testing.T) {
+	for _, schema := range TableSchemas() {
+		input := schema.createTableInput()
+		assert.NoError(t, input.Validate(), schema.Name)
+
+		declared := make(map[string]int)
+		for _, definition := range input.AttributeDefinitions {
+			declared[aws.StringValue(definition.AttributeName)]++
+		}
+
+		used := make(map[string]bool)
+		for _, element := range input.KeySchema {
+			used[aws.StringValue(element.AttributeName)] = true
+		}
+		for _, index := range input.GlobalSecondaryIndexes {
+			for _, element := range index.KeySchema {
+				used[aws.StringValue(element.AttributeName)] = true
+			}
+		}
+
+		// DynamoDB rejects attribute definitions that no key uses
+		assert.Equal(t, len(used), len(declared), schema.Name)
+		for name := range used {
+			assert.Equal(t, 1, declared[name], "%s.%s", schema.Name, name)
+		}
+	}
+}
+
+func TestSetStage(t *testing.T) {
+	defer SetStage(Stage)
+
+	SetStage("test")
+	assert.Equal(t, "realworld-test-article", ArticleTableName)
+	assert.Equal(t, ArticleTableName, TableSchemas()[3].Name)
+}
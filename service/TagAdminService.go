@@ -0,0 +1,373 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"realworld-go-nolambda/model"
+)
+
+var admins = make(map[string]bool)
+
+// SetAdmins sets the usernames allowed to manage tags and read the cache statistics. It must be called before the service layer is used.
+func SetAdmins(usernames []string) {
+	admins = make(map[string]bool, len(usernames))
+	for _, username := range usernames {
+		admins[username] = true
+	}
+}
+
+// IsAdmin reports whether the user with the given username may manage tags and read the cache statistics.
+func IsAdmin(username string) bool {
+	return username != "" && admins[username]
+}
+
+// TagDetails is a tag with the aliases that stand for it.
+type TagDetails struct {
+	model.Tag
+	Aliases []string
+}
+
+var errTagNotFound = model.NewInputError("tag", "not found")
+
+// GetTag returns a tag by any of its names, i.e. its own or one of its aliases in any form normalizing to them,
+// and whether it was found.
+func GetTag(name string) (TagDetails, bool, error) {
+	tag, err := canonicalTag(name)
+	if _, ok := err.(model.InputError); ok {
+		return TagDetails{}, false, nil
+	}
+
+	if err != nil {
+		return TagDetails{}, false, err
+	}
+
+	tags, err := GetStore().GetTags([]string{tag})
+	if err != nil {
+		return TagDetails{}, false, err
+	}
+
+	found, ok := tags[tag]
+	if !ok {
+		return TagDetails{}, false, nil
+	}
+
+	aliases, err := GetStore().QueryTagAliases(tag)
+	if err != nil {
+		return TagDetails{}, false, err
+	}
+
+	details := TagDetails{
+		Tag:     found,
+		Aliases: make([]string, 0, len(aliases)),
+	}
+	for _, alias := range aliases {
+		details.Aliases = append(details.Aliases, alias.Alias)
+	}
+	sort.Strings(details.Aliases)
+
+	return details, true, nil
+}
+
+// SetTagDescription sets the description of a tag by any of its names, creating the tag if it has no articles yet.
+func SetTagDescription(name string, description string) (string, error) {
+	tag, err := canonicalTag(name)
+	if err != nil {
+		return "", err
+	}
+
+	description = strings.TrimSpace(description)
+	if utf8.RuneCountInString(description) > model.MaxTagDescriptionLength {
+		return "", model.NewInputError("description", fmt.Sprintf("can't be longer than %d characters", model.MaxTagDescriptionLength))
+	}
+
+	return tag, GetStore().PutTagDescription(tag, description)
+}
+
+// RenameTag gives a tag a new name that isn't taken by another tag or its alias, see MergeTags.
+// The old name becomes an alias of the new one.
+func RenameTag(name string, newName string) (string, int, error) {
+	tag, err := canonicalTag(name)
+	if err != nil {
+		return "", 0, err
+	}
+
+	newTag, err := model.NormalizeTag(newName)
+	if err != nil {
+		return "", 0, err
+	}
+
+	aliases, err := GetStore().GetTagAliases([]string{newTag})
+	if err != nil {
+		return "", 0, err
+	}
+
+	// Renaming a tag to one of its aliases swaps them
+	if aliases[newTag].Tag == tag {
+		err = GetStore().DeleteTagAlias(newTag)
+		if err != nil {
+			return "", 0, err
+		}
+	} else if len(aliases) > 0 {
+		return "", 0, model.NewInputError("name", "is already taken by an alias of another tag")
+	} else {
+		tags, err := GetStore().GetTags([]string{newTag})
+		if err != nil {
+			return "", 0, err
+		}
+
+		if len(tags) > 0 {
+			return "", 0, model.NewInputError("name", "is already taken by another tag, merge into it instead")
+		}
+	}
+
+	numArticles, err := MergeTags(newTag, []string{tag})
+	return newTag, numArticles, err
+}
+
+// MergeTags merges tags into target: every published article linked with one of them is relinked with target,
+// their names and aliases become aliases of target, and target inherits the first of their descriptions if it has none.
+// Unpublished articles are relinked when they are published, since aliases are resolved then.
+// It returns the number of articles relinked. A tag linked with an article meanwhile is kept, merging again removes it.
+func MergeTags(target string, names []string) (int, error) {
+	target, err := model.NormalizeTag(target)
+	if err != nil {
+		return 0, err
+	}
+
+	aliases, err := GetStore().GetTagAliases([]string{target})
+	if err != nil {
+		return 0, err
+	}
+
+	if alias, ok := aliases[target]; ok {
+		return 0, model.NewInputError("tag", fmt.Sprintf("is an alias of %s", alias.Tag))
+	}
+
+	sources := make([]string, 0, len(names))
+	for _, name := range names {
+		source, err := canonicalTag(name)
+		if err != nil {
+			return 0, err
+		}
+
+		if source != target {
+			sources = append(sources, source)
+		}
+	}
+
+	if len(sources) == 0 {
+		return 0, model.NewInputError("tags", "must name at least one tag other than the one merged into")
+	}
+
+	tags, err := GetStore().GetTags(append([]string{target}, sources...))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, source := range sources {
+		if _, ok := tags[source]; !ok {
+			return 0, model.NewInputError("tags", fmt.Sprintf("%s not found", source))
+		}
+	}
+
+	numArticles := 0
+	for _, source := range sources {
+		// Aliases first, so that articles written meanwhile are linked with target already
+		err = moveTagAliases(source, target)
+		if err != nil {
+			return numArticles, err
+		}
+
+		numRelinked, err := relinkArticles(source, target)
+		numArticles += numRelinked
+		if err != nil {
+			return numArticles, err
+		}
+
+		if tags[target].Description == "" && tags[source].Description != "" {
+			err = GetStore().PutTagDescription(target, tags[source].Description)
+			if err != nil {
+				return numArticles, err
+			}
+
+			targetTag := tags[target]
+			targetTag.Description = tags[source].Description
+			tags[target] = targetTag
+		}
+
+		err = GetStore().DeleteTag(source)
+		if err != nil && !errors.Is(err, ErrConditionFailed) {
+			return numArticles, err
+		}
+	}
+
+	return numArticles, nil
+}
+
+// moveTagAliases makes source and its aliases aliases of target.
+func moveTagAliases(source string, target string) error {
+	aliases, err := GetStore().QueryTagAliases(source)
+	if err != nil {
+		return err
+	}
+
+	aliases = append(aliases, model.TagAlias{Alias: source})
+	for _, alias := range aliases {
+		err = GetStore().PutTagAlias(model.TagAlias{Alias: alias.Alias, Tag: target})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// relinkArticles replaces source with target in the tag list of every published article linked with source.
+func relinkArticles(source string, target string) (int, error) {
+	numArticles := 0
+	page := Page{Limit: articleBatchSize}
+
+	for {
+		articleIds, next, err := GetStore().QueryArticleIdsByTag(source, page)
+		if err != nil {
+			return numArticles, err
+		}
+
+		articles, err := getArticlesByArticleIds(articleIds)
+		if err != nil {
+			return numArticles, err
+		}
+
+		for _, article := range articles {
+			rewritten, err := rewriteArticleTags(article, func(tagList []string) ([]string, error) {
+				replaced := make([]string, 0, len(tagList))
+				for _, tag := range tagList {
+					if tag == source {
+						tag = target
+					}
+					replaced = append(replaced, tag)
+				}
+
+				canonical, _ := model.CanonicalTagList(replaced)
+				return canonical, nil
+			})
+			if err != nil {
+				return numArticles, err
+			}
+
+			if rewritten {
+				numArticles++
+			}
+		}
+
+		if next == nil {
+			return numArticles, nil
+		}
+
+		page.After = next
+	}
+}
+
+// PutTagAlias makes alias stand for tag. The alias can't be a tag itself, merge it into tag instead.
+func PutTagAlias(name string, alias string) (model.TagAlias, error) {
+	tag, err := canonicalTag(name)
+	if err != nil {
+		return model.TagAlias{}, err
+	}
+
+	alias, err = model.NormalizeTag(alias)
+	if err != nil {
+		return model.TagAlias{}, model.NewInputError("alias", "must be a valid tag")
+	}
+
+	if alias == tag {
+		return model.TagAlias{}, model.NewInputError("alias", "can't be the tag itself")
+	}
+
+	tags, err := GetStore().GetTags([]string{tag, alias})
+	if err != nil {
+		return model.TagAlias{}, err
+	}
+
+	if _, ok := tags[tag]; !ok {
+		return model.TagAlias{}, errTagNotFound
+	}
+
+	if _, ok := tags[alias]; ok {
+		return model.TagAlias{}, model.NewInputError("alias", "is a tag, merge it instead")
+	}
+
+	tagAlias := model.TagAlias{Alias: alias, Tag: tag}
+	return tagAlias, GetStore().PutTagAlias(tagAlias)
+}
+
+// DeleteTagAlias deletes an alias of a tag.
+func DeleteTagAlias(name string, alias string) error {
+	tag, err := canonicalTag(name)
+	if err != nil {
+		return err
+	}
+
+	alias, err = model.NormalizeTag(alias)
+	if err != nil {
+		return model.NewInputError("alias", "not found")
+	}
+
+	aliases, err := GetStore().GetTagAliases([]string{alias})
+	if err != nil {
+		return err
+	}
+
+	if aliases[alias].Tag != tag {
+		return model.NewInputError("alias", "not found")
+	}
+
+	return GetStore().DeleteTagAlias(alias)
+}
+
+// canonicalTag normalizes a tag name and resolves it if it is an alias.
+func canonicalTag(name string) (string, error) {
+	tag, err := model.NormalizeTag(name)
+	if err != nil {
+		return "", err
+	}
+
+	tags, err := resolveTagAliases([]string{tag})
+	if err != nil {
+		return "", err
+	}
+
+	return tags[0], nil
+}
+
+// resolveTagAliases replaces the aliases among normalized tags with the tags they stand for, dropping duplicates.
+func resolveTagAliases(tags []string) ([]string, error) {
+	if len(tags) == 0 {
+		return tags, nil
+	}
+
+	aliases, err := GetStore().GetTagAliases(tags)
+	if err != nil || len(aliases) == 0 {
+		return tags, err
+	}
+
+	resolved := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+
+	for _, tag := range tags {
+		if alias, ok := aliases[tag]; ok {
+			tag = alias.Tag
+		}
+
+		if !seen[tag] {
+			seen[tag] = true
+			resolved = append(resolved, tag)
+		}
+	}
+
+	return resolved, nil
+}
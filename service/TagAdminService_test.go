@@ -0,0 +1,97 @@
+package service
+
+import (
+	"testing"
+
+	"realworld-go-nolambda/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreTagAdministration(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		newTestUser(t, "alice")
+		a1 := newTestArticle(t, "alice", 1, "golang", "web")
+		a2 := newTestArticle(t, "alice", 2, "go-lang")
+		a3 := newTestArticle(t, "alice", 3, "go", "golang")
+
+		tag, err := SetTagDescription("Golang", "The Go programming language")
+		assert.NoError(t, err)
+		assert.Equal(t, "golang", tag)
+
+		// Renaming keeps the description, the old name becomes an alias
+		tag, numArticles, err := RenameTag("golang", "gopher")
+		assert.NoError(t, err)
+		assert.Equal(t, "gopher", tag)
+		assert.Equal(t, 2, numArticles)
+
+		// The rewritten articles keep their UpdatedAt, but not their ETag
+		renamed, err := GetArticleBySlug(a1.Slug)
+		assert.NoError(t, err)
+		assert.Equal(t, a1.UpdatedAt, renamed.UpdatedAt)
+		assert.NotEqual(t, a1.ETag(), renamed.ETag())
+		assert.Equal(t, ErrArticleModified, DeleteArticle(a1.Slug, "alice", a1.ETag()))
+
+		details, found, err := GetTag("GOLANG")
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, "gopher", details.Tag.Tag)
+		assert.Equal(t, "The Go programming language", details.Description)
+		assert.Equal(t, int64(2), details.ArticleCount)
+		assert.Equal(t, []string{"golang"}, details.Aliases)
+
+		_, _, err = RenameTag("web", "gopher")
+		assert.Error(t, err)
+
+		// Merging relinks articles once, even those that had both tags
+		numArticles, err = MergeTags("go", []string{"gopher", "go-lang"})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, numArticles)
+
+		details, found, err = GetTag("go")
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, int64(3), details.ArticleCount)
+		assert.Equal(t, "The Go programming language", details.Description)
+		assert.Equal(t, []string{"go-lang", "golang", "gopher"}, details.Aliases)
+
+		for _, article := range []model.Article{a1, a2, a3} {
+			article, err = GetArticleByArticleId(article.ArticleId)
+			assert.NoError(t, err)
+			assert.Contains(t, article.TagList, "go")
+			assert.NotContains(t, article.TagList, "golang")
+		}
+
+		_, found, err = GetTag("gopher")
+		assert.NoError(t, err)
+		assert.True(t, found)
+
+		tags, err := GetStore().GetTags([]string{"gopher", "golang", "go-lang"})
+		assert.NoError(t, err)
+		assert.Empty(t, tags)
+
+		// Aliases resolve on input and in queries
+		_, err = PutTagAlias("go", "Go Lang Two")
+		assert.NoError(t, err)
+
+		article := newTestArticle(t, "alice", 4, "GoLang", "go-lang-two", "web")
+		assert.Equal(t, []string{"go", "web"}, article.TagList)
+
+		articles, _, err := FindArticles(ArticleQuery{Tags: []string{"golang"}}, Page{Limit: 10})
+		assert.NoError(t, err)
+		assert.Len(t, articles, 4)
+
+		_, err = PutTagAlias("go", "web")
+		assert.Error(t, err)
+
+		_, err = PutTagAlias("golang", "gol")
+		assert.NoError(t, err)
+
+		assert.NoError(t, DeleteTagAlias("go", "gol"))
+		assert.Error(t, DeleteTagAlias("go", "gol"))
+
+		_, found, err = GetTag("unknown")
+		assert.NoError(t, err)
+		assert.False(t, found)
+	})
+}
@@ -1,43 +1,158 @@
 package service
 
 import (
-	//"realworld-go-nolambda/model"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
 	"realworld-go-nolambda/model"
+)
+
+// TagQuery selects the tags listed by QueryTags. At most one of Prefix and Window may be set.
+type TagQuery struct {
+	// Prefix lists the tags starting with it, for autocompletion
+	Prefix string
+	// Window ranks tags by the number of their articles created within it, up to now, instead of all of them
+	Window time.Duration
+}
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+const (
+	maxTagLimit       = 100
+	maxTrendingWindow = 90 * 24 * time.Hour
 )
 
 func GetTags() ([]string, error) {
-	const maxNumTags = 20
+	tagObjects, _, err := QueryTags(TagQuery{}, Page{Limit: 20})
+	if err != nil {
+		return nil, err
+	}
 
-	queryTags := dynamodb.QueryInput{
-		TableName:                 aws.String(TagTableName),
-		IndexName:                 aws.String("ArticleCount"),
-		KeyConditionExpression:    aws.String("Dummy=:zero"),
-		ExpressionAttributeValues: IntKey(":zero", 0),
-		Limit:                     aws.Int64(maxNumTags),
-		ScanIndexForward:          aws.Bool(false),
+	tags := make([]string, 0, len(tagObjects))
+	for _, tagObject := range tagObjects {
+		tags = append(tags, tagObject.Tag)
 	}
 
-	items, err := QueryItems(&queryTags, 0, maxNumTags)
+	return tags, nil
+}
+
+// QueryTags returns a page of the tags with articles, most articles first, and the position of the last one
+// if more may follow. Trending tags are counted over the window, and can't be paged beyond maxTagLimit.
+func QueryTags(query TagQuery, page Page) ([]model.Tag, *Cursor, error) {
+	err := validatePage(page)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	tagObjects := make([]model.Tag, len(items))
-	err = dynamodbattribute.UnmarshalListOfMaps(items, &tagObjects)
+	if page.Limit > maxTagLimit {
+		return nil, nil, model.NewInputError("limit", fmt.Sprintf("must be smaller or equal to %d", maxTagLimit))
+	}
+
+	if query.Window == 0 {
+		return GetStore().QueryTags(model.NormalizeTagPrefix(query.Prefix), page)
+	}
+
+	if query.Prefix != "" {
+		return nil, nil, model.NewInputError("prefix", "can't be combined with window")
+	}
+
+	if query.Window < time.Minute || query.Window > maxTrendingWindow {
+		return nil, nil, model.NewInputError("window", fmt.Sprintf("must be between 1m and %dd", maxTrendingWindow/(24*time.Hour)))
+	}
+
+	if page.After != nil {
+		return nil, nil, model.NewInputError("cursor", "can't be used with window")
+	}
+
+	if page.Offset+page.Limit > maxTagLimit {
+		return nil, nil, model.NewInputError("offset + limit", fmt.Sprintf("must be smaller or equal to %d with window", maxTagLimit))
+	}
+
+	// Rounded down to the minute, so that the result can be cached for a while
+	since := time.Now().UTC().Add(-query.Window).Truncate(time.Minute).UnixNano()
+
+	tags, err := GetStore().QueryTrendingTags(since, page.Offset+page.Limit)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	tags := make([]string, 0, len(tagObjects))
-	for _, tagObject := range tagObjects {
-		if tagObject.ArticleCount > 0 {
-			tags = append(tags, tagObject.Tag)
+	start, end := pageBounds(len(tags), page.Offset, page.Limit)
+	return tags[start:end], nil, nil
+}
+
+// NormalizeArticleTags rewrites the tag list of every published article into canonical form with aliases resolved,
+// relinking its tags, and then recounts the articles of every tag. Tags that can't be normalized are dropped and logged.
+// Revisions keep the tags they were written with. It returns the number of articles rewritten.
+// Tag counts are only exact if no article is written meanwhile.
+func NormalizeArticleTags() (int, error) {
+	numArticles := 0
+	page := Page{Limit: 100}
+
+	for {
+		articles, next, err := GetStore().QueryArticles(page)
+		if err != nil {
+			return numArticles, err
 		}
+
+		for _, article := range articles {
+			rewritten, err := rewriteArticleTags(article, func(tagList []string) ([]string, error) {
+				tagList, invalid := model.CanonicalTagList(tagList)
+				if len(invalid) > 0 {
+					log.Printf("article %d: dropping tags %q", article.ArticleId, invalid)
+				}
+
+				return resolveTagAliases(tagList)
+			})
+			if err != nil {
+				return numArticles, err
+			}
+
+			if rewritten {
+				numArticles++
+			}
+		}
+
+		if next == nil {
+			break
+		}
+
+		page.After = next
 	}
 
-	return tags, nil
+	return numArticles, GetStore().RecountTags()
+}
+
+// rewriteArticleTags replaces the tag list of an article with what rewrite makes of it, retrying with the current
+// version of the article if it is edited meanwhile. The article isn't edited, so it keeps its UpdatedAt and revision,
+// but its ETag changes with the tag list. It reports whether the tag list changed.
+func rewriteArticleTags(article model.Article, rewrite func(tagList []string) ([]string, error)) (bool, error) {
+	for {
+		tagList, err := rewrite(article.TagList)
+		if err != nil {
+			return false, err
+		}
+
+		if strings.Join(tagList, "\n") == strings.Join(article.TagList, "\n") && len(tagList) == len(article.TagList) {
+			return false, nil
+		}
+
+		newArticle := article
+		newArticle.TagList = tagList
+
+		err = GetStore().UpdateArticle(article, newArticle, nil)
+		if err == nil {
+			return true, updateSearchIndex(article, newArticle)
+		}
+
+		if !errors.Is(err, ErrConditionFailed) {
+			return false, err
+		}
+
+		var found bool
+		article, found, err = GetStore().GetArticle(article.ArticleId)
+		if err != nil || !found || !article.IsPublished() {
+			return false, err
+		}
+	}
 }
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"realworld-go-nolambda/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreNormalizeArticleTags(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		newTestUser(t, "alice")
+
+		// Written by a version that stored tags as they were typed
+		legacy := model.Article{
+			Title:     "Title",
+			TagList:   []string{"Go", "GO ", "Web Dev", "a/b"},
+			CreatedAt: 1,
+			UpdatedAt: 1,
+			Author:    "alice",
+			Status:    model.StatusPublished,
+			Revision:  1,
+		}
+		var err error
+		legacy.ArticleId, err = NextArticleId()
+		assert.NoError(t, err)
+		legacy.MakeSlug()
+		assert.NoError(t, GetStore().PutArticle(legacy, model.NewArticleRevision(legacy, "alice", model.ArticleFields)))
+
+		normalized := newTestArticle(t, "alice", 2, " Go", "go", "WEB  dev")
+		assert.Equal(t, []string{"go", "web-dev"}, normalized.TagList)
+
+		article := model.Article{Title: "Title", Description: "Description", Body: "Body", TagList: []string{"a/b"}, Author: "alice"}
+		assert.Error(t, PutArticle(&article))
+
+		numArticles, err := NormalizeArticleTags()
+		assert.NoError(t, err)
+		assert.Equal(t, 1, numArticles)
+
+		rewritten, err := GetArticleByArticleId(legacy.ArticleId)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"go", "web-dev"}, rewritten.TagList)
+		assert.Equal(t, legacy.UpdatedAt, rewritten.UpdatedAt)
+
+		// The tags as they were typed are gone
+		topTags, _, err := GetStore().QueryTags("", Page{Limit: 10})
+		assert.NoError(t, err)
+		assert.Len(t, topTags, 2)
+
+		tags, err := GetStore().GetTags([]string{"go", "web-dev"})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), tags["go"].ArticleCount)
+		assert.Equal(t, int64(2), tags["web-dev"].ArticleCount)
+
+		articles, _, err := FindArticles(ArticleQuery{Tags: []string{"WEB DEV"}}, Page{Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{normalized.Slug, legacy.Slug}, articleSlugs(articles))
+
+		// Already canonical
+		numArticles, err = NormalizeArticleTags()
+		assert.NoError(t, err)
+		assert.Equal(t, 0, numArticles)
+	})
+}
+
+func TestStoreQueryTags(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		now := time.Now().UTC()
+		old := now.Add(-30 * 24 * time.Hour).UnixNano()
+		recent := now.Add(-time.Hour).UnixNano()
+
+		newTestArticle(t, "alice", old, "go", "golang", "web_dev")
+		newTestArticle(t, "alice", old, "go", "golang")
+		newTestArticle(t, "alice", old, "go", "webdev")
+		newTestArticle(t, "alice", recent, "rust", "webdev")
+		newTestArticle(t, "alice", recent, "rust")
+
+		tagNames := func(tags []model.Tag) []string {
+			names := make([]string, 0, len(tags))
+			for _, tag := range tags {
+				names = append(names, tag.Tag)
+			}
+			return names
+		}
+
+		tags, next, err := QueryTags(TagQuery{}, Page{Limit: 2})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"go", "golang"}, tagNames(tags))
+		assert.Equal(t, int64(3), tags[0].ArticleCount)
+		assert.NotNil(t, next)
+
+		tags, next, err = QueryTags(TagQuery{}, Page{Limit: 2, After: next})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"rust", "webdev"}, tagNames(tags))
+		assert.NotNil(t, next)
+
+		tags, next, err = QueryTags(TagQuery{}, Page{Limit: 2, After: next})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"web_dev"}, tagNames(tags))
+		assert.Nil(t, next)
+
+		// The underscore isn't a wildcard
+		tags, _, err = QueryTags(TagQuery{Prefix: " WEB_"}, Page{Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"web_dev"}, tagNames(tags))
+
+		tags, _, err = QueryTags(TagQuery{Prefix: "Go"}, Page{Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"go", "golang"}, tagNames(tags))
+
+		tags, next, err = QueryTags(TagQuery{Window: 7 * 24 * time.Hour}, Page{Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"rust", "webdev"}, tagNames(tags))
+		assert.Equal(t, []int64{2, 1}, []int64{tags[0].ArticleCount, tags[1].ArticleCount})
+		assert.Nil(t, next)
+
+		tags, _, err = QueryTags(TagQuery{Window: 7 * 24 * time.Hour}, Page{Offset: 1, Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"webdev"}, tagNames(tags))
+
+		_, _, err = QueryTags(TagQuery{Prefix: "go", Window: time.Hour}, Page{Limit: 10})
+		assert.Error(t, err)
+
+		_, _, err = QueryTags(TagQuery{Window: 365 * 24 * time.Hour}, Page{Limit: 10})
+		assert.Error(t, err)
+
+		_, _, err = QueryTags(TagQuery{}, Page{Limit: 101})
+		assert.Error(t, err)
+
+		tagList, err := GetTags()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"go", "golang", "rust", "webdev", "web_dev"}, tagList)
+	})
+}
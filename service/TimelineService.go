@@ -0,0 +1,157 @@
+package service
+
+import (
+	"realworld-go-nolambda/model"
+)
+
+const (
+	// FeedModeRead builds the feed on every request by merging the latest articles of each followed author.
+	FeedModeRead = "read"
+	// FeedModeWrite copies every new article into the timeline of each follower of its author,
+	// so that the feed is a single paginated read.
+	FeedModeWrite = "write"
+)
+
+var feedMode = FeedModeRead
+
+// SetFeedMode chooses how the feed is built. It must be called before the service layer is used.
+// Timelines are only maintained in FeedModeWrite, they miss the activity that happened before switching to it.
+func SetFeedMode(mode string) {
+	feedMode = mode
+}
+
+// timelineBatchSize is the number of articles read per query when copying an author's articles into timelines.
+const timelineBatchSize = 100
+
+// maxTimelineBackfill bounds the articles copied into a timeline when following an author.
+const maxTimelineBackfill = 1000
+
+// fanOutArticle adds a new article to the timeline of every follower of its author.
+func fanOutArticle(article model.Article) error {
+	if feedMode != FeedModeWrite || !article.IsPublished() {
+		return nil
+	}
+
+	followers, err := GetStore().QueryFollowers(article.Author)
+	if err != nil {
+		return err
+	}
+
+	entries := make([]model.TimelineEntry, 0, len(followers))
+	for _, follower := range followers {
+		entries = append(entries, timelineEntry(follower, article))
+	}
+
+	return GetStore().PutTimelineEntries(entries)
+}
+
+// removeArticleFromTimelines removes a deleted or unpublished article from the timeline of every follower of its author.
+func removeArticleFromTimelines(article model.Article) error {
+	if feedMode != FeedModeWrite {
+		return nil
+	}
+
+	followers, err := GetStore().QueryFollowers(article.Author)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]model.TimelineKey, 0, len(followers))
+	for _, follower := range followers {
+		keys = append(keys, model.TimelineKey{Username: follower, ArticleId: article.ArticleId})
+	}
+
+	return GetStore().DeleteTimelineEntries(keys)
+}
+
+// backfillTimeline copies the latest articles of a newly followed author into the follower's timeline.
+func backfillTimeline(follower, publisher string) error {
+	if feedMode != FeedModeWrite {
+		return nil
+	}
+
+	return forEachArticlePage(publisher, maxTimelineBackfill, func(articles []model.Article) error {
+		entries := make([]model.TimelineEntry, 0, len(articles))
+		for _, article := range articles {
+			entries = append(entries, timelineEntry(follower, article))
+		}
+
+		return GetStore().PutTimelineEntries(entries)
+	})
+}
+
+// pruneTimeline removes every article of an unfollowed author from the follower's timeline.
+func pruneTimeline(follower, publisher string) error {
+	if feedMode != FeedModeWrite {
+		return nil
+	}
+
+	return forEachArticlePage(publisher, -1, func(articles []model.Article) error {
+		keys := make([]model.TimelineKey, 0, len(articles))
+		for _, article := range articles {
+			keys = append(keys, model.TimelineKey{Username: follower, ArticleId: article.ArticleId})
+		}
+
+		return GetStore().DeleteTimelineEntries(keys)
+	})
+}
+
+// forEachArticlePage calls fn with the articles of author newest first, in pages, until max articles
+// were read or, if max is negative, all of them.
+func forEachArticlePage(author string, max int, fn func(articles []model.Article) error) error {
+	page := Page{Limit: timelineBatchSize}
+	numArticles := 0
+
+	for max < 0 || numArticles < max {
+		articles, next, err := getArticlesByAuthor(author, page)
+		if err != nil {
+			return err
+		}
+
+		if max >= 0 && numArticles+len(articles) > max {
+			articles = articles[:max-numArticles]
+		}
+
+		if len(articles) > 0 {
+			err = fn(articles)
+			if err != nil {
+				return err
+			}
+		}
+
+		numArticles += len(articles)
+		if next == nil {
+			break
+		}
+
+		page.After = next
+	}
+
+	return nil
+}
+
+// getTimeline returns a page of the timeline of username, like GetFeed.
+func getTimeline(username string, page Page) ([]model.Article, *Cursor, error) {
+	articleIds, next, err := GetStore().QueryTimeline(username, page)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	articles, err := getArticlesByArticleIds(articleIds)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return articles, next, nil
+}
+
+func timelineEntry(follower string, article model.Article) model.TimelineEntry {
+	return model.TimelineEntry{
+		TimelineKey: model.TimelineKey{
+			Username:  follower,
+			ArticleId: article.ArticleId,
+		},
+		CreatedAt: article.CreatedAt,
+		Author:    article.Author,
+	}
+}
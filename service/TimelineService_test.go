@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreTimelineFeed(t *testing.T) {
+	SetFeedMode(FeedModeWrite)
+	defer SetFeedMode(FeedModeRead)
+
+	forEachStore(t, func(t *testing.T) {
+		newTestUser(t, "alice")
+		newTestUser(t, "bob")
+
+		// Backfilled on follow
+		older := newTestArticle(t, "alice", 1)
+		assert.NoError(t, Follow("bob", "alice"))
+
+		// Fanned out on write
+		newer := newTestArticle(t, "alice", 2)
+
+		feed, _, err := GetFeed(context.Background(), "bob", Page{Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{newer.Slug, older.Slug}, articleSlugs(feed))
+
+		feed, next, err := GetFeed(context.Background(), "bob", Page{Limit: 1})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{newer.Slug}, articleSlugs(feed))
+		feed, _, err = GetFeed(context.Background(), "bob", Page{Limit: 1, After: next})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{older.Slug}, articleSlugs(feed))
+
+		// Deleted articles leave the feed at once, and the timeline with the cleanup
+		assert.NoError(t, DeleteArticle(newer.Slug, "alice", ""))
+		feed, _, err = GetFeed(context.Background(), "bob", Page{Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{older.Slug}, articleSlugs(feed))
+
+		assert.NoError(t, CleanupDeletedArticles())
+		articleIds, _, err := GetStore().QueryTimeline("bob", Page{Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{older.ArticleId}, articleIds)
+
+		assert.NoError(t, Unfollow("bob", "alice"))
+		feed, _, err = GetFeed(context.Background(), "bob", Page{Limit: 10})
+		assert.NoError(t, err)
+		assert.Empty(t, feed)
+	})
+}
@@ -1,12 +1,7 @@
 package service
 
 import (
-	//"realworld-go-nolambda/model"
 	"realworld-go-nolambda/model"
-
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 )
 
 func PutUser(user model.User) error {
@@ -15,42 +10,7 @@ func PutUser(user model.User) error {
 		return err
 	}
 
-	userItem, err := dynamodbattribute.MarshalMap(user)
-	if err != nil {
-		return err
-	}
-
-	emailUser := model.EmailUser{
-		Email:    user.Email,
-		Username: user.Username,
-	}
-
-	emailUserItem, err := dynamodbattribute.MarshalMap(emailUser)
-	if err != nil {
-		return err
-	}
-
-	// Put a new user, make sure username and email are unique
-	transaction := dynamodb.TransactWriteItemsInput{
-		TransactItems: []*dynamodb.TransactWriteItem{
-			{
-				Put: &dynamodb.Put{
-					TableName:           aws.String(UserTableName),
-					Item:                userItem,
-					ConditionExpression: aws.String("attribute_not_exists(Username)"),
-				},
-			},
-			{
-				Put: &dynamodb.Put{
-					TableName:           aws.String(EmailUserTableName),
-					Item:                emailUserItem,
-					ConditionExpression: aws.String("attribute_not_exists(Email)"),
-				},
-			},
-		},
-	}
-
-	_, err = DynamoDB().TransactWriteItems(&transaction)
+	err = GetStore().PutUser(user)
 	if err != nil {
 		// TODO: distinguish:
 		// NewInputError("username", "has already been taken")
@@ -67,61 +27,7 @@ func UpdateUser(oldUser model.User, newUser model.User) error {
 		return err
 	}
 
-	transactItems := make([]*dynamodb.TransactWriteItem, 0, 3)
-
-	if oldUser.Email != newUser.Email {
-		newEmailUser := model.EmailUser{
-			Email:    newUser.Email,
-			Username: newUser.Username,
-		}
-
-		newEmailUserItem, err := dynamodbattribute.MarshalMap(newEmailUser)
-		if err != nil {
-			return err
-		}
-
-		// Link user with the new email
-		transactItems = append(transactItems, &dynamodb.TransactWriteItem{
-			Put: &dynamodb.Put{
-				TableName:           aws.String(EmailUserTableName),
-				Item:                newEmailUserItem,
-				ConditionExpression: aws.String("attribute_not_exists(Email)"),
-			},
-		})
-
-		// Unlink user from the old email
-		transactItems = append(transactItems, &dynamodb.TransactWriteItem{
-			Delete: &dynamodb.Delete{
-				TableName:           aws.String(EmailUserTableName),
-				Key:                 StringKey("Email", oldUser.Email),
-				ConditionExpression: aws.String("attribute_exists(Email)"),
-			},
-		})
-	}
-
-	newUserItem, err := dynamodbattribute.MarshalMap(newUser)
-	if err != nil {
-		return err
-	}
-
-	// Update user info
-	transactItems = append(transactItems, &dynamodb.TransactWriteItem{
-		Put: &dynamodb.Put{
-			TableName:                 aws.String(UserTableName),
-			Item:                      newUserItem,
-			ConditionExpression:       aws.String("Email = :email"),
-			ExpressionAttributeValues: StringKey(":email", oldUser.Email),
-		},
-	})
-
-	_, err = DynamoDB().TransactWriteItems(&dynamodb.TransactWriteItemsInput{
-		TransactItems: transactItems,
-	})
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return GetStore().UpdateUser(oldUser, newUser)
 }
 
 func GetUserByEmail(email string) (model.User, error) {
@@ -138,8 +44,7 @@ func GetUserByEmail(email string) (model.User, error) {
 }
 
 func GetUsernameByEmail(email string) (string, error) {
-	emailUser := model.EmailUser{}
-	found, err := GetItemByKey(EmailUserTableName, StringKey("Email", email), &emailUser)
+	username, found, err := GetStore().GetUsernameByEmail(email)
 
 	if err != nil {
 		return "", err
@@ -149,7 +54,7 @@ func GetUsernameByEmail(email string) (string, error) {
 		return "", model.NewInputError("email", "not found")
 	}
 
-	return emailUser.Username, nil
+	return username, nil
 }
 
 func GetUserByUsername(username string) (model.User, error) {
@@ -157,8 +62,7 @@ func GetUserByUsername(username string) (model.User, error) {
 		return model.User{}, model.NewInputError("username", "can't be blank")
 	}
 
-	user := model.User{}
-	found, err := GetItemByKey(UserTableName, StringKey("Username", username), &user)
+	user, found, err := GetStore().GetUser(username)
 
 	if err != nil {
 		return model.User{}, err
@@ -190,45 +94,11 @@ func GetUserListByUsername(usernames []string) ([]model.User, error) {
 		return make([]model.User, 0), nil
 	}
 
-	usernameSet := make(map[string]bool)
-	for _, username := range usernames {
-		usernameSet[username] = true
-	}
-
-	keys := make([]AWSObject, 0, len(usernameSet))
-	for username := range usernameSet {
-		keys = append(keys, StringKey("Username", username))
-	}
-
-	batchGetUsers := dynamodb.BatchGetItemInput{
-		RequestItems: map[string]*dynamodb.KeysAndAttributes{
-			UserTableName: {
-				Keys: keys,
-			},
-		},
-	}
-
-	responses, err := BatchGetItems(&batchGetUsers, len(usernames))
+	usersByUsername, err := GetStore().GetUsers(usernames)
 	if err != nil {
 		return nil, err
 	}
 
-	usersByUsername := make(map[string]model.User)
-
-	for _, response := range responses {
-		for _, items := range response {
-			for _, item := range items {
-				user := model.User{}
-				err = dynamodbattribute.UnmarshalMap(item, &user)
-				if err != nil {
-					return nil, err
-				}
-
-				usersByUsername[user.Username] = user
-			}
-		}
-	}
-
 	users := make([]model.User, 0, len(usernames))
 	for _, username := range usernames {
 		users = append(users, usersByUsername[username])
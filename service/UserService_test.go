@@ -0,0 +1,29 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreUsers(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+
+		alice := newTestUser(t, "alice")
+		assert.True(t, IsConditionalCheckFailed(PutUser(alice)))
+
+		user, err := GetUserByEmail("alice@example.com")
+		assert.NoError(t, err)
+		assert.Equal(t, alice, user)
+
+		updated := alice
+		updated.Email = "alice@example.org"
+		assert.NoError(t, UpdateUser(alice, updated))
+
+		_, err = GetUserByEmail("alice@example.com")
+		assert.Error(t, err)
+		user, err = GetUserByEmail("alice@example.org")
+		assert.NoError(t, err)
+		assert.Equal(t, "alice", user.Username)
+	})
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"errors"
 	"reflect"
 	"strconv"
 	"strings"
@@ -68,6 +69,10 @@ func IsUpdateBuilderEmpty(update expression.UpdateBuilder) bool {
 }
 
 func IsConditionalCheckFailed(err error) bool {
+	if errors.Is(err, ErrConditionFailed) {
+		return true
+	}
+
 	aerr, ok := err.(awserr.Error)
 	if !ok {
 		return false
@@ -0,0 +1,105 @@
+package util
+
+import "strings"
+
+// Operations of a DiffLine, as in the unified diff format.
+const (
+	DiffEqual  = " "
+	DiffDelete = "-"
+	DiffInsert = "+"
+)
+
+// maxDiffCells bounds the table of the longest common subsequence, i.e. the product of the numbers of lines
+// that differ between two texts. Beyond it, every differing line is reported as deleted and inserted.
+const maxDiffCells = 1 << 20
+
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// DiffLines returns a line-level diff turning oldText into newText, keeping the longest common subsequence of lines.
+func DiffLines(oldText, newText string) []DiffLine {
+	oldLines := splitLines(oldText)
+	newLines := splitLines(newText)
+
+	// Unchanged lines around the edits don't need the quadratic table
+	prefix := 0
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+
+	diff := make([]DiffLine, 0, len(oldLines)+len(newLines)-prefix-suffix)
+	for _, line := range oldLines[:prefix] {
+		diff = append(diff, DiffLine{DiffEqual, line})
+	}
+
+	diff = append(diff, diffMiddle(oldLines[prefix:len(oldLines)-suffix], newLines[prefix:len(newLines)-suffix])...)
+
+	for _, line := range oldLines[len(oldLines)-suffix:] {
+		diff = append(diff, DiffLine{DiffEqual, line})
+	}
+
+	return diff
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+func diffMiddle(oldLines, newLines []string) []DiffLine {
+	diff := make([]DiffLine, 0, len(oldLines)+len(newLines))
+
+	if len(oldLines)*len(newLines) > maxDiffCells {
+		for _, line := range oldLines {
+			diff = append(diff, DiffLine{DiffDelete, line})
+		}
+		for _, line := range newLines {
+			diff = append(diff, DiffLine{DiffInsert, line})
+		}
+		return diff
+	}
+
+	// common[i][j] is the length of the longest common subsequence of oldLines[i:] and newLines[j:]
+	common := make([][]int, len(oldLines)+1)
+	for i := range common {
+		common[i] = make([]int, len(newLines)+1)
+	}
+
+	for i := len(oldLines) - 1; i >= 0; i-- {
+		for j := len(newLines) - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = MaxInt(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(oldLines) || j < len(newLines) {
+		switch {
+		case i < len(oldLines) && j < len(newLines) && oldLines[i] == newLines[j]:
+			diff = append(diff, DiffLine{DiffEqual, oldLines[i]})
+			i++
+			j++
+		case j == len(newLines) || (i < len(oldLines) && common[i+1][j] >= common[i][j+1]):
+			diff = append(diff, DiffLine{DiffDelete, oldLines[i]})
+			i++
+		default:
+			diff = append(diff, DiffLine{DiffInsert, newLines[j]})
+			j++
+		}
+	}
+
+	return diff
+}
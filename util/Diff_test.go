@@ -0,0 +1,29 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffLines(t *testing.T) {
+	testCases := []struct {
+		oldText  string
+		newText  string
+		expected []DiffLine
+	}{
+		{"", "", []DiffLine{}},
+		{"a", "a", []DiffLine{{DiffEqual, "a"}}},
+		{"", "a\nb", []DiffLine{{DiffInsert, "a"}, {DiffInsert, "b"}}},
+		{"a\nb", "", []DiffLine{{DiffDelete, "a"}, {DiffDelete, "b"}}},
+		{"a\nb\nc", "a\nc", []DiffLine{{DiffEqual, "a"}, {DiffDelete, "b"}, {DiffEqual, "c"}}},
+		{"a\nb\nc\nd", "a\nx\nc\ny", []DiffLine{
+			{DiffEqual, "a"}, {DiffDelete, "b"}, {DiffInsert, "x"}, {DiffEqual, "c"}, {DiffDelete, "d"}, {DiffInsert, "y"},
+		}},
+	}
+
+	for _, testCase := range testCases {
+		actual := DiffLines(testCase.oldText, testCase.newText)
+		assert.Equal(t, testCase.expected, actual, "%+v", testCase)
+	}
+}
@@ -0,0 +1,28 @@
+package util
+
+import "strings"
+
+// MatchETag reports whether etag is listed in the value of an If-Match or If-None-Match header.
+// "*" matches any etag. With weak set, a W/ prefix is ignored as in the weak comparison of RFC 7232,
+// which If-None-Match uses; otherwise weak tags never match, as If-Match requires.
+func MatchETag(header string, etag string, weak bool) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+
+		if strings.HasPrefix(tag, "W/") {
+			if !weak {
+				continue
+			}
+			tag = tag[len("W/"):]
+		}
+
+		if tag == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
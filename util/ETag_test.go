@@ -0,0 +1,30 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchETag(t *testing.T) {
+	testCases := []struct {
+		header   string
+		etag     string
+		weak     bool
+		expected bool
+	}{
+		{"", `"a"`, false, false},
+		{`"a"`, `"a"`, false, true},
+		{`"b"`, `"a"`, false, false},
+		{`"b", "a"`, `"a"`, false, true},
+		{"*", `"a"`, false, true},
+		{`W/"a"`, `"a"`, false, false},
+		{`W/"a"`, `"a"`, true, true},
+		{`"a"`, `W/"a"`, true, true},
+	}
+
+	for _, testCase := range testCases {
+		actual := MatchETag(testCase.header, testCase.etag, testCase.weak)
+		assert.Equal(t, testCase.expected, actual, "%+v", testCase)
+	}
+}
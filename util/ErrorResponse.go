@@ -45,3 +45,15 @@ func NewUnauthorizedResponse(w http.ResponseWriter) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusUnauthorized)
 }
+
+func NewPreconditionFailedResponse(w http.ResponseWriter) {
+	EnableCors(&w)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusPreconditionFailed)
+}
+
+func NewForbiddenResponse(w http.ResponseWriter) {
+	EnableCors(&w)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+}
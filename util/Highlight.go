@@ -0,0 +1,83 @@
+package util
+
+import (
+	"html"
+	"strings"
+)
+
+// Highlight markers wrapped around the matching words of a snippet.
+const (
+	HighlightStart = "<mark>"
+	HighlightEnd   = "</mark>"
+)
+
+// snippetContext is the number of words kept before the first match of a snippet.
+const snippetContext = 8
+
+// Highlight returns an HTML snippet of text of about maxLength bytes around its first word whose term matches,
+// with every matching word wrapped in HighlightStart and HighlightEnd, and the rest escaped.
+// Text left out is marked with an ellipsis. It returns an empty string if no word matches.
+func Highlight(text string, matches func(term string) bool, maxLength int) string {
+	tokens := Tokenize(text)
+
+	first := -1
+	for i, token := range tokens {
+		if matches(token.Term) {
+			first = i
+			break
+		}
+	}
+
+	if first < 0 {
+		return ""
+	}
+
+	// Start a few words before the match, and end on the last word that fits
+	startToken := first - snippetContext
+	if startToken < 0 {
+		startToken = 0
+	}
+
+	start := tokens[startToken].Start
+	if startToken == 0 {
+		start = 0
+	}
+
+	end := len(text)
+	if end-start > maxLength {
+		end = tokens[first].End
+		for _, token := range tokens[first+1:] {
+			if token.End-start > maxLength {
+				break
+			}
+			end = token.End
+		}
+	}
+
+	var snippet strings.Builder
+	if start > 0 {
+		snippet.WriteString("…")
+	}
+
+	position := start
+	for _, token := range tokens[startToken:] {
+		if token.End > end {
+			break
+		}
+
+		if matches(token.Term) {
+			snippet.WriteString(html.EscapeString(text[position:token.Start]))
+			snippet.WriteString(HighlightStart)
+			snippet.WriteString(html.EscapeString(text[token.Start:token.End]))
+			snippet.WriteString(HighlightEnd)
+			position = token.End
+		}
+	}
+
+	snippet.WriteString(html.EscapeString(text[position:end]))
+	if end < len(text) {
+		snippet.WriteString("…")
+	}
+
+	return snippet.String()
+}
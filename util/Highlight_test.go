@@ -0,0 +1,28 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHighlight(t *testing.T) {
+	isGo := func(term string) bool { return term == "go" }
+
+	testCases := []struct {
+		text      string
+		maxLength int
+		expected  string
+	}{
+		{"Learn Go <fast>", 100, "Learn <mark>Go</mark> &lt;fast&gt;"},
+		{"Learn Rust", 100, ""},
+		{"go go", 100, "<mark>go</mark> <mark>go</mark>"},
+		{"a b c d e f g h i j go k l m", 100, "…c d e f g h i j <mark>go</mark> k l m"},
+		{"go a b c d e f g", 6, "<mark>go</mark> a b…"},
+	}
+
+	for _, testCase := range testCases {
+		actual := Highlight(testCase.text, isGo, testCase.maxLength)
+		assert.Equal(t, testCase.expected, actual, "%+v", testCase)
+	}
+}
@@ -0,0 +1,1098 @@
+package util
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gosimple/slug"
+)
+
+// markdownAllowList lists the only elements RenderMarkdown writes, with the only attributes they may have.
+var markdownAllowList = map[string]map[string]bool{
+	"p":          {},
+	"h1":         {"id": true},
+	"h2":         {"id": true},
+	"h3":         {"id": true},
+	"h4":         {"id": true},
+	"h5":         {"id": true},
+	"h6":         {"id": true},
+	"blockquote": {},
+	"ul":         {},
+	"ol":         {"start": true},
+	"li":         {},
+	"pre":        {},
+	"code":       {"class": true},
+	"em":         {},
+	"strong":     {},
+	"del":        {},
+	"a":          {"href": true, "title": true, "rel": true},
+	"img":        {"src": true, "alt": true, "title": true},
+	"hr":         {},
+	"br":         {},
+}
+
+// URL schemes allowed in links and images. URLs without a scheme are relative and always allowed.
+var (
+	linkSchemes  = []string{"http", "https", "mailto"}
+	imageSchemes = []string{"http", "https"}
+)
+
+// maxMarkdownDepth bounds the nesting of block quotes and lists. Deeper ones are rendered as paragraphs.
+const maxMarkdownDepth = 16
+
+// maxLinkTitleLength bounds the search for the end of a link title.
+const maxLinkTitleLength = 1000
+
+var (
+	fencePattern         = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})(.*)$")
+	atxHeadingPattern    = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ ]+(.*)|)$`)
+	closingHashesPattern = regexp.MustCompile(`(?:^| +)#+ *$`)
+	thematicBreakPattern = regexp.MustCompile(`^ {0,3}(?:(?:- *){3,}|(?:\* *){3,}|(?:_ *){3,})$`)
+	setextPattern        = regexp.MustCompile(`^ {0,3}(=+|-+) *$`)
+	quotePattern         = regexp.MustCompile(`^ {0,3}> ?`)
+	listItemPattern      = regexp.MustCompile(`^( {0,3})([-*+]|[0-9]{1,9}[.)])( +|$)`)
+	languagePattern      = regexp.MustCompile(`^[A-Za-z0-9_+#.-]+$`)
+	autolinkPattern      = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9+.-]{1,31}:[^\s<>]*$`)
+	emailPattern         = regexp.MustCompile(`^[A-Za-z0-9.!#$%&'*+/=?^_{|}~-]+@[A-Za-z0-9](?:[A-Za-z0-9-]{0,61}[A-Za-z0-9])?(?:\.[A-Za-z0-9](?:[A-Za-z0-9-]{0,61}[A-Za-z0-9])?)*$`)
+	entityPattern        = regexp.MustCompile(`^&(?:#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6}|[A-Za-z][A-Za-z0-9]{1,31});`)
+)
+
+type markdownBlockKind int
+
+const (
+	paragraphBlock markdownBlockKind = iota
+	headingBlock
+	codeBlock
+	quoteBlock
+	listBlock
+	ruleBlock
+)
+
+// markdownBlock is a block of a Markdown document: its inline text, the content of a code block,
+// or the blocks in a quote or in every item of a list.
+type markdownBlock struct {
+	kind     markdownBlockKind
+	level    int
+	text     string
+	language string
+	ordered  bool
+	start    int
+	tight    bool
+	children []markdownBlock
+	items    [][]markdownBlock
+}
+
+// MarkdownHeading is a heading of a Markdown document, with its text and the id of its element in the rendered HTML.
+type MarkdownHeading struct {
+	Level  int
+	Text   string
+	Anchor string
+}
+
+// listMarker is the marker starting an item of a list, e.g. "-" or "3.".
+type listMarker struct {
+	ordered   bool
+	delimiter byte
+	start     int
+	indent    int // of the content of the item
+	empty     bool
+}
+
+// RenderMarkdown converts Markdown to HTML. Raw HTML in the source is escaped rather than interpreted, only the elements
+// and attributes of markdownAllowList are written, and links and images only keep safe URLs,
+// so the result can be embedded in a page as is.
+// Headings get an id from their text, for links to them, see AnalyzeMarkdown.
+func RenderMarkdown(source string) string {
+	w := renderMarkdown(source)
+	return w.String()
+}
+
+// AnalyzeMarkdown returns the number of words in the text of a Markdown document, the way it reads once rendered,
+// and its headings in order, with the anchors RenderMarkdown identifies them by.
+func AnalyzeMarkdown(source string) (wordCount int, headings []MarkdownHeading) {
+	w := renderMarkdown(source)
+
+	for _, field := range strings.Fields(plainText(w.String())) {
+		if strings.IndexFunc(field, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0 {
+			wordCount++
+		}
+	}
+
+	return wordCount, w.headings
+}
+
+func renderMarkdown(source string) *htmlWriter {
+	source = strings.NewReplacer("\r\n", "\n", "\r", "\n", "\x00", "�").Replace(source)
+
+	lines := strings.Split(source, "\n")
+	for i, line := range lines {
+		lines[i] = expandTabs(line)
+	}
+
+	w := &htmlWriter{headings: make([]MarkdownHeading, 0)}
+	w.blocks(parseBlocks(lines, 0), false)
+	return w
+}
+
+// expandTabs replaces the tabs in line with spaces, up to the next multiple of 4 columns.
+func expandTabs(line string) string {
+	if !strings.Contains(line, "\t") {
+		return line
+	}
+
+	var expanded strings.Builder
+	column := 0
+	for _, r := range line {
+		if r == '\t' {
+			spaces := 4 - column%4
+			expanded.WriteString(strings.Repeat(" ", spaces))
+			column += spaces
+			continue
+		}
+		expanded.WriteRune(r)
+		column++
+	}
+	return expanded.String()
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+// indentation returns the number of spaces line starts with.
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+func parseListMarker(line string) (listMarker, bool) {
+	match := listItemPattern.FindStringSubmatch(line)
+	if match == nil {
+		return listMarker{}, false
+	}
+
+	marker := listMarker{
+		delimiter: match[2][len(match[2])-1],
+		indent:    len(match[0]),
+		empty:     isBlank(line[len(match[0]):]),
+	}
+
+	// Content indented by more than 4 spaces is an indented code block in the item
+	if len(match[3]) > 4 {
+		marker.indent = len(match[1]) + len(match[2]) + 1
+	}
+
+	if marker.empty {
+		marker.indent = len(match[1]) + len(match[2]) + 1
+	}
+
+	if len(match[2]) > 1 {
+		marker.ordered = true
+		marker.start, _ = strconv.Atoi(match[2][:len(match[2])-1])
+	}
+
+	return marker, true
+}
+
+// interruptsParagraph reports whether line starts a block rather than continuing a paragraph.
+func interruptsParagraph(line string) bool {
+	if fencePattern.MatchString(line) || atxHeadingPattern.MatchString(line) ||
+		thematicBreakPattern.MatchString(line) || quotePattern.MatchString(line) {
+		return true
+	}
+
+	// Only lists starting with a bullet or 1 can interrupt a paragraph, and never with an empty item
+	marker, ok := parseListMarker(line)
+	return ok && !marker.empty && (!marker.ordered || marker.start == 1)
+}
+
+// parseFence returns the marker and the language of the line opening a fenced code block.
+func parseFence(line string) (indent int, fence string, language string, ok bool) {
+	match := fencePattern.FindStringSubmatch(line)
+	if match == nil {
+		return 0, "", "", false
+	}
+
+	info := strings.TrimSpace(match[3])
+	if match[2][0] == '`' && strings.Contains(info, "`") {
+		return 0, "", "", false
+	}
+
+	if fields := strings.Fields(info); len(fields) > 0 {
+		language = fields[0]
+	}
+
+	return len(match[1]), match[2], language, true
+}
+
+func parseBlocks(lines []string, depth int) []markdownBlock {
+	blocks := make([]markdownBlock, 0)
+
+	for i := 0; i < len(lines); {
+		line := lines[i]
+
+		if isBlank(line) {
+			i++
+			continue
+		}
+
+		if indent, fence, language, ok := parseFence(line); ok {
+			block := markdownBlock{kind: codeBlock, language: language}
+			var content strings.Builder
+			for i++; i < len(lines); i++ {
+				trimmed := strings.TrimSpace(lines[i])
+				if indentation(lines[i]) < 4 && strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+					i++
+					break
+				}
+
+				strip := indentation(lines[i])
+				if strip > indent {
+					strip = indent
+				}
+				content.WriteString(lines[i][strip:])
+				content.WriteString("\n")
+			}
+
+			block.text = content.String()
+			blocks = append(blocks, block)
+			continue
+		}
+
+		if indentation(line) >= 4 {
+			end := i
+			for j := i; j < len(lines) && (isBlank(lines[j]) || indentation(lines[j]) >= 4); j++ {
+				if !isBlank(lines[j]) {
+					end = j + 1
+				}
+			}
+
+			var content strings.Builder
+			for _, codeLine := range lines[i:end] {
+				if len(codeLine) > 4 {
+					content.WriteString(codeLine[4:])
+				}
+				content.WriteString("\n")
+			}
+
+			blocks = append(blocks, markdownBlock{kind: codeBlock, text: content.String()})
+			i = end
+			continue
+		}
+
+		if match := atxHeadingPattern.FindStringSubmatch(line); match != nil {
+			text := closingHashesPattern.ReplaceAllString(strings.TrimSpace(match[2]), "")
+			blocks = append(blocks, markdownBlock{kind: headingBlock, level: len(match[1]), text: text})
+			i++
+			continue
+		}
+
+		if thematicBreakPattern.MatchString(line) {
+			blocks = append(blocks, markdownBlock{kind: ruleBlock})
+			i++
+			continue
+		}
+
+		if depth < maxMarkdownDepth && quotePattern.MatchString(line) {
+			quoted := make([]string, 0)
+			for ; i < len(lines); i++ {
+				if marker := quotePattern.FindString(lines[i]); marker != "" {
+					quoted = append(quoted, lines[i][len(marker):])
+					continue
+				}
+
+				// Lazy continuation lines of a paragraph in the quote
+				if isBlank(lines[i]) || isBlank(quoted[len(quoted)-1]) || interruptsParagraph(lines[i]) {
+					break
+				}
+				quoted = append(quoted, lines[i])
+			}
+
+			blocks = append(blocks, markdownBlock{kind: quoteBlock, children: parseBlocks(quoted, depth+1)})
+			continue
+		}
+
+		if marker, ok := parseListMarker(line); ok && depth < maxMarkdownDepth {
+			var block markdownBlock
+			block, i = parseList(lines, i, marker, depth)
+			blocks = append(blocks, block)
+			continue
+		}
+
+		paragraph := []string{strings.TrimLeft(line, " ")}
+		level := 0
+		for i++; i < len(lines) && !isBlank(lines[i]); i++ {
+			if match := setextPattern.FindStringSubmatch(lines[i]); match != nil {
+				level = 2
+				if match[1][0] == '=' {
+					level = 1
+				}
+				i++
+				break
+			}
+
+			if interruptsParagraph(lines[i]) {
+				break
+			}
+			paragraph = append(paragraph, strings.TrimLeft(lines[i], " "))
+		}
+
+		text := strings.TrimRight(strings.Join(paragraph, "\n"), " ")
+		if level > 0 {
+			blocks = append(blocks, markdownBlock{kind: headingBlock, level: level, text: text})
+		} else {
+			blocks = append(blocks, markdownBlock{kind: paragraphBlock, text: text})
+		}
+	}
+
+	return blocks
+}
+
+// parseList parses the list starting at lines[i] with first, and returns it with the index of the line after it.
+// A list is tight, its paragraphs not wrapped in <p>, unless blank lines separate its items or the blocks in one.
+func parseList(lines []string, i int, first listMarker, depth int) (markdownBlock, int) {
+	block := markdownBlock{kind: listBlock, ordered: first.ordered, start: first.start, tight: true}
+
+	for i < len(lines) {
+		marker, ok := parseListMarker(lines[i])
+		if !ok || marker.ordered != first.ordered || marker.delimiter != first.delimiter {
+			break
+		}
+
+		item := []string{""}
+		if len(lines[i]) > marker.indent {
+			item[0] = lines[i][marker.indent:]
+		}
+
+		blank := false
+		for i++; i < len(lines); i++ {
+			line := lines[i]
+			if isBlank(line) {
+				item = append(item, "")
+				blank = true
+				continue
+			}
+
+			if indentation(line) >= marker.indent {
+				item = append(item, line[marker.indent:])
+				continue
+			}
+
+			// Lazy continuation lines of a paragraph in the item
+			if blank || interruptsParagraph(line) || listItemPattern.MatchString(line) {
+				break
+			}
+			item = append(item, line)
+		}
+
+		trailing := 0
+		for len(item) > 1 && isBlank(item[len(item)-1]) {
+			item = item[:len(item)-1]
+			trailing++
+		}
+
+		for _, line := range item[1:] {
+			if isBlank(line) {
+				block.tight = false
+			}
+		}
+
+		if trailing > 0 && i < len(lines) {
+			if next, ok := parseListMarker(lines[i]); ok && next.ordered == first.ordered && next.delimiter == first.delimiter {
+				block.tight = false
+			}
+		}
+
+		block.items = append(block.items, parseBlocks(item, depth+1))
+	}
+
+	return block, i
+}
+
+// htmlWriter writes HTML, limited to the elements and attributes of markdownAllowList,
+// and keeps the headings it writes.
+type htmlWriter struct {
+	strings.Builder
+	headings []MarkdownHeading
+	anchors  anchorSet
+}
+
+// anchorSet hands out the anchors of the headings of a document: the slug of their text, numbered when it repeats
+// like a, a-1, a-2, and "section" for headings without letters or digits.
+type anchorSet struct {
+	used map[string]bool
+	next map[string]int // next number to try for a slug
+}
+
+func (anchors *anchorSet) add(text string) string {
+	if anchors.used == nil {
+		anchors.used = make(map[string]bool)
+		anchors.next = make(map[string]int)
+	}
+
+	base := slug.Make(text)
+	if base == "" {
+		base = "section"
+	}
+
+	anchor := base
+	for anchors.used[anchor] {
+		anchors.next[base]++
+		anchor = base + "-" + strconv.Itoa(anchors.next[base])
+	}
+
+	anchors.used[anchor] = true
+	return anchor
+}
+
+// start writes the start tag of an allowed element with its allowed, non-empty attributes, given as name-value pairs.
+func (w *htmlWriter) start(tag string, attributes ...string) {
+	allowed, ok := markdownAllowList[tag]
+	if !ok {
+		return
+	}
+
+	w.WriteString("<" + tag)
+	for i := 0; i+1 < len(attributes); i += 2 {
+		if allowed[attributes[i]] && attributes[i+1] != "" {
+			w.WriteString(" " + attributes[i] + `="` + html.EscapeString(attributes[i+1]) + `"`)
+		}
+	}
+	w.WriteString(">")
+}
+
+func (w *htmlWriter) end(tag string) {
+	if _, ok := markdownAllowList[tag]; ok {
+		w.WriteString("</" + tag + ">")
+	}
+}
+
+func (w *htmlWriter) text(text string) {
+	w.WriteString(html.EscapeString(text))
+}
+
+// blocks writes blocks, with the paragraphs of tight list items unwrapped.
+func (w *htmlWriter) blocks(blocks []markdownBlock, tight bool) {
+	for i, block := range blocks {
+		switch block.kind {
+		case paragraphBlock:
+			if tight {
+				w.WriteString(renderInline(block.text, false))
+				if i < len(blocks)-1 {
+					w.WriteString("\n")
+				}
+				continue
+			}
+			w.start("p")
+			w.WriteString(renderInline(block.text, false))
+			w.end("p")
+
+		case headingBlock:
+			tag := "h" + strconv.Itoa(block.level)
+			content := renderInline(block.text, false)
+			heading := MarkdownHeading{Level: block.level, Text: plainText(content)}
+			heading.Anchor = w.anchors.add(heading.Text)
+			w.headings = append(w.headings, heading)
+
+			w.start(tag, "id", heading.Anchor)
+			w.WriteString(content)
+			w.end(tag)
+
+		case codeBlock:
+			w.start("pre")
+			if languagePattern.MatchString(block.language) {
+				w.start("code", "class", "language-"+block.language)
+			} else {
+				w.start("code")
+			}
+			w.text(block.text)
+			w.end("code")
+			w.end("pre")
+
+		case quoteBlock:
+			w.start("blockquote")
+			w.WriteString("\n")
+			w.blocks(block.children, false)
+			w.end("blockquote")
+
+		case listBlock:
+			tag := "ul"
+			if block.ordered {
+				tag = "ol"
+			}
+			if block.ordered && block.start != 1 {
+				w.start(tag, "start", strconv.Itoa(block.start))
+			} else {
+				w.start(tag)
+			}
+			w.WriteString("\n")
+
+			for _, item := range block.items {
+				w.start("li")
+				if !block.tight || (len(item) > 0 && item[0].kind != paragraphBlock) {
+					w.WriteString("\n")
+				}
+				w.blocks(item, block.tight)
+				w.end("li")
+				w.WriteString("\n")
+			}
+			w.end(tag)
+
+		case ruleBlock:
+			w.start("hr")
+		}
+
+		w.WriteString("\n")
+	}
+}
+
+type inlineKind int
+
+const (
+	textInline inlineKind = iota
+	htmlInline
+	delimiterInline
+)
+
+// inlineNode is a piece of inline content: text still to be escaped, HTML already written by an htmlWriter,
+// or a run of emphasis delimiters with the tags matched on both of its sides.
+type inlineNode struct {
+	kind      inlineKind
+	text      string
+	char      byte
+	count     int // delimiters left unmatched
+	length    int // of the whole run
+	canOpen   bool
+	canClose  bool
+	openTags  []string // outermost last
+	closeTags []string // innermost first
+}
+
+// renderInline renders the inline content of a block: code spans, emphasis, links, images and line breaks.
+// Links can't contain other links.
+func renderInline(text string, inLink bool) string {
+	codeSpans := findCodeSpans(text)
+	brackets := matchBrackets(text, codeSpans)
+
+	nodes := make([]inlineNode, 0)
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			nodes = append(nodes, inlineNode{kind: textInline, text: literal.String()})
+			literal.Reset()
+		}
+	}
+	raw := func(markup string) {
+		flush()
+		nodes = append(nodes, inlineNode{kind: htmlInline, text: markup})
+	}
+
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text) && text[i+1] == '\n':
+			raw("<br>\n")
+			i += 2
+
+		case c == '\\' && i+1 < len(text) && isASCIIPunctuation(text[i+1]):
+			literal.WriteByte(text[i+1])
+			i += 2
+
+		case c == '`':
+			if end, ok := codeSpans[i]; ok {
+				raw(renderCodeSpan(text[i:end]))
+				i = end
+				continue
+			}
+			run := i
+			for run < len(text) && text[run] == '`' {
+				run++
+			}
+			literal.WriteString(text[i:run])
+			i = run
+
+		case c == '!' && i+1 < len(text) && text[i+1] == '[' && !inLink:
+			if markup, end, ok := parseLink(text, i+1, brackets, true); ok {
+				raw(markup)
+				i = end
+				continue
+			}
+			literal.WriteByte(c)
+			i++
+
+		case c == '[' && !inLink:
+			if markup, end, ok := parseLink(text, i, brackets, false); ok {
+				raw(markup)
+				i = end
+				continue
+			}
+			literal.WriteByte(c)
+			i++
+
+		case c == '<' && !inLink:
+			if markup, end, ok := parseAutolink(text, i); ok {
+				raw(markup)
+				i = end
+				continue
+			}
+			literal.WriteByte(c)
+			i++
+
+		case c == '&':
+			if entity := entityPattern.FindString(text[i:]); entity != "" {
+				literal.WriteString(html.UnescapeString(entity))
+				i += len(entity)
+				continue
+			}
+			literal.WriteByte(c)
+			i++
+
+		case c == '*' || c == '_' || c == '~':
+			run := i
+			for run < len(text) && text[run] == c {
+				run++
+			}
+			flush()
+			nodes = append(nodes, newDelimiterRun(text, i, run))
+			i = run
+
+		case c == '\n':
+			// Trailing spaces are dropped, and two or more make a hard break
+			flush()
+			spaces := 0
+			if last := len(nodes) - 1; last >= 0 && nodes[last].kind == textInline {
+				trimmed := strings.TrimRight(nodes[last].text, " ")
+				spaces = len(nodes[last].text) - len(trimmed)
+				nodes[last].text = trimmed
+			}
+			if spaces >= 2 {
+				raw("<br>\n")
+			} else {
+				literal.WriteByte('\n')
+			}
+			i++
+
+		default:
+			literal.WriteByte(c)
+			i++
+		}
+	}
+	flush()
+
+	matchEmphasis(nodes)
+
+	var w htmlWriter
+	for _, node := range nodes {
+		switch node.kind {
+		case textInline:
+			w.text(node.text)
+		case htmlInline:
+			w.WriteString(node.text)
+		case delimiterInline:
+			for _, tag := range node.closeTags {
+				w.end(tag)
+			}
+			w.text(strings.Repeat(string(node.char), node.count))
+			for j := len(node.openTags) - 1; j >= 0; j-- {
+				w.start(node.openTags[j])
+			}
+		}
+	}
+	return w.String()
+}
+
+func isASCIIPunctuation(c byte) bool {
+	return c < utf8.RuneSelf && unicode.IsPunct(rune(c)) || strings.IndexByte("$+<=>^`|~", c) >= 0
+}
+
+// findCodeSpans returns the end of the code span starting at each backtick run that has a closing run of the same length.
+func findCodeSpans(text string) map[int]int {
+	type run struct{ start, length int }
+	runs := make([]run, 0)
+	for i := 0; i < len(text); {
+		if text[i] == '\\' && i+1 < len(text) && text[i+1] == '`' {
+			i += 2
+			continue
+		}
+		if text[i] != '`' {
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(text) && text[i] == '`' {
+			i++
+		}
+		runs = append(runs, run{start, i - start})
+	}
+
+	// The runs of every length, to find the closing run of each opening run
+	byLength := make(map[int][]int)
+	for _, r := range runs {
+		byLength[r.length] = append(byLength[r.length], r.start)
+	}
+
+	spans := make(map[int]int)
+	end := 0
+	for _, r := range runs {
+		if r.start < end {
+			continue
+		}
+
+		starts := byLength[r.length]
+		next := sort.SearchInts(starts, r.start+1)
+		if next < len(starts) {
+			end = starts[next] + r.length
+			spans[r.start] = end
+		}
+	}
+	return spans
+}
+
+func renderCodeSpan(span string) string {
+	ticks := len(span) - len(strings.TrimLeft(span, "`"))
+	content := strings.ReplaceAll(span[ticks:len(span)-ticks], "\n", " ")
+	if len(content) > 2 && content[0] == ' ' && content[len(content)-1] == ' ' && strings.Trim(content, " ") != "" {
+		content = content[1 : len(content)-1]
+	}
+
+	var w htmlWriter
+	w.start("code")
+	w.text(content)
+	w.end("code")
+	return w.String()
+}
+
+// matchBrackets returns the position of the matching ] of every [ outside code spans.
+func matchBrackets(text string, codeSpans map[int]int) map[int]int {
+	matches := make(map[int]int)
+	open := make([]int, 0)
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '`':
+			if end, ok := codeSpans[i]; ok {
+				i = end - 1
+			}
+		case '[':
+			open = append(open, i)
+		case ']':
+			if len(open) > 0 {
+				matches[open[len(open)-1]] = i
+				open = open[:len(open)-1]
+			}
+		}
+	}
+	return matches
+}
+
+// parseLink parses an inline link [text](destination "title"), or an image if image is set, starting with the [ at start.
+// It returns the link as HTML and the position after it. Links with unsafe URLs keep their text but lose the link.
+func parseLink(text string, start int, brackets map[int]int, image bool) (string, int, bool) {
+	closing, ok := brackets[start]
+	if !ok || closing+1 >= len(text) || text[closing+1] != '(' {
+		return "", 0, false
+	}
+
+	destination, title, end, ok := parseLinkTarget(text, closing+2)
+	if !ok {
+		return "", 0, false
+	}
+
+	label := text[start+1 : closing]
+	var w htmlWriter
+	if image {
+		src, _ := safeURL(destination, imageSchemes...)
+		w.start("img", "src", src, "alt", plainText(renderInline(label, true)), "title", title)
+		return w.String(), end, true
+	}
+
+	href, safe := safeURL(destination, linkSchemes...)
+	if safe {
+		w.start("a", "href", href, "title", title, "rel", "nofollow")
+	}
+	w.WriteString(renderInline(label, true))
+	if safe {
+		w.end("a")
+	}
+	return w.String(), end, true
+}
+
+// parseLinkTarget parses the destination and optional title of a link from start to the closing ),
+// and returns them with the position after the ).
+func parseLinkTarget(text string, start int) (destination string, title string, end int, ok bool) {
+	i := start
+	skipSpaces := func() {
+		for i < len(text) && (text[i] == ' ' || text[i] == '\n') {
+			i++
+		}
+	}
+	skipSpaces()
+
+	if i < len(text) && text[i] == '<' {
+		closing := strings.IndexAny(text[i+1:], ">\n")
+		if closing < 0 || text[i+1+closing] != '>' {
+			return "", "", 0, false
+		}
+		destination = text[i+1 : i+1+closing]
+		i += closing + 2
+	} else {
+		destinationStart := i
+		depth := 0
+		for ; i < len(text); i++ {
+			c := text[i]
+			if c == '\\' && i+1 < len(text) && isASCIIPunctuation(text[i+1]) {
+				i++
+				continue
+			}
+			if c == ' ' || c == '\n' || c < 0x20 || (c == ')' && depth == 0) {
+				break
+			}
+			if c == '(' {
+				depth++
+			} else if c == ')' {
+				depth--
+			}
+		}
+		destination = text[destinationStart:i]
+	}
+
+	hasSpace := i < len(text) && (text[i] == ' ' || text[i] == '\n')
+	skipSpaces()
+
+	if hasSpace && i < len(text) && (text[i] == '"' || text[i] == '\'' || text[i] == '(') {
+		closer := text[i]
+		if closer == '(' {
+			closer = ')'
+		}
+
+		titleEnd := -1
+		for j := i + 1; j < len(text) && j <= i+maxLinkTitleLength; j++ {
+			if text[j] == '\\' {
+				j++
+				continue
+			}
+			if text[j] == closer {
+				titleEnd = j
+				break
+			}
+		}
+		if titleEnd < 0 {
+			return "", "", 0, false
+		}
+
+		title = unescapeMarkdown(text[i+1 : titleEnd])
+		i = titleEnd + 1
+		skipSpaces()
+	}
+
+	if i >= len(text) || text[i] != ')' {
+		return "", "", 0, false
+	}
+
+	return unescapeMarkdown(destination), title, i + 1, true
+}
+
+// parseAutolink parses an autolink <https://…> or <name@example.com> starting with the < at start.
+func parseAutolink(text string, start int) (string, int, bool) {
+	closing := strings.IndexAny(text[start+1:], "<> \n")
+	if closing < 0 || text[start+1+closing] != '>' {
+		return "", 0, false
+	}
+
+	target := text[start+1 : start+1+closing]
+	href := ""
+	switch {
+	case autolinkPattern.MatchString(target):
+		href = target
+	case emailPattern.MatchString(target):
+		href = "mailto:" + target
+	default:
+		return "", 0, false
+	}
+
+	var w htmlWriter
+	href, safe := safeURL(href, linkSchemes...)
+	if safe {
+		w.start("a", "href", href, "rel", "nofollow")
+	}
+	w.text(target)
+	if safe {
+		w.end("a")
+	}
+	return w.String(), start + closing + 2, true
+}
+
+// safeURL returns rawURL if it is relative or uses one of schemes, and false if it uses another scheme,
+// e.g. javascript: or data:, or can't be parsed.
+func safeURL(rawURL string, schemes ...string) (string, bool) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || strings.ContainsAny(rawURL, " \t\n") {
+		return "", false
+	}
+
+	if parsed.Scheme == "" {
+		return rawURL, true
+	}
+
+	for _, scheme := range schemes {
+		if strings.EqualFold(parsed.Scheme, scheme) {
+			return rawURL, true
+		}
+	}
+	return "", false
+}
+
+// unescapeMarkdown removes the backslashes escaping punctuation.
+func unescapeMarkdown(text string) string {
+	if !strings.Contains(text, "\\") {
+		return text
+	}
+
+	var unescaped strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\\' && i+1 < len(text) && isASCIIPunctuation(text[i+1]) {
+			i++
+		}
+		unescaped.WriteByte(text[i])
+	}
+	return unescaped.String()
+}
+
+// plainText returns the text of rendered inline HTML without its tags, e.g. for the alt text of an image.
+func plainText(markup string) string {
+	var text strings.Builder
+	inTag := false
+	for _, r := range markup {
+		switch {
+		case r == '<':
+			inTag = true
+		case r == '>':
+			inTag = false
+		case !inTag:
+			text.WriteRune(r)
+		}
+	}
+	return html.UnescapeString(text.String())
+}
+
+// newDelimiterRun returns the run of emphasis delimiters text[start:end], which can open emphasis if it is left-flanking
+// and close it if it is right-flanking. Underscores can't emphasize parts of words, and tildes only strike through in pairs.
+func newDelimiterRun(text string, start, end int) inlineNode {
+	before, after := ' ', ' '
+	if start > 0 {
+		before, _ = utf8.DecodeLastRuneInString(text[:start])
+	}
+	if end < len(text) {
+		after, _ = utf8.DecodeRuneInString(text[end:])
+	}
+
+	isPunctuation := func(r rune) bool { return unicode.IsPunct(r) || unicode.IsSymbol(r) }
+	leftFlanking := !unicode.IsSpace(after) && (!isPunctuation(after) || unicode.IsSpace(before) || isPunctuation(before))
+	rightFlanking := !unicode.IsSpace(before) && (!isPunctuation(before) || unicode.IsSpace(after) || isPunctuation(after))
+
+	node := inlineNode{
+		kind:     delimiterInline,
+		char:     text[start],
+		count:    end - start,
+		length:   end - start,
+		canOpen:  leftFlanking,
+		canClose: rightFlanking,
+	}
+
+	switch node.char {
+	case '_':
+		node.canOpen = leftFlanking && (!rightFlanking || isPunctuation(before))
+		node.canClose = rightFlanking && (!leftFlanking || isPunctuation(after))
+	case '~':
+		if node.length != 2 {
+			node.canOpen, node.canClose = false, false
+		}
+	}
+	return node
+}
+
+// matchEmphasis pairs the delimiter runs among nodes into emphasis, strong emphasis and strikethrough,
+// closing each with the nearest run that can open it, as CommonMark does. Unmatched delimiters stay text.
+func matchEmphasis(nodes []inlineNode) {
+	type bottomKey struct {
+		char    byte
+		canOpen bool
+		length  int
+	}
+
+	// openers holds the positions of the runs that may still open emphasis, and bottoms, for every kind of closer,
+	// the number of them known not to match it
+	openers := make([]int, 0)
+	bottoms := make(map[bottomKey]int)
+
+	for i := range nodes {
+		closer := &nodes[i]
+		if closer.kind != delimiterInline {
+			continue
+		}
+
+		if closer.canClose {
+			key := bottomKey{closer.char, closer.canOpen, closer.length % 3}
+			for closer.count > 0 {
+				found := -1
+				for j := len(openers) - 1; j >= bottoms[key] && j >= 0; j-- {
+					opener := &nodes[openers[j]]
+					if opener.char != closer.char || opener.count == 0 {
+						continue
+					}
+
+					// The rule of 3 keeps e.g. *a**b* from matching the ** with a *
+					if (opener.canClose || closer.canOpen) && (opener.length+closer.length)%3 == 0 &&
+						(opener.length%3 != 0 || closer.length%3 != 0) {
+						continue
+					}
+
+					found = j
+					break
+				}
+
+				if found < 0 {
+					bottoms[key] = len(openers)
+					break
+				}
+
+				opener := &nodes[openers[found]]
+				used, tag := 1, "em"
+				if opener.count >= 2 && closer.count >= 2 {
+					used, tag = 2, "strong"
+				}
+				if closer.char == '~' {
+					used, tag = 2, "del"
+				}
+
+				opener.openTags = append(opener.openTags, tag)
+				closer.closeTags = append(closer.closeTags, tag)
+				opener.count -= used
+				closer.count -= used
+
+				// Runs between the opener and the closer can no longer match
+				if opener.count > 0 {
+					openers = openers[:found+1]
+				} else {
+					openers = openers[:found]
+				}
+				for key, bottom := range bottoms {
+					if bottom > len(openers) {
+						bottoms[key] = len(openers)
+					}
+				}
+			}
+		}
+
+		if closer.canOpen && closer.count > 0 {
+			openers = append(openers, i)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package util
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	testCases := []struct {
+		source   string
+		expected string
+	}{
+		{"", ""},
+		{"Hello *world*, **bold** and ~~gone~~", "<p>Hello <em>world</em>, <strong>bold</strong> and <del>gone</del></p>\n"},
+		{"***both*** *a**b* snake_case_word", "<p><em><strong>both</strong></em> <em>a**b</em> snake_case_word</p>\n"},
+		{"# Title #\n\nSub\n---", "<h1 id=\"title\">Title</h1>\n<h2 id=\"sub\">Sub</h2>\n"},
+		{"one\ntwo  \nthree\\\nfour", "<p>one\ntwo<br>\nthree<br>\nfour</p>\n"},
+		{"`a <b>` \\*not\\*", "<p><code>a &lt;b&gt;</code> *not*</p>\n"},
+		{"```go\nif a < b {}\n```", "<pre><code class=\"language-go\">if a &lt; b {}\n</code></pre>\n"},
+		{"    indented\n\n    code", "<pre><code>indented\n\ncode\n</code></pre>\n"},
+		{"> quoted\nlazy\n> > nested", "<blockquote>\n<p>quoted\nlazy</p>\n<blockquote>\n<p>nested</p>\n</blockquote>\n</blockquote>\n"},
+		{"- a\n- b\n  - c", "<ul>\n<li>a</li>\n<li>b\n<ul>\n<li>c</li>\n</ul>\n</li>\n</ul>\n"},
+		{"3. three\n\n4. four", "<ol start=\"3\">\n<li>\n<p>three</p>\n</li>\n<li>\n<p>four</p>\n</li>\n</ol>\n"},
+		{"a\n\n***\n\nb", "<p>a</p>\n<hr>\n<p>b</p>\n"},
+		{"[site](https://example.com \"Example\")", "<p><a href=\"https://example.com\" title=\"Example\" rel=\"nofollow\">site</a></p>\n"},
+		{"![logo *x*](/logo.png)", "<p><img src=\"/logo.png\" alt=\"logo x\"></p>\n"},
+		{"<https://example.com?a=1&b=2> <jake@example.com>", "<p><a href=\"https://example.com?a=1&amp;b=2\" rel=\"nofollow\">https://example.com?a=1&amp;b=2</a> <a href=\"mailto:jake@example.com\" rel=\"nofollow\">jake@example.com</a></p>\n"},
+		{"&copy; &amp; &bogus;", "<p>© &amp; &amp;bogus;</p>\n"},
+
+		// Nothing executable gets through
+		{"<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n"},
+		{"<img src=x onerror=alert(1)>", "<p>&lt;img src=x onerror=alert(1)&gt;</p>\n"},
+		{"[click](javascript:alert(1))", "<p>click</p>\n"},
+		{"[click](JavaScript:alert(1)) <javascript:alert(1)>", "<p>click javascript:alert(1)</p>\n"},
+		{"[click](javascript&#58;alert(1))", "<p><a href=\"javascript&amp;#58;alert(1)\" rel=\"nofollow\">click</a></p>\n"},
+		{"![x](data:image/svg+xml;base64,PHN2Zz4=)", "<p><img alt=\"x\"></p>\n"},
+		{"[x](\"onmouseover=\"alert(1))", "<p><a href=\"&#34;onmouseover=&#34;alert(1)\" rel=\"nofollow\">x</a></p>\n"},
+		{"```\"><script>\nx\n```", "<pre><code>x\n</code></pre>\n"},
+	}
+
+	for _, testCase := range testCases {
+		actual := RenderMarkdown(testCase.source)
+		assert.Equal(t, testCase.expected, actual, "%q", testCase.source)
+	}
+}
+
+func TestRenderMarkdownNesting(t *testing.T) {
+	// Deep nesting is bounded rather than recursing without limit
+	actual := RenderMarkdown(strings.Repeat(">", 1000) + " deep")
+	assert.Equal(t, maxMarkdownDepth, strings.Count(actual, "<blockquote>"))
+
+	actual = RenderMarkdown(strings.Repeat("*a ", 10000) + strings.Repeat("b* ", 10000))
+	assert.Equal(t, 10000, strings.Count(actual, "<em>"))
+}
+
+func TestAnalyzeMarkdown(t *testing.T) {
+	wordCount, headings := AnalyzeMarkdown("# Intro\n\nSome *emphasized* words, a [link](https://example.com/a/b) and `code`.\n\n" +
+		"## Intro\n\n- one - two\n\n## Intro 1\n\n### C++ & *Go*\n\n# !!!")
+	assert.Equal(t, 15, wordCount)
+	assert.Equal(t, []MarkdownHeading{
+		{Level: 1, Text: "Intro", Anchor: "intro"},
+		{Level: 2, Text: "Intro", Anchor: "intro-1"},
+		{Level: 2, Text: "Intro 1", Anchor: "intro-1-1"},
+		{Level: 3, Text: "C++ & Go", Anchor: "c-and-go"},
+		{Level: 1, Text: "!!!", Anchor: "section"},
+	}, headings)
+
+	wordCount, headings = AnalyzeMarkdown("")
+	assert.Equal(t, 0, wordCount)
+	assert.Empty(t, headings)
+}
@@ -6,3 +6,10 @@ func MaxInt(x, y int) int {
 	}
 	return x
 }
+
+func MinInt(x, y int) int {
+	if x > y {
+		return y
+	}
+	return x
+}
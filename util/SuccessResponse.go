@@ -17,6 +17,7 @@ func EnableCors(w *http.ResponseWriter) {
 	(*w).Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
 	(*w).Header().Set("Access-Control-Allow-Credentials", "true")
    	(*w).Header().Set("Access-Control-Allow-Headers", "*")
+	(*w).Header().Set("Access-Control-Expose-Headers", "ETag, Link")
 }
 
 func NewSuccessResponse(body interface{}, w http.ResponseWriter, r *http.Request) {
@@ -47,3 +48,8 @@ func NewSuccessResponse(body interface{}, w http.ResponseWriter, r *http.Request
     w.WriteHeader(http.StatusOK)
     w.Write(message)
 }
+
+func NewNotModifiedResponse(w http.ResponseWriter) {
+	EnableCors(&w)
+	w.WriteHeader(http.StatusNotModified)
+}
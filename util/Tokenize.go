@@ -0,0 +1,74 @@
+package util
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxTermLength bounds the length in bytes of an indexed term. Longer words are rarely searched for,
+// e.g. hashes and URLs, and would only bloat the index.
+const maxTermLength = 64
+
+// Token is a word of a text: its lowercase term, and where it starts and ends in the text, in bytes.
+type Token struct {
+	Term  string
+	Start int
+	End   int
+}
+
+// Tokenize splits text into its words, i.e. its runs of letters and digits, dropping words longer than maxTermLength.
+func Tokenize(text string) []Token {
+	tokens := make([]Token, 0)
+
+	start := -1
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+
+		if start >= 0 {
+			tokens = appendToken(tokens, text, start, i)
+			start = -1
+		}
+	}
+
+	if start >= 0 {
+		tokens = appendToken(tokens, text, start, len(text))
+	}
+
+	return tokens
+}
+
+func appendToken(tokens []Token, text string, start, end int) []Token {
+	if end-start > maxTermLength {
+		return tokens
+	}
+
+	return append(tokens, Token{
+		Term:  strings.ToLower(text[start:end]),
+		Start: start,
+		End:   end,
+	})
+}
+
+// Terms returns the terms of the words of text, in order.
+func Terms(text string) []string {
+	tokens := Tokenize(text)
+
+	terms := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		terms = append(terms, token.Term)
+	}
+
+	return terms
+}
+
+// FirstRune returns the first character of s, or an empty string if s is empty.
+func FirstRune(s string) string {
+	_, size := utf8.DecodeRuneInString(s)
+	return s[:size]
+}
@@ -0,0 +1,14 @@
+package util
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(t, []Token{{"go", 0, 2}, {"über", 4, 9}, {"2", 10, 11}}, Tokenize("Go, Über-2"))
+	assert.Empty(t, Tokenize(" -- "))
+	assert.Empty(t, Tokenize(strings.Repeat("a", maxTermLength+1)))
+}